@@ -0,0 +1,190 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package awsidentity loads the content of a Teleport identity file from AWS
+// Secrets Manager or SSM Parameter Store and keeps it up to date.
+package awsidentity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/go-logr/logr"
+)
+
+// Config holds the configuration of the identity source. Exactly one of
+// SecretID and ParameterName must be set.
+type Config struct {
+	// SecretID is the name or ARN of a Secrets Manager secret holding the
+	// identity file content.
+	SecretID string
+	// ParameterName is the name or ARN of an SSM parameter holding the
+	// identity file content, decrypted if it is a SecureString.
+	ParameterName string
+	// Interval is the time between two refreshes.
+	Interval time.Duration
+	Log      logr.Logger
+}
+
+// fetchFunc returns the current identity file content.
+type fetchFunc func(ctx context.Context) (string, error)
+
+// secretsManagerAPI is the part of the Secrets Manager client used here.
+type secretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, in *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// ssmAPI is the part of the SSM client used here.
+type ssmAPI interface {
+	GetParameter(ctx context.Context, in *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// Source holds the identity file content fetched from AWS.
+type Source struct {
+	cfg   Config
+	fetch fetchFunc
+
+	mu       sync.RWMutex
+	identity string
+}
+
+// New creates a Source with the AWS default credential chain, such as IRSA
+// or EKS Pod Identity, and fetches the identity once. The region defaults to
+// the one of the secret or parameter ARN.
+func New(ctx context.Context, cfg Config) (*Source, error) {
+	if (cfg.SecretID == "") == (cfg.ParameterName == "") {
+		return nil, errors.New("exactly one of the secret and the parameter is required")
+	}
+
+	id := cfg.SecretID + cfg.ParameterName
+	var opts []func(*config.LoadOptions) error
+	if a, err := arn.Parse(id); err == nil && a.Region != "" {
+		opts = append(opts, config.WithRegion(a.Region))
+	}
+	awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+
+	var fetch fetchFunc
+	if cfg.SecretID != "" {
+		fetch = secretFetcher(secretsmanager.NewFromConfig(awsCfg), cfg.SecretID)
+	} else {
+		fetch = parameterFetcher(ssm.NewFromConfig(awsCfg), cfg.ParameterName)
+	}
+	return newSource(ctx, cfg, fetch)
+}
+
+// newSource creates a Source with the given fetch function and fetches the
+// identity once.
+func newSource(ctx context.Context, cfg Config, fetch fetchFunc) (*Source, error) {
+	s := &Source{cfg: cfg, fetch: fetch}
+	if _, err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// secretFetcher fetches the identity from a Secrets Manager secret.
+func secretFetcher(api secretsManagerAPI, id string) fetchFunc {
+	return func(ctx context.Context) (string, error) {
+		out, err := api.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+		if err != nil {
+			return "", fmt.Errorf("failed to get secret %q: %w", id, err)
+		}
+		if out.SecretString != nil {
+			return *out.SecretString, nil
+		}
+		return string(out.SecretBinary), nil
+	}
+}
+
+// parameterFetcher fetches the identity from an SSM parameter.
+func parameterFetcher(api ssmAPI, name string) fetchFunc {
+	return func(ctx context.Context) (string, error) {
+		out, err := api.GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String(name), WithDecryption: aws.Bool(true)})
+		if err != nil {
+			return "", fmt.Errorf("failed to get parameter %q: %w", name, err)
+		}
+		if out.Parameter == nil {
+			return "", fmt.Errorf("parameter %q has no value", name)
+		}
+		return aws.ToString(out.Parameter.Value), nil
+	}
+}
+
+// Identity returns the last fetched identity file content.
+func (s *Source) Identity() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.identity
+}
+
+// refresh fetches the identity and reports whether it changed.
+func (s *Source) refresh(ctx context.Context) (bool, error) {
+	identity, err := s.fetch(ctx)
+	if err != nil {
+		return false, err
+	}
+	if identity == "" {
+		return false, errors.New("identity is empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := identity != s.identity
+	s.identity = identity
+	return changed, nil
+}
+
+// Refresh fetches the identity right away, e.g. on a reload. A failed refresh
+// keeps the previous identity.
+func (s *Source) Refresh(ctx context.Context) error {
+	_, err := s.refresh(ctx)
+	return err
+}
+
+// Run refreshes the identity every interval until ctx is cancelled, and calls
+// onChange after the identity changed, e.g. to reconnect with the renewed
+// certificates. Failed refreshes keep the previous identity.
+func (s *Source) Run(ctx context.Context, onChange func()) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := s.refresh(ctx)
+			if err != nil {
+				s.cfg.Log.Error(err, "failed to refresh identity from AWS")
+				continue
+			}
+			if changed {
+				s.cfg.Log.Info("identity changed in AWS")
+				onChange()
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsidentity
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/go-logr/logr"
+)
+
+type fakeSecretsManager struct {
+	out *secretsmanager.GetSecretValueOutput
+}
+
+func (f fakeSecretsManager) GetSecretValue(_ context.Context, in *secretsmanager.GetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	if aws.ToString(in.SecretId) != "teleport-identity" {
+		return nil, errors.New("secret not found")
+	}
+	return f.out, nil
+}
+
+type fakeSSM struct{}
+
+func (fakeSSM) GetParameter(_ context.Context, in *ssm.GetParameterInput, _ ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	if !aws.ToBool(in.WithDecryption) {
+		return nil, errors.New("expected decryption")
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssmtypes.Parameter{Value: aws.String("identity")}}, nil
+}
+
+func TestFetchers(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		fetch   fetchFunc
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "secret string",
+			fetch: secretFetcher(fakeSecretsManager{&secretsmanager.GetSecretValueOutput{SecretString: aws.String("identity")}}, "teleport-identity"),
+			want:  "identity",
+		},
+		{
+			name:  "secret binary",
+			fetch: secretFetcher(fakeSecretsManager{&secretsmanager.GetSecretValueOutput{SecretBinary: []byte("identity")}}, "teleport-identity"),
+			want:  "identity",
+		},
+		{
+			name:    "missing secret",
+			fetch:   secretFetcher(fakeSecretsManager{}, "other"),
+			wantErr: true,
+		},
+		{
+			name:  "parameter",
+			fetch: parameterFetcher(fakeSSM{}, "/teleport/identity"),
+			want:  "identity",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.fetch(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("fetch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_RequiresOneSource(t *testing.T) {
+	if _, err := New(context.Background(), Config{}); err == nil {
+		t.Error("expected an error without secret and parameter")
+	}
+	if _, err := New(context.Background(), Config{SecretID: "a", ParameterName: "b"}); err == nil {
+		t.Error("expected an error with both secret and parameter")
+	}
+}
+
+func TestSource_Run(t *testing.T) {
+	identities := make(chan string, 3)
+	identities <- "v1"
+	fetch := func(context.Context) (string, error) {
+		select {
+		case identity := <-identities:
+			return identity, nil
+		default:
+			return "", errors.New("unavailable")
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, err := newSource(ctx, Config{Interval: time.Millisecond, Log: logr.Discard()}, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Identity(); got != "v1" {
+		t.Fatalf("Identity() = %q, want v1", got)
+	}
+
+	changed := make(chan struct{}, 1)
+	go s.Run(ctx, func() { changed <- struct{}{} })
+
+	// Unchanged identities and failed refreshes do not trigger onChange
+	identities <- "v1"
+	identities <- "v2"
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected onChange after the identity changed")
+	}
+	if got := s.Identity(); got != "v2" {
+		t.Errorf("Identity() = %q, want v2", got)
+	}
+	select {
+	case <-changed:
+		t.Error("expected a single onChange call")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"time"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func (c *Collector) updateAccessRequestMetrics(clusterName string, requests []teleport.AccessRequestInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inventory.AccessRequests = requests
+
+	now := time.Now()
+	pending, breached := 0, 0
+	var oldest time.Duration
+	roleCounts := make(map[string]int)
+	pendingRoleCounts := make(map[string]int)
+	for _, req := range requests {
+		for _, role := range req.Roles {
+			roleCounts[role]++
+			// Roles with requests but none pending are exported as 0
+			if req.State == teleport.AccessRequestPending {
+				pendingRoleCounts[role]++
+			} else if _, ok := pendingRoleCounts[role]; !ok {
+				pendingRoleCounts[role] = 0
+			}
+		}
+		if req.State != teleport.AccessRequestPending {
+			continue
+		}
+		pending++
+		age := now.Sub(req.Created)
+		if age > c.accessRequestSLA {
+			breached++
+		}
+		oldest = max(oldest, age)
+	}
+
+	metrics.AccessRequestsTotal.WithLabelValues(clusterName).Set(float64(len(requests)))
+	metrics.AccessRequestsPendingTotal.WithLabelValues(clusterName).Set(float64(pending))
+	metrics.AccessRequestsSLABreachedTotal.WithLabelValues(clusterName).Set(float64(breached))
+	metrics.AccessRequestOldestPendingAge.WithLabelValues(clusterName).Set(oldest.Seconds())
+	c.lastAccessRequestRoles = applyCounts(metrics.AccessRequestsByRole, clusterName, roleCounts, c.lastAccessRequestRoles)
+	c.lastPendingRequestRoles = applyCounts(metrics.AccessRequestsPendingByRole, clusterName, pendingRoleCounts, c.lastPendingRequestRoles)
+	c.log.V(1).Info("updated access request metrics", "count", len(requests), "pending", pending, "slaBreached", breached)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestCollector_UpdateAccessRequestMetrics(t *testing.T) {
+	metrics.AccessRequestsTotal.Reset()
+	metrics.AccessRequestsPendingTotal.Reset()
+	metrics.AccessRequestsSLABreachedTotal.Reset()
+	metrics.AccessRequestOldestPendingAge.Reset()
+
+	now := time.Now()
+	c := newTestCollector()
+	c.accessRequestSLA = time.Hour
+	c.updateAccessRequestMetrics("test-cluster", []teleport.AccessRequestInfo{
+		{Name: "recent", User: "alice", State: teleport.AccessRequestPending, Created: now.Add(-time.Minute)},
+		{Name: "overdue", User: "bob", State: teleport.AccessRequestPending, Created: now.Add(-3 * time.Hour)},
+		{Name: "approved", User: "carol", State: teleport.AccessRequestApproved, Created: now.Add(-48 * time.Hour)},
+	})
+
+	if got := testutil.ToFloat64(metrics.AccessRequestsTotal.WithLabelValues("test-cluster")); got != 3 {
+		t.Errorf("expected AccessRequestsTotal to be 3, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.AccessRequestsPendingTotal.WithLabelValues("test-cluster")); got != 2 {
+		t.Errorf("expected AccessRequestsPendingTotal to be 2, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.AccessRequestsSLABreachedTotal.WithLabelValues("test-cluster")); got != 1 {
+		t.Errorf("expected AccessRequestsSLABreachedTotal to be 1, got %f", got)
+	}
+	// The approved request is older, but no longer waits for a review
+	if got := testutil.ToFloat64(metrics.AccessRequestOldestPendingAge.WithLabelValues("test-cluster")); got < 3*3600 || got > 4*3600 {
+		t.Errorf("expected the oldest pending request to be 3h old, got %fs", got)
+	}
+
+	c.updateAccessRequestMetrics("test-cluster", nil)
+	if got := testutil.ToFloat64(metrics.AccessRequestOldestPendingAge.WithLabelValues("test-cluster")); got != 0 {
+		t.Errorf("expected no age without pending requests, got %f", got)
+	}
+}
+
+func TestCollector_UpdateAccessRequestMetrics_Roles(t *testing.T) {
+	metrics.AccessRequestsByRole.Reset()
+	metrics.AccessRequestsPendingByRole.Reset()
+
+	c := newTestCollector()
+	c.updateAccessRequestMetrics("test-cluster", []teleport.AccessRequestInfo{
+		{Name: "first", User: "alice", Roles: []string{"admin", "dba"}, State: teleport.AccessRequestPending, Created: time.Now()},
+		{Name: "second", User: "bob", Roles: []string{"admin"}, State: teleport.AccessRequestApproved, Created: time.Now()},
+		{Name: "third", User: "carol", Roles: []string{"auditor"}, State: teleport.AccessRequestDenied, Created: time.Now()},
+	})
+
+	for role, want := range map[string]float64{"admin": 2, "dba": 1, "auditor": 1} {
+		if got := testutil.ToFloat64(metrics.AccessRequestsByRole.WithLabelValues("test-cluster", role)); got != want {
+			t.Errorf("expected %v requests for role %s, got %f", want, role, got)
+		}
+	}
+	// Roles without pending requests are exported as 0
+	for role, want := range map[string]float64{"admin": 1, "dba": 1, "auditor": 0} {
+		if got := testutil.ToFloat64(metrics.AccessRequestsPendingByRole.WithLabelValues("test-cluster", role)); got != want {
+			t.Errorf("expected %v pending requests for role %s, got %f", want, role, got)
+		}
+	}
+	if got := testutil.CollectAndCount(metrics.AccessRequestsPendingByRole); got != 3 {
+		t.Errorf("expected 3 pending series, got %d", got)
+	}
+
+	// Roles no longer requested are removed
+	c.updateAccessRequestMetrics("test-cluster", nil)
+	if got := testutil.CollectAndCount(metrics.AccessRequestsByRole); got != 0 {
+		t.Errorf("expected no series without requests, got %d", got)
+	}
+	if got := testutil.CollectAndCount(metrics.AccessRequestsPendingByRole); got != 0 {
+		t.Errorf("expected no pending series without requests, got %d", got)
+	}
+}
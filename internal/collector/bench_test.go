@@ -0,0 +1,77 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	"github.com/giantswarm/teleport-exporter/internal/mock"
+)
+
+// benchmarkSizes are the numbers of nodes of the benchmarks; the other
+// resource types scale with them.
+var benchmarkSizes = []int{10000, 100000}
+
+// benchmarkCounts returns an inventory of n nodes with proportionate numbers
+// of the other resource types.
+func benchmarkCounts(n int) mock.Counts {
+	return mock.Counts{Nodes: n, KubeClusters: n / 100, Databases: n / 10, Apps: n / 10}
+}
+
+func BenchmarkCollect(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("nodes=%d", n), func(b *testing.B) {
+			c := New(Config{TeleportClient: mock.New(benchmarkCounts(n)), Log: logr.Discard()})
+			for b.Loop() {
+				if err := c.CollectOnce(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkCollect_Churn replaces 10% of the resources per collection, so
+// that every collection also deletes and adds *_info series.
+func BenchmarkCollect_Churn(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("nodes=%d", n), func(b *testing.B) {
+			c := New(Config{TeleportClient: mock.NewChurning(benchmarkCounts(n), 0.1), Log: logr.Discard()})
+			for b.Loop() {
+				if err := c.CollectOnce(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUpdateNodeMetrics(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("nodes=%d", n), func(b *testing.B) {
+			c := newTestCollector()
+			nodes := mock.Nodes(0, n, n/100)
+			for b.Loop() {
+				c.updateNodeMetrics(mock.ClusterName, nodes)
+			}
+		})
+	}
+}
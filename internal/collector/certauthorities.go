@@ -0,0 +1,39 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func (c *Collector) updateCertAuthorityMetrics(clusterName string, cas []teleport.CertAuthorityInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inventory.CertAuthorities = cas
+
+	current := make(infoSeries, len(cas))
+	expiries := make(map[seriesKey]float64, len(cas))
+	for _, ca := range cas {
+		key := seriesKey{name: ca.ClusterName, part: ca.Type}
+		current[key] = []string{clusterName, ca.ClusterName, ca.Type}
+		expiries[key] = float64(ca.Expires.Unix())
+	}
+	c.lastCertAuthorityExpiry = applyGaugeSeries(metrics.CertAuthorityExpiry, current, expiries, c.lastCertAuthorityExpiry)
+	c.log.V(1).Info("updated cert authority metrics", "count", len(cas))
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestCollector_UpdateCertAuthorityMetrics(t *testing.T) {
+	metrics.CertAuthorityExpiry.Reset()
+
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newTestCollector()
+	c.countsOnly = true
+	c.updateCertAuthorityMetrics("test-cluster", []teleport.CertAuthorityInfo{
+		{ClusterName: "test-cluster", Type: "host", Expires: expires},
+		{ClusterName: "test-cluster", Type: "user", Expires: expires.AddDate(1, 0, 0)},
+		{ClusterName: "leaf", Type: "host", Expires: expires},
+	})
+
+	if got := testutil.ToFloat64(metrics.CertAuthorityExpiry.WithLabelValues("test-cluster", "leaf", "host")); got != float64(expires.Unix()) {
+		t.Errorf("expected the expiry of the host CA of leaf, got %f", got)
+	}
+	if got := testutil.CollectAndCount(metrics.CertAuthorityExpiry); got != 3 {
+		t.Errorf("expected 3 expiry series also in counts-only mode, got %d", got)
+	}
+
+	// CAs of leaf clusters no longer trusted are removed
+	c.updateCertAuthorityMetrics("test-cluster", []teleport.CertAuthorityInfo{
+		{ClusterName: "test-cluster", Type: "host", Expires: expires},
+	})
+	if got := testutil.CollectAndCount(metrics.CertAuthorityExpiry); got != 1 {
+		t.Errorf("expected 1 expiry series, got %d", got)
+	}
+}
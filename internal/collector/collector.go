@@ -18,11 +18,15 @@ package collector
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math/rand"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/giantswarm/teleport-exporter/internal/metrics"
 	"github.com/giantswarm/teleport-exporter/internal/teleport"
@@ -31,44 +35,349 @@ import (
 const (
 	// maxBackoffMultiplier is the maximum multiplier for exponential backoff
 	maxBackoffMultiplier = 8
-	// jitterFraction is the fraction of the interval to use for jitter (0.1 = 10%)
-	jitterFraction = 0.1
+	// DefaultJitterFraction is the default fraction of the interval to use for
+	// jitter (0.1 = 10%)
+	DefaultJitterFraction = 0.1
+	// heartbeatInterval is how often the collector loop beats while waiting
+	heartbeatInterval = 10 * time.Second
+	// defaultRetryBackoff is the delay before the first retry of a failed API
+	// call within a collection, doubled for each further retry
+	defaultRetryBackoff = time.Second
 )
 
+// Resource types used as the "resource" label of the exporter health metrics.
+const (
+	resourceCluster         = "cluster"
+	resourceNodes           = "nodes"
+	resourceKubeClusters    = "kubernetes_clusters"
+	resourceDatabases       = "databases"
+	resourceApps            = "apps"
+	resourceUsers           = "users"
+	resourceLocks           = "locks"
+	resourceRoles           = "roles"
+	resourceTokens          = "tokens"
+	resourceAccessRequests  = "access_requests"
+	resourceSessions        = "sessions"
+	resourceRemoteClusters  = "remote_clusters"
+	resourceCertAuthorities = "cert_authorities"
+)
+
+// checks maps the resource types to the check label of
+// teleport_exporter_check_up, which has no cluster_name label so that it
+// keeps its series while the cluster name is unknown.
+var checks = map[string]string{
+	resourceCluster:         "connect",
+	resourceNodes:           "nodes",
+	resourceKubeClusters:    "kube",
+	resourceDatabases:       "db",
+	resourceApps:            "app",
+	resourceUsers:           "users",
+	resourceLocks:           "locks",
+	resourceRoles:           "roles",
+	resourceTokens:          "tokens",
+	resourceAccessRequests:  "access_requests",
+	resourceSessions:        "sessions",
+	resourceRemoteClusters:  "remote_clusters",
+	resourceCertAuthorities: "cert_authorities",
+}
+
+// TeleportClient is the part of the Teleport client used by the collector,
+// implemented by *teleport.Client and, in tests, by fakes.Client.
+type TeleportClient interface {
+	GetClusterName(ctx context.Context) (string, error)
+	GetNodes(ctx context.Context) ([]teleport.NodeInfo, error)
+	// ForEachNode calls fn for every node without keeping them all.
+	ForEachNode(ctx context.Context, fn func(teleport.NodeInfo)) error
+	GetKubeClusters(ctx context.Context) ([]teleport.KubeClusterInfo, error)
+	GetDatabases(ctx context.Context) ([]teleport.DatabaseInfo, error)
+	GetApps(ctx context.Context) ([]teleport.AppInfo, error)
+	GetUsers(ctx context.Context) ([]teleport.UserInfo, error)
+	GetLocks(ctx context.Context) ([]teleport.LockInfo, error)
+	GetRoles(ctx context.Context) ([]teleport.RoleInfo, error)
+	GetTokens(ctx context.Context) ([]teleport.TokenInfo, error)
+	GetAccessRequests(ctx context.Context) ([]teleport.AccessRequestInfo, error)
+	GetSessions(ctx context.Context) ([]teleport.SessionInfo, error)
+	GetRemoteClusters(ctx context.Context) ([]teleport.RemoteClusterInfo, error)
+	GetCertAuthorities(ctx context.Context) ([]teleport.CertAuthorityInfo, error)
+	// CheckAccess returns nil if the identity may read the resource type.
+	CheckAccess(ctx context.Context, resource string) error
+	// Reconnect reconnects with reloaded credentials.
+	Reconnect(ctx context.Context) error
+}
+
 // Config holds the configuration for the collector.
 type Config struct {
-	TeleportClient  *teleport.Client
+	TeleportClient  TeleportClient
 	RefreshInterval time.Duration
 	APITimeout      time.Duration
-	Log             logr.Logger
+	// JitterFraction is the fraction of the refresh interval by which each
+	// interval is randomly shortened or lengthened, e.g. 0.1 for ±10%. Zero
+	// collects at fixed intervals.
+	JitterFraction float64
+	// CollectOnStart skips the random delay before the first collection, so
+	// that metrics are available right after startup.
+	CollectOnStart bool
+	// CollectTimeout bounds a whole collection. Once it passed, the remaining
+	// resource types are skipped and the results gathered so far are kept.
+	// Zero means no deadline besides APITimeout for each call.
+	CollectTimeout time.Duration
+	// Retries is how often an API call failing with a timeout or connection
+	// error is retried within a collection before the resource type counts
+	// as failed.
+	Retries int
+	// MaxSeriesPerMetric caps the number of series of each *_info metric.
+	// Zero disables the limit.
+	MaxSeriesPerMetric int
+	// InfoLabels lists the Teleport labels copied onto the *_info metrics.
+	// It must match the configuration passed to metrics.Setup.
+	InfoLabels metrics.InfoLabels
+	// Shard selects the resource types collected by this replica.
+	Shard Shard
+	// ExtraResources lists the optional resource types to collect, see
+	// ParseExtraResources.
+	ExtraResources []string
+	// GroupBy lists the label groups to count resources by.
+	GroupBy GroupBy
+	// RequiredLabels lists the Teleport labels every node, database and
+	// application must have, to count the resources missing them.
+	RequiredLabels []string
+	// CountsOnly disables the *_info metrics, leaving only totals and
+	// breakdown counts.
+	CountsOnly bool
+	// PerServer additionally exports one *_server_info series per agent
+	// serving a Kubernetes cluster, database or application, to monitor the
+	// availability of the agents rather than the deduplicated resources.
+	PerServer bool
+	// UserWithoutMFAInfo additionally exports one series per local user
+	// without an MFA device, if users are collected.
+	UserWithoutMFAInfo bool
+	// UserLastLogin additionally exports the time each SSO user last logged
+	// in, if users are collected.
+	UserLastLogin bool
+	// AccessRequestSLA is the age after which pending access requests count
+	// as breaching the review SLA, if access requests are collected.
+	AccessRequestSLA time.Duration
+	// Redaction hides internal hostnames and addresses in the *_info metrics
+	// and the inventory.
+	Redaction Redaction
+	// PermissionDeniedInterval is how long a resource type is not collected
+	// after Teleport denied access to it. Zero treats access denied errors
+	// like any other error.
+	PermissionDeniedInterval time.Duration
+	// StateFile is the path the inventory is saved to after each
+	// collection, to be restored with RestoreState. Empty disables saving.
+	StateFile string
+	// StreamNodes counts the nodes page by page while listing them instead
+	// of keeping them, so that memory does not grow with the number of
+	// nodes. The inventory then has no nodes and the node cache is not used.
+	StreamNodes bool
+	Log         logr.Logger
+}
+
+// Status is a snapshot of the collector state for debugging. The consecutive
+// errors and backoff interval are those of the cluster name, which back off the
+// whole collection; failing resource types back off on their own.
+type Status struct {
+	ClusterName       string                    `json:"clusterName"`
+	LastSuccess       time.Time                 `json:"lastSuccess,omitzero"`
+	LastHeartbeat     time.Time                 `json:"lastHeartbeat"`
+	ConsecutiveErrors int                       `json:"consecutiveErrors"`
+	BackoffInterval   string                    `json:"backoffInterval"`
+	Resources         map[string]ResourceStatus `json:"resources"`
+}
+
+// ResourceStatus is the result of the last collection of a resource type.
+type ResourceStatus struct {
+	LastAttempt time.Time `json:"lastAttempt"`
+	LastSuccess time.Time `json:"lastSuccess"`
+	Error       string    `json:"error,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	// Class is the finer error class of Error, see teleport.ErrorClass.
+	Class string `json:"class,omitempty"`
+	// ConsecutiveErrors is the number of failed collections of the resource
+	// type since its last success.
+	ConsecutiveErrors int `json:"consecutiveErrors,omitempty"`
+	// BackoffInterval is the time between collections of the resource type
+	// while it fails.
+	BackoffInterval string `json:"backoffInterval,omitempty"`
+	// PermissionDenied is set while the resource type is not collected
+	// because Teleport denied access to it.
+	PermissionDenied bool `json:"permissionDenied,omitempty"`
+}
+
+// Inventory is the last successfully collected list of each resource type.
+type Inventory struct {
+	ClusterName     string                       `json:"clusterName"`
+	Nodes           []teleport.NodeInfo          `json:"nodes"`
+	KubeClusters    []teleport.KubeClusterInfo   `json:"kubernetesClusters"`
+	Databases       []teleport.DatabaseInfo      `json:"databases"`
+	Apps            []teleport.AppInfo           `json:"apps"`
+	Users           []teleport.UserInfo          `json:"users"`
+	Locks           []teleport.LockInfo          `json:"locks"`
+	Roles           []teleport.RoleInfo          `json:"roles"`
+	Tokens          []teleport.TokenInfo         `json:"tokens"`
+	AccessRequests  []teleport.AccessRequestInfo `json:"accessRequests"`
+	Sessions        []teleport.SessionInfo       `json:"sessions"`
+	RemoteClusters  []teleport.RemoteClusterInfo `json:"remoteClusters"`
+	CertAuthorities []teleport.CertAuthorityInfo `json:"certAuthorities"`
 }
 
 // Collector collects metrics from Teleport and exposes them to Prometheus.
 type Collector struct {
-	client          *teleport.Client
-	refreshInterval time.Duration
-	log             logr.Logger
+	client             TeleportClient
+	refreshInterval    time.Duration
+	apiTimeout         time.Duration
+	jitterFraction     float64
+	collectOnStart     bool
+	collectTimeout     time.Duration
+	retries            int
+	retryBackoff       time.Duration
+	infoLabels         metrics.InfoLabels
+	redaction          Redaction
+	maxSeriesPerMetric int
+	shard              Shard
+	extraResources     []string
+	countsOnly         bool
+	perServer          bool
+	userWithoutMFAInfo bool
+	userLastLogin      bool
+	accessRequestSLA   time.Duration
+	groupBy            GroupBy
+	requiredLabels     []string
+	deniedInterval     time.Duration
+	stateFile          string
+	streamNodes        bool
+	log                logr.Logger
 
 	// Tracking for smart metric cleanup (avoid Reset() gaps)
-	mu                     sync.RWMutex
-	lastNodesByKubeCluster map[string]struct{} // key: "kube_cluster"
-	lastKubeClusters       map[string]struct{} // key: "kube_cluster_name"
-	lastDbProtocols        map[string]struct{} // key: "protocol"
-	lastDbTypes            map[string]struct{} // key: "type"
-	lastClusterName        string
-	consecutiveErrors      int
+	mu                          sync.RWMutex
+	lastNodesByKubeCluster      countSeries         // key: "cluster_name", "kube_cluster"
+	lastNodeSubKinds            countSeries         // key: "cluster_name", "subkind"
+	lastNodeOSes                countSeries         // key: "cluster_name", "os"
+	lastNodeArches              countSeries         // key: "cluster_name", "arch"
+	lastKubeClusters            map[string]struct{} // key: "kube_cluster_name"
+	lastDbProtocols             countSeries         // key: "cluster_name", "protocol"
+	lastDbTypes                 countSeries         // key: "cluster_name", "type"
+	lastDbClouds                countSeries         // key: "cluster_name", "cloud"
+	lastDbInsecure              countSeries         // key: "cluster_name", "reason"
+	lastNodeInfo                infoSeries          // key: "node_name"
+	lastKubeClusterInfo         infoSeries          // key: "kube_cluster_name"
+	lastDatabaseInfo            infoSeries          // key: "database_name"
+	lastAppInfo                 infoSeries          // key: "app_name"
+	lastKubeServerInfo          infoSeries          // key: "kube_cluster_name", "host_id"
+	lastDatabaseServerInfo      infoSeries          // key: "database_name", "host_id"
+	lastAppServerInfo           infoSeries          // key: "app_name", "host_id"
+	lastDbInsecureInfo          infoSeries          // key: "database_name", "reason"
+	lastUserWithoutMFAInfo      infoSeries          // key: "user_name"
+	lastUserLastLogin           infoSeries          // key: "user_name"
+	lastUserLockExpiry          infoSeries          // key: "user_name"
+	lastLockTargets             countSeries         // key: "cluster_name", "target"
+	lastUserOrigins             countSeries         // key: "cluster_name", "origin"
+	lastRoleAssignments         roleAssignmentSeries
+	lastRoleRisks               countSeries         // key: "cluster_name", "risk"
+	lastTokenJoinMethods        countSeries         // key: "cluster_name", "join_method"
+	lastAccessRequestRoles      countSeries         // key: "cluster_name", "role"
+	lastPendingRequestRoles     countSeries         // key: "cluster_name", "role"
+	countedSessions             map[string]struct{} // key: session ID
+	lastRemoteClusters          infoSeries          // key: "remote_cluster"
+	lastRemoteClusterHeartbeats infoSeries          // key: "remote_cluster"
+	lastCertAuthorityExpiry     infoSeries          // key: "ca_cluster_name", "type"
+	lastNodeGroups              groupSeries
+	lastKubeClusterGroups       groupSeries
+	lastDatabaseGroups          groupSeries
+	lastAppGroups               groupSeries
+	lastNodesMissing            groupSeries
+	lastDatabasesMissing        groupSeries
+	lastAppsMissing             groupSeries
+	lastNodeOrigins             groupSeries
+	lastKubeClusterOrigins      groupSeries
+	lastDatabaseOrigins         groupSeries
+	lastAppOrigins              groupSeries
+	lastClusterName             string
+	lastSuccess                 time.Time
+	lastHeartbeat               time.Time
+	resources                   map[string]ResourceStatus // key: resource type
+	deniedUntil                 map[string]time.Time      // key: resource type
+	skippedCycles               map[string]int            // key: resource type
+	restored                    map[string]struct{}       // key: resource type
+	inventory                   Inventory
+	consecutiveErrors           int
+	// trigger holds a pending collection requested with Trigger
+	trigger chan struct{}
+}
+
+// DefaultLivenessMaxAge returns the default maximum age of the heartbeat
+// before the collector loop counts as stuck: the time a collection may take,
+// at least one refresh interval, plus a margin for the API calls outside of
+// the collection deadline, such as reconnects.
+func DefaultLivenessMaxAge(apiTimeout, collectTimeout, refreshInterval time.Duration) time.Duration {
+	return max(collectTimeout, refreshInterval) + 10*apiTimeout
 }
 
 // New creates a new Collector.
 func New(cfg Config) *Collector {
 	return &Collector{
-		client:                 cfg.TeleportClient,
-		refreshInterval:        cfg.RefreshInterval,
-		log:                    cfg.Log,
-		lastNodesByKubeCluster: make(map[string]struct{}),
-		lastKubeClusters:       make(map[string]struct{}),
-		lastDbProtocols:        make(map[string]struct{}),
-		lastDbTypes:            make(map[string]struct{}),
+		client:                      cfg.TeleportClient,
+		refreshInterval:             cfg.RefreshInterval,
+		apiTimeout:                  cfg.APITimeout,
+		jitterFraction:              cfg.JitterFraction,
+		collectOnStart:              cfg.CollectOnStart,
+		collectTimeout:              cfg.CollectTimeout,
+		retries:                     cfg.Retries,
+		retryBackoff:                defaultRetryBackoff,
+		infoLabels:                  cfg.InfoLabels,
+		redaction:                   cfg.Redaction,
+		maxSeriesPerMetric:          cfg.MaxSeriesPerMetric,
+		shard:                       cfg.Shard,
+		extraResources:              cfg.ExtraResources,
+		countsOnly:                  cfg.CountsOnly,
+		perServer:                   cfg.PerServer,
+		userWithoutMFAInfo:          cfg.UserWithoutMFAInfo,
+		userLastLogin:               cfg.UserLastLogin,
+		accessRequestSLA:            cfg.AccessRequestSLA,
+		groupBy:                     cfg.GroupBy,
+		requiredLabels:              cfg.RequiredLabels,
+		deniedInterval:              cfg.PermissionDeniedInterval,
+		stateFile:                   cfg.StateFile,
+		streamNodes:                 cfg.StreamNodes,
+		log:                         cfg.Log,
+		lastNodesByKubeCluster:      make(countSeries),
+		lastNodeSubKinds:            make(countSeries),
+		lastNodeOSes:                make(countSeries),
+		lastNodeArches:              make(countSeries),
+		lastKubeClusters:            make(map[string]struct{}),
+		lastDbProtocols:             make(countSeries),
+		lastDbTypes:                 make(countSeries),
+		lastDbClouds:                make(countSeries),
+		lastDbInsecure:              make(countSeries),
+		lastNodeInfo:                make(infoSeries),
+		lastKubeClusterInfo:         make(infoSeries),
+		lastDatabaseInfo:            make(infoSeries),
+		lastAppInfo:                 make(infoSeries),
+		lastKubeServerInfo:          make(infoSeries),
+		lastDatabaseServerInfo:      make(infoSeries),
+		lastAppServerInfo:           make(infoSeries),
+		lastDbInsecureInfo:          make(infoSeries),
+		lastUserWithoutMFAInfo:      make(infoSeries),
+		lastUserLastLogin:           make(infoSeries),
+		lastUserLockExpiry:          make(infoSeries),
+		lastLockTargets:             make(countSeries),
+		lastUserOrigins:             make(countSeries),
+		lastRoleAssignments:         make(roleAssignmentSeries),
+		lastRoleRisks:               make(countSeries),
+		lastTokenJoinMethods:        make(countSeries),
+		lastAccessRequestRoles:      make(countSeries),
+		lastPendingRequestRoles:     make(countSeries),
+		countedSessions:             make(map[string]struct{}),
+		lastRemoteClusters:          make(infoSeries),
+		lastRemoteClusterHeartbeats: make(infoSeries),
+		lastCertAuthorityExpiry:     make(infoSeries),
+		lastHeartbeat:               time.Now(),
+		resources:                   make(map[string]ResourceStatus),
+		deniedUntil:                 make(map[string]time.Time),
+		skippedCycles:               make(map[string]int),
+		restored:                    make(map[string]struct{}),
+		trigger:                     make(chan struct{}, 1),
 	}
 }
 
@@ -76,187 +385,602 @@ func New(cfg Config) *Collector {
 func (c *Collector) Run(ctx context.Context) {
 	c.log.Info("starting collector", "refreshInterval", c.refreshInterval)
 
-	// Initial collection with small random delay to avoid thundering herd on startup
-	initialJitter := time.Duration(rand.Int63n(int64(c.refreshInterval / 4)))
-	c.log.V(1).Info("waiting before initial collection", "jitter", initialJitter)
+	// Initial collection with small random delay to avoid thundering herd on
+	// startup, unless metrics are wanted right away
+	if !c.collectOnStart {
+		initialJitter := time.Duration(rand.Int63n(int64(c.refreshInterval / 4)))
+		c.log.V(1).Info("waiting before initial collection", "jitter", initialJitter)
 
-	select {
-	case <-ctx.Done():
-		return
-	case <-time.After(initialJitter):
-		c.collect(ctx)
+		if !c.waitOrTrigger(ctx, initialJitter) {
+			return
+		}
 	}
+	c.collect(ctx)
 
 	for {
 		// Calculate next interval with jitter and backoff
 		interval := c.calculateNextInterval()
 
-		select {
-		case <-ctx.Done():
+		if !c.waitOrTrigger(ctx, interval) {
 			c.log.Info("stopping collector")
 			return
-		case <-time.After(interval):
-			c.collect(ctx)
 		}
+		c.collect(ctx)
 	}
 }
 
+// Trigger requests a collection outside the schedule, e.g. after fixing the
+// role or the connectivity. The collection also retries the resource types
+// that are backing off or were denied access. It returns false if a
+// triggered collection is already pending.
+func (c *Collector) Trigger() bool {
+	select {
+	case c.trigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitOrTrigger is like wait, but also returns true as soon as a collection
+// is triggered, after clearing the backoff of all resource types.
+func (c *Collector) waitOrTrigger(ctx context.Context, d time.Duration) bool {
+	return c.waitFor(ctx, d, c.trigger)
+}
+
+// wait blocks for d, beating the heartbeat meanwhile. It returns false if ctx
+// is cancelled before d has passed.
+func (c *Collector) wait(ctx context.Context, d time.Duration) bool {
+	return c.waitFor(ctx, d, nil)
+}
+
+// waitFor implements wait and waitOrTrigger; a nil trigger never fires.
+func (c *Collector) waitFor(ctx context.Context, d time.Duration, trigger <-chan struct{}) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		c.beat()
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return true
+		case <-trigger:
+			c.log.Info("collection triggered")
+			c.clearBackoff()
+			return true
+		case <-ticker.C:
+		}
+	}
+}
+
+// clearBackoff makes the next collection collect all resource types of the
+// shard, including those backing off or denied access. Resource types still
+// denied access are disabled again by recordResult.
+func (c *Collector) clearBackoff() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for resource := range c.deniedUntil {
+		// Keep the entry, so that a success clears the metric
+		c.deniedUntil[resource] = time.Time{}
+	}
+	clear(c.skippedCycles)
+}
+
+// beat records that the collector loop is alive.
+func (c *Collector) beat() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastHeartbeat = time.Now()
+}
+
+// LastHeartbeat returns the last time the collector loop was alive. It beats
+// while waiting and after each API call of a collection, so an old heartbeat
+// means an API call is stuck.
+func (c *Collector) LastHeartbeat() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastHeartbeat
+}
+
 // calculateNextInterval returns the next polling interval with jitter and backoff.
 func (c *Collector) calculateNextInterval() time.Duration {
 	c.mu.RLock()
 	errors := c.consecutiveErrors
 	c.mu.RUnlock()
 
-	// Base interval
-	interval := c.refreshInterval
-
-	// Apply exponential backoff if we have consecutive errors
+	interval := c.backoffInterval(errors)
 	if errors > 0 {
-		multiplier := 1 << min(errors, maxBackoffMultiplier) // 2^errors, capped
-		interval = time.Duration(multiplier) * c.refreshInterval
 		c.log.V(1).Info("applying backoff", "consecutiveErrors", errors, "interval", interval)
 	}
 
-	// Add jitter (±10% of interval)
-	jitter := time.Duration(float64(interval) * jitterFraction * (2*rand.Float64() - 1))
+	// Add jitter (±jitterFraction of interval)
+	jitter := time.Duration(float64(interval) * c.jitterFraction * (2*rand.Float64() - 1))
 	interval += jitter
 
 	return interval
 }
 
-func (c *Collector) collect(ctx context.Context) {
+// backoffInterval returns the polling interval without jitter after the given
+// number of consecutive errors.
+func (c *Collector) backoffInterval(errors int) time.Duration {
+	return time.Duration(backoffMultiplier(errors)) * c.refreshInterval
+}
+
+// backoffMultiplier returns the number of refresh intervals to wait after the
+// given number of consecutive errors.
+func backoffMultiplier(errors int) int {
+	if errors == 0 {
+		return 1
+	}
+	return 1 << min(errors, maxBackoffMultiplier) // 2^errors, capped
+}
+
+// Status returns a snapshot of the collector state.
+func (c *Collector) Status() Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	resources := make(map[string]ResourceStatus, len(c.resources))
+	for resource, status := range c.resources {
+		resources[resource] = status
+	}
+	return Status{
+		ClusterName:       c.lastClusterName,
+		LastSuccess:       c.lastSuccess,
+		LastHeartbeat:     c.lastHeartbeat,
+		ConsecutiveErrors: c.consecutiveErrors,
+		BackoffInterval:   c.backoffInterval(c.consecutiveErrors).String(),
+		Resources:         resources,
+	}
+}
+
+// Inventory returns the last successfully collected resources. The returned
+// slices are never nil, so they encode as empty JSON lists before the first
+// collection, and must not be modified.
+func (c *Collector) Inventory() Inventory {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return Inventory{
+		ClusterName:     c.lastClusterName,
+		Nodes:           nonNil(c.inventory.Nodes),
+		KubeClusters:    nonNil(c.inventory.KubeClusters),
+		Databases:       nonNil(c.inventory.Databases),
+		Apps:            nonNil(c.inventory.Apps),
+		Users:           nonNil(c.inventory.Users),
+		Locks:           nonNil(c.inventory.Locks),
+		Roles:           nonNil(c.inventory.Roles),
+		Tokens:          nonNil(c.inventory.Tokens),
+		AccessRequests:  nonNil(c.inventory.AccessRequests),
+		Sessions:        nonNil(c.inventory.Sessions),
+		RemoteClusters:  nonNil(c.inventory.RemoteClusters),
+		CertAuthorities: nonNil(c.inventory.CertAuthorities),
+	}
+}
+
+// nonNil returns s, or an empty slice if s is nil.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// CollectOnce performs a single collection instead of running the collection
+// loop. It returns the errors of all failed API calls.
+func (c *Collector) CollectOnce(ctx context.Context) error {
+	return c.collect(ctx)
+}
+
+// CheckAccess checks which of the resource types collected by this replica
+// the identity may read, logs the result and exports it as
+// teleport_exporter_resource_access. It returns the result of the check of
+// each resource type, nil if the read succeeded. Resource types that could not
+// be checked for other reasons than a missing permission are not exported.
+func (c *Collector) CheckAccess(ctx context.Context) map[string]error {
+	results := make(map[string]error)
+	for _, resource := range append([]string{resourceCluster}, shardedResources...) {
+		if !c.owns(resource) {
+			continue
+		}
+		callCtx, cancel := c.withTimeout(ctx)
+		err := c.client.CheckAccess(callCtx, resource)
+		cancel()
+		results[resource] = err
+
+		switch {
+		case err == nil:
+			c.log.Info("access check passed", "resource", resource)
+			metrics.ResourceAccess.WithLabelValues(resource).Set(1)
+		case teleport.ErrorReason(err) == teleport.ErrorReasonPermissionDenied:
+			c.log.Info("access check failed, the role of the identity does not allow reading the resource type",
+				"resource", resource, "error", err)
+			metrics.ResourceAccess.WithLabelValues(resource).Set(0)
+			if resource != resourceCluster && c.deniedInterval > 0 {
+				c.mu.Lock()
+				c.deniedUntil[resource] = time.Now().Add(c.deniedInterval)
+				c.mu.Unlock()
+				metrics.ResourcePermissionDenied.WithLabelValues(resource).Set(1)
+			}
+		default:
+			c.log.Error(err, "failed to check access", "resource", resource)
+		}
+	}
+	return results
+}
+
+// collect performs a single collection and returns the errors of all failed
+// API calls.
+func (c *Collector) collect(ctx context.Context) error {
 	c.log.V(1).Info("collecting metrics from Teleport")
 
 	startTime := time.Now()
-	var hadErrors bool
+
+	// cycleCtx bounds the whole collection, so that slow API calls cannot
+	// freeze the collection loop
+	cycleCtx, cancelCycle := c.withCollectTimeout(ctx)
+	defer cancelCycle()
 
 	// Get cluster name
-	clusterName, err := c.client.GetClusterName(ctx)
+	callStart := time.Now()
+	clusterName, err := retry(cycleCtx, c, resourceCluster, c.client.GetClusterName)
+	c.beat()
 	if err != nil {
-		c.log.Error(err, "failed to get cluster name")
+		c.log.Error(err, "failed to get cluster name", "class", teleport.ErrorClass(err))
 		metrics.TeleportUp.Set(0)
 		// Use last known cluster name for error metrics, or "unknown" if not set
 		errorClusterName := c.lastClusterName
 		if errorClusterName == "" {
 			errorClusterName = "unknown"
 		}
-		metrics.CollectErrorsTotal.WithLabelValues(errorClusterName).Inc()
+		c.recordResult(errorClusterName, resourceCluster, callStart, err)
+		// Without the cluster name nothing is collected, so back off the
+		// whole collection
 		c.incrementErrors()
-		return
+		if needsReconnect(err) {
+			c.reconnect(ctx, err)
+		}
+		return fmt.Errorf("failed to get cluster name: %w", err)
 	}
+	c.recordResult(clusterName, resourceCluster, callStart, nil)
+	c.resetErrors()
 
 	metrics.TeleportUp.Set(1)
-	c.mu.Lock()
-	c.lastClusterName = clusterName
-	c.mu.Unlock()
-
-	// Collect nodes - on error, keep previous metrics (don't clear them)
-	nodes, err := c.client.GetNodes(ctx)
-	if err != nil {
-		c.log.Error(err, "failed to get nodes")
-		metrics.CollectErrorsTotal.WithLabelValues(clusterName).Inc()
-		hadErrors = true
-	} else {
-		c.updateNodeMetrics(clusterName, nodes)
-	}
+	c.setClusterName(clusterName)
 
-	// Collect Kubernetes clusters
-	kubeClusters, err := c.client.GetKubeClusters(ctx)
-	if err != nil {
-		c.log.Error(err, "failed to get Kubernetes clusters")
-		metrics.CollectErrorsTotal.WithLabelValues(clusterName).Inc()
-		hadErrors = true
+	// On errors, the previous metrics of a resource type are kept
+	cy := &cycle{ctx: cycleCtx, clusterName: clusterName}
+	if c.streamNodes {
+		collectResource(c, cy, resourceNodes, func(ctx context.Context) (*nodeCounts, error) {
+			return c.countNodes(ctx, clusterName)
+		}, c.applyNodeCounts)
 	} else {
-		c.updateKubeClusterMetrics(clusterName, kubeClusters)
+		collectResource(c, cy, resourceNodes, c.client.GetNodes, c.updateNodeMetrics)
 	}
+	collectResource(c, cy, resourceKubeClusters, c.client.GetKubeClusters, c.updateKubeClusterMetrics)
+	collectResource(c, cy, resourceDatabases, c.client.GetDatabases, c.updateDatabaseMetrics)
+	collectResource(c, cy, resourceApps, c.client.GetApps, c.updateAppMetrics)
+	collectResource(c, cy, resourceUsers, c.client.GetUsers, c.updateUserMetrics)
+	collectResource(c, cy, resourceLocks, c.client.GetLocks, c.updateLockMetrics)
+	collectResource(c, cy, resourceRoles, c.client.GetRoles, c.updateRoleMetrics)
+	collectResource(c, cy, resourceTokens, c.client.GetTokens, c.updateTokenMetrics)
+	collectResource(c, cy, resourceAccessRequests, c.client.GetAccessRequests, c.updateAccessRequestMetrics)
+	collectResource(c, cy, resourceSessions, c.client.GetSessions, c.updateSessionMetrics)
+	collectResource(c, cy, resourceRemoteClusters, c.client.GetRemoteClusters, c.updateRemoteClusterMetrics)
+	collectResource(c, cy, resourceCertAuthorities, c.client.GetCertAuthorities, c.updateCertAuthorityMetrics)
 
-	// Collect databases
-	databases, err := c.client.GetDatabases(ctx)
-	if err != nil {
-		c.log.Error(err, "failed to get databases")
-		metrics.CollectErrorsTotal.WithLabelValues(clusterName).Inc()
-		hadErrors = true
-	} else {
-		c.updateDatabaseMetrics(clusterName, databases)
+	// Expose the updated resource metrics to scrapes in one step
+	if err := metrics.Publish(); err != nil {
+		c.log.Error(err, "failed to publish metrics")
 	}
-
-	// Collect applications
-	apps, err := c.client.GetApps(ctx)
-	if err != nil {
-		c.log.Error(err, "failed to get applications")
-		metrics.CollectErrorsTotal.WithLabelValues(clusterName).Inc()
-		hadErrors = true
-	} else {
-		c.updateAppMetrics(clusterName, apps)
+	if c.stateFile != "" {
+		if err := c.saveState(); err != nil {
+			c.log.Error(err, "failed to save the inventory to the state file", "path", c.stateFile)
+		}
 	}
 
 	duration := time.Since(startTime)
-	metrics.CollectDuration.WithLabelValues(clusterName).Set(duration.Seconds())
+	if errors.Is(cycleCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		c.log.Info("collection timed out, keeping the results gathered so far",
+			"timeout", c.collectTimeout, "skipped", cy.skipped)
+		metrics.CollectionTimeoutsTotal.WithLabelValues(clusterName).Inc()
+		cy.errs = append(cy.errs, fmt.Errorf("collection timed out after %s, skipped %v", c.collectTimeout, cy.skipped))
+	}
+	// Only the required resource types decide about success, so that an
+	// optional one without permissions does not fail readiness. Those backing
+	// off after errors were not retried, so they are failures too.
+	hadErrors := c.backingOff() || slices.ContainsFunc(cy.skipped, required)
 
-	if hadErrors {
-		c.incrementErrors()
-	} else {
-		c.resetErrors()
+	if cy.reconnectErr != nil {
+		c.reconnect(ctx, cy.reconnectErr)
+	}
+	if !hadErrors {
+		c.mu.Lock()
+		c.lastSuccess = time.Now()
+		c.mu.Unlock()
 		metrics.LastSuccessfulCollectTime.WithLabelValues(clusterName).Set(float64(time.Now().Unix()))
 	}
 
 	c.log.V(1).Info("metrics collection completed", "duration", duration, "hadErrors", hadErrors)
+	return errors.Join(cy.errs...)
 }
 
-// incrementErrors increases the consecutive error count for backoff calculation.
-func (c *Collector) incrementErrors() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.consecutiveErrors++
+// cycle is the state of a single collection shared by the resource types.
+type cycle struct {
+	ctx         context.Context
+	clusterName string
+	// errs are the errors of the failed API calls
+	errs []error
+	// reconnectErr is the first error that needs a new connection
+	reconnectErr error
+	// skipped are the resource types not collected because the deadline of
+	// the collection passed
+	skipped []string
 }
 
-// resetErrors resets the consecutive error count after a successful collection.
-func (c *Collector) resetErrors() {
+// collectResource lists the resources of one type with fetch, retrying
+// transient errors, and updates their metrics with update. Resource types the
+// collector does not own, or that are backing off or denied access, are not
+// fetched.
+func collectResource[T any](c *Collector, cy *cycle, resource string, fetch func(context.Context) (T, error), update func(clusterName string, resources T)) {
+	if cy.ctx.Err() != nil {
+		if c.owns(resource) {
+			cy.skipped = append(cy.skipped, resource)
+		}
+		return
+	}
+	if !c.collects(resource) {
+		return
+	}
+
+	callStart := time.Now()
+	resources, err := retry(cy.ctx, c, resource, fetch)
+	// A slow collection is alive as long as its API calls return
+	c.beat()
+	c.recordResult(cy.clusterName, resource, callStart, err)
+	switch {
+	case err == nil:
+		update(cy.clusterName, resources)
+	case c.permissionDenied(err):
+		// recordResult disabled the resource type
+	default:
+		c.log.Error(err, "failed to get resources", "resource", resource, "class", teleport.ErrorClass(err))
+		if cy.reconnectErr == nil && needsReconnect(err) {
+			cy.reconnectErr = err
+		}
+		cy.errs = append(cy.errs, fmt.Errorf("failed to get %s: %w", resource, err))
+	}
+}
+
+// setClusterName records the name of the connected cluster and exports it as
+// teleport_exporter_cluster_info, deleting the series of a previous name.
+func (c *Collector) setClusterName(clusterName string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.consecutiveErrors = 0
+	if c.lastClusterName != "" && c.lastClusterName != clusterName {
+		metrics.ClusterInfo.DeleteLabelValues(c.lastClusterName)
+	}
+	c.lastClusterName = clusterName
+	metrics.ClusterInfo.WithLabelValues(clusterName).Set(1)
 }
 
-func (c *Collector) updateNodeMetrics(clusterName string, nodes []teleport.NodeInfo) {
+// withTimeout returns a context bounded by the API timeout, so that a hung API
+// call cannot stall the collection loop.
+func (c *Collector) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.apiTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.apiTimeout)
+}
+
+// retry calls fn with a context bounded by the API timeout. Timeouts and
+// connection errors, often caused by short network or gRPC blips, are retried
+// up to c.retries times with exponential backoff, as long as ctx is not done.
+func retry[T any](ctx context.Context, c *Collector, resource string, fn func(context.Context) (T, error)) (T, error) {
+	backoff := c.retryBackoff
+	for attempt := 0; ; attempt++ {
+		callCtx, cancel := c.withTimeout(ctx)
+		result, err := fn(callCtx)
+		cancel()
+		if err == nil || attempt >= c.retries || !transient(err) {
+			return result, err
+		}
+		c.log.V(1).Info("retrying failed API call", "resource", resource, "attempt", attempt+1, "backoff", backoff, "error", err)
+		if !c.wait(ctx, backoff) {
+			return result, err
+		}
+		backoff *= 2
+	}
+}
+
+// transient reports whether err may go away when the API call is retried.
+func transient(err error) bool {
+	switch teleport.ErrorReason(err) {
+	case teleport.ErrorReasonTimeout, teleport.ErrorReasonConnection:
+		return true
+	default:
+		return false
+	}
+}
+
+// withCollectTimeout returns a context bounded by the collection timeout.
+func (c *Collector) withCollectTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.collectTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.collectTimeout)
+}
+
+// collects reports whether the resource type is collected in this
+// collection: it is owned by this replica, is not disabled after a permission
+// denied error and is not backing off after errors. Each call while backing
+// off counts as one skipped collection.
+func (c *Collector) collects(resource string) bool {
+	if !c.owns(resource) {
+		return false
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if until, denied := c.deniedUntil[resource]; denied && time.Now().Before(until) {
+		c.log.V(1).Info("skipping resource type after permission denied error", "resource", resource, "until", until)
+		return false
+	}
+	if c.skippedCycles[resource] > 0 {
+		c.skippedCycles[resource]--
+		c.log.V(1).Info("skipping resource type to back off after errors", "resource", resource,
+			"consecutiveErrors", c.resources[resource].ConsecutiveErrors, "remainingSkips", c.skippedCycles[resource])
+		return false
+	}
+	return true
+}
 
-	// Count nodes by kube cluster
-	kubeClusterCounts := make(map[string]int)
-	identifiedCount := 0
-	unidentifiedCount := 0
-
-	for _, node := range nodes {
-		kubeCluster := extractKubeCluster(node)
-		kubeClusterCounts[kubeCluster]++
-		if kubeCluster == "unknown" {
-			unidentifiedCount++
-		} else {
-			identifiedCount++
+// backingOff reports whether any required resource type failed in its last
+// collection.
+func (c *Collector) backingOff() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for resource, status := range c.resources {
+		if resource != resourceCluster && required(resource) && status.ConsecutiveErrors > 0 {
+			return true
 		}
 	}
+	return false
+}
 
-	// Update per-kube-cluster metrics
-	currentKubeClusters := make(map[string]struct{}, len(kubeClusterCounts))
-	for kubeCluster, count := range kubeClusterCounts {
-		currentKubeClusters[kubeCluster] = struct{}{}
-		metrics.NodesByKubernetesCluster.WithLabelValues(clusterName, kubeCluster).Set(float64(count))
+// permissionDenied reports whether err disables the resource type instead of
+// counting as a collection error.
+func (c *Collector) permissionDenied(err error) bool {
+	return c.deniedInterval > 0 && teleport.ErrorReason(err) == teleport.ErrorReasonPermissionDenied
+}
+
+// reconnect rebuilds the Teleport client after connection or credential
+// errors, reloading the credentials. It is called at most once per collection,
+// so the backoff of the failing resource types also applies to reconnect
+// attempts.
+func (c *Collector) reconnect(ctx context.Context, cause error) {
+	if teleport.ErrorReason(cause) == teleport.ErrorReasonCredentials {
+		c.log.Info("Teleport rejected the credentials, reloading them", "error", cause)
+		metrics.CredentialReloadsTotal.Inc()
+	}
+	if err := c.client.Reconnect(ctx); err != nil {
+		c.log.Error(err, "failed to reconnect to Teleport")
+	}
+}
+
+// needsReconnect reports whether err indicates a broken connection to Teleport
+// or expired or rotated credentials. A client that has not connected yet
+// connects in the background instead.
+func needsReconnect(err error) bool {
+	if errors.Is(err, teleport.ErrNotConnected) {
+		return false
+	}
+	switch teleport.ErrorReason(err) {
+	case teleport.ErrorReasonConnection, teleport.ErrorReasonCredentials:
+		return true
+	default:
+		return false
 	}
+}
+
+// recordResult updates the health metrics and status of a resource after the
+// API call that started at start returned err.
+func (c *Collector) recordResult(clusterName, resource string, start time.Time, err error) {
+	metrics.CollectDuration.WithLabelValues(clusterName, resource).Observe(time.Since(start).Seconds())
 
-	// Remove stale kube cluster metrics
-	for kubeCluster := range c.lastNodesByKubeCluster {
-		if _, exists := currentKubeClusters[kubeCluster]; !exists {
-			metrics.NodesByKubernetesCluster.DeleteLabelValues(c.lastClusterName, kubeCluster)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := c.resources[resource]
+	status.LastAttempt = time.Now()
+
+	if err != nil {
+		reason := teleport.ErrorReason(err)
+		metrics.ResourceUp.WithLabelValues(clusterName, resource).Set(0)
+		metrics.CheckUp.WithLabelValues(checks[resource]).Set(0)
+		status.Error = err.Error()
+		status.Reason = reason
+		status.Class = teleport.ErrorClass(err)
+		if resource != resourceCluster && c.permissionDenied(err) {
+			// Retrying every collection would not help until the role changes
+			until := time.Now().Add(c.deniedInterval)
+			c.log.Info("permission denied, not collecting the resource type for a while",
+				"resource", resource, "until", until, "error", err)
+			c.deniedUntil[resource] = until
+			metrics.ResourcePermissionDenied.WithLabelValues(resource).Set(1)
+			status.PermissionDenied = true
+		} else {
+			metrics.CollectErrorsTotal.WithLabelValues(clusterName, resource, reason).Inc()
+			status.ConsecutiveErrors++
+			if resource != resourceCluster {
+				// Back off only the failing resource type, by skipping
+				// collections until the backoff interval has passed
+				status.BackoffInterval = c.backoffInterval(status.ConsecutiveErrors).String()
+				c.skippedCycles[resource] = backoffMultiplier(status.ConsecutiveErrors) - 1
+			}
 		}
+		c.resources[resource] = status
+		return
+	}
+	if _, denied := c.deniedUntil[resource]; denied {
+		delete(c.deniedUntil, resource)
+		metrics.ResourcePermissionDenied.WithLabelValues(resource).Set(0)
 	}
-	c.lastNodesByKubeCluster = currentKubeClusters
+	if _, restored := c.restored[resource]; restored {
+		delete(c.restored, resource)
+		metrics.InventoryRestored.WithLabelValues(resource).Set(0)
+	}
+	metrics.ResourceUp.WithLabelValues(clusterName, resource).Set(1)
+	metrics.CheckUp.WithLabelValues(checks[resource]).Set(1)
+	metrics.ResourceLastSuccessTime.WithLabelValues(clusterName, resource).Set(float64(time.Now().Unix()))
+	status.LastSuccess = status.LastAttempt
+	status.Error = ""
+	status.Reason = ""
+	status.Class = ""
+	status.PermissionDenied = false
+	status.ConsecutiveErrors = 0
+	status.BackoffInterval = ""
+	c.resources[resource] = status
+}
 
-	// Update aggregate metrics
-	metrics.NodesTotal.WithLabelValues(clusterName).Set(float64(len(nodes)))
-	metrics.NodesIdentifiedTotal.WithLabelValues(clusterName).Set(float64(identifiedCount))
-	metrics.NodesUnidentifiedTotal.WithLabelValues(clusterName).Set(float64(unidentifiedCount))
+// LastSuccess returns the time of the last collection without errors of the
+// required resource types, or the zero time if there was none yet.
+func (c *Collector) LastSuccess() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastSuccess
+}
 
-	c.log.V(1).Info("updated node metrics", "count", len(nodes), "identified", identifiedCount, "unidentified", unidentifiedCount, "kubeClusters", len(kubeClusterCounts))
+// incrementErrors increases the consecutive error count of the cluster name for
+// backoff calculation.
+func (c *Collector) incrementErrors() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.consecutiveErrors++
+}
+
+// resetErrors resets the consecutive error count after the cluster name was
+// fetched successfully.
+func (c *Collector) resetErrors() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.consecutiveErrors = 0
+}
+
+// updateNodeMetrics updates the node metrics and keeps the nodes in the
+// inventory.
+func (c *Collector) updateNodeMetrics(clusterName string, nodes []teleport.NodeInfo) {
+	counts := c.newNodeCounts(clusterName)
+	for _, node := range nodes {
+		c.countNode(counts, clusterName, node)
+	}
+
+	c.mu.Lock()
+	c.inventory.Nodes = c.redaction.Nodes(nodes)
+	c.mu.Unlock()
+	c.applyNodeCounts(clusterName, counts)
 }
 
 // extractKubeCluster extracts the Kubernetes cluster name from node labels or hostname.
@@ -315,13 +1039,20 @@ func (c *Collector) updateKubeClusterMetrics(clusterName string, clusters []tele
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	clusters = c.redaction.KubeClusters(clusters)
+	c.inventory.KubeClusters = clusters
+
 	// Count MC vs WC clusters and track cluster names
 	managementCount := 0
 	workloadCount := 0
 	currentClusters := make(map[string]struct{}, len(clusters))
+	currentInfo := make(infoSeries, len(clusters))
+	currentServerInfo := make(infoSeries)
 	for _, cluster := range clusters {
 		currentClusters[cluster.Name] = struct{}{}
-		metrics.KubernetesClusterInfo.WithLabelValues(clusterName, cluster.Name).Set(1)
+		currentInfo[seriesKey{name: cluster.Name}] = append([]string{clusterName, cluster.Name},
+			labelValues(cluster.Labels, c.infoLabels.KubeCluster)...)
+		addServerSeries(currentServerInfo, clusterName, cluster.Name, cluster.Servers)
 
 		// Classify as MC (no hyphen) or WC (has hyphen)
 		if isWorkloadCluster(cluster.Name) {
@@ -331,12 +1062,15 @@ func (c *Collector) updateKubeClusterMetrics(clusterName string, clusters []tele
 		}
 	}
 
-	// Remove stale cluster info metrics
-	for kubeClusterName := range c.lastKubeClusters {
-		if _, exists := currentClusters[kubeClusterName]; !exists {
-			metrics.KubernetesClusterInfo.DeleteLabelValues(c.lastClusterName, kubeClusterName)
-		}
+	// Update cluster info metrics, removing stale ones
+	c.lastKubeClusterInfo = c.applyInfoSeries("kubernetes_cluster_info", metrics.KubernetesClusterInfo, currentInfo, c.lastKubeClusterInfo)
+	if c.perServer {
+		c.lastKubeServerInfo = c.applyInfoSeries("kubernetes_server_info", metrics.KubernetesServerInfo, currentServerInfo, c.lastKubeServerInfo)
 	}
+	c.lastKubeClusterGroups = applyGroups(metrics.KubeClustersByLabel, clusterName, c.groupBy.KubeCluster, clusters,
+		func(k teleport.KubeClusterInfo) map[string]string { return k.Labels }, c.lastKubeClusterGroups)
+	c.lastKubeClusterOrigins = applyOrigins(clusterName, resourceKubeClusters, clusters,
+		func(k teleport.KubeClusterInfo) map[string]string { return k.Labels }, c.lastKubeClusterOrigins)
 	c.lastKubeClusters = currentClusters
 
 	// Update aggregate metrics
@@ -361,9 +1095,18 @@ func (c *Collector) updateDatabaseMetrics(clusterName string, databases []telepo
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	// Check the URIs before they are redacted
+	c.updateInsecureDatabases(clusterName, databases)
+
+	databases = c.redaction.Databases(databases)
+	c.inventory.Databases = databases
+
 	// Count databases by protocol and type
 	protocolCounts := make(map[string]int)
 	typeCounts := make(map[string]int)
+	cloudCounts := make(map[string]int)
+	currentInfo := make(infoSeries, len(databases))
+	currentServerInfo := make(infoSeries)
 
 	for _, db := range databases {
 		protocol := db.Protocol
@@ -374,44 +1117,166 @@ func (c *Collector) updateDatabaseMetrics(clusterName string, databases []telepo
 		if dbType == "" {
 			dbType = "unknown"
 		}
+		cloud := db.Cloud
+		if cloud == "" {
+			cloud = "unknown"
+		}
 		protocolCounts[protocol]++
 		typeCounts[dbType]++
+		cloudCounts[cloud]++
+		currentInfo[seriesKey{name: db.Name}] = append([]string{clusterName, db.Name, protocol, dbType},
+			labelValues(db.Labels, c.infoLabels.Database)...)
+		addServerSeries(currentServerInfo, clusterName, db.Name, db.Servers)
+	}
+	c.lastDatabaseInfo = c.applyInfoSeries("database_info", metrics.DatabaseInfo, currentInfo, c.lastDatabaseInfo)
+	if c.perServer {
+		c.lastDatabaseServerInfo = c.applyInfoSeries("database_server_info", metrics.DatabaseServerInfo, currentServerInfo, c.lastDatabaseServerInfo)
+	}
+	c.lastDatabaseGroups = applyGroups(metrics.DatabasesByLabel, clusterName, c.groupBy.Database, databases,
+		func(d teleport.DatabaseInfo) map[string]string { return d.Labels }, c.lastDatabaseGroups)
+	c.lastDatabasesMissing = applyMissingLabels(clusterName, resourceDatabases, c.requiredLabels, databases,
+		func(d teleport.DatabaseInfo) map[string]string { return d.Labels }, c.lastDatabasesMissing)
+	c.lastDatabaseOrigins = applyOrigins(clusterName, resourceDatabases, databases,
+		func(d teleport.DatabaseInfo) map[string]string { return d.Labels }, c.lastDatabaseOrigins)
+
+	// Update by-protocol, by-type and by-cloud metrics, removing stale ones
+	c.lastDbProtocols = applyCounts(metrics.DatabasesByProtocolTotal, clusterName, protocolCounts, c.lastDbProtocols)
+	c.lastDbTypes = applyCounts(metrics.DatabasesByTypeTotal, clusterName, typeCounts, c.lastDbTypes)
+	c.lastDbClouds = applyCounts(metrics.DatabasesByCloudTotal, clusterName, cloudCounts, c.lastDbClouds)
+
+	// Update total
+	metrics.DatabasesTotal.WithLabelValues(clusterName).Set(float64(len(databases)))
+	c.log.V(1).Info("updated database metrics", "count", len(databases), "protocols", len(protocolCounts), "types", len(typeCounts))
+}
+
+func (c *Collector) updateAppMetrics(clusterName string, apps []teleport.AppInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	apps = c.redaction.Apps(apps)
+	c.inventory.Apps = apps
+
+	currentInfo := make(infoSeries, len(apps))
+	currentServerInfo := make(infoSeries)
+	for _, app := range apps {
+		currentInfo[seriesKey{name: app.Name}] = append([]string{clusterName, app.Name, app.PublicAddr},
+			labelValues(app.Labels, c.infoLabels.App)...)
+		addServerSeries(currentServerInfo, clusterName, app.Name, app.Servers)
+	}
+	c.lastAppInfo = c.applyInfoSeries("app_info", metrics.AppInfo, currentInfo, c.lastAppInfo)
+	if c.perServer {
+		c.lastAppServerInfo = c.applyInfoSeries("app_server_info", metrics.AppServerInfo, currentServerInfo, c.lastAppServerInfo)
 	}
+	c.lastAppGroups = applyGroups(metrics.AppsByLabel, clusterName, c.groupBy.App, apps,
+		func(a teleport.AppInfo) map[string]string { return a.Labels }, c.lastAppGroups)
+	c.lastAppsMissing = applyMissingLabels(clusterName, resourceApps, c.requiredLabels, apps,
+		func(a teleport.AppInfo) map[string]string { return a.Labels }, c.lastAppsMissing)
+	c.lastAppOrigins = applyOrigins(clusterName, resourceApps, apps,
+		func(a teleport.AppInfo) map[string]string { return a.Labels }, c.lastAppOrigins)
 
-	// Update by-protocol metrics
-	currentProtocols := make(map[string]struct{}, len(protocolCounts))
-	for protocol, count := range protocolCounts {
-		currentProtocols[protocol] = struct{}{}
-		metrics.DatabasesByProtocolTotal.WithLabelValues(clusterName, protocol).Set(float64(count))
+	metrics.AppsTotal.WithLabelValues(clusterName).Set(float64(len(apps)))
+	c.log.V(1).Info("updated application metrics", "count", len(apps))
+}
+
+// countSeries holds the label values, cluster name and counted value, of the
+// series of a count metric, so that stale series are deleted with exactly the
+// labels they were set with, whatever the values contain.
+type countSeries map[[2]string]struct{}
+
+// applyCounts sets vec to the count of each value and deletes series from
+// last that are gone, including those of a previous cluster name. It returns
+// the series to track for the next update.
+func applyCounts(vec *prometheus.GaugeVec, clusterName string, counts map[string]int, last countSeries) countSeries {
+	current := make(countSeries, len(counts))
+	for value, count := range counts {
+		series := [2]string{clusterName, value}
+		vec.WithLabelValues(series[:]...).Set(float64(count))
+		current[series] = struct{}{}
 	}
-	for protocol := range c.lastDbProtocols {
-		if _, exists := currentProtocols[protocol]; !exists {
-			metrics.DatabasesByProtocolTotal.DeleteLabelValues(c.lastClusterName, protocol)
+	for series := range last {
+		if _, exists := current[series]; !exists {
+			vec.DeleteLabelValues(series[:]...)
 		}
 	}
+	return current
+}
+
+// seriesKey identifies the series of a resource by its name and, for metrics
+// with several series per resource, what tells them apart, e.g. the host ID
+// of a server.
+type seriesKey struct {
+	name string
+	part string
+}
 
-	// Update by-type metrics
-	currentTypes := make(map[string]struct{}, len(typeCounts))
-	for dbType, count := range typeCounts {
-		currentTypes[dbType] = struct{}{}
-		metrics.DatabasesByTypeTotal.WithLabelValues(clusterName, dbType).Set(float64(count))
+// infoSeries maps a resource to the label values of its *_info series.
+type infoSeries map[seriesKey][]string
+
+// applyInfoSeries sets every series in current to 1 on vec and deletes series
+// from last that are gone or whose label values changed. If current exceeds the
+// configured series limit, no series are emitted for the metric and the dropped
+// series are counted instead. It returns the series to track for the next update.
+func (c *Collector) applyInfoSeries(metric string, vec *prometheus.GaugeVec, current, last infoSeries) infoSeries {
+	return c.applyValueSeries(metric, vec, current, func(string) float64 { return 1 }, last)
+}
+
+// applyValueSeries is applyInfoSeries for per-resource series whose value is
+// not 1 but given by value for the name of the resource.
+func (c *Collector) applyValueSeries(metric string, vec *prometheus.GaugeVec, current infoSeries, value func(name string) float64, last infoSeries) infoSeries {
+	// In counts-only mode no info series are ever set, so there is nothing to
+	// delete either
+	if c.countsOnly {
+		return last
 	}
-	for dbType := range c.lastDbTypes {
-		if _, exists := currentTypes[dbType]; !exists {
-			metrics.DatabasesByTypeTotal.DeleteLabelValues(c.lastClusterName, dbType)
+
+	if c.maxSeriesPerMetric > 0 && len(current) > c.maxSeriesPerMetric {
+		c.log.Info("series limit exceeded, dropping info metric",
+			"metric", metric, "series", len(current), "limit", c.maxSeriesPerMetric)
+		metrics.SeriesDroppedTotal.WithLabelValues(metric).Add(float64(len(current)))
+		current = infoSeries{}
+	}
+
+	for key, values := range last {
+		if currentValues, exists := current[key]; !exists || !slices.Equal(currentValues, values) {
+			vec.DeleteLabelValues(values...)
 		}
 	}
+	for key, values := range current {
+		vec.WithLabelValues(values...).Set(value(key.name))
+	}
+	return current
+}
 
-	c.lastDbProtocols = currentProtocols
-	c.lastDbTypes = currentTypes
-	c.lastClusterName = clusterName
+// applyGaugeSeries sets every series in current to the value of its key in
+// values and deletes series in last that are not in current. Unlike
+// applyValueSeries, it applies in counts-only mode and is not capped, for
+// series few enough to alert on.
+func applyGaugeSeries(vec *prometheus.GaugeVec, current infoSeries, values map[seriesKey]float64, last infoSeries) infoSeries {
+	for key, labelValues := range last {
+		if currentValues, exists := current[key]; !exists || !slices.Equal(currentValues, labelValues) {
+			vec.DeleteLabelValues(labelValues...)
+		}
+	}
+	for key, labelValues := range current {
+		vec.WithLabelValues(labelValues...).Set(values[key])
+	}
+	return current
+}
 
-	// Update total
-	metrics.DatabasesTotal.WithLabelValues(clusterName).Set(float64(len(databases)))
-	c.log.V(1).Info("updated database metrics", "count", len(databases), "protocols", len(protocolCounts), "types", len(typeCounts))
+// addServerSeries adds the *_server_info series of each server of the named
+// resource to series.
+func addServerSeries(series infoSeries, clusterName, name string, servers []teleport.ServerInfo) {
+	for _, server := range servers {
+		series[seriesKey{name, server.HostID}] = []string{clusterName, name, server.HostID, server.Hostname}
+	}
 }
 
-func (c *Collector) updateAppMetrics(clusterName string, apps []teleport.AppInfo) {
-	metrics.AppsTotal.WithLabelValues(clusterName).Set(float64(len(apps)))
-	c.log.V(1).Info("updated application metrics", "count", len(apps))
+// labelValues returns the values of the given Teleport label keys, using an
+// empty string for labels the resource does not have.
+func labelValues(labels map[string]string, keys []string) []string {
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = labels[key]
+	}
+	return values
 }
@@ -17,12 +17,18 @@ limitations under the License.
 package collector
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/gravitational/trace"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"github.com/giantswarm/teleport-exporter/internal/fakes"
 	"github.com/giantswarm/teleport-exporter/internal/metrics"
 	"github.com/giantswarm/teleport-exporter/internal/teleport"
 )
@@ -30,11 +36,43 @@ import (
 // newTestCollector creates a Collector with initialized maps for testing.
 func newTestCollector() *Collector {
 	return &Collector{
-		log:                    logr.Discard(),
-		lastNodesByKubeCluster: make(map[string]struct{}),
-		lastKubeClusters:       make(map[string]struct{}),
-		lastDbProtocols:        make(map[string]struct{}),
-		lastDbTypes:            make(map[string]struct{}),
+		log:                         logr.Discard(),
+		lastNodesByKubeCluster:      make(countSeries),
+		lastNodeSubKinds:            make(countSeries),
+		lastNodeOSes:                make(countSeries),
+		lastNodeArches:              make(countSeries),
+		lastKubeClusters:            make(map[string]struct{}),
+		lastDbProtocols:             make(countSeries),
+		lastDbTypes:                 make(countSeries),
+		lastDbClouds:                make(countSeries),
+		lastDbInsecure:              make(countSeries),
+		lastNodeInfo:                make(infoSeries),
+		lastKubeClusterInfo:         make(infoSeries),
+		lastDatabaseInfo:            make(infoSeries),
+		lastAppInfo:                 make(infoSeries),
+		lastKubeServerInfo:          make(infoSeries),
+		lastDatabaseServerInfo:      make(infoSeries),
+		lastAppServerInfo:           make(infoSeries),
+		lastDbInsecureInfo:          make(infoSeries),
+		lastUserWithoutMFAInfo:      make(infoSeries),
+		lastUserLastLogin:           make(infoSeries),
+		lastUserLockExpiry:          make(infoSeries),
+		lastLockTargets:             make(countSeries),
+		lastUserOrigins:             make(countSeries),
+		lastRoleAssignments:         make(roleAssignmentSeries),
+		lastRoleRisks:               make(countSeries),
+		lastTokenJoinMethods:        make(countSeries),
+		lastAccessRequestRoles:      make(countSeries),
+		lastPendingRequestRoles:     make(countSeries),
+		countedSessions:             make(map[string]struct{}),
+		lastRemoteClusters:          make(infoSeries),
+		lastRemoteClusterHeartbeats: make(infoSeries),
+		lastCertAuthorityExpiry:     make(infoSeries),
+		resources:                   make(map[string]ResourceStatus),
+		deniedUntil:                 make(map[string]time.Time),
+		skippedCycles:               make(map[string]int),
+		restored:                    make(map[string]struct{}),
+		trigger:                     make(chan struct{}, 1),
 	}
 }
 
@@ -118,6 +156,33 @@ func TestCollector_UpdateNodeMetrics_IdentifiedVsUnidentified(t *testing.T) {
 	}
 }
 
+func TestCollector_UpdateNodeMetrics_Subkinds(t *testing.T) {
+	metrics.NodesBySubkind.Reset()
+
+	c := newTestCollector()
+
+	// Older Teleport versions report no subkind for Teleport nodes
+	nodes := []teleport.NodeInfo{
+		{Name: "node-1", Hostname: "host1", SubKind: teleport.NodeSubKindTeleport},
+		{Name: "node-2", Hostname: "host2"},
+		{Name: "node-3", Hostname: "host3", SubKind: teleport.NodeSubKindOpenSSH},
+	}
+	c.updateNodeMetrics("test-cluster", nodes)
+
+	if got := testutil.ToFloat64(metrics.NodesBySubkind.WithLabelValues("test-cluster", teleport.NodeSubKindTeleport)); got != 2 {
+		t.Errorf("expected 2 teleport nodes, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.NodesBySubkind.WithLabelValues("test-cluster", teleport.NodeSubKindOpenSSH)); got != 1 {
+		t.Errorf("expected 1 openssh node, got %f", got)
+	}
+
+	// Subkinds without nodes are removed
+	c.updateNodeMetrics("test-cluster", nodes[:2])
+	if got := testutil.CollectAndCount(metrics.NodesBySubkind); got != 1 {
+		t.Errorf("expected 1 subkind, got %d", got)
+	}
+}
+
 func TestExtractKubeCluster(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -268,18 +333,56 @@ func TestIsWorkloadCluster(t *testing.T) {
 	}
 }
 
+func TestCollector_SetClusterName(t *testing.T) {
+	metrics.ClusterInfo.Reset()
+
+	c := newTestCollector()
+	c.setClusterName("old-cluster")
+	c.setClusterName("new-cluster")
+
+	if got := testutil.CollectAndCount(metrics.ClusterInfo); got != 1 {
+		t.Errorf("expected only the series of the current cluster, got %d series", got)
+	}
+	if value := testutil.ToFloat64(metrics.ClusterInfo.WithLabelValues("new-cluster")); value != 1 {
+		t.Errorf("expected cluster_info of new-cluster to be 1, got %f", value)
+	}
+	if c.Status().ClusterName != "new-cluster" {
+		t.Errorf("expected cluster name new-cluster, got %q", c.Status().ClusterName)
+	}
+}
+
+func TestApplyCounts(t *testing.T) {
+	metrics.DatabasesByProtocolTotal.Reset()
+
+	last := applyCounts(metrics.DatabasesByProtocolTotal, "old-cluster", map[string]int{"postgres|mysql": 1, "mongodb": 2}, nil)
+	last = applyCounts(metrics.DatabasesByProtocolTotal, "new-cluster", map[string]int{"postgres|mysql": 3}, last)
+
+	// Series of the previous cluster name are deleted with the labels they
+	// were set with, whatever the values contain
+	if got := testutil.CollectAndCount(metrics.DatabasesByProtocolTotal); got != 1 {
+		t.Errorf("expected only the series of the new cluster, got %d series", got)
+	}
+	if value := testutil.ToFloat64(metrics.DatabasesByProtocolTotal.WithLabelValues("new-cluster", "postgres|mysql")); value != 3 {
+		t.Errorf("expected count 3, got %f", value)
+	}
+	if _, ok := last[[2]string{"new-cluster", "postgres|mysql"}]; !ok || len(last) != 1 {
+		t.Errorf("expected only the new series to be tracked, got %v", last)
+	}
+}
+
 func TestCollector_UpdateDatabaseMetrics(t *testing.T) {
 	// Reset metrics before test
 	metrics.DatabasesTotal.Reset()
 	metrics.DatabasesByProtocolTotal.Reset()
 	metrics.DatabasesByTypeTotal.Reset()
+	metrics.DatabasesByCloudTotal.Reset()
 
 	c := newTestCollector()
 
 	databases := []teleport.DatabaseInfo{
-		{Name: "postgres-db-1", Protocol: "postgres", Type: "rds"},
-		{Name: "postgres-db-2", Protocol: "postgres", Type: "rds"},
-		{Name: "mysql-db", Protocol: "mysql", Type: "self-hosted"},
+		{Name: "postgres-db-1", Protocol: "postgres", Type: "rds", Cloud: teleport.DatabaseCloudAWS},
+		{Name: "postgres-db-2", Protocol: "postgres", Type: "rds", Cloud: teleport.DatabaseCloudAWS},
+		{Name: "mysql-db", Protocol: "mysql", Type: "self-hosted", Cloud: teleport.DatabaseCloudSelfHosted},
 		{Name: "mongo-db", Protocol: "mongodb", Type: "self-hosted"},
 	}
 
@@ -311,6 +414,14 @@ func TestCollector_UpdateDatabaseMetrics(t *testing.T) {
 		t.Errorf("expected DatabasesByTypeTotal for self-hosted to be 2, got %f", selfHostedCount)
 	}
 
+	// Verify by-cloud counts, databases of older states have no cloud
+	if got := testutil.ToFloat64(metrics.DatabasesByCloudTotal.WithLabelValues("test-cluster", teleport.DatabaseCloudAWS)); got != 2 {
+		t.Errorf("expected DatabasesByCloudTotal for aws to be 2, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.DatabasesByCloudTotal.WithLabelValues("test-cluster", "unknown")); got != 1 {
+		t.Errorf("expected DatabasesByCloudTotal for unknown to be 1, got %f", got)
+	}
+
 	// Verify tracking maps
 	if len(c.lastDbProtocols) != 3 {
 		t.Errorf("expected lastDbProtocols to have 3 entries, got %d", len(c.lastDbProtocols))
@@ -348,10 +459,420 @@ func TestCollector_UpdateAppMetrics(t *testing.T) {
 	}
 }
 
+func TestCollector_Inventory(t *testing.T) {
+	c := newTestCollector()
+
+	inv := c.Inventory()
+	if inv.Nodes == nil || inv.KubeClusters == nil || inv.Databases == nil || inv.Apps == nil {
+		t.Fatalf("expected empty, non-nil lists before the first collection, got %+v", inv)
+	}
+
+	c.lastClusterName = "test-cluster"
+	c.updateAppMetrics("test-cluster", []teleport.AppInfo{
+		{Name: "grafana", Labels: map[string]string{"env": "prod"}},
+	})
+
+	inv = c.Inventory()
+	if inv.ClusterName != "test-cluster" {
+		t.Errorf("expected cluster name test-cluster, got %q", inv.ClusterName)
+	}
+	if len(inv.Apps) != 1 || inv.Apps[0].Labels["env"] != "prod" {
+		t.Errorf("expected the collected app with its labels, got %+v", inv.Apps)
+	}
+	if len(inv.Nodes) != 0 {
+		t.Errorf("expected no nodes, got %+v", inv.Nodes)
+	}
+}
+
+func TestCollector_InfoLabels(t *testing.T) {
+	infoLabels := metrics.InfoLabels{Node: []string{"env", "region"}}
+	if err := metrics.Setup(nil, metrics.Options{InfoLabels: infoLabels}); err != nil {
+		t.Fatalf("Setup() failed: %v", err)
+	}
+	defer func() { _ = metrics.Setup(nil, metrics.Options{}) }()
+
+	c := newTestCollector()
+	c.infoLabels = infoLabels
+
+	c.updateNodeMetrics("test-cluster", []teleport.NodeInfo{
+		{Name: "node-1", Hostname: "host1", Labels: map[string]string{"env": "prod", "region": "eu"}},
+		{Name: "node-2", Hostname: "host2", Labels: map[string]string{"env": "dev"}},
+	})
+
+	if got := testutil.CollectAndCount(metrics.NodeInfo); got != 2 {
+		t.Errorf("expected 2 node info series, got %d", got)
+	}
+	value := testutil.ToFloat64(metrics.NodeInfo.WithLabelValues("test-cluster", "node-1", "host1", "prod", "eu"))
+	if value != 1 {
+		t.Errorf("expected node-1 info to be 1, got %f", value)
+	}
+
+	// Relabelling a node replaces its series, removing a node deletes it
+	c.updateNodeMetrics("test-cluster", []teleport.NodeInfo{
+		{Name: "node-1", Hostname: "host1", Labels: map[string]string{"env": "staging", "region": "eu"}},
+	})
+
+	if got := testutil.CollectAndCount(metrics.NodeInfo); got != 1 {
+		t.Errorf("expected 1 node info series after update, got %d", got)
+	}
+	value = testutil.ToFloat64(metrics.NodeInfo.WithLabelValues("test-cluster", "node-1", "host1", "staging", "eu"))
+	if value != 1 {
+		t.Errorf("expected relabelled node-1 info to be 1, got %f", value)
+	}
+}
+
+func TestCollector_MaxSeriesPerMetric(t *testing.T) {
+	metrics.AppInfo.Reset()
+	metrics.SeriesDroppedTotal.Reset()
+
+	c := newTestCollector()
+	c.maxSeriesPerMetric = 2
+
+	c.updateAppMetrics("test-cluster", []teleport.AppInfo{{Name: "grafana"}, {Name: "prometheus"}})
+	if got := testutil.CollectAndCount(metrics.AppInfo); got != 2 {
+		t.Errorf("expected 2 app info series within limit, got %d", got)
+	}
+
+	// Exceeding the limit drops all series of the metric
+	c.updateAppMetrics("test-cluster", []teleport.AppInfo{{Name: "grafana"}, {Name: "prometheus"}, {Name: "alertmanager"}})
+	if got := testutil.CollectAndCount(metrics.AppInfo); got != 0 {
+		t.Errorf("expected no app info series over limit, got %d", got)
+	}
+	dropped := testutil.ToFloat64(metrics.SeriesDroppedTotal.WithLabelValues("app_info"))
+	if dropped != 3 {
+		t.Errorf("expected SeriesDroppedTotal for app_info to be 3, got %f", dropped)
+	}
+
+	// The total is still reported
+	totalValue := testutil.ToFloat64(metrics.AppsTotal.WithLabelValues("test-cluster"))
+	if totalValue != 3 {
+		t.Errorf("expected AppsTotal to be 3, got %f", totalValue)
+	}
+}
+
+func TestCollector_CountsOnly(t *testing.T) {
+	metrics.NodeInfo.Reset()
+	metrics.NodesTotal.Reset()
+	metrics.SeriesDroppedTotal.Reset()
+
+	c := newTestCollector()
+	c.countsOnly = true
+	c.maxSeriesPerMetric = 1
+
+	c.updateNodeMetrics("test-cluster", []teleport.NodeInfo{
+		{Name: "node-1", Hostname: "mc-node-1"},
+		{Name: "node-2", Hostname: "mc-node-2"},
+	})
+	if got := testutil.CollectAndCount(metrics.NodeInfo); got != 0 {
+		t.Errorf("expected no node info series in counts-only mode, got %d", got)
+	}
+	if got := testutil.CollectAndCount(metrics.SeriesDroppedTotal); got != 0 {
+		t.Errorf("expected no dropped series in counts-only mode, got %d", got)
+	}
+	if got := testutil.ToFloat64(metrics.NodesTotal.WithLabelValues("test-cluster")); got != 2 {
+		t.Errorf("expected NodesTotal to be 2, got %f", got)
+	}
+}
+
+func TestCollector_PerServer(t *testing.T) {
+	metrics.DatabaseServerInfo.Reset()
+
+	servers := []teleport.ServerInfo{
+		{HostID: "host-id-1", Hostname: "agent-1"},
+		{HostID: "host-id-2", Hostname: "agent-2"},
+	}
+	c := newTestCollector()
+	c.updateDatabaseMetrics("test-cluster", []teleport.DatabaseInfo{{Name: "db", Servers: servers}})
+	if got := testutil.CollectAndCount(metrics.DatabaseServerInfo); got != 0 {
+		t.Errorf("expected no server series without per-server metrics, got %d", got)
+	}
+
+	c.perServer = true
+	c.updateDatabaseMetrics("test-cluster", []teleport.DatabaseInfo{{Name: "db", Servers: servers}})
+	if got := testutil.CollectAndCount(metrics.DatabaseServerInfo); got != 2 {
+		t.Errorf("expected a series per server, got %d", got)
+	}
+	if got := testutil.ToFloat64(metrics.DatabaseServerInfo.WithLabelValues("test-cluster", "db", "host-id-2", "agent-2")); got != 1 {
+		t.Errorf("expected the series of agent-2 to be 1, got %f", got)
+	}
+
+	// A server going away deletes its series, while the database stays
+	c.updateDatabaseMetrics("test-cluster", []teleport.DatabaseInfo{{Name: "db", Servers: servers[:1]}})
+	if got := testutil.CollectAndCount(metrics.DatabaseServerInfo); got != 1 {
+		t.Errorf("expected the series of the removed server to be deleted, got %d series", got)
+	}
+}
+
+func TestCollector_PerServer_AmbiguousNames(t *testing.T) {
+	metrics.DatabaseServerInfo.Reset()
+
+	// Joined with a separator, both servers would have the key "a/b/c"
+	c := newTestCollector()
+	c.perServer = true
+	c.updateDatabaseMetrics("test-cluster", []teleport.DatabaseInfo{
+		{Name: "a/b", Servers: []teleport.ServerInfo{{HostID: "c", Hostname: "agent-1"}}},
+		{Name: "a", Servers: []teleport.ServerInfo{{HostID: "b/c", Hostname: "agent-2"}}},
+	})
+	if got := testutil.CollectAndCount(metrics.DatabaseServerInfo); got != 2 {
+		t.Errorf("expected a series per server, got %d", got)
+	}
+}
+
+func TestRecordResult(t *testing.T) {
+	metrics.CollectErrorsTotal.Reset()
+	metrics.ResourceUp.Reset()
+	metrics.CheckUp.Reset()
+	metrics.ResourceLastSuccessTime.Reset()
+
+	c := newTestCollector()
+	c.recordResult("test-cluster", resourceNodes, time.Now(), nil)
+	c.recordResult("test-cluster", resourceDatabases, time.Now(), context.DeadlineExceeded)
+	c.recordResult("test-cluster", resourceDatabases, time.Now(), errors.New("boom"))
+	c.recordResult("test-cluster", resourceDatabases, time.Now(), errors.New("boom again"))
+
+	value := testutil.ToFloat64(metrics.CollectErrorsTotal.WithLabelValues("test-cluster", "databases", "timeout"))
+	if value != 1 {
+		t.Errorf("expected 1 database timeout error, got %f", value)
+	}
+	value = testutil.ToFloat64(metrics.CollectErrorsTotal.WithLabelValues("test-cluster", "databases", "other"))
+	if value != 2 {
+		t.Errorf("expected 2 other database errors, got %f", value)
+	}
+
+	// Only the failing resource is reported as down
+	if value := testutil.ToFloat64(metrics.ResourceUp.WithLabelValues("test-cluster", "nodes")); value != 1 {
+		t.Errorf("expected nodes to be up, got %f", value)
+	}
+	if value := testutil.ToFloat64(metrics.ResourceUp.WithLabelValues("test-cluster", "databases")); value != 0 {
+		t.Errorf("expected databases to be down, got %f", value)
+	}
+	if got := testutil.CollectAndCount(metrics.ResourceLastSuccessTime); got != 1 {
+		t.Errorf("expected a last success timestamp only for nodes, got %d series", got)
+	}
+	if value := testutil.ToFloat64(metrics.CheckUp.WithLabelValues("nodes")); value != 1 {
+		t.Errorf("expected the nodes check to be up, got %f", value)
+	}
+	if value := testutil.ToFloat64(metrics.CheckUp.WithLabelValues("db")); value != 0 {
+		t.Errorf("expected the db check to be down, got %f", value)
+	}
+
+	// The status keeps the last error of each resource
+	status := c.Status()
+	if status.Resources[resourceNodes].Error != "" {
+		t.Errorf("expected no error for nodes, got %q", status.Resources[resourceNodes].Error)
+	}
+	if db := status.Resources[resourceDatabases]; db.Error != "boom again" || db.Reason != "other" || db.Class != "other" {
+		t.Errorf("expected last database error to be %q (other), got %q (%s, %s)", "boom again", db.Error, db.Reason, db.Class)
+	}
+}
+
+func TestRecordResult_PermissionDenied(t *testing.T) {
+	metrics.CollectErrorsTotal.Reset()
+	metrics.ResourcePermissionDenied.Reset()
+
+	c := newTestCollector()
+	c.deniedInterval = time.Hour
+	denied := trace.AccessDenied("access denied to perform action \"list\" on \"db_server\"")
+
+	c.recordResult("test-cluster", resourceDatabases, time.Now(), denied)
+	if !c.permissionDenied(denied) {
+		t.Error("expected access denied errors to disable the resource type")
+	}
+	if got := testutil.CollectAndCount(metrics.CollectErrorsTotal); got != 0 {
+		t.Errorf("expected no collection errors for access denied, got %d series", got)
+	}
+	if value := testutil.ToFloat64(metrics.ResourcePermissionDenied.WithLabelValues(resourceDatabases)); value != 1 {
+		t.Errorf("expected databases to be reported as denied, got %f", value)
+	}
+	if c.collects(resourceDatabases) {
+		t.Error("expected databases not to be collected while denied")
+	}
+	if !c.collects(resourceNodes) {
+		t.Error("expected nodes to be collected")
+	}
+	if !c.Status().Resources[resourceDatabases].PermissionDenied {
+		t.Error("expected the status to show databases as denied")
+	}
+
+	// Once the interval has passed, the resource type is retried
+	c.deniedUntil[resourceDatabases] = time.Now().Add(-time.Second)
+	if !c.collects(resourceDatabases) {
+		t.Error("expected databases to be collected again after the interval")
+	}
+	c.recordResult("test-cluster", resourceDatabases, time.Now(), nil)
+	if value := testutil.ToFloat64(metrics.ResourcePermissionDenied.WithLabelValues(resourceDatabases)); value != 0 {
+		t.Errorf("expected databases to be reported as allowed again, got %f", value)
+	}
+
+	// Without an interval, access denied is a regular error
+	c.deniedInterval = 0
+	c.recordResult("test-cluster", resourceDatabases, time.Now(), denied)
+	if value := testutil.ToFloat64(metrics.CollectErrorsTotal.WithLabelValues("test-cluster", resourceDatabases, "permission_denied")); value != 1 {
+		t.Errorf("expected 1 permission denied error, got %f", value)
+	}
+	if !c.collects(resourceDatabases) {
+		t.Error("expected databases to be collected without an interval")
+	}
+}
+
+func TestCollector_WithTimeout(t *testing.T) {
+	c := newTestCollector()
+
+	ctx, cancel := c.withTimeout(context.Background())
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("expected no deadline without API timeout")
+	}
+
+	c.apiTimeout = time.Second
+	ctx, cancel = c.withTimeout(context.Background())
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		t.Fatal("expected deadline with API timeout")
+	}
+	if remaining := time.Until(deadline); remaining > time.Second {
+		t.Errorf("expected deadline within 1s, got %v", remaining)
+	}
+}
+
+func TestCollector_WithCollectTimeout(t *testing.T) {
+	c := newTestCollector()
+
+	ctx, cancel := c.withCollectTimeout(context.Background())
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("expected no deadline without collection timeout")
+	}
+
+	// API calls of a collection inherit its deadline, even with a longer API timeout
+	c.collectTimeout = time.Second
+	c.apiTimeout = time.Minute
+	ctx, cancel = c.withCollectTimeout(context.Background())
+	defer cancel()
+	callCtx, callCancel := c.withTimeout(ctx)
+	defer callCancel()
+	deadline, ok := callCtx.Deadline()
+	if !ok {
+		t.Fatal("expected deadline with collection timeout")
+	}
+	if remaining := time.Until(deadline); remaining > time.Second {
+		t.Errorf("expected deadline within 1s, got %v", remaining)
+	}
+}
+
+func TestCollector_Trigger(t *testing.T) {
+	c := newTestCollector()
+	c.deniedUntil[resourceNodes] = time.Now().Add(time.Hour)
+	c.skippedCycles[resourceApps] = 3
+
+	if !c.Trigger() {
+		t.Fatal("expected the first trigger to be accepted")
+	}
+	if c.Trigger() {
+		t.Error("expected a second trigger to be rejected while one is pending")
+	}
+
+	if !c.waitOrTrigger(context.Background(), time.Hour) {
+		t.Fatal("expected a triggered wait to return true")
+	}
+	if !c.collects(resourceNodes) || !c.collects(resourceApps) {
+		t.Error("expected a triggered collection to collect resource types that are denied or backing off")
+	}
+	if !c.Trigger() {
+		t.Error("expected a trigger to be accepted after the pending one was handled")
+	}
+
+	// Retry backoffs are not interrupted by triggers
+	start := time.Now()
+	c.wait(context.Background(), 50*time.Millisecond)
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("expected wait to ignore triggers")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		retries   int
+		errs      []error
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "success",
+			retries:   2,
+			errs:      []error{nil},
+			wantCalls: 1,
+		},
+		{
+			name:      "transient error retried",
+			retries:   2,
+			errs:      []error{status.Error(codes.Unavailable, "unavailable"), context.DeadlineExceeded, nil},
+			wantCalls: 3,
+		},
+		{
+			name:      "retries exhausted",
+			retries:   1,
+			errs:      []error{status.Error(codes.Unavailable, "unavailable"), status.Error(codes.Unavailable, "unavailable"), nil},
+			wantCalls: 2,
+			wantErr:   true,
+		},
+		{
+			name:      "permanent error not retried",
+			retries:   2,
+			errs:      []error{trace.AccessDenied("access denied"), nil},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCollector()
+			c.retries = tt.retries
+
+			calls := 0
+			result, err := retry(context.Background(), c, resourceNodes, func(context.Context) (int, error) {
+				err := tt.errs[calls]
+				calls++
+				return calls, err
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, calls)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && result != calls {
+				t.Errorf("expected the result of the last call, got %d", result)
+			}
+		})
+	}
+}
+
+func TestCollector_Wait(t *testing.T) {
+	c := newTestCollector()
+
+	if !c.wait(context.Background(), time.Millisecond) {
+		t.Error("expected wait to return true after the duration passed")
+	}
+	if age := time.Since(c.LastHeartbeat()); age > time.Second {
+		t.Errorf("expected wait to beat the heartbeat, last heartbeat was %v ago", age)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if c.wait(ctx, time.Hour) {
+		t.Error("expected wait to return false when the context is cancelled")
+	}
+}
+
 func TestCollector_New(t *testing.T) {
 	cfg := Config{
 		TeleportClient:  nil, // Would be set in real usage
 		RefreshInterval: 60 * time.Second,
+		APITimeout:      30 * time.Second,
 		Log:             logr.Discard(),
 	}
 
@@ -360,6 +881,12 @@ func TestCollector_New(t *testing.T) {
 	if c.refreshInterval != 60*time.Second {
 		t.Errorf("expected refreshInterval to be 60s, got %v", c.refreshInterval)
 	}
+	if c.apiTimeout != 30*time.Second {
+		t.Errorf("expected apiTimeout to be 30s, got %v", c.apiTimeout)
+	}
+	if !c.LastSuccess().IsZero() {
+		t.Errorf("expected no LastSuccess before the first collection, got %v", c.LastSuccess())
+	}
 
 	// Verify maps are initialized
 	if c.lastNodesByKubeCluster == nil {
@@ -379,6 +906,7 @@ func TestCollector_New(t *testing.T) {
 func TestCollector_BackoffCalculation(t *testing.T) {
 	c := newTestCollector()
 	c.refreshInterval = 60 * time.Second
+	c.jitterFraction = DefaultJitterFraction
 
 	// With no errors, interval should be close to base (with some jitter)
 	interval := c.calculateNextInterval()
@@ -402,6 +930,89 @@ func TestCollector_BackoffCalculation(t *testing.T) {
 	}
 }
 
+func TestCollector_ResourceBackoff(t *testing.T) {
+	c := newTestCollector()
+	c.refreshInterval = time.Minute
+
+	// Two failures back off databases for 4 intervals, skipping 3 collections
+	c.recordResult("test-cluster", resourceDatabases, time.Now(), errors.New("boom"))
+	c.recordResult("test-cluster", resourceDatabases, time.Now(), errors.New("boom"))
+	for i := range 3 {
+		if c.collects(resourceDatabases) {
+			t.Errorf("expected databases to be skipped in collection %d", i+1)
+		}
+		if !c.collects(resourceNodes) {
+			t.Errorf("expected nodes to be collected in collection %d", i+1)
+		}
+	}
+	if !c.collects(resourceDatabases) {
+		t.Error("expected databases to be retried after the backoff")
+	}
+	if !c.backingOff() {
+		t.Error("expected the collector to back off while databases fail")
+	}
+	status := c.Status()
+	if db := status.Resources[resourceDatabases]; db.ConsecutiveErrors != 2 || db.BackoffInterval != "4m0s" {
+		t.Errorf("expected 2 database errors and a 4m0s backoff, got %d and %s", db.ConsecutiveErrors, db.BackoffInterval)
+	}
+	if status.ConsecutiveErrors != 0 {
+		t.Errorf("expected resource errors not to back off the collection, got %d consecutive errors", status.ConsecutiveErrors)
+	}
+
+	c.recordResult("test-cluster", resourceDatabases, time.Now(), nil)
+	if c.backingOff() {
+		t.Error("expected the backoff to end after a success")
+	}
+
+	// Failing optional resource types back off on their own, without failing
+	// the collection
+	c.recordResult("test-cluster", resourceUsers, time.Now(), errors.New("boom"))
+	if c.backingOff() {
+		t.Error("expected failing users not to fail the collection")
+	}
+	if !c.collects(resourceDatabases) {
+		t.Error("expected databases to be collected after a success")
+	}
+}
+
+func TestCollector_JitterFraction(t *testing.T) {
+	c := newTestCollector()
+	c.refreshInterval = 60 * time.Second
+
+	// Without jitter, collections happen at fixed intervals
+	for range 10 {
+		if interval := c.calculateNextInterval(); interval != 60*time.Second {
+			t.Fatalf("expected interval of exactly 60s without jitter, got %v", interval)
+		}
+	}
+
+	c.jitterFraction = 0.5
+	for range 10 {
+		if interval := c.calculateNextInterval(); interval < 30*time.Second || interval > 90*time.Second {
+			t.Fatalf("expected interval within 60s ±50%%, got %v", interval)
+		}
+	}
+}
+
+func TestCollector_StatusBackoff(t *testing.T) {
+	c := newTestCollector()
+	c.refreshInterval = 60 * time.Second
+
+	if got := c.Status().BackoffInterval; got != "1m0s" {
+		t.Errorf("expected backoff interval 1m0s without errors, got %s", got)
+	}
+
+	c.incrementErrors()
+	c.incrementErrors()
+	status := c.Status()
+	if status.ConsecutiveErrors != 2 {
+		t.Errorf("expected 2 consecutive errors, got %d", status.ConsecutiveErrors)
+	}
+	if status.BackoffInterval != "4m0s" {
+		t.Errorf("expected backoff interval 4m0s after 2 errors, got %s", status.BackoffInterval)
+	}
+}
+
 func TestCollector_ErrorTracking(t *testing.T) {
 	c := newTestCollector()
 
@@ -424,3 +1035,123 @@ func TestCollector_ErrorTracking(t *testing.T) {
 		t.Errorf("expected consecutiveErrors to be 0 after reset, got %d", c.consecutiveErrors)
 	}
 }
+
+// The fake client must implement the interface of the collector
+var _ TeleportClient = (*fakes.Client)(nil)
+
+func TestCollector_Collect(t *testing.T) {
+	metrics.NodesTotal.Reset()
+	metrics.DatabasesTotal.Reset()
+	metrics.ResourceUp.Reset()
+
+	fake := &fakes.Client{
+		ClusterName: "test-cluster",
+		Nodes:       []teleport.NodeInfo{{Name: "node-1"}, {Name: "node-2"}},
+		Databases:   []teleport.DatabaseInfo{{Name: "db", Protocol: "postgres"}},
+		Errors:      map[string]error{fakes.MethodGetApps: trace.ConnectionProblem(nil, "connection reset")},
+	}
+	c := newTestCollector()
+	c.client = fake
+
+	err := c.CollectOnce(context.Background())
+	if err == nil {
+		t.Fatal("expected the failing applications to fail the collection")
+	}
+	if got := testutil.ToFloat64(metrics.NodesTotal.WithLabelValues("test-cluster")); got != 2 {
+		t.Errorf("expected NodesTotal to be 2, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.DatabasesTotal.WithLabelValues("test-cluster")); got != 1 {
+		t.Errorf("expected DatabasesTotal to be 1, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.ResourceUp.WithLabelValues("test-cluster", resourceApps)); got != 0 {
+		t.Errorf("expected the applications to be down, got %f", got)
+	}
+	// The connection error reconnects once per collection
+	if got := fake.Calls(fakes.MethodReconnect); got != 1 {
+		t.Errorf("expected 1 reconnect, got %d", got)
+	}
+
+	// Without the cluster name, nothing else is collected
+	fake.Errors = map[string]error{fakes.MethodGetClusterName: errors.New("boom")}
+	if err := c.CollectOnce(context.Background()); err == nil {
+		t.Error("expected the failing cluster name to fail the collection")
+	}
+	if got := fake.Calls(fakes.MethodGetNodes); got != 1 {
+		t.Errorf("expected the nodes to not be collected without the cluster name, got %d calls", got)
+	}
+}
+
+func TestCollector_HeartbeatDuringSlowCollection(t *testing.T) {
+	const apiTimeout = 10 * time.Millisecond
+	delays := make(map[string]time.Duration)
+	for _, method := range []string{
+		fakes.MethodGetNodes, fakes.MethodGetKubeClusters, fakes.MethodGetDatabases, fakes.MethodGetApps,
+		fakes.MethodGetUsers, fakes.MethodGetLocks, fakes.MethodGetRoles, fakes.MethodGetTokens,
+		fakes.MethodGetAccessRequests, fakes.MethodGetSessions, fakes.MethodGetRemoteClusters, fakes.MethodGetCertAuthorities,
+	} {
+		delays[method] = 3 * apiTimeout
+	}
+	c := newTestCollector()
+	c.client = &fakes.Client{ClusterName: "test-cluster", Delays: delays}
+	c.apiTimeout = apiTimeout
+	c.collectTimeout = time.Second
+	c.extraResources = optionalResources
+
+	// Track the oldest heartbeat /healthz would see during the collection
+	done := make(chan struct{})
+	maxAge := make(chan time.Duration)
+	go func() {
+		var oldest time.Duration
+		for {
+			oldest = max(oldest, time.Since(c.LastHeartbeat()))
+			select {
+			case <-done:
+				maxAge <- oldest
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}()
+
+	c.beat()
+	start := time.Now()
+	_ = c.CollectOnce(context.Background())
+	duration := time.Since(start)
+	close(done)
+
+	if duration <= 10*apiTimeout || duration >= c.collectTimeout {
+		t.Fatalf("expected the collection to take between %v and %v, took %v", 10*apiTimeout, c.collectTimeout, duration)
+	}
+	if age := <-maxAge; age >= 10*apiTimeout {
+		t.Errorf("expected the heartbeat to stay younger than %v during the collection, got %v", 10*apiTimeout, age)
+	}
+}
+
+func TestDefaultLivenessMaxAge(t *testing.T) {
+	if got := DefaultLivenessMaxAge(30*time.Second, 5*time.Minute, time.Minute); got <= 5*time.Minute {
+		t.Errorf("expected the liveness max age to exceed the collect timeout, got %v", got)
+	}
+	if got := DefaultLivenessMaxAge(30*time.Second, 10*time.Second, time.Minute); got <= time.Minute {
+		t.Errorf("expected the liveness max age to exceed the refresh interval, got %v", got)
+	}
+}
+
+func TestNeedsReconnect(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection", trace.ConnectionProblem(errors.New("connection refused"), "failed to connect"), true},
+		{"expired certificate", status.Error(codes.Unavailable, "remote error: tls: expired certificate"), true},
+		{"access denied", trace.AccessDenied("access denied"), false},
+		{"timeout", context.DeadlineExceeded, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsReconnect(tt.err); got != tt.want {
+				t.Errorf("needsReconnect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
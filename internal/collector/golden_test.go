@@ -0,0 +1,168 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/giantswarm/teleport-exporter/internal/fakes"
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+)
+
+var update = flag.Bool("update", false, "Update the golden files of TestGolden.")
+
+// volatileSuffixes are the name suffixes of the metrics that change with
+// every run, which are not compared to the golden files.
+var volatileSuffixes = []string{"_timestamp_seconds", "_duration_seconds", "_age_seconds"}
+
+// stableGatherer gathers the metrics of g without the volatile ones.
+func stableGatherer(g prometheus.Gatherer) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		if err != nil {
+			return nil, err
+		}
+		stable := families[:0]
+		for _, mf := range families {
+			if !hasAnySuffix(mf.GetName(), volatileSuffixes) {
+				stable = append(stable, mf)
+			}
+		}
+		return stable, nil
+	})
+}
+
+func hasAnySuffix(s string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestGolden collects the inventory of testdata/golden/inventory.json and
+// compares the exposition of all metrics to testdata/golden/<case>.prom, to
+// catch renamed metrics and changed labels. Run it with -update to accept
+// intended changes.
+func TestGolden(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "golden", "inventory.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inv Inventory
+	if err := json.Unmarshal(data, &inv); err != nil {
+		t.Fatal(err)
+	}
+
+	env := []string{"env"}
+	infoLabels := metrics.InfoLabels{Node: env, KubeCluster: env, Database: env, App: env}
+	tests := []struct {
+		name string
+		cfg  Config
+		opts metrics.Options
+	}{
+		{name: "default"},
+		{
+			name: "labels",
+			cfg: Config{
+				InfoLabels:         infoLabels,
+				PerServer:          true,
+				ExtraResources:     []string{resourceUsers, resourceLocks, resourceRoles, resourceTokens, resourceAccessRequests, resourceSessions, resourceRemoteClusters, resourceCertAuthorities},
+				UserWithoutMFAInfo: true,
+				RequiredLabels:     []string{"env", "team"},
+			},
+			opts: metrics.Options{InfoLabels: infoLabels},
+		},
+		{name: "counts-only", cfg: Config{CountsOnly: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			if err := metrics.Setup(reg, tt.opts); err != nil {
+				t.Fatalf("Setup() failed: %v", err)
+			}
+			defer func() { _ = metrics.Setup(nil, metrics.Options{}) }()
+
+			cfg := tt.cfg
+			cfg.TeleportClient = &fakes.Client{
+				ClusterName:     inv.ClusterName,
+				Nodes:           inv.Nodes,
+				KubeClusters:    inv.KubeClusters,
+				Databases:       inv.Databases,
+				Apps:            inv.Apps,
+				Users:           inv.Users,
+				Locks:           inv.Locks,
+				Roles:           inv.Roles,
+				Tokens:          inv.Tokens,
+				AccessRequests:  inv.AccessRequests,
+				Sessions:        inv.Sessions,
+				RemoteClusters:  inv.RemoteClusters,
+				CertAuthorities: inv.CertAuthorities,
+			}
+			cfg.Log = logr.Discard()
+			if err := New(cfg).CollectOnce(context.Background()); err != nil {
+				t.Fatalf("CollectOnce() failed: %v", err)
+			}
+
+			gatherer := stableGatherer(reg)
+			golden := filepath.Join("testdata", "golden", tt.name+".prom")
+			if *update {
+				writeGolden(t, gatherer, golden)
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := testutil.GatherAndCompare(gatherer, bytes.NewReader(expected)); err != nil {
+				t.Errorf("metrics differ from %s, run the test with -update if the change is intended:\n%v", golden, err)
+			}
+		})
+	}
+}
+
+// writeGolden writes the exposition of the metrics of g to path.
+func writeGolden(t *testing.T, g prometheus.Gatherer, path string) {
+	t.Helper()
+	families, err := g.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	for _, mf := range families {
+		if _, err := expfmt.MetricFamilyToText(&buf, mf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
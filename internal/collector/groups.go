@@ -0,0 +1,95 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// groupSeparator joins the label keys and values of a group.
+const groupSeparator = "+"
+
+// GroupBy lists, per resource type, the groups of Teleport labels to count
+// resources by. Each group is a list of label keys.
+type GroupBy struct {
+	Node        [][]string
+	KubeCluster [][]string
+	Database    [][]string
+	App         [][]string
+}
+
+// ParseGroupBy parses a comma-separated list of groups, where each group joins
+// one or more label keys with +, e.g. "cluster,role+env".
+func ParseGroupBy(s string) [][]string {
+	var groups [][]string
+	for _, item := range strings.Split(s, ",") {
+		var keys []string
+		for _, key := range strings.Split(item, groupSeparator) {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			groups = append(groups, keys)
+		}
+	}
+	return groups
+}
+
+// groupSeries holds the label values of the series of a *_by_label metric.
+type groupSeries map[[3]string]struct{}
+
+// applyGroups sets vec to the number of resources per value of each group and
+// deletes series from last that are gone. Resources without a label count
+// towards an empty value.
+func applyGroups[T any](vec *prometheus.GaugeVec, clusterName string, groups [][]string, resources []T, labels func(T) map[string]string, last groupSeries) groupSeries {
+	counts := make(map[[3]string]int)
+	for _, resource := range resources {
+		countGroups(counts, clusterName, groups, labels(resource))
+	}
+	return applyGroupCounts(vec, counts, last)
+}
+
+// countGroups counts a resource with the given labels towards its value of
+// each group.
+func countGroups(counts map[[3]string]int, clusterName string, groups [][]string, labels map[string]string) {
+	for _, group := range groups {
+		values := make([]string, len(group))
+		for i, k := range group {
+			values[i] = labels[k]
+		}
+		counts[[3]string{clusterName, strings.Join(group, groupSeparator), strings.Join(values, groupSeparator)}]++
+	}
+}
+
+// applyGroupCounts sets vec to counts and deletes series from last that are
+// gone.
+func applyGroupCounts(vec *prometheus.GaugeVec, counts map[[3]string]int, last groupSeries) groupSeries {
+	current := make(groupSeries, len(counts))
+	for series, count := range counts {
+		vec.WithLabelValues(series[:]...).Set(float64(count))
+		current[series] = struct{}{}
+	}
+	for series := range last {
+		if _, exists := current[series]; !exists {
+			vec.DeleteLabelValues(series[:]...)
+		}
+	}
+	return current
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestParseGroupBy(t *testing.T) {
+	tests := []struct {
+		input string
+		want  [][]string
+	}{
+		{input: "", want: nil},
+		{input: "cluster", want: [][]string{{"cluster"}}},
+		{input: "cluster, role+env ,", want: [][]string{{"cluster"}, {"role", "env"}}},
+		{input: "role+", want: [][]string{{"role"}}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseGroupBy(tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseGroupBy(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestCollector_GroupBy(t *testing.T) {
+	metrics.NodesByLabel.Reset()
+
+	c := newTestCollector()
+	c.groupBy = GroupBy{Node: [][]string{{"cluster"}, {"role", "env"}}}
+
+	c.updateNodeMetrics("test-cluster", []teleport.NodeInfo{
+		{Name: "node-1", Labels: map[string]string{"cluster": "a", "role": "worker", "env": "prod"}},
+		{Name: "node-2", Labels: map[string]string{"cluster": "a", "role": "worker", "env": "prod"}},
+		{Name: "node-3", Labels: map[string]string{"cluster": "b", "role": "control-plane"}},
+	})
+
+	expected := map[[2]string]float64{
+		{"cluster", "a"}:               2,
+		{"cluster", "b"}:               1,
+		{"role+env", "worker+prod"}:    2,
+		{"role+env", "control-plane+"}: 1,
+	}
+	for series, want := range expected {
+		got := testutil.ToFloat64(metrics.NodesByLabel.WithLabelValues("test-cluster", series[0], series[1]))
+		if got != want {
+			t.Errorf("expected %v to be %f, got %f", series, want, got)
+		}
+	}
+	if got := testutil.CollectAndCount(metrics.NodesByLabel); got != len(expected) {
+		t.Errorf("expected %d series, got %d", len(expected), got)
+	}
+
+	// Values that disappear are removed
+	c.updateNodeMetrics("test-cluster", []teleport.NodeInfo{
+		{Name: "node-1", Labels: map[string]string{"cluster": "a", "role": "worker", "env": "prod"}},
+	})
+	if got := testutil.CollectAndCount(metrics.NodesByLabel); got != 2 {
+		t.Errorf("expected 2 series after nodes were removed, got %d", got)
+	}
+}
+
+func TestCollector_KubeClusterGroupBy(t *testing.T) {
+	metrics.KubeClustersByLabel.Reset()
+
+	c := newTestCollector()
+	c.groupBy = GroupBy{KubeCluster: [][]string{{"region"}, {"cloud"}}}
+
+	c.updateKubeClusterMetrics("test-cluster", []teleport.KubeClusterInfo{
+		{Name: "kube-1", Labels: map[string]string{"region": "eu-west-1", "cloud": "aws"}, Servers: []teleport.ServerInfo{{HostID: "agent-1"}, {HostID: "agent-2"}}},
+		{Name: "kube-2", Labels: map[string]string{"region": "eu-west-1", "cloud": "aws"}},
+		{Name: "kube-3", Labels: map[string]string{"cloud": "gcp"}},
+	})
+
+	// Clusters served by several agents count once
+	expected := map[[2]string]float64{
+		{"region", "eu-west-1"}: 2,
+		{"region", ""}:          1,
+		{"cloud", "aws"}:        2,
+		{"cloud", "gcp"}:        1,
+	}
+	for series, want := range expected {
+		got := testutil.ToFloat64(metrics.KubeClustersByLabel.WithLabelValues("test-cluster", series[0], series[1]))
+		if got != want {
+			t.Errorf("expected %v to be %f, got %f", series, want, got)
+		}
+	}
+	if got := testutil.CollectAndCount(metrics.KubeClustersByLabel); got != len(expected) {
+		t.Errorf("expected %d series, got %d", len(expected), got)
+	}
+}
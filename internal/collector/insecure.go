@@ -0,0 +1,82 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+// Reasons of teleport_exporter_databases_insecure_total.
+const (
+	reasonInsecureTLS = "insecure_tls"
+	reasonPlaintext   = "plaintext"
+)
+
+// updateInsecureDatabases updates the metrics of the databases with an
+// insecure configuration. The caller must hold c.mu.
+func (c *Collector) updateInsecureDatabases(clusterName string, databases []teleport.DatabaseInfo) {
+	// Reasons without databases are exported as 0, so that a graph starts at
+	// 0 rather than with a gap
+	reasons := map[string]int{reasonInsecureTLS: 0, reasonPlaintext: 0}
+	currentInfo := make(infoSeries)
+	for _, db := range databases {
+		for _, reason := range insecureReasons(db) {
+			reasons[reason]++
+			currentInfo[seriesKey{db.Name, reason}] = []string{clusterName, db.Name, reason}
+		}
+	}
+	c.lastDbInsecure = applyCounts(metrics.DatabasesInsecureTotal, clusterName, reasons, c.lastDbInsecure)
+	c.lastDbInsecureInfo = c.applyInfoSeries("database_insecure_info", metrics.DatabaseInsecureInfo, currentInfo, c.lastDbInsecureInfo)
+}
+
+// insecureReasons returns why the configuration of db is insecure: Teleport
+// does not verify its certificate, or its URI disables TLS.
+func insecureReasons(db teleport.DatabaseInfo) []string {
+	var reasons []string
+	if db.TLSMode == teleport.TLSModeInsecure {
+		reasons = append(reasons, reasonInsecureTLS)
+	}
+	if plaintextURI(db.URI) {
+		reasons = append(reasons, reasonPlaintext)
+	}
+	return reasons
+}
+
+// plaintextURI reports whether a database URI disables TLS, with the http
+// scheme, the sslmode=disable parameter of PostgreSQL or the tls=false and
+// ssl=false parameters of MongoDB.
+func plaintextURI(uri string) bool {
+	// Most URIs are only host:port, which does not parse as a URL
+	if !strings.Contains(uri, "://") {
+		uri = "//" + uri
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "http" {
+		return true
+	}
+	query := u.Query()
+	return query.Get("sslmode") == "disable" ||
+		strings.EqualFold(query.Get("tls"), "false") ||
+		strings.EqualFold(query.Get("ssl"), "false")
+}
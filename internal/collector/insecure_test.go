@@ -0,0 +1,97 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestPlaintextURI(t *testing.T) {
+	tests := map[string]bool{
+		"":                                              false,
+		"postgres.example.com:5432":                     false,
+		"postgres://db.example.com:5432/app":            false,
+		"db.example.com:5432?sslmode=require":           false,
+		"10.0.0.1:5432?sslmode=disable":                 true,
+		"postgres://db.example.com/app?sslmode=disable": true,
+		"mongodb://mongo.example.com:27017/?tls=false":  true,
+		"mongodb://mongo.example.com:27017/?ssl=FALSE":  true,
+		"http://clickhouse.example.com:8123":            true,
+		"https://clickhouse.example.com:8443":           false,
+	}
+	for uri, want := range tests {
+		if got := plaintextURI(uri); got != want {
+			t.Errorf("plaintextURI(%q) = %t, want %t", uri, got, want)
+		}
+	}
+}
+
+func TestInsecureReasons(t *testing.T) {
+	db := teleport.DatabaseInfo{TLSMode: teleport.TLSModeInsecure, URI: "http://es.example.com:9200"}
+	if got, want := insecureReasons(db), []string{reasonInsecureTLS, reasonPlaintext}; !slices.Equal(got, want) {
+		t.Errorf("insecureReasons() = %v, want %v", got, want)
+	}
+	db = teleport.DatabaseInfo{TLSMode: teleport.TLSModeVerifyCA, URI: "db.example.com:5432"}
+	if got := insecureReasons(db); got != nil {
+		t.Errorf("expected no reasons, got %v", got)
+	}
+}
+
+func TestCollector_UpdateInsecureDatabases(t *testing.T) {
+	metrics.DatabasesInsecureTotal.Reset()
+	metrics.DatabaseInsecureInfo.Reset()
+
+	c := newTestCollector()
+	c.redaction = Redaction{Fields: []string{RedactURI}, Mode: RedactModeDrop}
+	c.updateDatabaseMetrics("test-cluster", []teleport.DatabaseInfo{
+		{Name: "orders", TLSMode: teleport.TLSModeVerifyFull, URI: "orders.example.com:5432"},
+		{Name: "legacy", TLSMode: teleport.TLSModeInsecure, URI: "legacy.example.com:5432?sslmode=disable"},
+		{Name: "search", TLSMode: teleport.TLSModeVerifyFull, URI: "http://search.example.com:9200"},
+	})
+
+	expected := map[string]float64{reasonInsecureTLS: 1, reasonPlaintext: 2}
+	for reason, want := range expected {
+		if got := testutil.ToFloat64(metrics.DatabasesInsecureTotal.WithLabelValues("test-cluster", reason)); got != want {
+			t.Errorf("expected %s to be %f, got %f", reason, want, got)
+		}
+	}
+	if got := testutil.CollectAndCount(metrics.DatabaseInsecureInfo); got != 3 {
+		t.Errorf("expected 3 info series, got %d", got)
+	}
+	if got := testutil.ToFloat64(metrics.DatabaseInsecureInfo.WithLabelValues("test-cluster", "legacy", reasonPlaintext)); got != 1 {
+		t.Errorf("expected legacy to be plaintext, got %f", got)
+	}
+	// The URIs are checked before they are redacted
+	if uri := c.inventory.Databases[1].URI; uri != "" {
+		t.Errorf("expected the URI to be redacted, got %q", uri)
+	}
+
+	// Fixed databases are removed, reasons without databases stay at 0
+	c.updateDatabaseMetrics("test-cluster", []teleport.DatabaseInfo{{Name: "orders", TLSMode: teleport.TLSModeVerifyFull}})
+	if got := testutil.CollectAndCount(metrics.DatabaseInsecureInfo); got != 0 {
+		t.Errorf("expected no info series, got %d", got)
+	}
+	if got := testutil.CollectAndCount(metrics.DatabasesInsecureTotal); got != 2 {
+		t.Errorf("expected 2 series, got %d", got)
+	}
+}
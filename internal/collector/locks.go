@@ -0,0 +1,80 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"slices"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func (c *Collector) updateLockMetrics(clusterName string, locks []teleport.LockInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inventory.Locks = locks
+
+	expiries := userLockExpiries(locks)
+	current := make(infoSeries, len(expiries))
+	for user := range expiries {
+		current[seriesKey{name: user}] = []string{clusterName, user}
+	}
+	for key, values := range c.lastUserLockExpiry {
+		if currentValues, exists := current[key]; !exists || !slices.Equal(currentValues, values) {
+			metrics.UserLockExpiry.DeleteLabelValues(values...)
+		}
+	}
+	for key, values := range current {
+		metrics.UserLockExpiry.WithLabelValues(values...).Set(expiries[key.name])
+	}
+	c.lastUserLockExpiry = current
+
+	// A lock of several targets counts towards each of their kinds
+	targets := make(map[string]int)
+	for _, lock := range locks {
+		for kind := range lock.Targets {
+			targets[kind]++
+		}
+	}
+
+	metrics.UsersLockedTotal.WithLabelValues(clusterName).Set(float64(len(expiries)))
+	c.lastLockTargets = applyCounts(metrics.LocksByTarget, clusterName, targets, c.lastLockTargets)
+	c.log.V(1).Info("updated lock metrics", "count", len(locks), "lockedUsers", len(expiries))
+}
+
+// userLockExpiries returns the locked users with the Unix time their last
+// lock expires, or 0 if one of their locks does not expire.
+func userLockExpiries(locks []teleport.LockInfo) map[string]float64 {
+	expiries := make(map[string]float64)
+	for _, lock := range locks {
+		user := lock.Targets[teleport.LockTargetUser]
+		if user == "" {
+			continue
+		}
+		expiry, locked := expiries[user]
+		switch {
+		case locked && expiry == 0:
+			// Already locked until the lock is deleted
+		case lock.Expires == nil:
+			expiries[user] = 0
+		default:
+			expiries[user] = max(expiry, float64(lock.Expires.Unix()))
+		}
+	}
+	return expiries
+}
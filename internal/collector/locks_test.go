@@ -0,0 +1,89 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestCollector_UpdateLockMetrics(t *testing.T) {
+	metrics.UsersLockedTotal.Reset()
+	metrics.UserLockExpiry.Reset()
+
+	soon := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := soon.Add(time.Hour)
+	c := newTestCollector()
+	c.updateLockMetrics("test-cluster", []teleport.LockInfo{
+		{Name: "lock-1", Targets: map[string]string{teleport.LockTargetUser: "alice"}, Expires: &soon},
+		{Name: "lock-2", Targets: map[string]string{teleport.LockTargetUser: "alice"}, Expires: &later},
+		{Name: "lock-3", Targets: map[string]string{teleport.LockTargetUser: "bob"}, Expires: &soon},
+		{Name: "lock-4", Targets: map[string]string{teleport.LockTargetUser: "bob"}},
+		{Name: "lock-5", Targets: map[string]string{"role": "admin"}},
+	})
+
+	if got := testutil.ToFloat64(metrics.UsersLockedTotal.WithLabelValues("test-cluster")); got != 2 {
+		t.Errorf("expected UsersLockedTotal to be 2, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.UserLockExpiry.WithLabelValues("test-cluster", "alice")); got != float64(later.Unix()) {
+		t.Errorf("expected alice to be locked until the last lock expires, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.UserLockExpiry.WithLabelValues("test-cluster", "bob")); got != 0 {
+		t.Errorf("expected bob to be locked without expiry, got %f", got)
+	}
+
+	// Unlocked users are removed
+	c.updateLockMetrics("test-cluster", []teleport.LockInfo{
+		{Name: "lock-4", Targets: map[string]string{teleport.LockTargetUser: "bob"}},
+	})
+	if got := testutil.CollectAndCount(metrics.UserLockExpiry); got != 1 {
+		t.Errorf("expected 1 expiry series, got %d", got)
+	}
+	if got := testutil.ToFloat64(metrics.UsersLockedTotal.WithLabelValues("test-cluster")); got != 1 {
+		t.Errorf("expected UsersLockedTotal to be 1, got %f", got)
+	}
+}
+
+func TestCollector_UpdateLockMetrics_Targets(t *testing.T) {
+	metrics.LocksByTarget.Reset()
+
+	c := newTestCollector()
+	c.updateLockMetrics("test-cluster", []teleport.LockInfo{
+		{Name: "lock-1", Targets: map[string]string{teleport.LockTargetUser: "alice"}},
+		{Name: "lock-2", Targets: map[string]string{teleport.LockTargetUser: "bob", "mfa_device": "yubikey"}},
+		{Name: "lock-3", Targets: map[string]string{"server_id": "node-1"}},
+	})
+
+	for target, want := range map[string]float64{teleport.LockTargetUser: 2, "mfa_device": 1, "server_id": 1} {
+		if got := testutil.ToFloat64(metrics.LocksByTarget.WithLabelValues("test-cluster", target)); got != want {
+			t.Errorf("expected %v locks of target %s, got %f", want, target, got)
+		}
+	}
+
+	// Target kinds without locks are removed
+	c.updateLockMetrics("test-cluster", []teleport.LockInfo{
+		{Name: "lock-1", Targets: map[string]string{teleport.LockTargetUser: "alice"}},
+	})
+	if got := testutil.CollectAndCount(metrics.LocksByTarget); got != 1 {
+		t.Errorf("expected 1 target series, got %d", got)
+	}
+}
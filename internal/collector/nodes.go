@@ -0,0 +1,121 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"context"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+// nodeCounts are the node metrics of one collection. Nodes are added one at a
+// time, so that they need not be kept in memory while listing them.
+type nodeCounts struct {
+	total        int
+	identified   int
+	unidentified int
+	kubeClusters map[string]int
+	subKinds     map[string]int
+	oses         map[string]int
+	arches       map[string]int
+	groups       map[[3]string]int
+	missing      map[[3]string]int
+	origins      map[[3]string]int
+	// info holds the node_info series, which stay empty in counts-only mode
+	info infoSeries
+}
+
+// newNodeCounts returns the node metrics of a collection without nodes.
+func (c *Collector) newNodeCounts(clusterName string) *nodeCounts {
+	return &nodeCounts{
+		kubeClusters: make(map[string]int),
+		subKinds:     make(map[string]int),
+		oses:         make(map[string]int),
+		arches:       make(map[string]int),
+		groups:       make(map[[3]string]int),
+		missing:      missingLabelCounts(clusterName, resourceNodes, c.requiredLabels),
+		origins:      make(map[[3]string]int),
+		info:         make(infoSeries),
+	}
+}
+
+// countNode adds node to counts. The kube cluster is extracted from the
+// unredacted hostname, while node_info gets the redacted one.
+func (c *Collector) countNode(counts *nodeCounts, clusterName string, node teleport.NodeInfo) {
+	counts.total++
+	subKind := node.SubKind
+	if subKind == "" {
+		subKind = teleport.NodeSubKindTeleport
+	}
+	counts.subKinds[subKind]++
+	nodeOS, nodeArch := nodePlatform(node)
+	counts.oses[nodeOS]++
+	counts.arches[nodeArch]++
+	kubeCluster := extractKubeCluster(node)
+	counts.kubeClusters[kubeCluster]++
+	if kubeCluster == "unknown" {
+		counts.unidentified++
+	} else {
+		counts.identified++
+	}
+
+	countGroups(counts.groups, clusterName, c.groupBy.Node, node.Labels)
+	countMissingLabels(counts.missing, clusterName, resourceNodes, c.requiredLabels, node.Labels)
+	countOrigin(counts.origins, clusterName, resourceNodes, node.Labels)
+
+	if !c.countsOnly {
+		redacted := c.redaction.Node(node)
+		counts.info[seriesKey{name: redacted.Name}] = append([]string{clusterName, redacted.Name, redacted.Hostname},
+			labelValues(redacted.Labels, c.infoLabels.Node)...)
+	}
+}
+
+// countNodes lists the nodes page by page and counts them while they come in,
+// without keeping them.
+func (c *Collector) countNodes(ctx context.Context, clusterName string) (*nodeCounts, error) {
+	counts := c.newNodeCounts(clusterName)
+	err := c.client.ForEachNode(ctx, func(node teleport.NodeInfo) {
+		c.countNode(counts, clusterName, node)
+	})
+	return counts, err
+}
+
+// applyNodeCounts sets the node metrics to counts, removing stale series.
+func (c *Collector) applyNodeCounts(clusterName string, counts *nodeCounts) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastNodeInfo = c.applyInfoSeries("node_info", metrics.NodeInfo, counts.info, c.lastNodeInfo)
+	c.lastNodeGroups = applyGroupCounts(metrics.NodesByLabel, counts.groups, c.lastNodeGroups)
+	c.lastNodesMissing = applyGroupCounts(metrics.ResourcesMissingLabel, counts.missing, c.lastNodesMissing)
+	c.lastNodeOrigins = applyGroupCounts(metrics.ResourcesByOrigin, counts.origins, c.lastNodeOrigins)
+
+	// Update per-kube-cluster metrics, removing stale ones
+	c.lastNodesByKubeCluster = applyCounts(metrics.NodesByKubernetesCluster, clusterName, counts.kubeClusters, c.lastNodesByKubeCluster)
+	c.lastNodeSubKinds = applyCounts(metrics.NodesBySubkind, clusterName, counts.subKinds, c.lastNodeSubKinds)
+	c.lastNodeOSes = applyCounts(metrics.NodesByOS, clusterName, counts.oses, c.lastNodeOSes)
+	c.lastNodeArches = applyCounts(metrics.NodesByArch, clusterName, counts.arches, c.lastNodeArches)
+
+	// Update aggregate metrics
+	metrics.NodesTotal.WithLabelValues(clusterName).Set(float64(counts.total))
+	metrics.NodesIdentifiedTotal.WithLabelValues(clusterName).Set(float64(counts.identified))
+	metrics.NodesUnidentifiedTotal.WithLabelValues(clusterName).Set(float64(counts.unidentified))
+
+	c.log.V(1).Info("updated node metrics", "count", counts.total, "identified", counts.identified,
+		"unidentified", counts.unidentified, "kubeClusters", len(counts.kubeClusters))
+}
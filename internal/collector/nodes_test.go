@@ -0,0 +1,76 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/fakes"
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestCollector_StreamNodes(t *testing.T) {
+	metrics.NodesTotal.Reset()
+	metrics.NodesBySubkind.Reset()
+	metrics.NodeInfo.Reset()
+
+	fake := &fakes.Client{
+		ClusterName: "test-cluster",
+		Nodes: []teleport.NodeInfo{
+			{Name: "node-1", Hostname: "node-1.mycluster.local"},
+			{Name: "node-2", SubKind: teleport.NodeSubKindOpenSSH},
+		},
+	}
+	c := newTestCollector()
+	c.client = fake
+	c.streamNodes = true
+
+	if err := c.CollectOnce(context.Background()); err != nil {
+		t.Fatalf("CollectOnce() failed: %v", err)
+	}
+	if got := fake.Calls(fakes.MethodGetNodes); got != 1 {
+		t.Errorf("expected the nodes to be listed once, got %d calls", got)
+	}
+	if got := testutil.ToFloat64(metrics.NodesTotal.WithLabelValues("test-cluster")); got != 2 {
+		t.Errorf("expected NodesTotal to be 2, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.NodesBySubkind.WithLabelValues("test-cluster", teleport.NodeSubKindOpenSSH)); got != 1 {
+		t.Errorf("expected 1 OpenSSH node, got %f", got)
+	}
+	if got := testutil.CollectAndCount(metrics.NodeInfo); got != 2 {
+		t.Errorf("expected 2 node_info series, got %d", got)
+	}
+	if nodes := c.Inventory().Nodes; len(nodes) != 0 {
+		t.Errorf("expected streamed nodes not to be kept in the inventory, got %v", nodes)
+	}
+
+	// Nodes gone from the next listing are removed
+	fake.Nodes = fake.Nodes[:1]
+	if err := c.CollectOnce(context.Background()); err != nil {
+		t.Fatalf("CollectOnce() failed: %v", err)
+	}
+	if got := testutil.ToFloat64(metrics.NodesTotal.WithLabelValues("test-cluster")); got != 1 {
+		t.Errorf("expected NodesTotal to be 1, got %f", got)
+	}
+	if got := testutil.CollectAndCount(metrics.NodeInfo); got != 1 {
+		t.Errorf("expected 1 node_info series, got %d", got)
+	}
+}
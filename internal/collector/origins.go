@@ -0,0 +1,46 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+)
+
+// originLabel is the label Teleport sets to where a resource comes from, e.g.
+// "dynamic" for resources created with tctl or "cloud" for resources found by
+// the discovery service.
+const originLabel = "teleport.dev/origin"
+
+// applyOrigins sets teleport_exporter_resources_by_origin to the number of
+// resources of the resource type per value of the origin label, "unknown" for
+// resources without one, and deletes series from last that are gone.
+func applyOrigins[T any](clusterName, resource string, resources []T, labels func(T) map[string]string, last groupSeries) groupSeries {
+	counts := make(map[[3]string]int)
+	for _, r := range resources {
+		countOrigin(counts, clusterName, resource, labels(r))
+	}
+	return applyGroupCounts(metrics.ResourcesByOrigin, counts, last)
+}
+
+// countOrigin counts a resource with the given labels towards its origin.
+func countOrigin(counts map[[3]string]int, clusterName, resource string, labels map[string]string) {
+	origin := labels[originLabel]
+	if origin == "" {
+		origin = "unknown"
+	}
+	counts[[3]string{clusterName, resource, origin}]++
+}
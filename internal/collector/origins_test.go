@@ -0,0 +1,62 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestCollector_ResourcesByOrigin(t *testing.T) {
+	metrics.ResourcesByOrigin.Reset()
+
+	c := newTestCollector()
+	c.updateDatabaseMetrics("test-cluster", []teleport.DatabaseInfo{
+		{Name: "orders", Protocol: "postgres", Labels: map[string]string{originLabel: "cloud"}},
+		{Name: "users", Protocol: "postgres", Labels: map[string]string{originLabel: "cloud"}},
+		{Name: "legacy", Protocol: "mysql", Labels: map[string]string{originLabel: "dynamic"}},
+		{Name: "cache", Protocol: "redis"},
+	})
+	c.updateAppMetrics("test-cluster", []teleport.AppInfo{
+		{Name: "grafana", Labels: map[string]string{originLabel: "config-file"}},
+	})
+
+	if got := testutil.ToFloat64(metrics.ResourcesByOrigin.WithLabelValues("test-cluster", resourceDatabases, "cloud")); got != 2 {
+		t.Errorf("expected 2 discovered databases, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.ResourcesByOrigin.WithLabelValues("test-cluster", resourceDatabases, "unknown")); got != 1 {
+		t.Errorf("expected 1 database without origin, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.ResourcesByOrigin.WithLabelValues("test-cluster", resourceApps, "config-file")); got != 1 {
+		t.Errorf("expected 1 app from the config file, got %f", got)
+	}
+	if got := testutil.CollectAndCount(metrics.ResourcesByOrigin); got != 4 {
+		t.Errorf("expected 4 series, got %d", got)
+	}
+
+	// Origins without resources are removed, those of other types are kept
+	c.updateDatabaseMetrics("test-cluster", []teleport.DatabaseInfo{
+		{Name: "orders", Protocol: "postgres", Labels: map[string]string{originLabel: "cloud"}},
+	})
+	if got := testutil.CollectAndCount(metrics.ResourcesByOrigin); got != 2 {
+		t.Errorf("expected 2 series, got %d", got)
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import "github.com/giantswarm/teleport-exporter/internal/teleport"
+
+// Teleport does not report the operating system and architecture of nodes in
+// their heartbeats, so they are read from the first of these labels that is
+// set, e.g. static labels copied from the Kubernetes node labels.
+var (
+	osLabels   = []string{"kubernetes.io/os", "beta.kubernetes.io/os", "os"}
+	archLabels = []string{"kubernetes.io/arch", "beta.kubernetes.io/arch", "arch"}
+)
+
+// nodePlatform returns the operating system and architecture of node, or
+// "unknown" for those without a label.
+func nodePlatform(node teleport.NodeInfo) (os, arch string) {
+	return firstLabel(node.Labels, osLabels), firstLabel(node.Labels, archLabels)
+}
+
+// firstLabel returns the value of the first of keys set in labels, or
+// "unknown".
+func firstLabel(labels map[string]string, keys []string) string {
+	for _, key := range keys {
+		if value := labels[key]; value != "" {
+			return value
+		}
+	}
+	return "unknown"
+}
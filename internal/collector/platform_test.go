@@ -0,0 +1,71 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestNodePlatform(t *testing.T) {
+	tests := []struct {
+		labels   map[string]string
+		wantOS   string
+		wantArch string
+	}{
+		{labels: map[string]string{"kubernetes.io/os": "linux", "kubernetes.io/arch": "arm64"}, wantOS: "linux", wantArch: "arm64"},
+		{labels: map[string]string{"beta.kubernetes.io/arch": "amd64", "arch": "x86_64"}, wantOS: "unknown", wantArch: "amd64"},
+		{labels: map[string]string{"os": "linux", "arch": ""}, wantOS: "linux", wantArch: "unknown"},
+		{labels: nil, wantOS: "unknown", wantArch: "unknown"},
+	}
+	for _, tt := range tests {
+		os, arch := nodePlatform(teleport.NodeInfo{Name: "node", Labels: tt.labels})
+		if os != tt.wantOS || arch != tt.wantArch {
+			t.Errorf("nodePlatform(%v) = %s, %s, want %s, %s", tt.labels, os, arch, tt.wantOS, tt.wantArch)
+		}
+	}
+}
+
+func TestCollector_UpdateNodeMetrics_Platforms(t *testing.T) {
+	metrics.NodesByOS.Reset()
+	metrics.NodesByArch.Reset()
+
+	c := newTestCollector()
+	nodes := []teleport.NodeInfo{
+		{Name: "node-1", Hostname: "host1", Labels: map[string]string{"os": "linux", "arch": "amd64"}},
+		{Name: "node-2", Hostname: "host2", Labels: map[string]string{"os": "linux", "arch": "arm64"}},
+		{Name: "node-3", Hostname: "host3", Labels: map[string]string{"os": "linux", "arch": "arm64"}},
+	}
+	c.updateNodeMetrics("test-cluster", nodes)
+
+	if got := testutil.ToFloat64(metrics.NodesByOS.WithLabelValues("test-cluster", "linux")); got != 3 {
+		t.Errorf("expected 3 linux nodes, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.NodesByArch.WithLabelValues("test-cluster", "arm64")); got != 2 {
+		t.Errorf("expected 2 arm64 nodes, got %f", got)
+	}
+
+	// Architectures without nodes are removed
+	c.updateNodeMetrics("test-cluster", nodes[1:])
+	if got := testutil.CollectAndCount(metrics.NodesByArch); got != 1 {
+		t.Errorf("expected 1 architecture, got %d", got)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+// Redactable fields, named after their metric label where they have one.
+const (
+	RedactHostname   = "hostname"
+	RedactAddress    = "address"
+	RedactPublicAddr = "public_addr"
+	RedactURI        = "uri"
+)
+
+// Redaction modes.
+const (
+	// RedactModeHash replaces values by a short hash, so that series and
+	// inventory entries stay distinguishable.
+	RedactModeHash = "hash"
+	// RedactModeDrop replaces values by an empty string.
+	RedactModeDrop = "drop"
+)
+
+var redactableFields = []string{RedactHostname, RedactAddress, RedactPublicAddr, RedactURI}
+
+// Redaction hides fields that reveal internal hostnames or IPs in the *_info
+// metrics, the inventory endpoints and the export. The zero value redacts
+// nothing.
+type Redaction struct {
+	// Fields lists the fields to redact.
+	Fields []string
+	// Mode is RedactModeHash or RedactModeDrop.
+	Mode string
+}
+
+// ParseRedaction parses a comma-separated list of fields to redact with the
+// given mode. An empty list returns the zero Redaction.
+func ParseRedaction(fields, mode string) (Redaction, error) {
+	if fields == "" {
+		return Redaction{}, nil
+	}
+	if mode != RedactModeHash && mode != RedactModeDrop {
+		return Redaction{}, fmt.Errorf("invalid redaction mode %q, must be %s or %s", mode, RedactModeHash, RedactModeDrop)
+	}
+	r := Redaction{Mode: mode}
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(redactableFields, field) {
+			return Redaction{}, fmt.Errorf("invalid redacted field %q, must be one of %s", field, strings.Join(redactableFields, ", "))
+		}
+		r.Fields = append(r.Fields, field)
+	}
+	return r, nil
+}
+
+// value returns v redacted if field is redacted.
+func (r Redaction) value(field, v string) string {
+	if v == "" || !slices.Contains(r.Fields, field) {
+		return v
+	}
+	if r.Mode == RedactModeDrop {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Nodes returns a copy of nodes with the redacted fields replaced.
+func (r Redaction) Nodes(nodes []teleport.NodeInfo) []teleport.NodeInfo {
+	if len(r.Fields) == 0 {
+		return nodes
+	}
+	redacted := make([]teleport.NodeInfo, len(nodes))
+	for i, node := range nodes {
+		redacted[i] = r.Node(node)
+	}
+	return redacted
+}
+
+// Node returns node with the redacted fields replaced.
+func (r Redaction) Node(node teleport.NodeInfo) teleport.NodeInfo {
+	node.Hostname = r.value(RedactHostname, node.Hostname)
+	node.Address = r.value(RedactAddress, node.Address)
+	return node
+}
+
+// Apps returns a copy of apps with the redacted fields replaced.
+func (r Redaction) Apps(apps []teleport.AppInfo) []teleport.AppInfo {
+	if len(r.Fields) == 0 {
+		return apps
+	}
+	redacted := make([]teleport.AppInfo, len(apps))
+	for i, app := range apps {
+		app.PublicAddr = r.value(RedactPublicAddr, app.PublicAddr)
+		app.URI = r.value(RedactURI, app.URI)
+		app.Servers = r.servers(app.Servers)
+		redacted[i] = app
+	}
+	return redacted
+}
+
+// KubeClusters returns a copy of clusters with the hostnames of their servers
+// redacted.
+func (r Redaction) KubeClusters(clusters []teleport.KubeClusterInfo) []teleport.KubeClusterInfo {
+	if len(r.Fields) == 0 {
+		return clusters
+	}
+	redacted := make([]teleport.KubeClusterInfo, len(clusters))
+	for i, cluster := range clusters {
+		cluster.Servers = r.servers(cluster.Servers)
+		redacted[i] = cluster
+	}
+	return redacted
+}
+
+// Databases returns a copy of databases with the redacted fields replaced.
+func (r Redaction) Databases(databases []teleport.DatabaseInfo) []teleport.DatabaseInfo {
+	if len(r.Fields) == 0 {
+		return databases
+	}
+	redacted := make([]teleport.DatabaseInfo, len(databases))
+	for i, db := range databases {
+		db.URI = r.value(RedactURI, db.URI)
+		db.Servers = r.servers(db.Servers)
+		redacted[i] = db
+	}
+	return redacted
+}
+
+// servers returns a copy of servers with their hostnames redacted.
+func (r Redaction) servers(servers []teleport.ServerInfo) []teleport.ServerInfo {
+	if servers == nil || !slices.Contains(r.Fields, RedactHostname) {
+		return servers
+	}
+	redacted := make([]teleport.ServerInfo, len(servers))
+	for i, server := range servers {
+		server.Hostname = r.value(RedactHostname, server.Hostname)
+		redacted[i] = server
+	}
+	return redacted
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestParseRedaction(t *testing.T) {
+	tests := []struct {
+		fields  string
+		mode    string
+		want    Redaction
+		wantErr bool
+	}{
+		{fields: "", mode: "", want: Redaction{}},
+		{fields: "hostname, public_addr", mode: RedactModeHash, want: Redaction{Fields: []string{RedactHostname, RedactPublicAddr}, Mode: RedactModeHash}},
+		{fields: "address", mode: RedactModeDrop, want: Redaction{Fields: []string{RedactAddress}, Mode: RedactModeDrop}},
+		{fields: "hostname", mode: "mask", wantErr: true},
+		{fields: "name", mode: RedactModeHash, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fields+"/"+tt.mode, func(t *testing.T) {
+			got, err := ParseRedaction(tt.fields, tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRedaction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got.Fields, tt.want.Fields) || got.Mode != tt.want.Mode {
+				t.Errorf("ParseRedaction() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedaction_Nodes(t *testing.T) {
+	nodes := []teleport.NodeInfo{{Name: "node-1", Hostname: "host1.internal", Address: "10.0.0.1:3022"}}
+
+	hashed := Redaction{Fields: []string{RedactHostname}, Mode: RedactModeHash}.Nodes(nodes)
+	if h := hashed[0].Hostname; len(h) != 16 || h == nodes[0].Hostname {
+		t.Errorf("expected a 16 digit hash, got %q", h)
+	}
+	if hashed[0].Address != nodes[0].Address {
+		t.Errorf("expected the address to be kept, got %q", hashed[0].Address)
+	}
+	if again := (Redaction{Fields: []string{RedactHostname}, Mode: RedactModeHash}).Nodes(nodes); again[0].Hostname != hashed[0].Hostname {
+		t.Error("expected stable hashes")
+	}
+	if nodes[0].Hostname != "host1.internal" {
+		t.Error("expected the input to be left unchanged")
+	}
+
+	dropped := Redaction{Fields: []string{RedactHostname, RedactAddress}, Mode: RedactModeDrop}.Nodes(nodes)
+	if dropped[0].Hostname != "" || dropped[0].Address != "" {
+		t.Errorf("expected dropped values, got %+v", dropped[0])
+	}
+}
+
+func TestRedaction_Apps(t *testing.T) {
+	apps := []teleport.AppInfo{{Name: "grafana", PublicAddr: "grafana.internal", URI: "http://10.0.0.2:3000"}}
+
+	redacted := Redaction{Fields: []string{RedactURI}, Mode: RedactModeDrop}.Apps(apps)
+	if redacted[0].URI != "" || redacted[0].PublicAddr != "grafana.internal" {
+		t.Errorf("expected only the URI to be dropped, got %+v", redacted[0])
+	}
+}
+
+func TestRedaction_Databases(t *testing.T) {
+	databases := []teleport.DatabaseInfo{{Name: "orders", URI: "10.0.0.3:5432", Servers: []teleport.ServerInfo{{HostID: "host-id-1", Hostname: "agent.internal"}}}}
+
+	redacted := Redaction{Fields: []string{RedactURI}, Mode: RedactModeDrop}.Databases(databases)
+	if got := redacted[0]; got.URI != "" || got.Servers[0].Hostname != "agent.internal" {
+		t.Errorf("expected only the URI to be dropped, got %+v", got)
+	}
+}
+
+func TestRedaction_KubeClusters(t *testing.T) {
+	clusters := []teleport.KubeClusterInfo{{Name: "mc", Servers: []teleport.ServerInfo{{HostID: "host-id-1", Hostname: "agent.internal"}}}}
+
+	redacted := Redaction{Fields: []string{RedactHostname}, Mode: RedactModeDrop}.KubeClusters(clusters)
+	if got := redacted[0].Servers[0]; got.Hostname != "" || got.HostID != "host-id-1" {
+		t.Errorf("expected only the server hostname to be dropped, got %+v", got)
+	}
+	if clusters[0].Servers[0].Hostname != "agent.internal" {
+		t.Error("expected the input to be left unchanged")
+	}
+}
+
+func TestCollector_RedactedNodeInfo(t *testing.T) {
+	metrics.NodeInfo.Reset()
+	metrics.NodesByKubernetesCluster.Reset()
+
+	c := newTestCollector()
+	c.redaction = Redaction{Fields: []string{RedactHostname}, Mode: RedactModeDrop}
+	c.updateNodeMetrics("test-cluster", []teleport.NodeInfo{{Name: "node-1", Hostname: "host1.mycluster.example.com"}})
+
+	// The kube cluster is still extracted from the hostname
+	if _, ok := c.lastNodesByKubeCluster[[2]string{"test-cluster", "mycluster"}]; !ok {
+		t.Errorf("expected the kube cluster from the hostname, got %v", c.lastNodesByKubeCluster)
+	}
+	if got := c.Inventory().Nodes[0].Hostname; got != "" {
+		t.Errorf("expected the hostname to be dropped from the inventory, got %q", got)
+	}
+	if got := c.lastNodeInfo[seriesKey{name: "node-1"}][2]; got != "" {
+		t.Errorf("expected the hostname label to be dropped, got %q", got)
+	}
+}
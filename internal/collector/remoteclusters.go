@@ -0,0 +1,51 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func (c *Collector) updateRemoteClusterMetrics(clusterName string, remoteClusters []teleport.RemoteClusterInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inventory.RemoteClusters = remoteClusters
+
+	current := make(infoSeries, len(remoteClusters))
+	online := make(map[seriesKey]float64, len(remoteClusters))
+	heartbeats := make(infoSeries, len(remoteClusters))
+	lastHeartbeats := make(map[seriesKey]float64, len(remoteClusters))
+	var offline int
+	for _, rc := range remoteClusters {
+		key := seriesKey{name: rc.Name}
+		current[key] = []string{clusterName, rc.Name}
+		if rc.Status == teleport.RemoteClusterOnline {
+			online[key] = 1
+		} else {
+			offline++
+		}
+		if rc.LastHeartbeat != nil {
+			heartbeats[key] = []string{clusterName, rc.Name}
+			lastHeartbeats[key] = float64(rc.LastHeartbeat.Unix())
+		}
+	}
+	c.lastRemoteClusters = applyGaugeSeries(metrics.RemoteClusterOnline, current, online, c.lastRemoteClusters)
+	c.lastRemoteClusterHeartbeats = applyGaugeSeries(metrics.RemoteClusterLastHeartbeat, heartbeats, lastHeartbeats, c.lastRemoteClusterHeartbeats)
+	c.log.V(1).Info("updated remote cluster metrics", "count", len(remoteClusters), "offline", offline)
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestCollector_UpdateRemoteClusterMetrics(t *testing.T) {
+	metrics.RemoteClusterOnline.Reset()
+	metrics.RemoteClusterLastHeartbeat.Reset()
+
+	heartbeat := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newTestCollector()
+	c.updateRemoteClusterMetrics("test-cluster", []teleport.RemoteClusterInfo{
+		{Name: "leaf-1", Status: teleport.RemoteClusterOnline, LastHeartbeat: &heartbeat},
+		{Name: "leaf-2", Status: teleport.RemoteClusterOffline},
+	})
+
+	if got := testutil.ToFloat64(metrics.RemoteClusterOnline.WithLabelValues("test-cluster", "leaf-1")); got != 1 {
+		t.Errorf("expected leaf-1 to be online, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.RemoteClusterOnline.WithLabelValues("test-cluster", "leaf-2")); got != 0 {
+		t.Errorf("expected leaf-2 to be offline, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.RemoteClusterLastHeartbeat.WithLabelValues("test-cluster", "leaf-1")); got != float64(heartbeat.Unix()) {
+		t.Errorf("expected the last heartbeat of leaf-1, got %f", got)
+	}
+	if got := testutil.CollectAndCount(metrics.RemoteClusterLastHeartbeat); got != 1 {
+		t.Errorf("expected no heartbeat of a leaf cluster that never connected, got %d series", got)
+	}
+
+	// Removed leaf clusters are removed
+	c.updateRemoteClusterMetrics("test-cluster", []teleport.RemoteClusterInfo{
+		{Name: "leaf-2", Status: teleport.RemoteClusterOffline},
+	})
+	if got := testutil.CollectAndCount(metrics.RemoteClusterOnline); got != 1 {
+		t.Errorf("expected 1 online series, got %d", got)
+	}
+	if got := testutil.CollectAndCount(metrics.RemoteClusterLastHeartbeat); got != 0 {
+		t.Errorf("expected no heartbeat series, got %d", got)
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+)
+
+// applyMissingLabels sets teleport_exporter_resources_missing_label_total to
+// the number of resources of the resource type without each of the required
+// labels, or with an empty value, and deletes series from last that are
+// gone. Labels that no resource misses are exported as 0.
+func applyMissingLabels[T any](clusterName, resource string, required []string, resources []T, labels func(T) map[string]string, last groupSeries) groupSeries {
+	counts := missingLabelCounts(clusterName, resource, required)
+	for _, r := range resources {
+		countMissingLabels(counts, clusterName, resource, required, labels(r))
+	}
+	return applyGroupCounts(metrics.ResourcesMissingLabel, counts, last)
+}
+
+// missingLabelCounts returns the counts of resources without each of the
+// required labels, all 0.
+func missingLabelCounts(clusterName, resource string, required []string) map[[3]string]int {
+	counts := make(map[[3]string]int, len(required))
+	for _, key := range required {
+		counts[[3]string{clusterName, resource, key}] = 0
+	}
+	return counts
+}
+
+// countMissingLabels counts a resource with the given labels towards each of
+// the required labels it misses.
+func countMissingLabels(counts map[[3]string]int, clusterName, resource string, required []string, labels map[string]string) {
+	for _, key := range required {
+		if labels[key] == "" {
+			counts[[3]string{clusterName, resource, key}]++
+		}
+	}
+}
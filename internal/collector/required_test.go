@@ -0,0 +1,76 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestCollector_RequiredLabels(t *testing.T) {
+	metrics.ResourcesMissingLabel.Reset()
+
+	c := newTestCollector()
+	c.requiredLabels = []string{"team", "env"}
+	c.updateNodeMetrics("test-cluster", []teleport.NodeInfo{
+		{Name: "node-1", Labels: map[string]string{"team": "a", "env": "prod"}},
+		{Name: "node-2", Labels: map[string]string{"env": "prod"}},
+		{Name: "node-3", Labels: map[string]string{"team": ""}},
+	})
+	c.updateAppMetrics("test-cluster", []teleport.AppInfo{
+		{Name: "app-1", Labels: map[string]string{"team": "a", "env": "prod"}},
+	})
+
+	expected := map[[2]string]float64{
+		{resourceNodes, "team"}: 2,
+		{resourceNodes, "env"}:  1,
+		{resourceApps, "team"}:  0,
+		{resourceApps, "env"}:   0,
+	}
+	for series, want := range expected {
+		got := testutil.ToFloat64(metrics.ResourcesMissingLabel.WithLabelValues("test-cluster", series[0], series[1]))
+		if got != want {
+			t.Errorf("expected %v to be %f, got %f", series, want, got)
+		}
+	}
+	if got := testutil.CollectAndCount(metrics.ResourcesMissingLabel); got != len(expected) {
+		t.Errorf("expected %d series, got %d", len(expected), got)
+	}
+
+	// Series of a previous cluster name are removed
+	c.updateNodeMetrics("renamed-cluster", nil)
+	if got := testutil.CollectAndCount(metrics.ResourcesMissingLabel); got != len(expected) {
+		t.Errorf("expected %d series after the rename, got %d", len(expected), got)
+	}
+	if got := testutil.ToFloat64(metrics.ResourcesMissingLabel.WithLabelValues("renamed-cluster", resourceNodes, "team")); got != 0 {
+		t.Errorf("expected no nodes to miss the team label, got %f", got)
+	}
+}
+
+func TestCollector_RequiredLabels_None(t *testing.T) {
+	metrics.ResourcesMissingLabel.Reset()
+
+	c := newTestCollector()
+	c.updateNodeMetrics("test-cluster", []teleport.NodeInfo{{Name: "node-1"}})
+	if got := testutil.CollectAndCount(metrics.ResourcesMissingLabel); got != 0 {
+		t.Errorf("expected no series without required labels, got %d", got)
+	}
+}
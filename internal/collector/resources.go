@@ -0,0 +1,60 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// optionalResources are the resource types only collected if listed in
+// Config.ExtraResources, since reading them needs permissions beyond those
+// for the resources served by Teleport agents.
+var optionalResources = []string{resourceUsers, resourceLocks, resourceRoles, resourceTokens, resourceAccessRequests, resourceSessions, resourceRemoteClusters, resourceCertAuthorities}
+
+// required reports whether the collection of the resource type decides about
+// the success of a collection, which optional resource types do not.
+func required(resource string) bool {
+	return !slices.Contains(optionalResources, resource)
+}
+
+// ParseExtraResources parses a comma-separated list of optional resource
+// types to collect, e.g. "users,locks".
+func ParseExtraResources(s string) ([]string, error) {
+	var resources []string
+	for _, resource := range strings.Split(s, ",") {
+		resource = strings.TrimSpace(resource)
+		if resource == "" || slices.Contains(resources, resource) {
+			continue
+		}
+		if !slices.Contains(optionalResources, resource) {
+			return nil, fmt.Errorf("invalid extra resource %q, must be one of %s", resource, strings.Join(optionalResources, ", "))
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+// owns reports whether this replica collects the resource type: it belongs to
+// the shard and, if it is optional, is enabled.
+func (c *Collector) owns(resource string) bool {
+	if slices.Contains(optionalResources, resource) && !slices.Contains(c.extraResources, resource) {
+		return false
+	}
+	return c.shard.owns(resource)
+}
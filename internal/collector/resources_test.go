@@ -0,0 +1,70 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExtraResources(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{input: "", want: nil},
+		{input: "users", want: []string{resourceUsers}},
+		{input: " users , users,", want: []string{resourceUsers}},
+		{input: "nodes", wantErr: true},
+		{input: "users,widgets", wantErr: true},
+		{input: "locks,users", want: []string{resourceLocks, resourceUsers}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseExtraResources(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseExtraResources(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseExtraResources(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestCollector_Owns(t *testing.T) {
+	c := newTestCollector()
+	if !c.owns(resourceNodes) {
+		t.Error("expected the nodes to be owned without a shard")
+	}
+	if c.owns(resourceUsers) {
+		t.Error("expected the users to not be owned unless enabled")
+	}
+
+	c.extraResources = []string{resourceUsers}
+	if !c.owns(resourceUsers) {
+		t.Error("expected the enabled users to be owned")
+	}
+	// Optional resource types are still sharded
+	for index := range 2 {
+		c.shard = Shard{Index: index, Count: 2}
+		if c.owns(resourceUsers) != c.shard.owns(resourceUsers) {
+			t.Errorf("expected shard %v to own the users only if the shard does", c.shard)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"slices"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+// Risks of teleport_exporter_roles_risky_total.
+const (
+	riskWildcardLogins    = "wildcard_logins"
+	riskWildcardLabels    = "wildcard_labels"
+	riskWildcardResources = "wildcard_resources"
+)
+
+func (c *Collector) updateRoleMetrics(clusterName string, roles []teleport.RoleInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inventory.Roles = roles
+
+	// Risks without roles are exported as 0, so that a graph starts at 0
+	// rather than with a gap
+	risks := map[string]int{riskWildcardLogins: 0, riskWildcardLabels: 0, riskWildcardResources: 0}
+	for _, role := range roles {
+		for _, risk := range roleRisks(role) {
+			risks[risk]++
+		}
+	}
+	c.lastRoleRisks = applyCounts(metrics.RolesRiskyTotal, clusterName, risks, c.lastRoleRisks)
+
+	metrics.RolesTotal.WithLabelValues(clusterName).Set(float64(len(roles)))
+	c.updateRoleAssignments(clusterName)
+	c.log.V(1).Info("updated role metrics", "count", len(roles), "risks", risks)
+}
+
+// Kinds of teleport_exporter_role_assignments.
+const (
+	assigneeUser = "user"
+	assigneeBot  = "bot"
+)
+
+// roleAssignmentSeries holds the label values of the series of
+// teleport_exporter_role_assignments: cluster name, role and kind.
+type roleAssignmentSeries map[[3]string]struct{}
+
+// updateRoleAssignments sets the number of users and bots each role is
+// assigned to from the last collected users, and 0 for the last collected
+// roles assigned to no one. Until users are collected, there are no series,
+// since every role would appear unused. It must be called with c.mu held.
+func (c *Collector) updateRoleAssignments(clusterName string) {
+	counts := make(map[[3]string]int)
+	if c.inventory.Users != nil {
+		for _, role := range c.inventory.Roles {
+			counts[[3]string{clusterName, role.Name, assigneeUser}] = 0
+			counts[[3]string{clusterName, role.Name, assigneeBot}] = 0
+		}
+		for _, user := range c.inventory.Users {
+			kind := assigneeUser
+			if user.Bot {
+				kind = assigneeBot
+			}
+			for _, role := range user.Roles {
+				counts[[3]string{clusterName, role, kind}]++
+			}
+		}
+	}
+
+	current := make(roleAssignmentSeries, len(counts))
+	for series, count := range counts {
+		metrics.RoleAssignments.WithLabelValues(series[:]...).Set(float64(count))
+		current[series] = struct{}{}
+	}
+	for series := range c.lastRoleAssignments {
+		if _, exists := current[series]; !exists {
+			metrics.RoleAssignments.DeleteLabelValues(series[:]...)
+		}
+	}
+	c.lastRoleAssignments = current
+}
+
+// roleRisks returns the risks of role: node logins of any user, labels
+// matching every resource of a kind, or rules for all resources.
+func roleRisks(role teleport.RoleInfo) []string {
+	var risks []string
+	if slices.Contains(role.Logins, "*") {
+		risks = append(risks, riskWildcardLogins)
+	}
+	if len(role.WildcardLabels) > 0 {
+		risks = append(risks, riskWildcardLabels)
+	}
+	if role.WildcardResources {
+		risks = append(risks, riskWildcardResources)
+	}
+	return risks
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestRoleRisks(t *testing.T) {
+	tests := []struct {
+		name string
+		role teleport.RoleInfo
+		want []string
+	}{
+		{name: "narrow", role: teleport.RoleInfo{Logins: []string{"ubuntu"}}, want: nil},
+		{name: "wildcard logins", role: teleport.RoleInfo{Logins: []string{"ubuntu", "*"}}, want: []string{riskWildcardLogins}},
+		{
+			name: "all",
+			role: teleport.RoleInfo{Logins: []string{"*"}, WildcardLabels: []string{"node"}, WildcardResources: true},
+			want: []string{riskWildcardLogins, riskWildcardLabels, riskWildcardResources},
+		},
+	}
+	for _, tt := range tests {
+		if got := roleRisks(tt.role); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: roleRisks() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCollector_UpdateRoleMetrics(t *testing.T) {
+	metrics.RolesTotal.Reset()
+	metrics.RolesRiskyTotal.Reset()
+
+	c := newTestCollector()
+	c.updateRoleMetrics("test-cluster", []teleport.RoleInfo{
+		{Name: "access", Logins: []string{"ubuntu"}},
+		{Name: "editor", WildcardResources: true},
+		{Name: "admin", Logins: []string{"*"}, WildcardLabels: []string{"node", "app"}, WildcardResources: true},
+	})
+
+	if got := testutil.ToFloat64(metrics.RolesTotal.WithLabelValues("test-cluster")); got != 3 {
+		t.Errorf("expected RolesTotal to be 3, got %f", got)
+	}
+	expected := map[string]float64{riskWildcardLogins: 1, riskWildcardLabels: 1, riskWildcardResources: 2}
+	for risk, want := range expected {
+		if got := testutil.ToFloat64(metrics.RolesRiskyTotal.WithLabelValues("test-cluster", risk)); got != want {
+			t.Errorf("expected %s to be %f, got %f", risk, want, got)
+		}
+	}
+
+	// Risks without roles stay at 0
+	c.updateRoleMetrics("test-cluster", nil)
+	if got := testutil.CollectAndCount(metrics.RolesRiskyTotal); got != 3 {
+		t.Errorf("expected 3 series, got %d", got)
+	}
+	if got := testutil.ToFloat64(metrics.RolesRiskyTotal.WithLabelValues("test-cluster", riskWildcardResources)); got != 0 {
+		t.Errorf("expected no roles with wildcard resources, got %f", got)
+	}
+}
+
+func TestCollector_UpdateRoleAssignments(t *testing.T) {
+	metrics.RoleAssignments.Reset()
+
+	c := newTestCollector()
+	// Without users, every role would appear unused
+	c.updateRoleMetrics("test-cluster", []teleport.RoleInfo{{Name: "access"}, {Name: "admin"}, {Name: "auditor"}})
+	if got := testutil.CollectAndCount(metrics.RoleAssignments); got != 0 {
+		t.Errorf("expected no series before users are collected, got %d", got)
+	}
+
+	c.updateUserMetrics("test-cluster", []teleport.UserInfo{
+		{Name: "alice", Type: teleport.UserTypeLocal, MFA: teleport.MFAWebauthn, Roles: []string{"access", "admin"}},
+		{Name: "bob", Type: teleport.UserTypeLocal, MFA: teleport.MFAWebauthn, Roles: []string{"access"}},
+		{Name: "bot-ci", Type: teleport.UserTypeLocal, Bot: true, MFA: teleport.MFAUnknown, Roles: []string{"bot-ci"}},
+	})
+	expected := map[[2]string]float64{
+		{"access", assigneeUser}:  2,
+		{"admin", assigneeUser}:   1,
+		{"auditor", assigneeUser}: 0,
+		{"auditor", assigneeBot}:  0,
+		{"bot-ci", assigneeBot}:   1,
+	}
+	for series, want := range expected {
+		if got := testutil.ToFloat64(metrics.RoleAssignments.WithLabelValues("test-cluster", series[0], series[1])); got != want {
+			t.Errorf("expected role %s to be assigned to %v of kind %s, got %f", series[0], want, series[1], got)
+		}
+	}
+
+	// Deleted roles no longer assigned to anyone are removed
+	c.updateRoleMetrics("test-cluster", []teleport.RoleInfo{{Name: "access"}, {Name: "admin"}})
+	if got := testutil.CollectAndCount(metrics.RoleAssignments); got != 5 {
+		t.Errorf("expected 5 series, got %d", got)
+	}
+}
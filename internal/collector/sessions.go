@@ -0,0 +1,84 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"time"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+// sessionRecordingGrace is how long after a session ended its recording may
+// still be uploaded before the session counts as not recorded.
+const sessionRecordingGrace = 10 * time.Minute
+
+// updateSessionMetrics counts the sessions that ended at least
+// sessionRecordingGrace ago, once each. Sessions are searched within a sliding
+// window, so the IDs of the counted sessions are kept until they leave it.
+func (c *Collector) updateSessionMetrics(clusterName string, sessions []teleport.SessionInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inventory.Sessions = sessions
+
+	cutoff := time.Now().Add(-sessionRecordingGrace)
+	current := make(map[string]struct{}, len(sessions))
+	ended, withoutRecording := 0, 0
+	for _, session := range sessions {
+		if session.Ended.After(cutoff) {
+			continue
+		}
+		current[session.ID] = struct{}{}
+		if _, counted := c.countedSessions[session.ID]; counted {
+			continue
+		}
+		ended++
+		if missingRecording(session) {
+			withoutRecording++
+		}
+	}
+	c.countedSessions = current
+
+	metrics.SessionsEndedTotal.WithLabelValues(clusterName).Add(float64(ended))
+	metrics.SessionsWithoutRecordingTotal.WithLabelValues(clusterName).Add(float64(withoutRecording))
+	c.log.V(1).Info("updated session metrics", "count", len(sessions), "ended", ended, "withoutRecording", withoutRecording)
+}
+
+// restoreSessions marks the saved sessions as counted without counting them
+// again, since they were counted before the restart.
+func (c *Collector) restoreSessions(clusterName string, sessions []teleport.SessionInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inventory.Sessions = sessions
+	cutoff := time.Now().Add(-sessionRecordingGrace)
+	for _, session := range sessions {
+		if !session.Ended.After(cutoff) {
+			c.countedSessions[session.ID] = struct{}{}
+		}
+	}
+	metrics.SessionsEndedTotal.WithLabelValues(clusterName).Add(0)
+	metrics.SessionsWithoutRecordingTotal.WithLabelValues(clusterName).Add(0)
+}
+
+// missingRecording reports whether the recording mode required a recording
+// of session but none was uploaded. Sessions of Teleport versions that did
+// not report the recording mode are not considered.
+func missingRecording(session teleport.SessionInfo) bool {
+	return session.Recording != "" && session.Recording != teleport.RecordingOff && !session.Recorded
+}
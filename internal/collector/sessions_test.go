@@ -0,0 +1,86 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestCollector_UpdateSessionMetrics(t *testing.T) {
+	metrics.SessionsEndedTotal.Reset()
+	metrics.SessionsWithoutRecordingTotal.Reset()
+
+	now := time.Now()
+	c := newTestCollector()
+	sessions := []teleport.SessionInfo{
+		{ID: "recorded", User: "alice", Ended: now.Add(-time.Hour), Recording: "node", Recorded: true},
+		{ID: "missing", User: "bob", Ended: now.Add(-30 * time.Minute), Recording: "proxy"},
+		{ID: "off", User: "carol", Ended: now.Add(-30 * time.Minute), Recording: teleport.RecordingOff},
+		{ID: "unknown", User: "dave", Ended: now.Add(-30 * time.Minute)},
+		// Still within the grace period for the upload
+		{ID: "recent", User: "erin", Ended: now.Add(-time.Minute), Recording: "node"},
+	}
+	c.updateSessionMetrics("test-cluster", sessions)
+
+	if got := testutil.ToFloat64(metrics.SessionsEndedTotal.WithLabelValues("test-cluster")); got != 4 {
+		t.Errorf("expected SessionsEndedTotal to be 4, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.SessionsWithoutRecordingTotal.WithLabelValues("test-cluster")); got != 1 {
+		t.Errorf("expected SessionsWithoutRecordingTotal to be 1, got %f", got)
+	}
+
+	// Sessions are counted once, the recent one once its grace period passed
+	sessions[4].Ended = now.Add(-time.Hour)
+	c.updateSessionMetrics("test-cluster", sessions)
+	if got := testutil.ToFloat64(metrics.SessionsEndedTotal.WithLabelValues("test-cluster")); got != 5 {
+		t.Errorf("expected SessionsEndedTotal to be 5, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.SessionsWithoutRecordingTotal.WithLabelValues("test-cluster")); got != 2 {
+		t.Errorf("expected SessionsWithoutRecordingTotal to be 2, got %f", got)
+	}
+	if len(c.countedSessions) != 5 {
+		t.Errorf("expected 5 counted sessions, got %d", len(c.countedSessions))
+	}
+
+	// Sessions that left the search window are forgotten
+	c.updateSessionMetrics("test-cluster", sessions[:1])
+	if len(c.countedSessions) != 1 {
+		t.Errorf("expected 1 counted session, got %d", len(c.countedSessions))
+	}
+}
+
+func TestCollector_RestoreSessions(t *testing.T) {
+	metrics.SessionsEndedTotal.Reset()
+	metrics.SessionsWithoutRecordingTotal.Reset()
+
+	c := newTestCollector()
+	sessions := []teleport.SessionInfo{
+		{ID: "missing", User: "bob", Ended: time.Now().Add(-30 * time.Minute), Recording: "proxy"},
+	}
+	c.restoreSessions("test-cluster", sessions)
+	c.updateSessionMetrics("test-cluster", sessions)
+
+	if got := testutil.ToFloat64(metrics.SessionsWithoutRecordingTotal.WithLabelValues("test-cluster")); got != 0 {
+		t.Errorf("expected restored sessions not to be counted again, got %f", got)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// shardedResources are the resource types partitioned across shards. The
+// cluster name is fetched by every shard.
+var shardedResources = []string{resourceNodes, resourceKubeClusters, resourceDatabases, resourceApps, resourceUsers, resourceLocks, resourceRoles, resourceTokens, resourceAccessRequests, resourceSessions, resourceRemoteClusters, resourceCertAuthorities}
+
+// Shard selects the resource types collected by one of several replicas. The
+// zero value collects all of them.
+type Shard struct {
+	// Index is the zero-based index of this replica.
+	Index int
+	// Count is the total number of replicas.
+	Count int
+}
+
+// ParseShard parses a shard in the N/M form, where 0 <= N < M. An empty
+// string returns the zero Shard.
+func ParseShard(s string) (Shard, error) {
+	if s == "" {
+		return Shard{}, nil
+	}
+	index, count, ok := strings.Cut(s, "/")
+	if !ok {
+		return Shard{}, fmt.Errorf("invalid shard %q, must be N/M", s)
+	}
+	n, err := strconv.Atoi(index)
+	if err != nil {
+		return Shard{}, fmt.Errorf("invalid shard index %q: %w", index, err)
+	}
+	m, err := strconv.Atoi(count)
+	if err != nil {
+		return Shard{}, fmt.Errorf("invalid shard count %q: %w", count, err)
+	}
+	if m < 1 || n < 0 || n >= m {
+		return Shard{}, fmt.Errorf("invalid shard %q, must satisfy 0 <= N < M", s)
+	}
+	return Shard{Index: n, Count: m}, nil
+}
+
+// String returns the shard in the N/M form.
+func (s Shard) String() string {
+	if s.Count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// owns reports whether the shard collects the given resource type. Resource
+// types are assigned round-robin rather than by hash, so that the few types
+// spread evenly over the shards.
+func (s Shard) owns(resource string) bool {
+	if s.Count <= 1 {
+		return true
+	}
+	i := slices.Index(shardedResources, resource)
+	return i < 0 || i%s.Count == s.Index
+}
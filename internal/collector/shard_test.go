@@ -0,0 +1,77 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import "testing"
+
+func TestParseShard(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Shard
+		wantErr bool
+	}{
+		{input: "", want: Shard{}},
+		{input: "0/1", want: Shard{Index: 0, Count: 1}},
+		{input: "1/3", want: Shard{Index: 1, Count: 3}},
+		{input: "3/3", wantErr: true},
+		{input: "-1/3", wantErr: true},
+		{input: "0/0", wantErr: true},
+		{input: "1", wantErr: true},
+		{input: "a/b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseShard(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseShard(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseShard(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShard_Owns(t *testing.T) {
+	for _, count := range []int{0, 1, 2, 3, 4, 5} {
+		// Every resource type must be owned by exactly one shard
+		owners := make(map[string]int)
+		for index := range max(count, 1) {
+			s := Shard{Index: index, Count: count}
+			if !s.owns(resourceCluster) {
+				t.Errorf("shard %v does not own %s", s, resourceCluster)
+			}
+			for _, resource := range shardedResources {
+				if s.owns(resource) {
+					owners[resource]++
+				}
+			}
+		}
+		for _, resource := range shardedResources {
+			if owners[resource] != 1 {
+				t.Errorf("count %d: %s is owned by %d shards, want 1", count, resource, owners[resource])
+			}
+		}
+	}
+
+	// Two shards split the resource types evenly
+	s := Shard{Index: 0, Count: 2}
+	if !s.owns(resourceNodes) || s.owns(resourceKubeClusters) || !s.owns(resourceDatabases) || s.owns(resourceApps) {
+		t.Errorf("unexpected resource types for shard %v", s)
+	}
+}
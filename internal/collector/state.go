@@ -0,0 +1,161 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+)
+
+// state is the content of the state file. Resource types that were never
+// collected are null, so that they are not restored as empty.
+type state struct {
+	SavedAt time.Time `json:"savedAt"`
+	Inventory
+}
+
+// saveState writes the last collected inventory to the state file. The file
+// is replaced atomically, so a crash while writing keeps the previous state.
+func (c *Collector) saveState() error {
+	c.mu.RLock()
+	data, err := json.Marshal(state{SavedAt: time.Now(), Inventory: Inventory{
+		ClusterName:     c.lastClusterName,
+		Nodes:           c.inventory.Nodes,
+		KubeClusters:    c.inventory.KubeClusters,
+		Databases:       c.inventory.Databases,
+		Apps:            c.inventory.Apps,
+		Users:           c.inventory.Users,
+		Locks:           c.inventory.Locks,
+		Roles:           c.inventory.Roles,
+		Tokens:          c.inventory.Tokens,
+		AccessRequests:  c.inventory.AccessRequests,
+		Sessions:        c.inventory.Sessions,
+		RemoteClusters:  c.inventory.RemoteClusters,
+		CertAuthorities: c.inventory.CertAuthorities,
+	}})
+	c.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := c.stateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.stateFile)
+}
+
+// RestoreState exports the metrics of the inventory saved in the state file,
+// so that a restart does not blank the *_info series until the first
+// collection. Restored resource types are marked in
+// teleport_exporter_inventory_restored until they are collected again. A
+// missing state file is not an error.
+//
+// The inventory is saved after redaction, so with redacted hostnames the
+// restored nodes are only assigned to Kubernetes clusters by their labels.
+func (c *Collector) RestoreState() error {
+	data, err := os.ReadFile(c.stateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		c.log.Info("no state file to restore the inventory from", "path", c.stateFile)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("failed to parse state file %s: %w", c.stateFile, err)
+	}
+	if s.ClusterName == "" {
+		return fmt.Errorf("state file %s has no cluster name", c.stateFile)
+	}
+
+	// The saved inventory is already redacted
+	redaction := c.redaction
+	c.redaction = Redaction{}
+	defer func() { c.redaction = redaction }()
+
+	c.setClusterName(s.ClusterName)
+	var restored []string
+	if s.Nodes != nil && c.owns(resourceNodes) {
+		c.updateNodeMetrics(s.ClusterName, s.Nodes)
+		restored = append(restored, resourceNodes)
+	}
+	if s.KubeClusters != nil && c.owns(resourceKubeClusters) {
+		c.updateKubeClusterMetrics(s.ClusterName, s.KubeClusters)
+		restored = append(restored, resourceKubeClusters)
+	}
+	if s.Databases != nil && c.owns(resourceDatabases) {
+		c.updateDatabaseMetrics(s.ClusterName, s.Databases)
+		restored = append(restored, resourceDatabases)
+	}
+	if s.Apps != nil && c.owns(resourceApps) {
+		c.updateAppMetrics(s.ClusterName, s.Apps)
+		restored = append(restored, resourceApps)
+	}
+	if s.Users != nil && c.owns(resourceUsers) {
+		c.updateUserMetrics(s.ClusterName, s.Users)
+		restored = append(restored, resourceUsers)
+	}
+	if s.Locks != nil && c.owns(resourceLocks) {
+		c.updateLockMetrics(s.ClusterName, s.Locks)
+		restored = append(restored, resourceLocks)
+	}
+	if s.Roles != nil && c.owns(resourceRoles) {
+		c.updateRoleMetrics(s.ClusterName, s.Roles)
+		restored = append(restored, resourceRoles)
+	}
+	if s.Tokens != nil && c.owns(resourceTokens) {
+		c.updateTokenMetrics(s.ClusterName, s.Tokens)
+		restored = append(restored, resourceTokens)
+	}
+	if s.AccessRequests != nil && c.owns(resourceAccessRequests) {
+		c.updateAccessRequestMetrics(s.ClusterName, s.AccessRequests)
+		restored = append(restored, resourceAccessRequests)
+	}
+	if s.Sessions != nil && c.owns(resourceSessions) {
+		c.restoreSessions(s.ClusterName, s.Sessions)
+		restored = append(restored, resourceSessions)
+	}
+	if s.RemoteClusters != nil && c.owns(resourceRemoteClusters) {
+		c.updateRemoteClusterMetrics(s.ClusterName, s.RemoteClusters)
+		restored = append(restored, resourceRemoteClusters)
+	}
+	if s.CertAuthorities != nil && c.owns(resourceCertAuthorities) {
+		c.updateCertAuthorityMetrics(s.ClusterName, s.CertAuthorities)
+		restored = append(restored, resourceCertAuthorities)
+	}
+
+	c.mu.Lock()
+	for _, resource := range restored {
+		c.restored[resource] = struct{}{}
+		metrics.InventoryRestored.WithLabelValues(resource).Set(1)
+	}
+	c.mu.Unlock()
+	if err := metrics.Publish(); err != nil {
+		return err
+	}
+
+	c.log.Info("restored the inventory from the state file",
+		"path", c.stateFile, "clusterName", s.ClusterName, "resources", restored, "age", time.Since(s.SavedAt).Round(time.Second))
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestCollector_State(t *testing.T) {
+	metrics.NodesTotal.Reset()
+	metrics.AppsTotal.Reset()
+	metrics.InventoryRestored.Reset()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	c := newTestCollector()
+	c.stateFile = path
+	c.setClusterName("test-cluster")
+	c.updateNodeMetrics("test-cluster", []teleport.NodeInfo{{Name: "node-1"}, {Name: "node-2"}})
+	if err := c.saveState(); err != nil {
+		t.Fatalf("saveState() failed: %v", err)
+	}
+	metrics.NodesTotal.Reset()
+
+	restored := newTestCollector()
+	restored.stateFile = path
+	if err := restored.RestoreState(); err != nil {
+		t.Fatalf("RestoreState() failed: %v", err)
+	}
+	if got := testutil.ToFloat64(metrics.NodesTotal.WithLabelValues("test-cluster")); got != 2 {
+		t.Errorf("expected the restored NodesTotal to be 2, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.InventoryRestored.WithLabelValues(resourceNodes)); got != 1 {
+		t.Errorf("expected the nodes to be marked as restored, got %f", got)
+	}
+	// Never collected resource types are not restored as empty
+	if got := testutil.CollectAndCount(metrics.AppsTotal); got != 0 {
+		t.Errorf("expected no restored apps, got %d series", got)
+	}
+	if got := restored.Status().ClusterName; got != "test-cluster" {
+		t.Errorf("expected the restored cluster name, got %q", got)
+	}
+
+	restored.recordResult("test-cluster", resourceNodes, time.Now(), nil)
+	if got := testutil.ToFloat64(metrics.InventoryRestored.WithLabelValues(resourceNodes)); got != 0 {
+		t.Errorf("expected the nodes to no longer be marked as restored after a collection, got %f", got)
+	}
+}
+
+func TestCollector_RestoreState_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	c := newTestCollector()
+	c.stateFile = filepath.Join(dir, "missing.json")
+	if err := c.RestoreState(); err != nil {
+		t.Errorf("expected a missing state file to be ignored, got %v", err)
+	}
+
+	c.stateFile = filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(c.stateFile, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RestoreState(); err == nil {
+		t.Error("expected an invalid state file to fail")
+	}
+}
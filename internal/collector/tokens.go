@@ -0,0 +1,54 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"time"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func (c *Collector) updateTokenMetrics(clusterName string, tokens []teleport.TokenInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inventory.Tokens = tokens
+
+	now := time.Now()
+	expired, withoutExpiry := 0, 0
+	joinMethods := make(map[string]int)
+	for _, token := range tokens {
+		joinMethod := token.JoinMethod
+		if joinMethod == "" {
+			joinMethod = "unknown"
+		}
+		joinMethods[joinMethod]++
+		switch {
+		case token.Expires == nil:
+			withoutExpiry++
+		case !token.Expires.After(now):
+			expired++
+		}
+	}
+
+	metrics.TokensTotal.WithLabelValues(clusterName).Set(float64(len(tokens)))
+	metrics.TokensExpiredTotal.WithLabelValues(clusterName).Set(float64(expired))
+	metrics.TokensWithoutExpiryTotal.WithLabelValues(clusterName).Set(float64(withoutExpiry))
+	c.lastTokenJoinMethods = applyCounts(metrics.TokensByJoinMethod, clusterName, joinMethods, c.lastTokenJoinMethods)
+	c.log.V(1).Info("updated provision token metrics", "count", len(tokens), "expired", expired, "withoutExpiry", withoutExpiry)
+}
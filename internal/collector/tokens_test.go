@@ -0,0 +1,82 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestCollector_UpdateTokenMetrics(t *testing.T) {
+	metrics.TokensTotal.Reset()
+	metrics.TokensExpiredTotal.Reset()
+	metrics.TokensWithoutExpiryTotal.Reset()
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	c := newTestCollector()
+	c.updateTokenMetrics("test-cluster", []teleport.TokenInfo{
+		{Name: "expired", JoinMethod: "token", Roles: []string{"Node"}, Expires: &past},
+		{Name: "valid", JoinMethod: "token", Roles: []string{"Node"}, Expires: &future},
+		{Name: "iam", JoinMethod: "iam", Roles: []string{"Node"}},
+		{Name: "kubernetes", JoinMethod: "kubernetes", Roles: []string{"Bot"}},
+	})
+
+	expected := map[string]float64{"total": 4, "expired": 1, "withoutExpiry": 2}
+	got := map[string]float64{
+		"total":         testutil.ToFloat64(metrics.TokensTotal.WithLabelValues("test-cluster")),
+		"expired":       testutil.ToFloat64(metrics.TokensExpiredTotal.WithLabelValues("test-cluster")),
+		"withoutExpiry": testutil.ToFloat64(metrics.TokensWithoutExpiryTotal.WithLabelValues("test-cluster")),
+	}
+	for name, want := range expected {
+		if got[name] != want {
+			t.Errorf("expected %s to be %f, got %f", name, want, got[name])
+		}
+	}
+	if got := len(c.Inventory().Tokens); got != 4 {
+		t.Errorf("expected 4 tokens in the inventory, got %d", got)
+	}
+}
+
+func TestCollector_UpdateTokenMetrics_JoinMethods(t *testing.T) {
+	metrics.TokensByJoinMethod.Reset()
+
+	c := newTestCollector()
+	c.updateTokenMetrics("test-cluster", []teleport.TokenInfo{
+		{Name: "static-1", JoinMethod: "token", Roles: []string{"Node"}},
+		{Name: "static-2", JoinMethod: "token", Roles: []string{"App"}},
+		{Name: "github", JoinMethod: "github", Roles: []string{"Bot"}},
+	})
+	for joinMethod, want := range map[string]float64{"token": 2, "github": 1} {
+		if got := testutil.ToFloat64(metrics.TokensByJoinMethod.WithLabelValues("test-cluster", joinMethod)); got != want {
+			t.Errorf("expected %v tokens of join method %s, got %f", want, joinMethod, got)
+		}
+	}
+
+	// Join methods without tokens are removed
+	c.updateTokenMetrics("test-cluster", []teleport.TokenInfo{
+		{Name: "github", JoinMethod: "github", Roles: []string{"Bot"}},
+	})
+	if got := testutil.CollectAndCount(metrics.TokensByJoinMethod); got != 1 {
+		t.Errorf("expected 1 join method series, got %d", got)
+	}
+}
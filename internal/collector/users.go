@@ -0,0 +1,71 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func (c *Collector) updateUserMetrics(clusterName string, users []teleport.UserInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inventory.Users = users
+
+	withoutMFACount := 0
+	currentWithoutMFAInfo := make(infoSeries)
+	origins := make(map[string]int)
+	currentLastLogin := make(infoSeries)
+	lastLogins := make(map[string]float64)
+	for _, user := range users {
+		if user.LastLogin != nil {
+			currentLastLogin[seriesKey{name: user.Name}] = []string{clusterName, user.Name}
+			lastLogins[user.Name] = float64(user.LastLogin.Unix())
+		}
+		// Users restored from the state of an older version have no origin
+		if user.Origin != "" {
+			origins[user.Origin]++
+		}
+		if !withoutMFA(user) {
+			continue
+		}
+		withoutMFACount++
+		currentWithoutMFAInfo[seriesKey{name: user.Name}] = []string{clusterName, user.Name}
+	}
+	if c.userWithoutMFAInfo {
+		c.lastUserWithoutMFAInfo = c.applyInfoSeries("user_without_mfa_info", metrics.UserWithoutMFAInfo, currentWithoutMFAInfo, c.lastUserWithoutMFAInfo)
+	}
+	if c.userLastLogin {
+		c.lastUserLastLogin = c.applyValueSeries("user_last_login_timestamp_seconds", metrics.UserLastLogin, currentLastLogin, func(user string) float64 {
+			return lastLogins[user]
+		}, c.lastUserLastLogin)
+	}
+
+	metrics.UsersTotal.WithLabelValues(clusterName).Set(float64(len(users)))
+	metrics.UsersWithoutMFATotal.WithLabelValues(clusterName).Set(float64(withoutMFACount))
+	c.lastUserOrigins = applyCounts(metrics.UsersByOrigin, clusterName, origins, c.lastUserOrigins)
+	c.updateRoleAssignments(clusterName)
+	c.log.V(1).Info("updated user metrics", "count", len(users), "withoutMFA", withoutMFACount)
+}
+
+// withoutMFA reports whether user is a local user without an MFA device. SSO
+// users authenticate with their identity provider, which enforces its own
+// MFA, and bots cannot enroll MFA devices.
+func withoutMFA(user teleport.UserInfo) bool {
+	return user.Type == teleport.UserTypeLocal && !user.Bot && user.MFA == teleport.MFANone
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/fakes"
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestCollector_UpdateUserMetrics(t *testing.T) {
+	metrics.UsersTotal.Reset()
+	metrics.UsersWithoutMFATotal.Reset()
+	metrics.UserWithoutMFAInfo.Reset()
+
+	c := newTestCollector()
+	c.userWithoutMFAInfo = true
+	c.updateUserMetrics("test-cluster", []teleport.UserInfo{
+		{Name: "alice", Type: teleport.UserTypeLocal, MFA: teleport.MFAWebauthn},
+		{Name: "bob", Type: teleport.UserTypeLocal, MFA: teleport.MFANone},
+		{Name: "carol@example.com", Type: teleport.UserTypeSSO, MFA: teleport.MFANone},
+		{Name: "bot-ci", Type: teleport.UserTypeLocal, Bot: true, MFA: teleport.MFANone},
+	})
+
+	if got := testutil.ToFloat64(metrics.UsersTotal.WithLabelValues("test-cluster")); got != 4 {
+		t.Errorf("expected UsersTotal to be 4, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.UsersWithoutMFATotal.WithLabelValues("test-cluster")); got != 1 {
+		t.Errorf("expected UsersWithoutMFATotal to be 1, got %f", got)
+	}
+	if got := testutil.ToFloat64(metrics.UserWithoutMFAInfo.WithLabelValues("test-cluster", "bob")); got != 1 {
+		t.Errorf("expected an info series for bob, got %f", got)
+	}
+	if got := testutil.CollectAndCount(metrics.UserWithoutMFAInfo); got != 1 {
+		t.Errorf("expected 1 info series, got %d", got)
+	}
+
+	// Users that enroll a device are removed
+	c.updateUserMetrics("test-cluster", []teleport.UserInfo{
+		{Name: "bob", Type: teleport.UserTypeLocal, MFA: teleport.MFATOTP},
+	})
+	if got := testutil.CollectAndCount(metrics.UserWithoutMFAInfo); got != 0 {
+		t.Errorf("expected no info series, got %d", got)
+	}
+	if got := len(c.Inventory().Users); got != 1 {
+		t.Errorf("expected 1 user in the inventory, got %d", got)
+	}
+}
+
+func TestCollector_UpdateUserMetrics_NoInfo(t *testing.T) {
+	metrics.UserWithoutMFAInfo.Reset()
+
+	c := newTestCollector()
+	c.updateUserMetrics("test-cluster", []teleport.UserInfo{
+		{Name: "bob", Type: teleport.UserTypeLocal, MFA: teleport.MFANone},
+	})
+	if got := testutil.CollectAndCount(metrics.UserWithoutMFAInfo); got != 0 {
+		t.Errorf("expected no info series unless enabled, got %d", got)
+	}
+}
+
+func TestCollector_UpdateUserMetrics_Origins(t *testing.T) {
+	metrics.UsersByOrigin.Reset()
+
+	c := newTestCollector()
+	c.updateUserMetrics("test-cluster", []teleport.UserInfo{
+		{Name: "alice", Type: teleport.UserTypeLocal, Origin: teleport.UserOriginLocal},
+		{Name: "bob@example.com", Type: teleport.UserTypeSSO, Origin: "saml"},
+		{Name: "carol@example.com", Type: teleport.UserTypeSSO, Origin: "saml"},
+		{Name: "bot-ci", Type: teleport.UserTypeLocal, Bot: true, Origin: teleport.UserOriginBot},
+	})
+	if got := testutil.ToFloat64(metrics.UsersByOrigin.WithLabelValues("test-cluster", "saml")); got != 2 {
+		t.Errorf("expected 2 saml users, got %f", got)
+	}
+	if got := testutil.CollectAndCount(metrics.UsersByOrigin); got != 3 {
+		t.Errorf("expected 3 origins, got %d", got)
+	}
+
+	// Origins without users are removed
+	c.updateUserMetrics("test-cluster", []teleport.UserInfo{
+		{Name: "bob@example.com", Type: teleport.UserTypeSSO, Origin: "saml"},
+	})
+	if got := testutil.CollectAndCount(metrics.UsersByOrigin); got != 1 {
+		t.Errorf("expected 1 origin, got %d", got)
+	}
+}
+
+func TestCollector_CollectUsers(t *testing.T) {
+	fake := &fakes.Client{
+		ClusterName: "test-cluster",
+		Users:       []teleport.UserInfo{{Name: "alice", Type: teleport.UserTypeLocal, MFA: teleport.MFANone}},
+	}
+	c := newTestCollector()
+	c.client = fake
+
+	if err := c.CollectOnce(context.Background()); err != nil {
+		t.Fatalf("CollectOnce() failed: %v", err)
+	}
+	if got := fake.Calls(fakes.MethodGetUsers); got != 0 {
+		t.Errorf("expected the users to not be collected unless enabled, got %d calls", got)
+	}
+
+	c.extraResources = []string{resourceUsers}
+	if err := c.CollectOnce(context.Background()); err != nil {
+		t.Fatalf("CollectOnce() failed: %v", err)
+	}
+	if got := fake.Calls(fakes.MethodGetUsers); got != 1 {
+		t.Errorf("expected the enabled users to be collected, got %d calls", got)
+	}
+}
+
+func TestCollector_UpdateUserMetrics_LastLogin(t *testing.T) {
+	metrics.UserLastLogin.Reset()
+
+	loggedIn := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := newTestCollector()
+	c.userLastLogin = true
+	c.updateUserMetrics("test-cluster", []teleport.UserInfo{
+		{Name: "alice", Type: teleport.UserTypeLocal, MFA: teleport.MFAWebauthn},
+		{Name: "carol@example.com", Type: teleport.UserTypeSSO, MFA: teleport.MFANone, LastLogin: &loggedIn},
+	})
+	if got := testutil.ToFloat64(metrics.UserLastLogin.WithLabelValues("test-cluster", "carol@example.com")); got != float64(loggedIn.Unix()) {
+		t.Errorf("expected the last login of carol, got %f", got)
+	}
+	if got := testutil.CollectAndCount(metrics.UserLastLogin); got != 1 {
+		t.Errorf("expected no series for users without a login time, got %d", got)
+	}
+
+	// Deleted users are removed
+	c.updateUserMetrics("test-cluster", nil)
+	if got := testutil.CollectAndCount(metrics.UserLastLogin); got != 0 {
+		t.Errorf("expected no series, got %d", got)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package docs implements the docs-metrics subcommand, which writes a
+// Markdown table of all metrics, to keep documentation and alert catalogs in
+// sync with the code.
+package docs
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+)
+
+// Run runs the docs-metrics subcommand with the given arguments and returns
+// the exit code.
+func Run(args []string) int {
+	return run(args, os.Stdout)
+}
+
+// run runs the docs-metrics subcommand and writes the table to w, unless an
+// output file is set.
+func run(args []string, w io.Writer) int {
+	var (
+		namespace         string
+		output            string
+		nodeLabels        string
+		kubeClusterLabels string
+		databaseLabels    string
+		appLabels         string
+	)
+
+	fs := flag.NewFlagSet("docs-metrics", flag.ExitOnError)
+	fs.StringVar(&namespace, "metrics-namespace", metrics.DefaultNamespace, "Prefix of the metric names.")
+	fs.StringVar(&output, "output", "", "Path of the file to write the table to (default stdout).")
+	fs.StringVar(&nodeLabels, "node-label-to-metric-label", "", "Comma-separated list of Teleport node labels, as for the exporter.")
+	fs.StringVar(&kubeClusterLabels, "kube-cluster-label-to-metric-label", "", "Comma-separated list of Teleport Kubernetes cluster labels, as for the exporter.")
+	fs.StringVar(&databaseLabels, "database-label-to-metric-label", "", "Comma-separated list of Teleport database labels, as for the exporter.")
+	fs.StringVar(&appLabels, "app-label-to-metric-label", "", "Comma-separated list of Teleport application labels, as for the exporter.")
+	fs.Parse(args)
+
+	if err := metrics.Setup(nil, metrics.Options{
+		Namespace: namespace,
+		InfoLabels: metrics.InfoLabels{
+			Node:        splitList(nodeLabels),
+			KubeCluster: splitList(kubeClusterLabels),
+			Database:    splitList(databaseLabels),
+			App:         splitList(appLabels),
+		},
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid metrics options: %v\n", err)
+		return 1
+	}
+	docs, err := metrics.Docs()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to describe the metrics: %v\n", err)
+		return 1
+	}
+
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create %s: %v\n", output, err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeMarkdown(w, docs); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write the table: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeMarkdown writes one table row per metric.
+func writeMarkdown(w io.Writer, docs []metrics.Doc) error {
+	var b strings.Builder
+	b.WriteString("| Metric | Type | Description | Labels |\n")
+	b.WriteString("|--------|------|-------------|--------|\n")
+	for _, doc := range docs {
+		labels := make([]string, len(doc.Labels))
+		for i, label := range doc.Labels {
+			labels[i] = "`" + label + "`"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", doc.Name, doc.Type, escape(doc.Help), strings.Join(labels, ", "))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escape escapes the characters of s that would break a table cell.
+func escape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// splitList splits a comma-separated flag value into its non-empty, trimmed elements.
+func splitList(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
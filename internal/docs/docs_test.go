@@ -0,0 +1,46 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	var buf bytes.Buffer
+	if code := run([]string{"--metrics-namespace=teleport", "--node-label-to-metric-label=env"}, &buf); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	out := buf.String()
+
+	if !strings.HasPrefix(out, "| Metric | Type | Description | Labels |\n") {
+		t.Errorf("expected the table header, got:\n%s", out)
+	}
+	for _, row := range []string{
+		"| `teleport_up` | gauge | Whether the exporter can successfully connect to Teleport (1 = connected, 0 = disconnected). |  |",
+		"| `teleport_node_info` | gauge | Information about each SSH node registered in Teleport (value is always 1). | `cluster_name`, `node_name`, `hostname`, `label_env` |",
+	} {
+		if !strings.Contains(out, row) {
+			t.Errorf("expected %q in the table, got:\n%s", row, out)
+		}
+	}
+	if strings.Contains(out, "teleport_exporter_") {
+		t.Errorf("expected all metrics in the configured namespace, got:\n%s", out)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package emf writes the exporter metrics in CloudWatch Embedded Metric Format.
+package emf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// maxDimensions is the CloudWatch limit of dimensions per metric.
+const maxDimensions = 30
+
+// Config holds the configuration of the EMF writer.
+type Config struct {
+	// Output receives one EMF JSON document per line.
+	Output io.Writer
+	// Namespace is the CloudWatch namespace of the metrics.
+	Namespace string
+	// Interval is the time between two writes.
+	Interval time.Duration
+	// Prefix restricts the written metrics to the names starting with it.
+	Prefix   string
+	Gatherer prometheus.Gatherer
+	Log      logr.Logger
+}
+
+// Writer periodically writes gauges as their value and counters as the
+// increase since the previous write.
+type Writer struct {
+	cfg Config
+	// last holds the counter values of the previous write, keyed by metric
+	// name and labels.
+	last map[string]float64
+}
+
+// New creates a Writer.
+func New(cfg Config) *Writer {
+	return &Writer{cfg: cfg, last: make(map[string]float64)}
+}
+
+// Run writes the metrics every interval until ctx is cancelled, and once more
+// before returning, so that the terminal state is written on shutdown.
+func (w *Writer) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := w.Write(time.Now()); err != nil {
+				w.cfg.Log.Error(err, "failed to write the final EMF metrics")
+			}
+			return
+		case <-ticker.C:
+			if err := w.Write(time.Now()); err != nil {
+				w.cfg.Log.Error(err, "failed to write EMF metrics")
+			}
+		}
+	}
+}
+
+// Write gathers the metrics and writes them with the given timestamp.
+func (w *Writer) Write(now time.Time) error {
+	families, err := w.cfg.Gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+
+	enc := json.NewEncoder(w.cfg.Output)
+	for _, mf := range families {
+		name := mf.GetName()
+		if !strings.HasPrefix(name, w.cfg.Prefix) {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			var value float64
+			unit := "None"
+			switch mf.GetType() {
+			case dto.MetricType_GAUGE:
+				value = m.GetGauge().GetValue()
+			case dto.MetricType_COUNTER:
+				key := seriesKey(name, m.GetLabel())
+				current := m.GetCounter().GetValue()
+				last, seen := w.last[key]
+				w.last[key] = current
+				// A counter lower than before has been reset
+				if !seen || current < last {
+					continue
+				}
+				value = current - last
+				unit = "Count"
+			default:
+				continue
+			}
+			if err := enc.Encode(w.document(name, value, unit, m.GetLabel(), now)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// seriesKey identifies a series by its name and labels.
+func seriesKey(name string, labels []*dto.LabelPair) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, l := range labels {
+		b.WriteString("\xff" + l.GetName() + "=" + l.GetValue())
+	}
+	return b.String()
+}
+
+// document builds the EMF document of a single series. Its labels become
+// the CloudWatch dimensions.
+func (w *Writer) document(name string, value float64, unit string, labels []*dto.LabelPair, now time.Time) map[string]any {
+	doc := map[string]any{name: value}
+	dimensions := make([]string, 0, len(labels))
+	for _, l := range labels {
+		// CloudWatch rejects empty dimension values
+		if l.GetValue() == "" {
+			continue
+		}
+		doc[l.GetName()] = l.GetValue()
+		dimensions = append(dimensions, l.GetName())
+	}
+	sort.Strings(dimensions)
+	if len(dimensions) > maxDimensions {
+		dimensions = dimensions[:maxDimensions]
+	}
+
+	doc["_aws"] = map[string]any{
+		"Timestamp": now.UnixMilli(),
+		"CloudWatchMetrics": []map[string]any{{
+			"Namespace":  w.cfg.Namespace,
+			"Dimensions": [][]string{dimensions},
+			"Metrics":    []map[string]string{{"Name": name, "Unit": unit}},
+		}},
+	}
+	return doc
+}
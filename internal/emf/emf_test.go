@@ -0,0 +1,108 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package emf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestWriter_Write(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	nodes := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_nodes_total"}, []string{"cluster_name", "empty"})
+	errs := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_errors_total"})
+	other := prometheus.NewGauge(prometheus.GaugeOpts{Name: "other_gauge"})
+	reg.MustRegister(nodes, errs, other)
+
+	var out bytes.Buffer
+	w := New(Config{
+		Output:    &out,
+		Namespace: "Teleport",
+		Interval:  time.Minute,
+		Prefix:    "test_",
+		Gatherer:  reg,
+		Log:       logr.Discard(),
+	})
+
+	nodes.WithLabelValues("example", "").Set(3)
+	errs.Add(2)
+	now := time.UnixMilli(1700000000000)
+
+	// The first write only records the counter values
+	if err := w.Write(now); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := `{"_aws":{"CloudWatchMetrics":[{"Dimensions":[["cluster_name"]],"Metrics":[{"Name":"test_nodes_total","Unit":"None"}],"Namespace":"Teleport"}],"Timestamp":1700000000000},"cluster_name":"example","test_nodes_total":3}` + "\n"
+	if got := out.String(); got != want {
+		t.Errorf("first write = %s, want %s", got, want)
+	}
+
+	out.Reset()
+	errs.Add(5)
+	if err := w.Write(now); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	dec := json.NewDecoder(&out)
+	var docs []map[string]any
+	for dec.More() {
+		var doc map[string]any
+		if err := dec.Decode(&doc); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("got %d documents, want 2", len(docs))
+	}
+	if got := docs[0]["test_errors_total"]; got != 5.0 {
+		t.Errorf("test_errors_total = %v, want 5", got)
+	}
+}
+
+func TestWriter_RunWritesOnShutdown(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	up := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_up"})
+	reg.MustRegister(up)
+
+	var out bytes.Buffer
+	w := New(Config{
+		Output:    &out,
+		Namespace: "Teleport",
+		Interval:  time.Hour,
+		Prefix:    "test_",
+		Gatherer:  reg,
+		Log:       logr.Discard(),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w.Run(ctx)
+
+	var doc map[string]any
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("expected a final document, got %q: %v", out.String(), err)
+	}
+	if got := doc["test_up"]; got != 0.0 {
+		t.Errorf("test_up = %v, want 0", got)
+	}
+}
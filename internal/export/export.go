@@ -0,0 +1,265 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package export implements the export subcommand, which writes a snapshot of
+// the cluster inventory for audits.
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+	"go.yaml.in/yaml/v2"
+
+	"github.com/giantswarm/teleport-exporter/internal/awsidentity"
+	"github.com/giantswarm/teleport-exporter/internal/collector"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+// snapshot is the document written by the export subcommand.
+type snapshot struct {
+	CollectedAt time.Time `json:"collectedAt"`
+	collector.Inventory
+}
+
+// Run runs the export subcommand with the given arguments and returns the
+// exit code.
+func Run(args []string) int {
+	var (
+		teleportAddr string
+		identityFile string
+		awsSecret    string
+		awsParameter string
+		certFile     string
+		keyFile      string
+		credsCAFile  string
+		profileName  string
+		profileDir   string
+		apiTimeout   time.Duration
+		insecure     bool
+		alpnUpgrade  bool
+		caFile       string
+		tlsMin       string
+		tlsCiphers   string
+		namespaces   string
+		redactFields string
+		redactMode   string
+		connMode     string
+		output       string
+		format       string
+	)
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.StringVar(&teleportAddr, "teleport-addr", "", "The address of the Teleport proxy/auth server (e.g., teleport.example.com:443).")
+	fs.StringVar(&identityFile, "identity-file", "", "Path to the identity file for authentication.")
+	fs.StringVar(&awsSecret, "identity-aws-secret", "", "Name or ARN of an AWS Secrets Manager secret holding the identity file content.")
+	fs.StringVar(&awsParameter, "identity-aws-parameter", "", "Name or ARN of an AWS SSM parameter holding the identity file content.")
+	fs.StringVar(&certFile, "cert-file", "", "Path to a PEM encoded Teleport client certificate to authenticate with.")
+	fs.StringVar(&keyFile, "key-file", "", "Path to the PEM encoded private key of cert-file.")
+	fs.StringVar(&credsCAFile, "ca-file", "", "Path to the PEM encoded Teleport cluster CA certificates.")
+	fs.StringVar(&profileName, "profile", "", "Name of a tsh profile to authenticate with.")
+	fs.StringVar(&profileDir, "profile-dir", "", "Directory of the tsh profiles (default ~/.tsh).")
+	fs.DurationVar(&apiTimeout, "api-timeout", 30*time.Second, "Timeout for Teleport API calls.")
+	fs.BoolVar(&insecure, "insecure", false, "Skip TLS certificate verification (not recommended for production).")
+	fs.BoolVar(&alpnUpgrade, "alpn-conn-upgrade", false, "Tunnel the connection to Teleport through an HTTP upgrade, for proxies with TLS routing behind load balancers that strip ALPN.")
+	fs.StringVar(&connMode, "connection-mode", teleport.ConnectionModeAuto, "How to connect to --teleport-addr: auto, proxy or auth.")
+	fs.StringVar(&caFile, "teleport-ca-file", "", "Path to a PEM bundle of CA certificates to verify the Teleport proxy with, in addition to the system CAs.")
+	fs.StringVar(&tlsMin, "tls-min-version", "1.2", "Minimum TLS version of the connection to Teleport: 1.2 or 1.3.")
+	fs.StringVar(&namespaces, "teleport-namespace", "default", "Comma-separated list of Teleport namespaces to list resources in.")
+	fs.StringVar(&tlsCiphers, "tls-cipher-suites", "", "Comma separated TLS 1.2 cipher suites allowed on the connection to Teleport (Go defaults if empty).")
+	fs.StringVar(&redactFields, "redact-fields", "", "Comma-separated list of fields to redact in the snapshot: hostname, address, public_addr, uri.")
+	fs.StringVar(&redactMode, "redact-mode", collector.RedactModeHash, "How to redact --redact-fields: hash or drop.")
+	fs.StringVar(&output, "output", "-", "Path of the snapshot file, - for stdout.")
+	fs.StringVar(&format, "format", "yaml", "Format of the snapshot: yaml or json.")
+	fs.Parse(args)
+
+	zapLog, err := zap.NewProduction()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create logger: %v\n", err)
+		return 1
+	}
+	defer zapLog.Sync()
+	log := zapr.NewLogger(zapLog)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	var identityContent func() string
+	if awsSecret != "" || awsParameter != "" {
+		src, err := awsidentity.New(ctx, awsidentity.Config{
+			SecretID:      awsSecret,
+			ParameterName: awsParameter,
+			Log:           log.WithName("aws-identity"),
+		})
+		if err != nil {
+			log.Error(err, "failed to load identity from AWS")
+			return 1
+		}
+		identityContent = src.Identity
+	}
+
+	if err := teleport.ValidateCredentials(teleport.Config{
+		IdentityFile:    identityFile,
+		IdentityContent: identityContent,
+		CertFile:        certFile,
+		KeyFile:         keyFile,
+		CAFile:          credsCAFile,
+		Profile:         profileName,
+	}); err != nil {
+		log.Error(err, "invalid credentials")
+		return 1
+	}
+	if teleportAddr == "" && profileName != "" {
+		if teleportAddr, err = teleport.ProfileProxyAddr(profileDir, profileName); err != nil {
+			log.Error(err, "invalid profile")
+			return 1
+		}
+	}
+	if teleportAddr == "" {
+		log.Error(nil, "teleport-addr is required")
+		return 1
+	}
+	minTLSVersion, err := teleport.ParseTLSVersion(tlsMin)
+	if err != nil {
+		log.Error(err, "invalid tls-min-version")
+		return 1
+	}
+	cipherSuites, err := teleport.ParseCipherSuites(tlsCiphers)
+	if err != nil {
+		log.Error(err, "invalid tls-cipher-suites")
+		return 1
+	}
+	redaction, err := collector.ParseRedaction(redactFields, redactMode)
+	if err != nil {
+		log.Error(err, "invalid redaction")
+		return 1
+	}
+	if format != "yaml" && format != "json" {
+		log.Error(nil, "format must be yaml or json", "format", format)
+		return 1
+	}
+	if caFile != "" {
+		if err := teleport.UseCAFile(caFile); err != nil {
+			log.Error(err, "invalid Teleport CA file")
+			return 1
+		}
+	}
+
+	client, err := teleport.NewClient(teleport.Config{
+		ProxyAddr:       teleportAddr,
+		IdentityFile:    identityFile,
+		IdentityContent: identityContent,
+		CertFile:        certFile,
+		KeyFile:         keyFile,
+		CAFile:          credsCAFile,
+		Profile:         profileName,
+		ProfileDir:      profileDir,
+		Insecure:        insecure,
+		ConnectionMode:  connMode,
+		ALPNConnUpgrade: alpnUpgrade,
+		TLSMinVersion:   minTLSVersion,
+		TLSCipherSuites: cipherSuites,
+		APITimeout:      apiTimeout,
+		Namespaces:      teleport.ParseNamespaces(namespaces),
+		Log:             log.WithName("teleport-client"),
+	})
+	if err != nil {
+		log.Error(err, "failed to create Teleport client")
+		return 1
+	}
+	defer client.Close()
+
+	snap, err := fetchSnapshot(ctx, client)
+	if err != nil {
+		log.Error(err, "failed to fetch inventory")
+		return 1
+	}
+	snap.Nodes = redaction.Nodes(snap.Nodes)
+	snap.KubeClusters = redaction.KubeClusters(snap.KubeClusters)
+	snap.Databases = redaction.Databases(snap.Databases)
+	snap.Apps = redaction.Apps(snap.Apps)
+	data, err := encodeSnapshot(snap, format)
+	if err != nil {
+		log.Error(err, "failed to encode snapshot")
+		return 1
+	}
+
+	if output == "-" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(output, data, 0o600)
+	}
+	if err != nil {
+		log.Error(err, "failed to write snapshot")
+		return 1
+	}
+	log.Info("exported inventory", "output", output,
+		"nodes", len(snap.Nodes),
+		"kubernetesClusters", len(snap.KubeClusters),
+		"databases", len(snap.Databases),
+		"apps", len(snap.Apps),
+	)
+	return 0
+}
+
+// fetchSnapshot fetches all resources. Unlike the collector, it fails on the
+// first error, since a partial snapshot is misleading in an audit.
+func fetchSnapshot(ctx context.Context, client *teleport.Client) (snapshot, error) {
+	snap := snapshot{CollectedAt: time.Now().UTC()}
+	var err error
+	if snap.ClusterName, err = client.GetClusterName(ctx); err != nil {
+		return snap, fmt.Errorf("failed to get cluster name: %w", err)
+	}
+	if snap.Nodes, err = client.GetNodes(ctx); err != nil {
+		return snap, fmt.Errorf("failed to get nodes: %w", err)
+	}
+	if snap.KubeClusters, err = client.GetKubeClusters(ctx); err != nil {
+		return snap, fmt.Errorf("failed to get Kubernetes clusters: %w", err)
+	}
+	if snap.Databases, err = client.GetDatabases(ctx); err != nil {
+		return snap, fmt.Errorf("failed to get databases: %w", err)
+	}
+	if snap.Apps, err = client.GetApps(ctx); err != nil {
+		return snap, fmt.Errorf("failed to get applications: %w", err)
+	}
+	return snap, nil
+}
+
+// encodeSnapshot encodes snap as JSON or YAML. YAML is converted from JSON so
+// that both formats use the same field names.
+func encodeSnapshot(snap snapshot, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if format == "json" {
+		return append(data, '\n'), nil
+	}
+
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
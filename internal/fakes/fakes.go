@@ -0,0 +1,241 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fakes provides an in-memory implementation of the Teleport client
+// used by the collector, to test collections without a Teleport cluster and
+// to serve the synthetic resources of the --mock mode.
+package fakes
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+// Methods of Client, used as keys of Client.Errors and Client.Calls.
+const (
+	MethodGetClusterName     = "GetClusterName"
+	MethodGetNodes           = "GetNodes"
+	MethodGetKubeClusters    = "GetKubeClusters"
+	MethodGetDatabases       = "GetDatabases"
+	MethodGetApps            = "GetApps"
+	MethodGetUsers           = "GetUsers"
+	MethodGetLocks           = "GetLocks"
+	MethodGetRoles           = "GetRoles"
+	MethodGetTokens          = "GetTokens"
+	MethodGetAccessRequests  = "GetAccessRequests"
+	MethodGetSessions        = "GetSessions"
+	MethodGetRemoteClusters  = "GetRemoteClusters"
+	MethodGetCertAuthorities = "GetCertAuthorities"
+	MethodCheckAccess        = "CheckAccess"
+	MethodReconnect          = "Reconnect"
+)
+
+// Client is a configurable in-memory Teleport client. The zero value serves
+// an empty cluster named "fake". Its fields must not be changed while a call
+// is in flight.
+type Client struct {
+	// ClusterName is the cluster name returned by GetClusterName; empty
+	// means "fake".
+	ClusterName     string
+	Nodes           []teleport.NodeInfo
+	KubeClusters    []teleport.KubeClusterInfo
+	Databases       []teleport.DatabaseInfo
+	Apps            []teleport.AppInfo
+	Users           []teleport.UserInfo
+	Locks           []teleport.LockInfo
+	Roles           []teleport.RoleInfo
+	Tokens          []teleport.TokenInfo
+	AccessRequests  []teleport.AccessRequestInfo
+	Sessions        []teleport.SessionInfo
+	RemoteClusters  []teleport.RemoteClusterInfo
+	CertAuthorities []teleport.CertAuthorityInfo
+	// Errors makes every call of the method fail with the error.
+	Errors map[string]error
+	// AccessErrors makes CheckAccess fail for the resource type.
+	AccessErrors map[string]error
+	// Delays slow down every call of the method, regardless of the deadline
+	// of its context, like an API call that does not honour it.
+	Delays map[string]time.Duration
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// call records a call of method and returns the error it should fail with.
+func (f *Client) call(ctx context.Context, method string) error {
+	time.Sleep(f.Delays[method])
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	f.calls[method]++
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.Errors[method]
+}
+
+// Calls returns how often method was called.
+func (f *Client) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+// GetClusterName returns ClusterName.
+func (f *Client) GetClusterName(ctx context.Context) (string, error) {
+	if err := f.call(ctx, MethodGetClusterName); err != nil {
+		return "", err
+	}
+	if f.ClusterName == "" {
+		return "fake", nil
+	}
+	return f.ClusterName, nil
+}
+
+// GetNodes returns a copy of Nodes.
+func (f *Client) GetNodes(ctx context.Context) ([]teleport.NodeInfo, error) {
+	if err := f.call(ctx, MethodGetNodes); err != nil {
+		return nil, err
+	}
+	return nonNil(slices.Clone(f.Nodes)), nil
+}
+
+// ForEachNode calls fn for each of Nodes, counting as a GetNodes call.
+func (f *Client) ForEachNode(ctx context.Context, fn func(teleport.NodeInfo)) error {
+	if err := f.call(ctx, MethodGetNodes); err != nil {
+		return err
+	}
+	for _, node := range f.Nodes {
+		fn(node)
+	}
+	return nil
+}
+
+// GetKubeClusters returns a copy of KubeClusters.
+func (f *Client) GetKubeClusters(ctx context.Context) ([]teleport.KubeClusterInfo, error) {
+	if err := f.call(ctx, MethodGetKubeClusters); err != nil {
+		return nil, err
+	}
+	return nonNil(slices.Clone(f.KubeClusters)), nil
+}
+
+// GetDatabases returns a copy of Databases.
+func (f *Client) GetDatabases(ctx context.Context) ([]teleport.DatabaseInfo, error) {
+	if err := f.call(ctx, MethodGetDatabases); err != nil {
+		return nil, err
+	}
+	return nonNil(slices.Clone(f.Databases)), nil
+}
+
+// GetApps returns a copy of Apps.
+func (f *Client) GetApps(ctx context.Context) ([]teleport.AppInfo, error) {
+	if err := f.call(ctx, MethodGetApps); err != nil {
+		return nil, err
+	}
+	return nonNil(slices.Clone(f.Apps)), nil
+}
+
+// GetUsers returns a copy of Users.
+func (f *Client) GetUsers(ctx context.Context) ([]teleport.UserInfo, error) {
+	if err := f.call(ctx, MethodGetUsers); err != nil {
+		return nil, err
+	}
+	return nonNil(slices.Clone(f.Users)), nil
+}
+
+// GetLocks returns a copy of Locks.
+func (f *Client) GetLocks(ctx context.Context) ([]teleport.LockInfo, error) {
+	if err := f.call(ctx, MethodGetLocks); err != nil {
+		return nil, err
+	}
+	return nonNil(slices.Clone(f.Locks)), nil
+}
+
+// GetRoles returns a copy of Roles.
+func (f *Client) GetRoles(ctx context.Context) ([]teleport.RoleInfo, error) {
+	if err := f.call(ctx, MethodGetRoles); err != nil {
+		return nil, err
+	}
+	return nonNil(slices.Clone(f.Roles)), nil
+}
+
+// GetTokens returns a copy of Tokens.
+func (f *Client) GetTokens(ctx context.Context) ([]teleport.TokenInfo, error) {
+	if err := f.call(ctx, MethodGetTokens); err != nil {
+		return nil, err
+	}
+	return nonNil(slices.Clone(f.Tokens)), nil
+}
+
+// GetAccessRequests returns a copy of AccessRequests.
+func (f *Client) GetAccessRequests(ctx context.Context) ([]teleport.AccessRequestInfo, error) {
+	if err := f.call(ctx, MethodGetAccessRequests); err != nil {
+		return nil, err
+	}
+	return nonNil(slices.Clone(f.AccessRequests)), nil
+}
+
+// GetSessions returns a copy of Sessions.
+func (f *Client) GetSessions(ctx context.Context) ([]teleport.SessionInfo, error) {
+	if err := f.call(ctx, MethodGetSessions); err != nil {
+		return nil, err
+	}
+	return nonNil(slices.Clone(f.Sessions)), nil
+}
+
+// GetRemoteClusters returns a copy of RemoteClusters.
+func (f *Client) GetRemoteClusters(ctx context.Context) ([]teleport.RemoteClusterInfo, error) {
+	if err := f.call(ctx, MethodGetRemoteClusters); err != nil {
+		return nil, err
+	}
+	return nonNil(slices.Clone(f.RemoteClusters)), nil
+}
+
+// GetCertAuthorities returns a copy of CertAuthorities.
+func (f *Client) GetCertAuthorities(ctx context.Context) ([]teleport.CertAuthorityInfo, error) {
+	if err := f.call(ctx, MethodGetCertAuthorities); err != nil {
+		return nil, err
+	}
+	return nonNil(slices.Clone(f.CertAuthorities)), nil
+}
+
+// CheckAccess returns the error of the resource type in AccessErrors.
+func (f *Client) CheckAccess(ctx context.Context, resource string) error {
+	if err := f.call(ctx, MethodCheckAccess); err != nil {
+		return err
+	}
+	return f.AccessErrors[resource]
+}
+
+// Reconnect only records the call.
+func (f *Client) Reconnect(ctx context.Context) error {
+	return f.call(ctx, MethodReconnect)
+}
+
+// nonNil returns s, or an empty slice if s is nil, like the lists of the real
+// client.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
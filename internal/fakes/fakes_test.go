@@ -0,0 +1,56 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fakes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestClient(t *testing.T) {
+	boom := errors.New("boom")
+	f := &Client{
+		Nodes:  []teleport.NodeInfo{{Name: "node-1"}},
+		Errors: map[string]error{MethodGetApps: boom},
+	}
+
+	if name, err := f.GetClusterName(context.Background()); err != nil || name != "fake" {
+		t.Errorf("GetClusterName() = %q, %v, want fake", name, err)
+	}
+	nodes, err := f.GetNodes(context.Background())
+	if err != nil || len(nodes) != 1 {
+		t.Errorf("GetNodes() = %v, %v, want one node", nodes, err)
+	}
+	if dbs, err := f.GetDatabases(context.Background()); err != nil || dbs == nil {
+		t.Errorf("GetDatabases() = %v, %v, want an empty list", dbs, err)
+	}
+	if _, err := f.GetApps(context.Background()); !errors.Is(err, boom) {
+		t.Errorf("GetApps() error = %v, want %v", err, boom)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := f.GetNodes(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetNodes() with a cancelled context error = %v, want %v", err, context.Canceled)
+	}
+	if got := f.Calls(MethodGetNodes); got != 2 {
+		t.Errorf("Calls(GetNodes) = %d, want 2", got)
+	}
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gen implements the gen subcommand, which generates configuration
+// derived from the exported metrics, e.g. Prometheus alerting rules.
+package gen
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"go.yaml.in/yaml/v2"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+)
+
+// Output formats of gen rules.
+const (
+	FormatPrometheus     = "prometheus"
+	FormatPrometheusRule = "prometheusrule"
+)
+
+// Run runs the gen subcommand with the given arguments and returns the exit
+// code.
+func Run(args []string) int {
+	return run(args, os.Stdout)
+}
+
+// run runs the gen subcommand and writes the result to w, unless an output
+// file is set.
+func run(args []string, w io.Writer) int {
+	if len(args) == 0 || args[0] != "rules" {
+		fmt.Fprintln(os.Stderr, "usage: teleport-exporter gen rules [flags]")
+		return 1
+	}
+
+	var (
+		opts          ruleOptions
+		format        string
+		name          string
+		k8sNamespace  string
+		output        string
+		metricsPrefix string
+	)
+	fs := flag.NewFlagSet("gen rules", flag.ExitOnError)
+	fs.StringVar(&format, "format", FormatPrometheus, "Output format: prometheus (a rule file) or prometheusrule (a Prometheus Operator PrometheusRule).")
+	fs.StringVar(&name, "name", "teleport-exporter", "Name of the PrometheusRule and of the rule group.")
+	fs.StringVar(&k8sNamespace, "namespace", "", "Kubernetes namespace of the PrometheusRule.")
+	fs.StringVar(&output, "output", "", "Path of the file to write the rules to (default stdout).")
+	fs.StringVar(&metricsPrefix, "metrics-namespace", metrics.DefaultNamespace, "Prefix of the metric names, as for the exporter.")
+	fs.DurationVar(&opts.StaleAfter, "stale-after", 15*time.Minute, "Age of the last successful collection after which the exporter is stale.")
+	fs.DurationVar(&opts.DownFor, "down-for", 5*time.Minute, "How long Teleport must be unreachable before alerting.")
+	fs.StringVar(&opts.StaleSeverity, "stale-severity", "warning", "Severity label of the stale exporter alert.")
+	fs.StringVar(&opts.DownSeverity, "down-severity", "critical", "Severity label of the Teleport down alert.")
+	fs.DurationVar(&opts.LeafDisconnectedFor, "leaf-disconnected-for", 15*time.Minute, "How long a leaf cluster must be disconnected before alerting.")
+	fs.DurationVar(&opts.CAExpiresWithin, "ca-expires-within", 30*24*time.Hour, "Time before the expiry of a CA certificate from which on to alert.")
+	fs.StringVar(&opts.LeafSeverity, "leaf-severity", "warning", "Severity label of the leaf cluster disconnected alert.")
+	fs.StringVar(&opts.CASeverity, "ca-severity", "warning", "Severity label of the CA expiring alert.")
+	fs.Parse(args[1:])
+
+	if format != FormatPrometheus && format != FormatPrometheusRule {
+		fmt.Fprintf(os.Stderr, "format must be %s or %s\n", FormatPrometheus, FormatPrometheusRule)
+		return 1
+	}
+	if opts.StaleAfter <= 0 || opts.DownFor < 0 {
+		fmt.Fprintln(os.Stderr, "stale-after must be positive and down-for must not be negative")
+		return 1
+	}
+	if opts.CAExpiresWithin <= 0 || opts.LeafDisconnectedFor < 0 {
+		fmt.Fprintln(os.Stderr, "ca-expires-within must be positive and leaf-disconnected-for must not be negative")
+		return 1
+	}
+	opts.Namespace = metricsPrefix
+	opts.GroupName = name
+
+	var doc any = rules(opts)
+	if format == FormatPrometheusRule {
+		doc = prometheusRule{
+			APIVersion: "monitoring.coreos.com/v1",
+			Kind:       "PrometheusRule",
+			Metadata:   objectMeta{Name: name, Namespace: k8sNamespace},
+			Spec:       rules(opts),
+		}
+	}
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode the rules: %v\n", err)
+		return 1
+	}
+
+	if output != "" {
+		if err := os.WriteFile(output, data, 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write %s: %v\n", output, err)
+			return 1
+		}
+		return 0
+	}
+	if _, err := w.Write(data); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write the rules: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// ruleOptions parameterizes the generated alerting rules.
+type ruleOptions struct {
+	// Namespace is the prefix of the metric names.
+	Namespace string
+	// GroupName is the name of the rule group.
+	GroupName string
+	// StaleAfter is the age of the last successful collection after which
+	// the exporter is stale.
+	StaleAfter time.Duration
+	// DownFor is how long Teleport must be unreachable before alerting.
+	DownFor       time.Duration
+	StaleSeverity string
+	DownSeverity  string
+	// LeafDisconnectedFor is how long a leaf cluster must be disconnected
+	// before alerting.
+	LeafDisconnectedFor time.Duration
+	// CAExpiresWithin is the time before the expiry of a CA certificate from
+	// which on to alert.
+	CAExpiresWithin time.Duration
+	LeafSeverity    string
+	CASeverity      string
+}
+
+// ruleFile is a Prometheus rule file, and the spec of a PrometheusRule.
+type ruleFile struct {
+	Groups []ruleGroup `yaml:"groups"`
+}
+
+type ruleGroup struct {
+	Name  string `yaml:"name"`
+	Rules []rule `yaml:"rules"`
+}
+
+type rule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+type prometheusRule struct {
+	APIVersion string     `yaml:"apiVersion"`
+	Kind       string     `yaml:"kind"`
+	Metadata   objectMeta `yaml:"metadata"`
+	Spec       ruleFile   `yaml:"spec"`
+}
+
+type objectMeta struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+// rules returns the alerting rules on the exported metrics. The rules on leaf
+// clusters and CAs only fire if the exporter collects the remote_clusters and
+// cert_authorities resource types.
+func rules(opts ruleOptions) ruleFile {
+	ns := opts.Namespace
+	if ns == "" {
+		ns = metrics.DefaultNamespace
+	}
+	return ruleFile{Groups: []ruleGroup{{
+		Name: opts.GroupName,
+		Rules: []rule{
+			{
+				Alert: "TeleportExporterStale",
+				Expr: fmt.Sprintf("time() - max by (cluster_name) (%s_last_successful_collect_timestamp_seconds) > %d",
+					ns, int64(opts.StaleAfter.Seconds())),
+				Labels: map[string]string{"severity": opts.StaleSeverity},
+				Annotations: map[string]string{
+					"summary":     "The Teleport exporter has not collected successfully for " + promDuration(opts.StaleAfter) + ".",
+					"description": "The metrics of Teleport cluster {{ $labels.cluster_name }} were last collected {{ $value | humanizeDuration }} ago.",
+				},
+			},
+			{
+				Alert:  "TeleportDown",
+				Expr:   fmt.Sprintf("%s_up == 0", ns),
+				For:    forDuration(opts.DownFor),
+				Labels: map[string]string{"severity": opts.DownSeverity},
+				Annotations: map[string]string{
+					"summary":     "The Teleport exporter cannot reach Teleport.",
+					"description": "The Teleport exporter {{ $labels.instance }} has not reached Teleport for " + promDuration(opts.DownFor) + ".",
+				},
+			},
+			{
+				Alert:  "TeleportLeafClusterDisconnected",
+				Expr:   fmt.Sprintf("%s_remote_cluster_online == 0", ns),
+				For:    forDuration(opts.LeafDisconnectedFor),
+				Labels: map[string]string{"severity": opts.LeafSeverity},
+				Annotations: map[string]string{
+					"summary":     "A Teleport leaf cluster is disconnected from its root cluster.",
+					"description": "Leaf cluster {{ $labels.remote_cluster }} of Teleport cluster {{ $labels.cluster_name }} has been disconnected for " + promDuration(opts.LeafDisconnectedFor) + ".",
+				},
+			},
+			{
+				Alert: "TeleportCAExpiringSoon",
+				Expr: fmt.Sprintf("%s_cert_authority_expiry_timestamp_seconds - time() < %d",
+					ns, int64(opts.CAExpiresWithin.Seconds())),
+				Labels: map[string]string{"severity": opts.CASeverity},
+				Annotations: map[string]string{
+					"summary":     "A Teleport CA certificate expires within " + promDuration(opts.CAExpiresWithin) + ".",
+					"description": "The {{ $labels.type }} CA of Teleport cluster {{ $labels.ca_cluster_name }} expires in {{ $value | humanizeDuration }}; rotate it before.",
+				},
+			},
+		},
+	}}}
+}
+
+// forDuration returns the for clause of d, empty to alert right away.
+func forDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return promDuration(d)
+}
+
+// promDuration formats d as a Prometheus duration, e.g. 5m instead of 5m0s.
+func promDuration(d time.Duration) string {
+	return model.Duration(d).String()
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gen
+
+import (
+	"bytes"
+	"testing"
+
+	"go.yaml.in/yaml/v2"
+)
+
+func TestRun_Rules(t *testing.T) {
+	var buf bytes.Buffer
+	if code := run([]string{"rules", "--stale-after=10m", "--down-for=2m", "--metrics-namespace=teleport"}, &buf); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+
+	var file ruleFile
+	if err := yaml.Unmarshal(buf.Bytes(), &file); err != nil {
+		t.Fatalf("failed to parse the rules: %v", err)
+	}
+	if len(file.Groups) != 1 || len(file.Groups[0].Rules) != 4 {
+		t.Fatalf("expected one group with 4 rules, got %+v", file)
+	}
+	stale, down, leaf, ca := file.Groups[0].Rules[0], file.Groups[0].Rules[1], file.Groups[0].Rules[2], file.Groups[0].Rules[3]
+	if want := "time() - max by (cluster_name) (teleport_last_successful_collect_timestamp_seconds) > 600"; stale.Expr != want {
+		t.Errorf("stale expr = %q, want %q", stale.Expr, want)
+	}
+	if down.Expr != "teleport_up == 0" || down.For != "2m" || down.Labels["severity"] != "critical" {
+		t.Errorf("unexpected Teleport down rule %+v", down)
+	}
+	if leaf.Expr != "teleport_remote_cluster_online == 0" || leaf.For != "15m" || leaf.Labels["severity"] != "warning" {
+		t.Errorf("unexpected leaf cluster disconnected rule %+v", leaf)
+	}
+	if want := "teleport_cert_authority_expiry_timestamp_seconds - time() < 2592000"; ca.Expr != want || ca.For != "" {
+		t.Errorf("CA expiring rule = %+v, want expr %q", ca, want)
+	}
+}
+
+func TestRun_RulesThresholds(t *testing.T) {
+	var buf bytes.Buffer
+	if code := run([]string{"rules", "--leaf-disconnected-for=1h", "--ca-expires-within=168h", "--ca-severity=critical"}, &buf); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+
+	var file ruleFile
+	if err := yaml.Unmarshal(buf.Bytes(), &file); err != nil {
+		t.Fatalf("failed to parse the rules: %v", err)
+	}
+	leaf, ca := file.Groups[0].Rules[2], file.Groups[0].Rules[3]
+	if leaf.For != "1h" {
+		t.Errorf("leaf cluster disconnected for = %q, want 1h", leaf.For)
+	}
+	if ca.Expr != "teleport_exporter_cert_authority_expiry_timestamp_seconds - time() < 604800" || ca.Labels["severity"] != "critical" {
+		t.Errorf("unexpected CA expiring rule %+v", ca)
+	}
+}
+
+func TestRun_PrometheusRule(t *testing.T) {
+	var buf bytes.Buffer
+	if code := run([]string{"rules", "--format=prometheusrule", "--namespace=monitoring"}, &buf); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+
+	var rule prometheusRule
+	if err := yaml.Unmarshal(buf.Bytes(), &rule); err != nil {
+		t.Fatalf("failed to parse the PrometheusRule: %v", err)
+	}
+	if rule.Kind != "PrometheusRule" || rule.Metadata.Namespace != "monitoring" || len(rule.Spec.Groups) != 1 {
+		t.Errorf("unexpected PrometheusRule %+v", rule)
+	}
+}
+
+func TestRun_Invalid(t *testing.T) {
+	for _, args := range [][]string{nil, {"dashboards"}, {"rules", "--format=json"}, {"rules", "--stale-after=0"}, {"rules", "--ca-expires-within=0"}, {"rules", "--leaf-disconnected-for=-1m"}} {
+		if code := run(args, &bytes.Buffer{}); code != 1 {
+			t.Errorf("run(%q) = %d, want 1", args, code)
+		}
+	}
+}
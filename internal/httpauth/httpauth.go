@@ -0,0 +1,186 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package httpauth protects HTTP handlers with bearer token or basic authentication.
+package httpauth
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Config holds the accepted credentials. A request is authorized if it matches
+// any of them.
+type Config struct {
+	// BearerToken is accepted in an "Authorization: Bearer <token>" header.
+	BearerToken string
+	// Users maps user names to bcrypt password hashes accepted via basic auth.
+	Users map[string]string
+}
+
+// Enabled returns whether any credentials are configured.
+func (c Config) Enabled() bool {
+	return c.BearerToken != "" || len(c.Users) > 0
+}
+
+// LoadBearerToken reads a bearer token from path, ignoring surrounding whitespace.
+func LoadBearerToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("bearer token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// LoadHtpasswd reads "user:hash" lines with bcrypt hashes, as written by
+// "htpasswd -B", from path. Empty lines and lines starting with # are ignored.
+func LoadHtpasswd(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	users := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, hash, ok := strings.Cut(line, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("%s:%d: expected user:hash", path, lineNum)
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("%s:%d: password of user %q is not a bcrypt hash", path, lineNum, user)
+		}
+		users[user] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("no users found in %s", path)
+	}
+	return users, nil
+}
+
+// Handler returns next wrapped so that requests without valid credentials are
+// rejected with 401. If no credentials are configured, next is returned as is.
+func Handler(cfg Config, next http.Handler) http.Handler {
+	if !cfg.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg.authorized(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cfg.unauthorized(w)
+	})
+}
+
+// Files holds the credentials loaded from a bearer token file and an htpasswd
+// file, so that they can be reloaded while serving requests.
+type Files struct {
+	// BearerTokenFile is the path of the bearer token file, if any.
+	BearerTokenFile string
+	// HtpasswdFile is the path of the htpasswd file, if any.
+	HtpasswdFile string
+
+	cfg atomic.Pointer[Config]
+}
+
+// Enabled returns whether any credential file is configured.
+func (f *Files) Enabled() bool {
+	return f.BearerTokenFile != "" || f.HtpasswdFile != ""
+}
+
+// Load reads the configured files. If any of them cannot be loaded, the
+// previously loaded credentials are kept.
+func (f *Files) Load() error {
+	var cfg Config
+	var err error
+	if f.BearerTokenFile != "" {
+		if cfg.BearerToken, err = LoadBearerToken(f.BearerTokenFile); err != nil {
+			return fmt.Errorf("failed to load bearer token: %w", err)
+		}
+	}
+	if f.HtpasswdFile != "" {
+		if cfg.Users, err = LoadHtpasswd(f.HtpasswdFile); err != nil {
+			return fmt.Errorf("failed to load htpasswd file: %w", err)
+		}
+	}
+	f.cfg.Store(&cfg)
+	return nil
+}
+
+// Handler is like the Handler function, but checks requests against the
+// credentials of the last successful Load. Until then, all requests are
+// rejected. If no file is configured, next is returned as is.
+func (f *Files) Handler(next http.Handler) http.Handler {
+	if !f.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := f.cfg.Load()
+		if cfg == nil {
+			cfg = &Config{}
+		}
+		if cfg.authorized(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cfg.unauthorized(w)
+	})
+}
+
+// unauthorized rejects a request, asking for basic auth if users are
+// configured.
+func (c Config) unauthorized(w http.ResponseWriter) {
+	if len(c.Users) > 0 {
+		w.Header().Set("WWW-Authenticate", `Basic realm="teleport-exporter"`)
+	}
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// authorized returns whether r carries any of the configured credentials.
+func (c Config) authorized(r *http.Request) bool {
+	if c.BearerToken != "" {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok &&
+			subtle.ConstantTimeCompare([]byte(token), []byte(c.BearerToken)) == 1 {
+			return true
+		}
+	}
+	if user, password, ok := r.BasicAuth(); ok {
+		if hash, exists := c.Users[user]; exists {
+			return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+		}
+	}
+	return false
+}
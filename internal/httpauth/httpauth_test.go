@@ -0,0 +1,159 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHandler(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	cfg := Config{
+		BearerToken: "token",
+		Users:       map[string]string{"prometheus": string(hash)},
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := Handler(cfg, next)
+
+	tests := []struct {
+		name           string
+		setAuth        func(r *http.Request)
+		expectedStatus int
+	}{
+		{"no credentials", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"valid bearer token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }, http.StatusOK},
+		{"invalid bearer token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer wrong") }, http.StatusUnauthorized},
+		{"valid basic auth", func(r *http.Request) { r.SetBasicAuth("prometheus", "secret") }, http.StatusOK},
+		{"wrong password", func(r *http.Request) { r.SetBasicAuth("prometheus", "wrong") }, http.StatusUnauthorized},
+		{"unknown user", func(r *http.Request) { r.SetBasicAuth("grafana", "secret") }, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			tt.setAuth(req)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_Disabled(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	rec := httptest.NewRecorder()
+	Handler(Config{}, next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200 without configured credentials, got %d", rec.Code)
+	}
+}
+
+func TestFiles_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("old\n"), 0o600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+	files := &Files{BearerTokenFile: path}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := files.Handler(next)
+	status := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := status("old"); code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 before loading, got %d", code)
+	}
+	if err := files.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if code := status("old"); code != http.StatusOK {
+		t.Errorf("expected status 200 with the loaded token, got %d", code)
+	}
+
+	if err := os.WriteFile(path, []byte("new\n"), 0o600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+	if err := files.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if code := status("old"); code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 with the replaced token, got %d", code)
+	}
+	if code := status("new"); code != http.StatusOK {
+		t.Errorf("expected status 200 with the reloaded token, got %d", code)
+	}
+
+	// A broken file keeps the previous credentials
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+	if err := files.Load(); err == nil {
+		t.Error("expected Load() to fail with an empty token file")
+	}
+	if code := status("new"); code != http.StatusOK {
+		t.Errorf("expected status 200 with the previous token, got %d", code)
+	}
+}
+
+func TestLoadHtpasswd(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid")
+	if err := os.WriteFile(valid, []byte("# scrapers\nprometheus:"+string(hash)+"\n\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	users, err := LoadHtpasswd(valid)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if users["prometheus"] != string(hash) {
+		t.Errorf("expected hash for user prometheus, got %q", users["prometheus"])
+	}
+
+	plain := filepath.Join(dir, "plain")
+	if err := os.WriteFile(plain, []byte("prometheus:secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadHtpasswd(plain); err == nil {
+		t.Error("expected error for non-bcrypt password, got nil")
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Doc describes a metric created by Setup.
+type Doc struct {
+	Name   string
+	Type   string
+	Help   string
+	Labels []string
+}
+
+// descPattern matches the string representation of a prometheus.Desc, which
+// has no accessors for its name, help and labels.
+var descPattern = regexp.MustCompile(`^Desc\{fqName: ("(?:[^"\\]|\\.)*"), help: ("(?:[^"\\]|\\.)*"), constLabels: \{.*\}, variableLabels: \{(.*)\}\}$`)
+
+// Docs describes all metrics created by the last call of Setup, sorted by
+// name. The Go runtime and process metrics are not included.
+func Docs() ([]Doc, error) {
+	var docs []Doc
+	for _, c := range append(resourceCollectors(), exporterCollectors()...) {
+		ch := make(chan *prometheus.Desc, 1)
+		go func() {
+			c.Describe(ch)
+			close(ch)
+		}()
+		for desc := range ch {
+			doc, err := parseDesc(desc)
+			if err != nil {
+				return nil, err
+			}
+			doc.Type = metricType(c)
+			docs = append(docs, doc)
+		}
+	}
+	slices.SortFunc(docs, func(a, b Doc) int { return strings.Compare(a.Name, b.Name) })
+	return docs, nil
+}
+
+// parseDesc returns the name, help and labels of desc.
+func parseDesc(desc *prometheus.Desc) (Doc, error) {
+	m := descPattern.FindStringSubmatch(desc.String())
+	if m == nil {
+		return Doc{}, fmt.Errorf("failed to parse metric descriptor %s", desc)
+	}
+	name, err := strconv.Unquote(m[1])
+	if err != nil {
+		return Doc{}, fmt.Errorf("failed to parse metric descriptor %s: %w", desc, err)
+	}
+	help, err := strconv.Unquote(m[2])
+	if err != nil {
+		return Doc{}, fmt.Errorf("failed to parse metric descriptor %s: %w", desc, err)
+	}
+	var labels []string
+	if m[3] != "" {
+		labels = strings.Split(m[3], ",")
+	}
+	return Doc{Name: name, Help: help, Labels: labels}, nil
+}
+
+// metricType returns the Prometheus type of c.
+func metricType(c prometheus.Collector) string {
+	switch c.(type) {
+	case *prometheus.GaugeVec, prometheus.Gauge:
+		return "gauge"
+	case *prometheus.CounterVec, prometheus.Counter:
+		return "counter"
+	case *prometheus.HistogramVec, prometheus.Histogram:
+		return "histogram"
+	default:
+		return "untyped"
+	}
+}
@@ -17,136 +17,1022 @@ limitations under the License.
 package metrics
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-const (
-	namespace = "teleport_exporter"
-)
+// DefaultNamespace is the prefix of all metric names unless overridden with
+// Options.Namespace.
+const DefaultNamespace = "teleport_exporter"
 
+// All metrics are created by Setup, since their names depend on the configured
+// namespace and the *_info metrics carry a configurable set of labels.
 var (
 	// --- Connection Status ---
 
 	// TeleportUp indicates whether the exporter can successfully connect to Teleport.
-	TeleportUp = promauto.NewGauge(prometheus.GaugeOpts{
+	TeleportUp prometheus.Gauge
+
+	// CheckUp indicates per check whether the exporter can reach Teleport
+	// (connect) and collect each resource type.
+	CheckUp *prometheus.GaugeVec
+
+	// ClusterInfo carries the name of the connected Teleport cluster as a label.
+	ClusterInfo *prometheus.GaugeVec
+
+	// --- SSH Nodes ---
+
+	// NodesTotal is the total number of SSH nodes registered in Teleport.
+	NodesTotal *prometheus.GaugeVec
+
+	// NodesIdentifiedTotal is the count of nodes where we could identify the Kubernetes cluster.
+	NodesIdentifiedTotal *prometheus.GaugeVec
+
+	// NodesUnidentifiedTotal is the count of nodes where we couldn't identify the Kubernetes cluster.
+	NodesUnidentifiedTotal *prometheus.GaugeVec
+
+	// NodesByKubernetesCluster shows the count of SSH nodes per Kubernetes cluster.
+	NodesByKubernetesCluster *prometheus.GaugeVec
+
+	// NodesBySubkind shows the count of SSH nodes per subkind, e.g. openssh
+	// for agentless nodes.
+	NodesBySubkind *prometheus.GaugeVec
+
+	// NodesByOS shows the count of SSH nodes per operating system label.
+	NodesByOS *prometheus.GaugeVec
+
+	// NodesByArch shows the count of SSH nodes per architecture label.
+	NodesByArch *prometheus.GaugeVec
+
+	// NodesByLabel shows the count of SSH nodes per value of the configured label groups.
+	NodesByLabel *prometheus.GaugeVec
+
+	// NodeInfo provides information about each SSH node.
+	NodeInfo *prometheus.GaugeVec
+
+	// --- Kubernetes Clusters ---
+
+	// KubeClustersTotal is the total number of Kubernetes clusters registered in Teleport.
+	KubeClustersTotal *prometheus.GaugeVec
+
+	// KubeManagementClustersTotal is the count of management clusters (no hyphen in name).
+	KubeManagementClustersTotal *prometheus.GaugeVec
+
+	// KubeWorkloadClustersTotal is the count of workload clusters (has hyphen in name).
+	KubeWorkloadClustersTotal *prometheus.GaugeVec
+
+	// KubeClustersByLabel shows the count of Kubernetes clusters per value of the configured label groups.
+	KubeClustersByLabel *prometheus.GaugeVec
+
+	// KubernetesClusterInfo provides information about each Kubernetes cluster.
+	KubernetesClusterInfo *prometheus.GaugeVec
+
+	// KubernetesServerInfo provides information about each agent serving a
+	// Kubernetes cluster, if per-server metrics are enabled.
+	KubernetesServerInfo *prometheus.GaugeVec
+
+	// --- Databases ---
+
+	// DatabasesTotal is the total number of databases registered in Teleport.
+	DatabasesTotal *prometheus.GaugeVec
+
+	// DatabasesByProtocolTotal shows database count per protocol.
+	DatabasesByProtocolTotal *prometheus.GaugeVec
+
+	// DatabasesByTypeTotal shows database count per type.
+	DatabasesByTypeTotal *prometheus.GaugeVec
+
+	// DatabasesByCloudTotal shows database count per hosting cloud.
+	DatabasesByCloudTotal *prometheus.GaugeVec
+
+	// DatabasesByLabel shows the count of databases per value of the configured label groups.
+	DatabasesByLabel *prometheus.GaugeVec
+
+	// DatabaseInfo provides information about each database.
+	DatabaseInfo *prometheus.GaugeVec
+
+	// DatabaseServerInfo provides information about each agent serving a
+	// database, if per-server metrics are enabled.
+	DatabaseServerInfo *prometheus.GaugeVec
+
+	// DatabasesInsecureTotal shows the count of databases per insecure
+	// configuration.
+	DatabasesInsecureTotal *prometheus.GaugeVec
+
+	// DatabaseInsecureInfo lists the databases with an insecure configuration.
+	DatabaseInsecureInfo *prometheus.GaugeVec
+
+	// --- Applications ---
+
+	// AppsTotal is the total number of applications registered in Teleport.
+	AppsTotal *prometheus.GaugeVec
+
+	// AppsByLabel shows the count of applications per value of the configured label groups.
+	AppsByLabel *prometheus.GaugeVec
+
+	// AppInfo provides information about each application.
+	AppInfo *prometheus.GaugeVec
+
+	// AppServerInfo provides information about each agent serving an
+	// application, if per-server metrics are enabled.
+	AppServerInfo *prometheus.GaugeVec
+
+	// --- Label policy ---
+
+	// ResourcesMissingLabel is the number of resources without each of the
+	// required labels, if any are configured.
+	ResourcesMissingLabel *prometheus.GaugeVec
+
+	// ResourcesByOrigin is the number of resources per value of the
+	// teleport.dev/origin label.
+	ResourcesByOrigin *prometheus.GaugeVec
+
+	// --- Users ---
+
+	// UsersTotal is the total number of Teleport users, if users are collected.
+	UsersTotal *prometheus.GaugeVec
+
+	// UsersWithoutMFATotal is the number of local users without an MFA device.
+	UsersWithoutMFATotal *prometheus.GaugeVec
+
+	// UserWithoutMFAInfo lists the local users without an MFA device, if
+	// enabled.
+	UserWithoutMFAInfo *prometheus.GaugeVec
+
+	// UsersByOrigin is the number of users by origin, e.g. local or saml.
+	UsersByOrigin *prometheus.GaugeVec
+
+	// UserLastLogin is the time each SSO user last logged in, if enabled.
+	UserLastLogin *prometheus.GaugeVec
+
+	// --- Locks ---
+
+	// UsersLockedTotal is the number of users locked by a lock in force, if
+	// locks are collected.
+	UsersLockedTotal *prometheus.GaugeVec
+
+	// UserLockExpiry is the time the locks of each locked user expire.
+	UserLockExpiry *prometheus.GaugeVec
+
+	// LocksByTarget is the number of locks in force per kind of locked
+	// target, e.g. user or role.
+	LocksByTarget *prometheus.GaugeVec
+
+	// --- Roles ---
+
+	// RolesTotal is the total number of Teleport roles, if roles are
+	// collected.
+	RolesTotal *prometheus.GaugeVec
+
+	// RolesRiskyTotal is the number of roles granting broad access, by risk.
+	RolesRiskyTotal *prometheus.GaugeVec
+
+	// RoleAssignments is the number of users and bots each role is assigned
+	// to, if users are collected.
+	RoleAssignments *prometheus.GaugeVec
+
+	// --- Provision tokens ---
+
+	// TokensTotal is the total number of provision tokens, if tokens are
+	// collected.
+	TokensTotal *prometheus.GaugeVec
+
+	// TokensExpiredTotal is the number of provision tokens past their expiry
+	// that are still present.
+	TokensExpiredTotal *prometheus.GaugeVec
+
+	// TokensWithoutExpiryTotal is the number of provision tokens that do not
+	// expire.
+	TokensWithoutExpiryTotal *prometheus.GaugeVec
+
+	// TokensByJoinMethod is the number of provision tokens per join method.
+	TokensByJoinMethod *prometheus.GaugeVec
+
+	// --- Access requests ---
+
+	// AccessRequestsTotal is the total number of access requests, if access
+	// requests are collected.
+	AccessRequestsTotal *prometheus.GaugeVec
+
+	// AccessRequestsPendingTotal is the number of access requests waiting for
+	// a review.
+	AccessRequestsPendingTotal *prometheus.GaugeVec
+
+	// AccessRequestsSLABreachedTotal is the number of pending access requests
+	// older than the configured SLA.
+	AccessRequestsSLABreachedTotal *prometheus.GaugeVec
+
+	// AccessRequestOldestPendingAge is the age of the oldest pending access
+	// request.
+	AccessRequestOldestPendingAge *prometheus.GaugeVec
+
+	// AccessRequestsByRole is the number of access requests per requested
+	// role.
+	AccessRequestsByRole *prometheus.GaugeVec
+
+	// AccessRequestsPendingByRole is the number of pending access requests
+	// per requested role.
+	AccessRequestsPendingByRole *prometheus.GaugeVec
+
+	// SessionsEndedTotal counts the sessions that ended, if sessions are
+	// collected.
+	SessionsEndedTotal *prometheus.CounterVec
+
+	// SessionsWithoutRecordingTotal counts the sessions that ended without
+	// an uploaded recording although the recording mode required one.
+	SessionsWithoutRecordingTotal *prometheus.CounterVec
+
+	// --- Trust ---
+
+	// RemoteClusterOnline is whether each leaf cluster is connected to the
+	// cluster, if remote clusters are collected.
+	RemoteClusterOnline *prometheus.GaugeVec
+
+	// RemoteClusterLastHeartbeat is the time each leaf cluster was last
+	// connected.
+	RemoteClusterLastHeartbeat *prometheus.GaugeVec
+
+	// CertAuthorityExpiry is the time the TLS certificate of each CA expires,
+	// if cert authorities are collected.
+	CertAuthorityExpiry *prometheus.GaugeVec
+
+	// --- Probes ---
+
+	// AppProbeSuccess is whether the last HTTP probe of each application
+	// got an answer, if applications are probed.
+	AppProbeSuccess *prometheus.GaugeVec
+
+	// AppProbeDuration is how long the last HTTP probe of each application
+	// took.
+	AppProbeDuration *prometheus.GaugeVec
+
+	// AppProbeStatusCode is the HTTP status code of the last probe of each
+	// application.
+	AppProbeStatusCode *prometheus.GaugeVec
+
+	// DatabaseProbeSuccess is whether the last login probe of each database
+	// through Teleport succeeded, if databases are probed.
+	DatabaseProbeSuccess *prometheus.GaugeVec
+
+	// DatabaseProbeDuration is how long the last login probe of each
+	// database took.
+	DatabaseProbeDuration *prometheus.GaugeVec
+
+	// --- Teleport API ---
+
+	// GRPCConnectionState shows the current state of the gRPC connection to Teleport.
+	GRPCConnectionState *prometheus.GaugeVec
+
+	// ConnectionHealthy shows whether the last health check of the connection
+	// to Teleport succeeded.
+	ConnectionHealthy prometheus.Gauge
+
+	// GRPCReconnectsTotal is the total number of times the gRPC connection became ready again.
+	GRPCReconnectsTotal prometheus.Counter
+
+	// CredentialReloadsTotal is the total number of reconnects with reloaded
+	// credentials after certificate errors.
+	CredentialReloadsTotal prometheus.Counter
+
+	// CARotationChangesTotal is the total number of detected changes of the
+	// rotation state of the Teleport cluster CAs.
+	CARotationChangesTotal prometheus.Counter
+
+	// ConfigLastReloadSuccessful shows whether the last reload on SIGHUP or
+	// /-/reload succeeded.
+	ConfigLastReloadSuccessful prometheus.Gauge
+
+	// ConfigLastReloadSuccessTime is the timestamp of the last successful reload.
+	ConfigLastReloadSuccessTime prometheus.Gauge
+
+	// CredentialsSource shows which configured credential source the
+	// connection to Teleport authenticated with.
+	CredentialsSource *prometheus.GaugeVec
+
+	// GRPCClientHandledTotal is the total number of gRPC calls to Teleport by status code.
+	GRPCClientHandledTotal *prometheus.CounterVec
+
+	// GRPCClientMsgSentBytes tracks the size of gRPC messages sent to Teleport.
+	GRPCClientMsgSentBytes *prometheus.HistogramVec
+
+	// GRPCClientMsgReceivedBytes tracks the size of gRPC messages received from Teleport.
+	GRPCClientMsgReceivedBytes *prometheus.HistogramVec
+
+	// APIRequestDuration tracks the duration of each Teleport API call.
+	APIRequestDuration *prometheus.HistogramVec
+
+	// APIRequestsTotal is the total number of Teleport API calls by result.
+	APIRequestsTotal *prometheus.CounterVec
+
+	// APIErrorsTotal is the total number of failed Teleport API calls by
+	// error class.
+	APIErrorsTotal *prometheus.CounterVec
+
+	// CacheAgeSeconds is the age of the cached API result last served for each resource type.
+	CacheAgeSeconds *prometheus.GaugeVec
+
+	// --- Exporter Health ---
+
+	// CollectDuration tracks how long collecting each resource type takes.
+	CollectDuration *prometheus.HistogramVec
+
+	// CollectErrorsTotal is the total number of errors encountered during metrics collection,
+	// by resource type and error reason.
+	CollectErrorsTotal *prometheus.CounterVec
+
+	// CollectionTimeoutsTotal is the total number of collections aborted
+	// because they exceeded the collection timeout.
+	CollectionTimeoutsTotal *prometheus.CounterVec
+
+	// ResourceUp indicates whether the last API call for each resource type succeeded.
+	ResourceUp *prometheus.GaugeVec
+
+	// ResourceLastSuccessTime is the timestamp of the last successful API call for each resource type.
+	ResourceLastSuccessTime *prometheus.GaugeVec
+
+	// ResourceAccess shows whether the identity may read each resource type,
+	// as checked on startup.
+	ResourceAccess *prometheus.GaugeVec
+
+	// ResourcePermissionDenied shows which resource types are not collected
+	// because Teleport denied access to them.
+	ResourcePermissionDenied *prometheus.GaugeVec
+
+	// InventoryRestored shows which resource types are exported from the
+	// inventory restored from the state file rather than collected.
+	InventoryRestored *prometheus.GaugeVec
+
+	// SeriesDroppedTotal counts info series that were not emitted because of the series limit.
+	SeriesDroppedTotal *prometheus.CounterVec
+
+	// LastSuccessfulCollectTime is the timestamp of the last successful collection.
+	LastSuccessfulCollectTime *prometheus.GaugeVec
+)
+
+// InfoLabels configures which Teleport resource labels are copied onto the
+// *_info metrics as additional Prometheus labels.
+type InfoLabels struct {
+	Node        []string
+	KubeCluster []string
+	Database    []string
+	App         []string
+}
+
+// Options configures the metrics created by Setup.
+type Options struct {
+	// Namespace is the prefix of all metric names. Defaults to DefaultNamespace.
+	Namespace string
+	// InfoLabels lists the Teleport labels copied onto the *_info metrics.
+	InfoLabels InfoLabels
+}
+
+func init() {
+	// Create the metrics with the default options so they are usable before
+	// Setup is called. They are only registered by Setup.
+	if err := Setup(nil, Options{}); err != nil {
+		panic(err)
+	}
+}
+
+// Setup creates all metrics with the given options and registers them with reg,
+// unless reg is nil. Each *_info metric carries one additional label per
+// configured Teleport label key. Label names are derived with LabelName; keys
+// that map to the same label name are rejected. Since metrics cannot change
+// once registered, it must be called at most once with a non-nil reg.
+func Setup(reg prometheus.Registerer, opts Options) error {
+	l := opts.InfoLabels
+	for _, keys := range [][]string{l.Node, l.KubeCluster, l.Database, l.App} {
+		seen := make(map[string]string, len(keys))
+		for _, key := range keys {
+			name := LabelName(key)
+			if other, ok := seen[name]; ok {
+				return fmt.Errorf("labels %q and %q both map to metric label %q", other, key, name)
+			}
+			seen[name] = key
+		}
+	}
+
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+
+	TeleportUp = prometheus.NewGauge(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Name:      "up",
 		Help:      "Whether the exporter can successfully connect to Teleport (1 = connected, 0 = disconnected).",
 	})
 
-	// --- SSH Nodes ---
+	CheckUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "check_up",
+		Help:      "Whether the last run of the check succeeded (1 = up, 0 = down): connect fetches the cluster name, the others list their resource type.",
+	}, []string{"check"})
 
-	// NodesTotal is the total number of SSH nodes registered in Teleport.
-	NodesTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	ClusterInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cluster_info",
+		Help:      "Information about the connected Teleport cluster, always 1.",
+	}, []string{"cluster_name"})
+
+	NodesTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Name:      "nodes_total",
 		Help:      "Total number of SSH nodes registered in the Teleport cluster.",
 	}, []string{"cluster_name"})
 
-	// NodesIdentifiedTotal is the count of nodes where we could identify the Kubernetes cluster.
-	NodesIdentifiedTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	NodesIdentifiedTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Name:      "nodes_identified_total",
 		Help:      "Number of SSH nodes with identified Kubernetes cluster (via labels or hostname).",
 	}, []string{"cluster_name"})
 
-	// NodesUnidentifiedTotal is the count of nodes where we couldn't identify the Kubernetes cluster.
-	NodesUnidentifiedTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	NodesUnidentifiedTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Name:      "nodes_unidentified_total",
 		Help:      "Number of SSH nodes with unknown Kubernetes cluster.",
 	}, []string{"cluster_name"})
 
-	// NodesByKubernetesCluster shows the count of SSH nodes per Kubernetes cluster.
-	NodesByKubernetesCluster = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	NodesByKubernetesCluster = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Name:      "nodes_by_kubernetes_cluster",
 		Help:      "Number of SSH nodes per Kubernetes cluster.",
 	}, []string{"cluster_name", "kube_cluster"})
 
-	// --- Kubernetes Clusters ---
+	NodesBySubkind = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "nodes_by_subkind",
+		Help:      "Number of SSH nodes per subkind: teleport for nodes running a Teleport agent, openssh and openssh-ec2-ice for agentless nodes.",
+	}, []string{"cluster_name", "subkind"})
 
-	// KubeClustersTotal is the total number of Kubernetes clusters registered in Teleport.
-	KubeClustersTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	NodesByOS = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "nodes_by_os",
+		Help:      "Number of SSH nodes per operating system, from the kubernetes.io/os, beta.kubernetes.io/os or os label (unknown without one).",
+	}, []string{"cluster_name", "os"})
+
+	NodesByArch = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "nodes_by_arch",
+		Help:      "Number of SSH nodes per architecture, from the kubernetes.io/arch, beta.kubernetes.io/arch or arch label (unknown without one).",
+	}, []string{"cluster_name", "arch"})
+
+	NodesByLabel = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "nodes_by_label",
+		Help:      "Number of SSH nodes per value of the configured label groups (key and value join multiple labels with +).",
+	}, []string{"cluster_name", "key", "value"})
+
+	NodeInfo = newInfoVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "node_info",
+		Help:      "Information about each SSH node registered in Teleport (value is always 1).",
+	}, []string{"cluster_name", "node_name", "hostname"}, l.Node)
+
+	KubeClustersTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Name:      "kubernetes_clusters_total",
 		Help:      "Total number of Kubernetes clusters registered in the Teleport cluster.",
 	}, []string{"cluster_name"})
 
-	// KubeManagementClustersTotal is the count of management clusters (no hyphen in name).
-	KubeManagementClustersTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	KubeManagementClustersTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Name:      "kubernetes_management_clusters_total",
 		Help:      "Number of management clusters (cluster names without hyphen).",
 	}, []string{"cluster_name"})
 
-	// KubeWorkloadClustersTotal is the count of workload clusters (has hyphen in name).
-	KubeWorkloadClustersTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	KubeWorkloadClustersTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Name:      "kubernetes_workload_clusters_total",
 		Help:      "Number of workload clusters (cluster names with hyphen).",
 	}, []string{"cluster_name"})
 
-	// KubernetesClusterInfo provides information about each Kubernetes cluster.
-	KubernetesClusterInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	KubeClustersByLabel = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "kubernetes_clusters_by_label",
+		Help:      "Number of Kubernetes clusters per value of the configured label groups (key and value join multiple labels with +).",
+	}, []string{"cluster_name", "key", "value"})
+
+	KubernetesClusterInfo = newInfoVec(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Name:      "kubernetes_cluster_info",
 		Help:      "Information about each Kubernetes cluster registered in Teleport (value is always 1).",
-	}, []string{"cluster_name", "kube_cluster_name"})
+	}, []string{"cluster_name", "kube_cluster_name"}, l.KubeCluster)
 
-	// --- Databases ---
+	KubernetesServerInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "kubernetes_server_info",
+		Help:      "Information about each Teleport agent serving a Kubernetes cluster (value is always 1).",
+	}, []string{"cluster_name", "kube_cluster_name", "host_id", "hostname"})
 
-	// DatabasesTotal is the total number of databases registered in Teleport.
-	DatabasesTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	DatabasesTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Name:      "databases_total",
 		Help:      "Total number of databases registered in the Teleport cluster.",
 	}, []string{"cluster_name"})
 
-	// DatabasesByProtocolTotal shows database count per protocol.
-	DatabasesByProtocolTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	DatabasesByProtocolTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Name:      "databases_by_protocol_total",
 		Help:      "Number of databases by protocol (postgres, mysql, mongodb, etc.).",
 	}, []string{"cluster_name", "protocol"})
 
-	// DatabasesByTypeTotal shows database count per type.
-	DatabasesByTypeTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	DatabasesByTypeTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Name:      "databases_by_type_total",
 		Help:      "Number of databases by type (rds, self-hosted, cloud-sql, etc.).",
 	}, []string{"cluster_name", "type"})
 
-	// --- Applications ---
+	DatabasesByCloudTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "databases_by_cloud_total",
+		Help:      "Number of databases by the cloud hosting them (aws, gcp, azure, mongo-atlas or self-hosted).",
+	}, []string{"cluster_name", "cloud"})
 
-	// AppsTotal is the total number of applications registered in Teleport.
-	AppsTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	DatabasesByLabel = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "databases_by_label",
+		Help:      "Number of databases per value of the configured label groups (key and value join multiple labels with +).",
+	}, []string{"cluster_name", "key", "value"})
+
+	DatabaseInfo = newInfoVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "database_info",
+		Help:      "Information about each database registered in Teleport (value is always 1).",
+	}, []string{"cluster_name", "database_name", "protocol", "type"}, l.Database)
+
+	DatabaseServerInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "database_server_info",
+		Help:      "Information about each Teleport agent serving a database (value is always 1).",
+	}, []string{"cluster_name", "database_name", "host_id", "hostname"})
+
+	DatabasesInsecureTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "databases_insecure_total",
+		Help:      "Number of databases with an insecure configuration, by reason: insecure_tls (the certificate of the database is not verified) or plaintext (the URI disables TLS).",
+	}, []string{"cluster_name", "reason"})
+
+	DatabaseInsecureInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "database_insecure_info",
+		Help:      "Information about each database with an insecure configuration, by reason (value is always 1).",
+	}, []string{"cluster_name", "database_name", "reason"})
+
+	AppsTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Name:      "apps_total",
 		Help:      "Total number of applications registered in the Teleport cluster.",
 	}, []string{"cluster_name"})
 
-	// --- Exporter Health ---
+	AppsByLabel = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "apps_by_label",
+		Help:      "Number of applications per value of the configured label groups (key and value join multiple labels with +).",
+	}, []string{"cluster_name", "key", "value"})
 
-	// CollectDuration tracks the duration of the last metrics collection.
-	CollectDuration = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	AppInfo = newInfoVec(prometheus.GaugeOpts{
 		Namespace: namespace,
-		Name:      "collect_duration_seconds",
-		Help:      "Duration of the last metrics collection in seconds.",
+		Name:      "app_info",
+		Help:      "Information about each application registered in Teleport (value is always 1).",
+	}, []string{"cluster_name", "app_name", "public_addr"}, l.App)
+
+	AppServerInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "app_server_info",
+		Help:      "Information about each Teleport agent serving an application (value is always 1).",
+	}, []string{"cluster_name", "app_name", "host_id", "hostname"})
+
+	ResourcesMissingLabel = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "resources_missing_label_total",
+		Help:      "Number of nodes, databases and applications without each of the required labels, or with an empty value.",
+	}, []string{"cluster_name", "resource", "label"})
+
+	ResourcesByOrigin = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "resources_by_origin",
+		Help:      "Number of nodes, Kubernetes clusters, databases and applications per value of the teleport.dev/origin label, e.g. dynamic, config-file or cloud (unknown without one).",
+	}, []string{"cluster_name", "resource", "origin"})
+
+	UsersTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "users_total",
+		Help:      "Total number of users of the Teleport cluster, including SSO users and bots.",
+	}, []string{"cluster_name"})
+
+	UsersWithoutMFATotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "users_without_mfa_total",
+		Help:      "Number of local Teleport users, not counting bots, without a registered MFA device.",
+	}, []string{"cluster_name"})
+
+	UsersByOrigin = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "users_by_origin",
+		Help:      "Number of Teleport users by origin: local, bot, the SSO connector kind (github, saml, oidc) or the identity service they are synced from (okta, entra-id).",
+	}, []string{"cluster_name", "origin"})
+
+	UserLastLogin = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "user_last_login_timestamp_seconds",
+		Help:      "Unix time each Teleport SSO user last logged in. Teleport records no login time of local users.",
+	}, []string{"cluster_name", "user_name"})
+
+	UserWithoutMFAInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "user_without_mfa_info",
+		Help:      "Information about each local Teleport user without a registered MFA device (value is always 1).",
+	}, []string{"cluster_name", "user_name"})
+
+	UsersLockedTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "users_locked_total",
+		Help:      "Number of Teleport users locked by a lock in force.",
+	}, []string{"cluster_name"})
+
+	UserLockExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "user_lock_expiry_timestamp_seconds",
+		Help:      "Unix time the last lock in force of each locked Teleport user expires, 0 if a lock does not expire.",
+	}, []string{"cluster_name", "user_name"})
+
+	LocksByTarget = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "locks_by_target",
+		Help:      "Number of Teleport locks in force per kind of locked target. A lock of several targets counts towards each of them.",
+	}, []string{"cluster_name", "target"})
+
+	RolesTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "roles_total",
+		Help:      "Total number of roles of the Teleport cluster, not counting system roles.",
+	}, []string{"cluster_name"})
+
+	RolesRiskyTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "roles_risky_total",
+		Help:      "Number of Teleport roles granting broad access, by risk: wildcard_logins (any node login), wildcard_labels (all resources of a kind) or wildcard_resources (rules for all resources).",
+	}, []string{"cluster_name", "risk"})
+
+	RoleAssignments = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "role_assignments",
+		Help:      "Number of Teleport users (kind user) and bots (kind bot) each role is assigned to. Roles assigned to no one are 0 if roles are collected.",
+	}, []string{"cluster_name", "role", "kind"})
+
+	TokensTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tokens_total",
+		Help:      "Total number of provision tokens of the Teleport cluster.",
+	}, []string{"cluster_name"})
+
+	TokensExpiredTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tokens_expired_total",
+		Help:      "Number of provision tokens past their expiry that Teleport did not delete yet.",
+	}, []string{"cluster_name"})
+
+	TokensWithoutExpiryTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tokens_without_expiry_total",
+		Help:      "Number of provision tokens that never expire.",
+	}, []string{"cluster_name"})
+
+	TokensByJoinMethod = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tokens_by_join_method",
+		Help:      "Number of provision tokens per join method.",
+	}, []string{"cluster_name", "join_method"})
+
+	AccessRequestsTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "access_requests_total",
+		Help:      "Total number of access requests of the Teleport cluster, in any state.",
+	}, []string{"cluster_name"})
+
+	AccessRequestsPendingTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "access_requests_pending_total",
+		Help:      "Number of access requests waiting for a review.",
+	}, []string{"cluster_name"})
+
+	AccessRequestsSLABreachedTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "access_requests_sla_breached_total",
+		Help:      "Number of pending access requests older than the configured review SLA.",
+	}, []string{"cluster_name"})
+
+	AccessRequestOldestPendingAge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "access_request_oldest_pending_age_seconds",
+		Help:      "Age of the oldest pending access request in seconds, 0 if none is pending.",
+	}, []string{"cluster_name"})
+
+	AccessRequestsByRole = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "access_requests_by_role",
+		Help:      "Number of access requests, in any state, per requested role. A request for several roles counts towards each of them.",
+	}, []string{"cluster_name", "role"})
+
+	AccessRequestsPendingByRole = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "access_requests_pending_by_role",
+		Help:      "Number of access requests waiting for a review per requested role. A request for several roles counts towards each of them.",
+	}, []string{"cluster_name", "role"})
+
+	SessionsEndedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sessions_ended_total",
+		Help:      "Total number of sessions that ended, counted once their recording grace period passed.",
+	}, []string{"cluster_name"})
+
+	SessionsWithoutRecordingTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sessions_without_recording_total",
+		Help:      "Total number of sessions that ended without an uploaded recording although the recording mode required one.",
 	}, []string{"cluster_name"})
 
-	// CollectErrorsTotal is the total number of errors encountered during metrics collection.
-	CollectErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	RemoteClusterOnline = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "remote_cluster_online",
+		Help:      "Whether each leaf cluster is connected to the Teleport cluster (1) or not (0).",
+	}, []string{"cluster_name", "remote_cluster"})
+
+	RemoteClusterLastHeartbeat = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "remote_cluster_last_heartbeat_timestamp_seconds",
+		Help:      "Unix time each leaf cluster was last connected to the Teleport cluster.",
+	}, []string{"cluster_name", "remote_cluster"})
+
+	CertAuthorityExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cert_authority_expiry_timestamp_seconds",
+		Help:      "Unix time the first active TLS certificate of each host, user and database CA of the cluster and its leaf clusters expires.",
+	}, []string{"cluster_name", "ca_cluster_name", "type"})
+
+	AppProbeSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "app_probe_success",
+		Help:      "Whether the last HTTP probe of each application got an answer other than a server error (1) or not (0).",
+	}, []string{"cluster_name", "app_name"})
+
+	AppProbeDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "app_probe_duration_seconds",
+		Help:      "Duration of the last HTTP probe of each application in seconds, until the answer or the failure.",
+	}, []string{"cluster_name", "app_name"})
+
+	AppProbeStatusCode = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "app_probe_http_status_code",
+		Help:      "HTTP status code of the last probe of each application, 0 if the probe got no answer.",
+	}, []string{"cluster_name", "app_name"})
+
+	DatabaseProbeSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "database_probe_success",
+		Help:      "Whether the last login probe of each database through the Teleport proxy and a database agent succeeded (1) or not (0).",
+	}, []string{"cluster_name", "database_name"})
+
+	DatabaseProbeDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "database_probe_duration_seconds",
+		Help:      "Duration of the last login probe of each database through the Teleport proxy in seconds, until the login or the failure.",
+	}, []string{"cluster_name", "database_name"})
+
+	GRPCConnectionState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "grpc_connection_state",
+		Help:      "State of the gRPC connection to Teleport (1 for the current state, 0 for all others).",
+	}, []string{"state"})
+
+	ConnectionHealthy = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "connection_healthy",
+		Help:      "Whether the last health check (Ping) of the connection to Teleport succeeded (1 = healthy, 0 = unhealthy).",
+	})
+
+	GRPCReconnectsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_reconnects_total",
+		Help:      "Total number of times the gRPC connection to Teleport became ready again after being lost.",
+	})
+
+	CredentialReloadsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "credential_reloads_total",
+		Help:      "Total number of reconnects to Teleport with reloaded credentials after expired or rejected certificates.",
+	})
+
+	CARotationChangesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ca_rotation_changes_total",
+		Help:      "Total number of detected changes of the rotation state of the Teleport cluster CAs, each followed by a reload of the credentials.",
+	})
+
+	ConfigLastReloadSuccessful = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "config_last_reload_successful",
+		Help:      "Whether the last reload of the credentials on SIGHUP or /-/reload succeeded (1 = success, 0 = failure).",
+	})
+
+	ConfigLastReloadSuccessTime = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "config_last_reload_success_timestamp_seconds",
+		Help:      "Unix timestamp of the last successful reload of the credentials, or of the start of the exporter.",
+	})
+
+	CredentialsSource = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "credentials_source",
+		Help:      "Credential source the connection to Teleport authenticated with (1 for the current source, 0 for all others).",
+	}, []string{"source"})
+
+	GRPCClientHandledTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_handled_total",
+		Help:      "Total number of gRPC calls to Teleport completed by the client, by status code.",
+	}, []string{"grpc_service", "grpc_method", "grpc_code"})
+
+	GRPCClientMsgSentBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_msg_sent_bytes",
+		Help:      "Size of gRPC messages sent to Teleport in bytes.",
+		Buckets:   prometheus.ExponentialBuckets(256, 4, 8), // 256B to 4MiB
+	}, []string{"grpc_service", "grpc_method"})
+
+	GRPCClientMsgReceivedBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_msg_received_bytes",
+		Help:      "Size of gRPC messages received from Teleport in bytes.",
+		Buckets:   prometheus.ExponentialBuckets(256, 4, 8), // 256B to 4MiB
+	}, []string{"grpc_service", "grpc_method"})
+
+	APIRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Duration of Teleport API calls in seconds.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10), // 50ms to ~25s
+	}, []string{"method"})
+
+	APIRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_total",
+		Help:      "Total number of Teleport API calls by result (success, timeout, permission_denied, connection, credentials, other).",
+	}, []string{"method", "result"})
+
+	APIErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_errors_total",
+		Help:      "Total number of failed Teleport API calls by error class (timeout, permission_denied, rate_limited, not_found, auth_expired, tls, connection_refused, connection, other).",
+	}, []string{"method", "class"})
+
+	CacheAgeSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cache_age_seconds",
+		Help:      "Age of the cached Teleport API result last served to the collector, by resource type.",
+	}, []string{"resource"})
+
+	CollectDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "collect_duration_seconds",
+		Help:      "Duration of fetching each resource type from the Teleport API in seconds.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10), // 50ms to ~25s
+	}, []string{"cluster_name", "resource"})
+
+	CollectErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace: namespace,
 		Name:      "collect_errors_total",
-		Help:      "Total number of errors encountered during metrics collection.",
+		Help:      "Total number of errors encountered during metrics collection by resource type and reason (timeout, permission_denied, connection, credentials, other).",
+	}, []string{"cluster_name", "resource", "reason"})
+
+	CollectionTimeoutsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "collection_timeouts_total",
+		Help:      "Total number of collections that exceeded the collection timeout, skipping the remaining resource types.",
 	}, []string{"cluster_name"})
 
-	// LastSuccessfulCollectTime is the timestamp of the last successful collection.
-	LastSuccessfulCollectTime = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	ResourceUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "resource_up",
+		Help:      "Whether the last collection of the resource type succeeded (1 = success, 0 = failure).",
+	}, []string{"cluster_name", "resource"})
+
+	ResourceLastSuccessTime = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "resource_last_success_timestamp_seconds",
+		Help:      "Unix timestamp of the last successful collection of the resource type.",
+	}, []string{"cluster_name", "resource"})
+
+	ResourceAccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "resource_access",
+		Help:      "Whether the identity may read the resource type, as checked on startup (1 = allowed, 0 = permission denied).",
+	}, []string{"resource"})
+
+	ResourcePermissionDenied = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "resource_permission_denied",
+		Help:      "Whether the resource type is not collected because Teleport denied access to it (1 = denied, 0 = allowed again).",
+	}, []string{"resource"})
+
+	InventoryRestored = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "inventory_restored",
+		Help:      "Whether the metrics of the resource type are restored from the state file and not collected since the start (1 = restored, 0 = collected again).",
+	}, []string{"resource"})
+
+	SeriesDroppedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "series_dropped_total",
+		Help:      "Total number of series not emitted because a metric exceeded the configured series limit.",
+	}, []string{"metric"})
+
+	LastSuccessfulCollectTime = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Name:      "last_successful_collect_timestamp_seconds",
 		Help:      "Unix timestamp of the last successful metrics collection.",
 	}, []string{"cluster_name"})
-)
+
+	// The resource metrics are exposed through snapshots, see Publish
+	staging = prometheus.NewRegistry()
+	for _, c := range resourceCollectors() {
+		if err := staging.Register(c); err != nil {
+			return err
+		}
+	}
+	published = &snapshotCollector{}
+
+	if reg == nil {
+		return nil
+	}
+	for _, c := range append(exporterCollectors(), published) {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resourceCollectors returns the resource metrics, which are exposed through
+// snapshots.
+func resourceCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		NodesTotal, NodesIdentifiedTotal, NodesUnidentifiedTotal, NodesByKubernetesCluster, NodesBySubkind, NodesByOS, NodesByArch, NodesByLabel, NodeInfo,
+		KubeClustersTotal, KubeManagementClustersTotal, KubeWorkloadClustersTotal, KubeClustersByLabel, KubernetesClusterInfo, KubernetesServerInfo,
+		DatabasesTotal, DatabasesByProtocolTotal, DatabasesByTypeTotal, DatabasesByCloudTotal, DatabasesByLabel, DatabaseInfo, DatabaseServerInfo,
+		DatabasesInsecureTotal, DatabaseInsecureInfo,
+		AppsTotal, AppsByLabel, AppInfo, AppServerInfo,
+		ResourcesMissingLabel, ResourcesByOrigin,
+		UsersTotal, UsersWithoutMFATotal, UserWithoutMFAInfo, UsersByOrigin, UserLastLogin,
+		UsersLockedTotal, UserLockExpiry, LocksByTarget,
+		RolesTotal, RolesRiskyTotal, RoleAssignments,
+		TokensTotal, TokensExpiredTotal, TokensWithoutExpiryTotal, TokensByJoinMethod,
+		AccessRequestsTotal, AccessRequestsPendingTotal, AccessRequestsSLABreachedTotal, AccessRequestOldestPendingAge,
+		AccessRequestsByRole, AccessRequestsPendingByRole,
+		SessionsEndedTotal, SessionsWithoutRecordingTotal,
+		RemoteClusterOnline, RemoteClusterLastHeartbeat, CertAuthorityExpiry,
+	}
+}
+
+// exporterCollectors returns the metrics that are registered directly.
+func exporterCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		TeleportUp, CheckUp, ClusterInfo,
+		GRPCConnectionState, ConnectionHealthy, GRPCReconnectsTotal, CredentialReloadsTotal, CARotationChangesTotal, ConfigLastReloadSuccessful, ConfigLastReloadSuccessTime, CredentialsSource,
+		GRPCClientHandledTotal, GRPCClientMsgSentBytes, GRPCClientMsgReceivedBytes,
+		APIRequestDuration, APIRequestsTotal, APIErrorsTotal, CacheAgeSeconds,
+		CollectDuration, CollectErrorsTotal, CollectionTimeoutsTotal, ResourceUp, ResourceLastSuccessTime, ResourceAccess, ResourcePermissionDenied, InventoryRestored, SeriesDroppedTotal, LastSuccessfulCollectTime,
+		AppProbeSuccess, AppProbeDuration, AppProbeStatusCode, DatabaseProbeSuccess, DatabaseProbeDuration,
+	}
+}
+
+// newInfoVec creates a GaugeVec with the base labels followed by one label per
+// Teleport label key.
+func newInfoVec(opts prometheus.GaugeOpts, base, labelKeys []string) *prometheus.GaugeVec {
+	labels := make([]string, 0, len(base)+len(labelKeys))
+	labels = append(labels, base...)
+	for _, key := range labelKeys {
+		labels = append(labels, LabelName(key))
+	}
+	return prometheus.NewGaugeVec(opts, labels)
+}
+
+// LabelName converts a Teleport label key into a valid Prometheus label name by
+// prefixing it with "label_" and replacing invalid characters with underscores,
+// e.g. "giantswarm.io/cluster" becomes "label_giantswarm_io_cluster".
+func LabelName(key string) string {
+	var b strings.Builder
+	b.WriteString("label_")
+	for _, r := range key {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
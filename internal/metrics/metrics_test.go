@@ -17,8 +17,10 @@ limitations under the License.
 package metrics
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
@@ -50,16 +52,12 @@ func TestNodesTotal(t *testing.T) {
 func TestCollectDuration(t *testing.T) {
 	CollectDuration.Reset()
 
-	CollectDuration.WithLabelValues("test-cluster").Set(0.5)
-	value := testutil.ToFloat64(CollectDuration.WithLabelValues("test-cluster"))
-	if value != 0.5 {
-		t.Errorf("expected CollectDuration to be 0.5, got %f", value)
-	}
+	CollectDuration.WithLabelValues("test-cluster", "nodes").Observe(0.5)
+	CollectDuration.WithLabelValues("test-cluster", "nodes").Observe(1.5)
+	CollectDuration.WithLabelValues("test-cluster", "apps").Observe(0.1)
 
-	CollectDuration.WithLabelValues("test-cluster").Set(1.5)
-	value = testutil.ToFloat64(CollectDuration.WithLabelValues("test-cluster"))
-	if value != 1.5 {
-		t.Errorf("expected CollectDuration to be 1.5, got %f", value)
+	if count := testutil.CollectAndCount(CollectDuration); count != 2 {
+		t.Errorf("expected 2 CollectDuration series, got %d", count)
 	}
 }
 
@@ -106,19 +104,161 @@ func TestAppsTotal(t *testing.T) {
 
 func TestCollectErrorsTotal(t *testing.T) {
 	// Get initial value for test-cluster
-	initialValue := testutil.ToFloat64(CollectErrorsTotal.WithLabelValues("test-cluster"))
+	initialValue := testutil.ToFloat64(CollectErrorsTotal.WithLabelValues("test-cluster", "nodes", "timeout"))
 
 	// Increment and verify
-	CollectErrorsTotal.WithLabelValues("test-cluster").Inc()
-	value := testutil.ToFloat64(CollectErrorsTotal.WithLabelValues("test-cluster"))
+	CollectErrorsTotal.WithLabelValues("test-cluster", "nodes", "timeout").Inc()
+	value := testutil.ToFloat64(CollectErrorsTotal.WithLabelValues("test-cluster", "nodes", "timeout"))
 	if value != initialValue+1 {
 		t.Errorf("expected CollectErrorsTotal to be %f, got %f", initialValue+1, value)
 	}
 
 	// Increment again
-	CollectErrorsTotal.WithLabelValues("test-cluster").Inc()
-	value = testutil.ToFloat64(CollectErrorsTotal.WithLabelValues("test-cluster"))
+	CollectErrorsTotal.WithLabelValues("test-cluster", "nodes", "timeout").Inc()
+	value = testutil.ToFloat64(CollectErrorsTotal.WithLabelValues("test-cluster", "nodes", "timeout"))
 	if value != initialValue+2 {
 		t.Errorf("expected CollectErrorsTotal to be %f, got %f", initialValue+2, value)
 	}
 }
+
+func TestLabelName(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{"env", "label_env"},
+		{"giantswarm.io/cluster", "label_giantswarm_io_cluster"},
+		{"teleport.dev/origin", "label_teleport_dev_origin"},
+		{"Region_1", "label_Region_1"},
+	}
+
+	for _, tt := range tests {
+		if result := LabelName(tt.key); result != tt.expected {
+			t.Errorf("LabelName(%q) = %q, expected %q", tt.key, result, tt.expected)
+		}
+	}
+}
+
+func TestSetup(t *testing.T) {
+	defer func() { _ = Setup(nil, Options{}) }()
+
+	if err := Setup(nil, Options{InfoLabels: InfoLabels{Node: []string{"a.b", "a/b"}}}); err == nil {
+		t.Error("expected error for colliding label names, got nil")
+	}
+
+	reg := prometheus.NewRegistry()
+	if err := Setup(reg, Options{InfoLabels: InfoLabels{Node: []string{"env"}, App: []string{"env"}}}); err != nil {
+		t.Fatalf("expected no error for same label on different resources, got %v", err)
+	}
+
+	NodeInfo.WithLabelValues("test-cluster", "node-1", "host1", "prod").Set(1)
+	if err := Publish(); err != nil {
+		t.Fatalf("Publish() failed: %v", err)
+	}
+	if count, err := testutil.GatherAndCount(reg, "teleport_exporter_node_info"); err != nil || count != 1 {
+		t.Errorf("expected 1 registered node info series, got %d (err: %v)", count, err)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	defer func() { _ = Setup(nil, Options{}) }()
+
+	reg := prometheus.NewRegistry()
+	if err := Setup(reg, Options{InfoLabels: InfoLabels{Node: []string{"env"}}}); err != nil {
+		t.Fatalf("Setup() failed: %v", err)
+	}
+
+	// Resource metrics are not exposed before they are published
+	NodesTotal.WithLabelValues("test-cluster").Set(2)
+	NodeInfo.WithLabelValues("test-cluster", "node-1", "host1", "prod").Set(1)
+	if count, err := testutil.GatherAndCount(reg, "teleport_exporter_nodes_total"); err != nil || count != 0 {
+		t.Errorf("expected no series before Publish, got %d (err: %v)", count, err)
+	}
+
+	if err := Publish(); err != nil {
+		t.Fatalf("Publish() failed: %v", err)
+	}
+	expected := `
+# HELP teleport_exporter_node_info Information about each SSH node registered in Teleport (value is always 1).
+# TYPE teleport_exporter_node_info gauge
+teleport_exporter_node_info{cluster_name="test-cluster",hostname="host1",label_env="prod",node_name="node-1"} 1
+# HELP teleport_exporter_nodes_total Total number of SSH nodes registered in the Teleport cluster.
+# TYPE teleport_exporter_nodes_total gauge
+teleport_exporter_nodes_total{cluster_name="test-cluster"} 2
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "teleport_exporter_nodes_total", "teleport_exporter_node_info"); err != nil {
+		t.Error(err)
+	}
+
+	// Updates in progress stay invisible until the next Publish
+	NodesTotal.WithLabelValues("test-cluster").Set(3)
+	NodeInfo.Reset()
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "teleport_exporter_nodes_total", "teleport_exporter_node_info"); err != nil {
+		t.Error(err)
+	}
+
+	if err := Publish(); err != nil {
+		t.Fatalf("Publish() failed: %v", err)
+	}
+	if count, err := testutil.GatherAndCount(reg, "teleport_exporter_node_info"); err != nil || count != 0 {
+		t.Errorf("expected no node info series after publishing the reset, got %d (err: %v)", count, err)
+	}
+}
+
+func TestSetup_Namespace(t *testing.T) {
+	defer func() { _ = Setup(nil, Options{}) }()
+
+	reg := prometheus.NewRegistry()
+	if err := Setup(reg, Options{Namespace: "teleport"}); err != nil {
+		t.Fatalf("Setup() failed: %v", err)
+	}
+
+	TeleportUp.Set(1)
+	NodesTotal.WithLabelValues("test-cluster").Set(10)
+	if err := Publish(); err != nil {
+		t.Fatalf("Publish() failed: %v", err)
+	}
+	for _, name := range []string{"teleport_up", "teleport_nodes_total"} {
+		if count, err := testutil.GatherAndCount(reg, name); err != nil || count != 1 {
+			t.Errorf("expected 1 series for %s, got %d (err: %v)", name, count, err)
+		}
+	}
+	if count, err := testutil.GatherAndCount(reg, "teleport_exporter_up"); err != nil || count != 0 {
+		t.Errorf("expected no series with default namespace, got %d (err: %v)", count, err)
+	}
+}
+
+func TestDocs(t *testing.T) {
+	docs, err := Docs()
+	if err != nil {
+		t.Fatalf("Docs() error = %v", err)
+	}
+
+	byName := make(map[string]Doc, len(docs))
+	for _, doc := range docs {
+		if _, ok := byName[doc.Name]; ok {
+			t.Errorf("duplicate metric %s", doc.Name)
+		}
+		byName[doc.Name] = doc
+	}
+	tests := []struct {
+		name   string
+		typ    string
+		labels []string
+	}{
+		{name: "teleport_exporter_up", typ: "gauge"},
+		{name: "teleport_exporter_nodes_total", typ: "gauge", labels: []string{"cluster_name"}},
+		{name: "teleport_exporter_grpc_reconnects_total", typ: "counter"},
+		{name: "teleport_exporter_collect_duration_seconds", typ: "histogram", labels: []string{"cluster_name", "resource"}},
+	}
+	for _, tt := range tests {
+		doc, ok := byName[tt.name]
+		if !ok {
+			t.Errorf("expected %s in the docs", tt.name)
+			continue
+		}
+		if doc.Type != tt.typ || strings.Join(doc.Labels, ",") != strings.Join(tt.labels, ",") || doc.Help == "" {
+			t.Errorf("unexpected doc of %s: %+v", tt.name, doc)
+		}
+	}
+}
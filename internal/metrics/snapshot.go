@@ -0,0 +1,102 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	// staging holds the resource metrics while a collection updates them.
+	// They are only exposed once the collection publishes them.
+	staging *prometheus.Registry
+
+	// published exposes the resource metrics of the last published collection.
+	published *snapshotCollector
+)
+
+// snapshotCollector exposes an immutable set of const metrics that is replaced
+// atomically, so that a scrape never sees a half-updated collection.
+type snapshotCollector struct {
+	metrics atomic.Pointer[[]prometheus.Metric]
+}
+
+// Describe sends no descriptors, which makes the collector unchecked, since
+// the set of metrics changes with every collection.
+func (c *snapshotCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect sends the metrics of the last snapshot.
+func (c *snapshotCollector) Collect(ch chan<- prometheus.Metric) {
+	if metrics := c.metrics.Load(); metrics != nil {
+		for _, m := range *metrics {
+			ch <- m
+		}
+	}
+}
+
+// Publish exposes the current state of the resource metrics (totals,
+// breakdowns and *_info metrics) as one snapshot. It is called once the
+// collection has updated all of them.
+func Publish() error {
+	families, err := staging.Gather()
+	if err != nil {
+		return err
+	}
+
+	var metrics []prometheus.Metric
+	for _, mf := range families {
+		if len(mf.GetMetric()) == 0 {
+			continue
+		}
+		valueType := prometheus.GaugeValue
+		if mf.GetType() == dto.MetricType_COUNTER {
+			valueType = prometheus.CounterValue
+		}
+
+		// All series of a vec have the same label names, in the sorted order
+		// of the gathered label pairs
+		labelNames := make([]string, 0, len(mf.GetMetric()[0].GetLabel()))
+		for _, l := range mf.GetMetric()[0].GetLabel() {
+			labelNames = append(labelNames, l.GetName())
+		}
+		sort.Strings(labelNames)
+		desc := prometheus.NewDesc(mf.GetName(), mf.GetHelp(), labelNames, nil)
+
+		for _, m := range mf.GetMetric() {
+			labelValues := make([]string, 0, len(m.GetLabel()))
+			for _, l := range m.GetLabel() {
+				labelValues = append(labelValues, l.GetValue())
+			}
+			value := m.GetGauge().GetValue()
+			if valueType == prometheus.CounterValue {
+				value = m.GetCounter().GetValue()
+			}
+			cm, err := prometheus.NewConstMetric(desc, valueType, value, labelValues...)
+			if err != nil {
+				return err
+			}
+			metrics = append(metrics, cm)
+		}
+	}
+
+	published.metrics.Store(&metrics)
+	return nil
+}
@@ -0,0 +1,537 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mock generates synthetic Teleport resources for the --mock mode,
+// which runs the exporter without contacting Teleport, e.g. to build
+// dashboards or load test Prometheus.
+package mock
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/teleport-exporter/internal/fakes"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+// ClusterName is the name of the synthetic Teleport cluster.
+const ClusterName = "mock.example.com"
+
+// DefaultCounts is the default of --mock-resources.
+const DefaultCounts = "nodes=100,kubernetes_clusters=10,databases=20,apps=20,users=50,locks=5,roles=10,tokens=10,access_requests=10,sessions=20,remote_clusters=5,cert_authorities=6"
+
+// Counts is the number of synthetic resources of each type.
+type Counts struct {
+	Nodes           int
+	KubeClusters    int
+	Databases       int
+	Apps            int
+	Users           int
+	Locks           int
+	Roles           int
+	Tokens          int
+	AccessRequests  int
+	Sessions        int
+	RemoteClusters  int
+	CertAuthorities int
+}
+
+// ParseCounts parses a comma-separated list of resource=count pairs, e.g.
+// "nodes=1000,apps=50". Resource types that are not listed get no resources.
+func ParseCounts(s string) (Counts, error) {
+	var counts Counts
+	fields := map[string]*int{
+		teleport.CacheNodes:           &counts.Nodes,
+		teleport.CacheKubeClusters:    &counts.KubeClusters,
+		teleport.CacheDatabases:       &counts.Databases,
+		teleport.CacheApps:            &counts.Apps,
+		teleport.CacheUsers:           &counts.Users,
+		teleport.CacheLocks:           &counts.Locks,
+		teleport.CacheRoles:           &counts.Roles,
+		teleport.CacheTokens:          &counts.Tokens,
+		teleport.CacheAccessRequests:  &counts.AccessRequests,
+		teleport.CacheSessions:        &counts.Sessions,
+		teleport.CacheRemoteClusters:  &counts.RemoteClusters,
+		teleport.CacheCertAuthorities: &counts.CertAuthorities,
+	}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		resource, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return Counts{}, fmt.Errorf("invalid mock resource count %q, must be resource=count", item)
+		}
+		field, known := fields[strings.TrimSpace(resource)]
+		if !known {
+			keys := slices.Sorted(maps.Keys(fields))
+			return Counts{}, fmt.Errorf("invalid mock resource %q, must be one of %s", resource, strings.Join(keys, ", "))
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || count < 0 {
+			return Counts{}, fmt.Errorf("invalid mock resource count %q, must be a non-negative number", item)
+		}
+		*field = count
+	}
+	return counts, nil
+}
+
+var (
+	envs         = []string{"production", "staging", "testing"}
+	protocols    = []string{"postgres", "mysql", "mongodb", "redis"}
+	dbTypes      = []string{"rds", "self-hosted", "cloudsql"}
+	dbClouds     = []string{teleport.DatabaseCloudAWS, teleport.DatabaseCloudSelfHosted, teleport.DatabaseCloudGCP}
+	dbOrigins    = []string{"cloud", "dynamic", "cloud"}
+	mfaStates    = []string{teleport.MFAWebauthn, teleport.MFATOTP, teleport.MFAWebauthn, teleport.MFANone}
+	nodeSubKinds = []string{teleport.NodeSubKindTeleport, teleport.NodeSubKindTeleport, teleport.NodeSubKindTeleport, teleport.NodeSubKindOpenSSH, teleport.NodeSubKindOpenSSHEICE}
+	arches       = []string{"amd64", "amd64", "amd64", "arm64"}
+	ssoOrigins   = []string{"github", "saml", "okta"}
+	joinMethods  = []string{"token", "iam", "kubernetes", "token"}
+	tlsModes     = []string{teleport.TLSModeVerifyFull, teleport.TLSModeVerifyCA, teleport.TLSModeVerifyFull, teleport.TLSModeVerifyFull, teleport.TLSModeInsecure}
+)
+
+// New returns a client serving the given number of synthetic resources. The
+// resources are the same on every call, so the metrics are stable.
+func New(counts Counts) *fakes.Client {
+	return &fakes.Client{
+		ClusterName:     ClusterName,
+		Nodes:           Nodes(0, counts.Nodes, counts.KubeClusters),
+		KubeClusters:    KubeClusters(0, counts.KubeClusters),
+		Databases:       Databases(0, counts.Databases),
+		Apps:            Apps(0, counts.Apps),
+		Users:           Users(0, counts.Users),
+		Locks:           Locks(0, counts.Locks),
+		Roles:           Roles(0, counts.Roles),
+		Tokens:          Tokens(0, counts.Tokens),
+		AccessRequests:  AccessRequests(0, counts.AccessRequests),
+		Sessions:        Sessions(0, counts.Sessions),
+		RemoteClusters:  RemoteClusters(0, counts.RemoteClusters),
+		CertAuthorities: CertAuthorities(0, counts.CertAuthorities),
+	}
+}
+
+// Churning serves the synthetic resources of counts like New, but replaces a
+// fraction of the resources of each type with new ones on every list call,
+// to load test the series churn of e.g. short-lived nodes.
+type Churning struct {
+	counts Counts
+	churn  float64
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// NewChurning returns a client replacing the fraction churn, between 0 and
+// 1, of the resources on every list call.
+func NewChurning(counts Counts, churn float64) *Churning {
+	return &Churning{counts: counts, churn: churn, calls: make(map[string]int)}
+}
+
+// first returns the index of the first of the n resources served by the
+// current call of method, which moves on by the churned resources per call.
+func (c *Churning) first(method string, n int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	call := c.calls[method]
+	c.calls[method]++
+	return int(float64(n)*c.churn) * call
+}
+
+// GetClusterName returns ClusterName.
+func (c *Churning) GetClusterName(ctx context.Context) (string, error) {
+	return ClusterName, ctx.Err()
+}
+
+// GetNodes returns the current nodes.
+func (c *Churning) GetNodes(ctx context.Context) ([]teleport.NodeInfo, error) {
+	return Nodes(c.first(teleport.CacheNodes, c.counts.Nodes), c.counts.Nodes, c.counts.KubeClusters), ctx.Err()
+}
+
+// ForEachNode calls fn for each of the current nodes.
+func (c *Churning) ForEachNode(ctx context.Context, fn func(teleport.NodeInfo)) error {
+	nodes, err := c.GetNodes(ctx)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		fn(node)
+	}
+	return nil
+}
+
+// GetKubeClusters returns the current Kubernetes clusters.
+func (c *Churning) GetKubeClusters(ctx context.Context) ([]teleport.KubeClusterInfo, error) {
+	return KubeClusters(c.first(teleport.CacheKubeClusters, c.counts.KubeClusters), c.counts.KubeClusters), ctx.Err()
+}
+
+// GetDatabases returns the current databases.
+func (c *Churning) GetDatabases(ctx context.Context) ([]teleport.DatabaseInfo, error) {
+	return Databases(c.first(teleport.CacheDatabases, c.counts.Databases), c.counts.Databases), ctx.Err()
+}
+
+// GetApps returns the current applications.
+func (c *Churning) GetApps(ctx context.Context) ([]teleport.AppInfo, error) {
+	return Apps(c.first(teleport.CacheApps, c.counts.Apps), c.counts.Apps), ctx.Err()
+}
+
+// GetUsers returns the current users.
+func (c *Churning) GetUsers(ctx context.Context) ([]teleport.UserInfo, error) {
+	return Users(c.first(teleport.CacheUsers, c.counts.Users), c.counts.Users), ctx.Err()
+}
+
+// GetLocks returns the current locks.
+func (c *Churning) GetLocks(ctx context.Context) ([]teleport.LockInfo, error) {
+	return Locks(c.first(teleport.CacheLocks, c.counts.Locks), c.counts.Locks), ctx.Err()
+}
+
+// GetRoles returns the current roles.
+func (c *Churning) GetRoles(ctx context.Context) ([]teleport.RoleInfo, error) {
+	return Roles(c.first(teleport.CacheRoles, c.counts.Roles), c.counts.Roles), ctx.Err()
+}
+
+// GetTokens returns the current provision tokens.
+func (c *Churning) GetTokens(ctx context.Context) ([]teleport.TokenInfo, error) {
+	return Tokens(c.first(teleport.CacheTokens, c.counts.Tokens), c.counts.Tokens), ctx.Err()
+}
+
+// GetAccessRequests returns the current access requests.
+func (c *Churning) GetAccessRequests(ctx context.Context) ([]teleport.AccessRequestInfo, error) {
+	return AccessRequests(c.first(teleport.CacheAccessRequests, c.counts.AccessRequests), c.counts.AccessRequests), ctx.Err()
+}
+
+// GetSessions returns the current sessions.
+func (c *Churning) GetSessions(ctx context.Context) ([]teleport.SessionInfo, error) {
+	return Sessions(c.first(teleport.CacheSessions, c.counts.Sessions), c.counts.Sessions), ctx.Err()
+}
+
+// GetRemoteClusters returns the current remote clusters.
+func (c *Churning) GetRemoteClusters(ctx context.Context) ([]teleport.RemoteClusterInfo, error) {
+	return RemoteClusters(c.first(teleport.CacheRemoteClusters, c.counts.RemoteClusters), c.counts.RemoteClusters), ctx.Err()
+}
+
+// GetCertAuthorities returns the current cert authorities.
+func (c *Churning) GetCertAuthorities(ctx context.Context) ([]teleport.CertAuthorityInfo, error) {
+	return CertAuthorities(c.first(teleport.CacheCertAuthorities, c.counts.CertAuthorities), c.counts.CertAuthorities), ctx.Err()
+}
+
+// CheckAccess grants access to all resource types.
+func (c *Churning) CheckAccess(ctx context.Context, resource string) error {
+	return ctx.Err()
+}
+
+// Reconnect does nothing.
+func (c *Churning) Reconnect(ctx context.Context) error {
+	return nil
+}
+
+// kubeClusterName returns the name of the i-th synthetic Kubernetes cluster:
+// every fifth is a management cluster, the others are workload clusters.
+func kubeClusterName(i int) string {
+	if i%5 == 0 {
+		return fmt.Sprintf("mc%02d", i/5)
+	}
+	return fmt.Sprintf("wc-%02d", i)
+}
+
+// hostID returns the i-th synthetic host ID.
+func hostID(i int) string {
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", i)
+}
+
+// Nodes returns n synthetic SSH nodes starting at index first, spread over
+// kubeClusters Kubernetes clusters, or without a Kubernetes cluster if
+// kubeClusters is 0.
+func Nodes(first, n, kubeClusters int) []teleport.NodeInfo {
+	nodes := make([]teleport.NodeInfo, n)
+	for j := range nodes {
+		i := first + j
+		labels := map[string]string{
+			"env":                envs[i%len(envs)],
+			"kubernetes.io/os":   "linux",
+			"kubernetes.io/arch": arches[i%len(arches)],
+		}
+		hostname := fmt.Sprintf("node-%04d", i)
+		if kubeClusters > 0 {
+			cluster := kubeClusterName(i % kubeClusters)
+			labels["giantswarm.io/cluster"] = cluster
+			hostname = fmt.Sprintf("node-%04d.%s.mock.internal", i, cluster)
+		}
+		nodes[j] = teleport.NodeInfo{
+			Name:      hostID(i),
+			Hostname:  hostname,
+			Address:   fmt.Sprintf("10.0.%d.%d:3022", i/250, i%250+1),
+			Labels:    labels,
+			Namespace: "default",
+			SubKind:   nodeSubKinds[i%len(nodeSubKinds)],
+		}
+	}
+	return nodes
+}
+
+// KubeClusters returns n synthetic Kubernetes clusters starting at index
+// first, each served by one agent.
+func KubeClusters(first, n int) []teleport.KubeClusterInfo {
+	clusters := make([]teleport.KubeClusterInfo, n)
+	for j := range clusters {
+		i := first + j
+		name := kubeClusterName(i)
+		clusters[j] = teleport.KubeClusterInfo{
+			Name:    name,
+			Labels:  map[string]string{"env": envs[i%len(envs)]},
+			Servers: []teleport.ServerInfo{{HostID: hostID(100000 + i), Hostname: "kube-agent-" + name}},
+		}
+	}
+	return clusters
+}
+
+// Databases returns n synthetic databases of varying protocols, types and TLS
+// modes starting at index first, each served by two agents.
+func Databases(first, n int) []teleport.DatabaseInfo {
+	databases := make([]teleport.DatabaseInfo, n)
+	for j := range databases {
+		i := first + j
+		databases[j] = teleport.DatabaseInfo{
+			Name:     fmt.Sprintf("db-%04d", i),
+			Protocol: protocols[i%len(protocols)],
+			Type:     dbTypes[i%len(dbTypes)],
+			Cloud:    dbClouds[i%len(dbClouds)],
+			URI:      fmt.Sprintf("db-%04d.internal:5432", i),
+			Labels:   map[string]string{"env": envs[i%len(envs)], "teleport.dev/origin": dbOrigins[i%len(dbOrigins)]},
+			TLSMode:  tlsModes[i%len(tlsModes)],
+			Servers: []teleport.ServerInfo{
+				{HostID: hostID(200000 + 2*i), Hostname: fmt.Sprintf("db-agent-%04d-a", i)},
+				{HostID: hostID(200000 + 2*i + 1), Hostname: fmt.Sprintf("db-agent-%04d-b", i)},
+			},
+		}
+	}
+	return databases
+}
+
+// Apps returns n synthetic applications starting at index first, each served
+// by one agent.
+func Apps(first, n int) []teleport.AppInfo {
+	apps := make([]teleport.AppInfo, n)
+	for j := range apps {
+		i := first + j
+		name := fmt.Sprintf("app-%04d", i)
+		apps[j] = teleport.AppInfo{
+			Name:       name,
+			PublicAddr: name + ".mock.example.com",
+			URI:        fmt.Sprintf("http://10.1.%d.%d:8080", i/250, i%250+1),
+			Labels:     map[string]string{"env": envs[i%len(envs)]},
+			Servers:    []teleport.ServerInfo{{HostID: hostID(300000 + i), Hostname: "app-agent-" + name}},
+		}
+	}
+	return apps
+}
+
+// Users returns n synthetic users starting at index first: every tenth is a
+// bot, every third of the others an SSO user, and a quarter of the others
+// have no MFA device. Users are assigned one of the first five roles of
+// Roles, and SSO users last logged in up to a month ago.
+func Users(first, n int) []teleport.UserInfo {
+	users := make([]teleport.UserInfo, n)
+	for j := range users {
+		i := first + j
+		user := teleport.UserInfo{
+			Name:   fmt.Sprintf("user-%04d", i),
+			Type:   teleport.UserTypeLocal,
+			MFA:    mfaStates[i%len(mfaStates)],
+			Origin: teleport.UserOriginLocal,
+			Roles:  []string{fmt.Sprintf("role-%04d", i%5)},
+		}
+		switch {
+		case i%10 == 9:
+			user.Name = fmt.Sprintf("bot-%04d", i)
+			user.Bot = true
+			user.MFA = teleport.MFAUnknown
+			user.Origin = teleport.UserOriginBot
+		case i%3 == 2:
+			user.Name = fmt.Sprintf("user-%04d@example.com", i)
+			user.Type = teleport.UserTypeSSO
+			user.Origin = ssoOrigins[(i/3)%len(ssoOrigins)]
+			lastLogin := time.Now().Truncate(time.Hour).Add(-time.Duration(i%720) * time.Hour)
+			user.LastLogin = &lastLogin
+		}
+		users[j] = user
+	}
+	return users
+}
+
+// Locks returns n synthetic locks of users starting at index first. Every
+// other lock expires within a day, the others are in force until deleted.
+func Locks(first, n int) []teleport.LockInfo {
+	hour := time.Now().Truncate(time.Hour)
+	locks := make([]teleport.LockInfo, n)
+	for j := range locks {
+		i := first + j
+		lock := teleport.LockInfo{
+			Name:    fmt.Sprintf("lock-%04d", i),
+			Targets: map[string]string{teleport.LockTargetUser: fmt.Sprintf("user-%04d", i)},
+			Message: "locked by the mock mode",
+		}
+		if i%2 == 0 {
+			expires := hour.Add(time.Duration(i%24+1) * time.Hour)
+			lock.Expires = &expires
+		}
+		locks[j] = lock
+	}
+	return locks
+}
+
+// Roles returns n synthetic roles starting at index first. Every fifth role
+// allows any node login, every fourth matches all nodes and every tenth has
+// rules for all resources.
+func Roles(first, n int) []teleport.RoleInfo {
+	roles := make([]teleport.RoleInfo, n)
+	for j := range roles {
+		i := first + j
+		role := teleport.RoleInfo{
+			Name:   fmt.Sprintf("role-%04d", i),
+			Logins: []string{"ubuntu"},
+		}
+		if i%5 == 4 {
+			role.Logins = append(role.Logins, "*")
+		}
+		if i%4 == 3 {
+			role.WildcardLabels = []string{"node"}
+		}
+		role.WildcardResources = i%10 == 9
+		roles[j] = role
+	}
+	return roles
+}
+
+// Tokens returns n synthetic provision tokens of varying join methods
+// starting at index first. Tokens of the token join method expire within a
+// day, except every tenth token, which expired an hour ago; the others do not
+// expire.
+func Tokens(first, n int) []teleport.TokenInfo {
+	hour := time.Now().Truncate(time.Hour)
+	tokens := make([]teleport.TokenInfo, n)
+	for j := range tokens {
+		i := first + j
+		token := teleport.TokenInfo{
+			Name:       fmt.Sprintf("token-%04d", i),
+			JoinMethod: joinMethods[i%len(joinMethods)],
+			Roles:      []string{"Node"},
+		}
+		switch {
+		case i%10 == 9:
+			expires := hour.Add(-time.Hour)
+			token.Expires = &expires
+		case token.JoinMethod == "token":
+			expires := hour.Add(time.Duration(i%24+1) * time.Hour)
+			token.Expires = &expires
+		}
+		tokens[j] = token
+	}
+	return tokens
+}
+
+// AccessRequests returns n synthetic access requests starting at index
+// first, created up to seven hours ago. Every third request is approved, the
+// others are pending.
+func AccessRequests(first, n int) []teleport.AccessRequestInfo {
+	hour := time.Now().Truncate(time.Hour)
+	requests := make([]teleport.AccessRequestInfo, n)
+	for j := range requests {
+		i := first + j
+		state := teleport.AccessRequestPending
+		if i%3 == 2 {
+			state = teleport.AccessRequestApproved
+		}
+		requests[j] = teleport.AccessRequestInfo{
+			Name:    fmt.Sprintf("request-%04d", i),
+			User:    fmt.Sprintf("user-%04d", i),
+			Roles:   []string{"admin"},
+			State:   state,
+			Created: hour.Add(-time.Duration(i%8) * time.Hour),
+		}
+	}
+	return requests
+}
+
+// Sessions returns n synthetic sessions starting at index first, ended up to
+// 50 minutes before the last full hour. Every tenth session has no uploaded
+// recording.
+func Sessions(first, n int) []teleport.SessionInfo {
+	hour := time.Now().Truncate(time.Hour)
+	sessions := make([]teleport.SessionInfo, n)
+	for j := range sessions {
+		i := first + j
+		sessions[j] = teleport.SessionInfo{
+			ID:        fmt.Sprintf("session-%04d", i),
+			User:      fmt.Sprintf("user-%04d", i),
+			Ended:     hour.Add(-time.Duration(i%6) * 10 * time.Minute),
+			Recording: "node",
+			Recorded:  i%10 != 9,
+		}
+	}
+	return sessions
+}
+
+// RemoteClusters returns n synthetic leaf clusters starting at index first,
+// last connected up to 4 minutes before the last full hour. Every fifth
+// leaf cluster is offline.
+func RemoteClusters(first, n int) []teleport.RemoteClusterInfo {
+	hour := time.Now().Truncate(time.Hour)
+	clusters := make([]teleport.RemoteClusterInfo, n)
+	for j := range clusters {
+		i := first + j
+		heartbeat := hour.Add(-time.Duration(i%5) * time.Minute)
+		status := teleport.RemoteClusterOnline
+		if i%5 == 4 {
+			status = teleport.RemoteClusterOffline
+		}
+		clusters[j] = teleport.RemoteClusterInfo{
+			Name:          fmt.Sprintf("leaf-%04d.example.com", i),
+			Status:        status,
+			LastHeartbeat: &heartbeat,
+		}
+	}
+	return clusters
+}
+
+// CertAuthorities returns n synthetic host, user and database CAs starting at
+// index first, of the mock cluster and its leaf clusters. They expire between
+// 10 and 3650 days after the last full hour.
+func CertAuthorities(first, n int) []teleport.CertAuthorityInfo {
+	hour := time.Now().Truncate(time.Hour)
+	caTypes := []string{"host", "user", "db"}
+	expiries := []int{3650, 3650, 365, 10}
+	cas := make([]teleport.CertAuthorityInfo, n)
+	for j := range cas {
+		i := first + j
+		clusterName := ClusterName
+		if leaf := i / len(caTypes); leaf > 0 {
+			clusterName = fmt.Sprintf("leaf-%04d.example.com", leaf-1)
+		}
+		cas[j] = teleport.CertAuthorityInfo{
+			ClusterName: clusterName,
+			Type:        caTypes[i%len(caTypes)],
+			Expires:     hour.AddDate(0, 0, expiries[i%len(expiries)]),
+		}
+	}
+	return cas
+}
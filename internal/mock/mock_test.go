@@ -0,0 +1,231 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mock
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+func TestParseCounts(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Counts
+		wantErr bool
+	}{
+		{input: "", want: Counts{}},
+		{input: DefaultCounts, want: Counts{Nodes: 100, KubeClusters: 10, Databases: 20, Apps: 20, Users: 50, Locks: 5, Roles: 10, Tokens: 10, AccessRequests: 10, Sessions: 20, RemoteClusters: 5, CertAuthorities: 6}},
+		{input: " nodes = 5 , apps=1", want: Counts{Nodes: 5, Apps: 1}},
+		{input: "nodes", wantErr: true},
+		{input: "widgets=5", wantErr: true},
+		{input: "nodes=-1", wantErr: true},
+		{input: "nodes=many", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseCounts(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCounts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseCounts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	clt := New(Counts{Nodes: 12, KubeClusters: 6, Databases: 3, Apps: 2})
+
+	nodes, err := clt.GetNodes(context.Background())
+	if err != nil || len(nodes) != 12 {
+		t.Fatalf("GetNodes() = %d nodes, %v, want 12", len(nodes), err)
+	}
+	if got := nodes[1].Labels["giantswarm.io/cluster"]; got != "wc-01" {
+		t.Errorf("expected node 1 in cluster wc-01, got %q", got)
+	}
+	seen := make(map[string]bool)
+	for _, node := range nodes {
+		if seen[node.Name] {
+			t.Errorf("duplicate node name %q", node.Name)
+		}
+		seen[node.Name] = true
+	}
+
+	clusters, _ := clt.GetKubeClusters(context.Background())
+	if len(clusters) != 6 || clusters[0].Name != "mc00" || clusters[5].Name != "mc01" {
+		t.Errorf("unexpected Kubernetes clusters %+v", clusters)
+	}
+	databases, _ := clt.GetDatabases(context.Background())
+	if len(databases) != 3 || len(databases[0].Servers) != 2 {
+		t.Errorf("expected 3 databases with 2 servers each, got %+v", databases)
+	}
+	users, _ := clt.GetUsers(context.Background())
+	if len(users) != 0 {
+		t.Errorf("expected no users, got %+v", users)
+	}
+	if name, _ := clt.GetClusterName(context.Background()); name != ClusterName {
+		t.Errorf("GetClusterName() = %q, want %q", name, ClusterName)
+	}
+}
+
+func TestChurning(t *testing.T) {
+	clt := NewChurning(Counts{Nodes: 10, Apps: 4}, 0.3)
+
+	first, _ := clt.GetNodes(context.Background())
+	second, _ := clt.GetNodes(context.Background())
+	if len(first) != 10 || len(second) != 10 {
+		t.Fatalf("expected 10 nodes per call, got %d and %d", len(first), len(second))
+	}
+	// 3 of the 10 nodes are replaced per call
+	if second[0].Name != first[3].Name || second[9].Name == first[9].Name {
+		t.Errorf("expected the first 3 nodes to be replaced, got %s..%s after %s..%s",
+			second[0].Name, second[9].Name, first[0].Name, first[9].Name)
+	}
+	// The resource types churn independently
+	apps, _ := clt.GetApps(context.Background())
+	if apps[0].Name != "app-0000" {
+		t.Errorf("expected the first apps call to start at app-0000, got %s", apps[0].Name)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	users := Users(0, 20)
+	var bots, sso, withoutMFA int
+	for _, user := range users {
+		switch {
+		case user.Bot:
+			bots++
+		case user.Type == teleport.UserTypeSSO:
+			sso++
+		case user.MFA == teleport.MFANone:
+			withoutMFA++
+		}
+	}
+	if bots != 2 || sso != 6 || withoutMFA != 3 {
+		t.Errorf("expected 2 bots, 6 SSO users and 3 local users without MFA, got %d, %d and %d", bots, sso, withoutMFA)
+	}
+}
+
+func TestLocks(t *testing.T) {
+	locks := Locks(0, 4)
+	var expiring int
+	for _, lock := range locks {
+		if lock.Targets[teleport.LockTargetUser] == "" {
+			t.Errorf("expected lock %s to target a user", lock.Name)
+		}
+		if lock.Expires != nil {
+			expiring++
+		}
+	}
+	if expiring != 2 {
+		t.Errorf("expected 2 expiring locks, got %d", expiring)
+	}
+}
+
+func TestRoles(t *testing.T) {
+	var wildcardLogins, wildcardLabels, wildcardResources int
+	for _, role := range Roles(0, 20) {
+		if slices.Contains(role.Logins, "*") {
+			wildcardLogins++
+		}
+		if len(role.WildcardLabels) > 0 {
+			wildcardLabels++
+		}
+		if role.WildcardResources {
+			wildcardResources++
+		}
+	}
+	if wildcardLogins != 4 || wildcardLabels != 5 || wildcardResources != 2 {
+		t.Errorf("expected 4, 5 and 2 risky roles, got %d, %d and %d", wildcardLogins, wildcardLabels, wildcardResources)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	var expired, withoutExpiry int
+	for _, token := range Tokens(0, 20) {
+		switch {
+		case token.Expires == nil:
+			withoutExpiry++
+		case token.Expires.Before(time.Now()):
+			expired++
+		}
+	}
+	if expired != 2 || withoutExpiry != 9 {
+		t.Errorf("expected 2 expired tokens and 9 without expiry, got %d and %d", expired, withoutExpiry)
+	}
+}
+
+func TestAccessRequests(t *testing.T) {
+	var pending int
+	for _, req := range AccessRequests(0, 9) {
+		if req.State == teleport.AccessRequestPending {
+			pending++
+		}
+		if time.Since(req.Created) > 8*time.Hour {
+			t.Errorf("expected request %s to be created within 8 hours, got %s", req.Name, req.Created)
+		}
+	}
+	if pending != 6 {
+		t.Errorf("expected 6 pending requests, got %d", pending)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	var withoutRecording int
+	for _, session := range Sessions(0, 20) {
+		if !session.Recorded {
+			withoutRecording++
+		}
+	}
+	if withoutRecording != 2 {
+		t.Errorf("expected 2 sessions without recording, got %d", withoutRecording)
+	}
+}
+
+func TestRemoteClusters(t *testing.T) {
+	var offline int
+	for _, rc := range RemoteClusters(0, 10) {
+		if rc.Status != teleport.RemoteClusterOnline {
+			offline++
+		}
+	}
+	if offline != 2 {
+		t.Errorf("expected 2 offline remote clusters, got %d", offline)
+	}
+}
+
+func TestCertAuthorities(t *testing.T) {
+	cas := CertAuthorities(0, 6)
+	if cas[0].ClusterName != ClusterName || cas[3].ClusterName != "leaf-0000.example.com" {
+		t.Errorf("expected the first 3 CAs to be of the mock cluster and the next of a leaf cluster, got %+v", cas)
+	}
+	var expiring int
+	for _, ca := range cas {
+		if time.Until(ca.Expires) < 30*24*time.Hour {
+			expiring++
+		}
+	}
+	if expiring != 1 {
+		t.Errorf("expected 1 CA expiring within 30 days, got %d", expiring)
+	}
+}
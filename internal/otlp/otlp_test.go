@@ -0,0 +1,66 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestParseHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", input: "", want: map[string]string{}},
+		{name: "single", input: "authorization=Bearer abc", want: map[string]string{"authorization": "Bearer abc"}},
+		{name: "multiple with spaces", input: " a=1 , b = 2 ,", want: map[string]string{"a": "1", "b": "2"}},
+		{name: "value with equals", input: "a=b=c", want: map[string]string{"a": "b=c"}},
+		{name: "missing value", input: "a", wantErr: true},
+		{name: "missing key", input: "=b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHeaders(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHeaders(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseHeaders(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStart_InvalidProtocol(t *testing.T) {
+	_, err := Start(context.Background(), Config{
+		Endpoint: "localhost:4317",
+		Protocol: "udp",
+		Interval: time.Minute,
+		Gatherer: prometheus.NewRegistry(),
+	})
+	if err == nil {
+		t.Error("expected error for unsupported protocol")
+	}
+}
@@ -0,0 +1,294 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+	"github.com/giantswarm/teleport-exporter/internal/version"
+)
+
+// Targets of AppConfig.Target.
+const (
+	// TargetPublicAddr probes the public address of an application, which
+	// is served by the Teleport proxy.
+	TargetPublicAddr = "public_addr"
+	// TargetURI probes the address the Teleport agent forwards the requests
+	// of an application to.
+	TargetURI = "uri"
+)
+
+// AppSource is the part of the Teleport client the prober lists the
+// applications with.
+type AppSource interface {
+	GetClusterName(ctx context.Context) (string, error)
+	GetApps(ctx context.Context) ([]teleport.AppInfo, error)
+}
+
+// AppCertIssuer issues the certificates the probes of the public addresses
+// authenticate to the Teleport proxy with, implemented by *teleport.Client.
+type AppCertIssuer interface {
+	AppCert(ctx context.Context, clusterName string, app teleport.AppInfo) (tls.Certificate, error)
+}
+
+// loginPaths are the path prefixes of the pages the Teleport proxy redirects
+// unauthenticated requests of an application to.
+var loginPaths = []string{"/web/launch", "/web/login"}
+
+// AppConfig holds the configuration of the application prober.
+type AppConfig struct {
+	Source AppSource
+	// Interval is the time between two rounds of probes.
+	Interval time.Duration
+	// Timeout is the timeout of a single probe.
+	Timeout time.Duration
+	// Target is either TargetPublicAddr or TargetURI.
+	Target string
+	// Certs authenticates the probes of TargetPublicAddr, so that the
+	// Teleport proxy forwards them to the application. Without it, the proxy
+	// redirects every probe to its login page, which counts as a failure.
+	Certs AppCertIssuer
+	// Transport sends the probes, http.DefaultTransport if nil. It must be an
+	// *http.Transport if Certs is set.
+	Transport http.RoundTripper
+	Log       logr.Logger
+}
+
+// AppProber periodically sends a GET request to every HTTP application and
+// exports whether and how fast it answered.
+type AppProber struct {
+	cfg    AppConfig
+	client *http.Client
+	last   series
+
+	mu sync.Mutex
+	// authClients are the clients with the certificate of each application,
+	// by application name
+	authClients map[string]authClient
+}
+
+// authClient is an HTTP client with the certificate of an application.
+type authClient struct {
+	client *http.Client
+	// renewAt is when half of the validity of the certificate passed
+	renewAt time.Time
+}
+
+// appTarget is an application and the URL it is probed at.
+type appTarget struct {
+	app teleport.AppInfo
+	url string
+}
+
+// NewAppProber creates an AppProber.
+func NewAppProber(cfg AppConfig) (*AppProber, error) {
+	if cfg.Target != TargetPublicAddr && cfg.Target != TargetURI {
+		return nil, fmt.Errorf("unsupported probe target %q, must be %q or %q", cfg.Target, TargetPublicAddr, TargetURI)
+	}
+	transport := cfg.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &AppProber{cfg: cfg, client: newProbeClient(transport, cfg.Timeout), last: make(series), authClients: make(map[string]authClient)}, nil
+}
+
+// newProbeClient returns an HTTP client that does not follow redirects, so
+// that a redirect of the application, e.g. to its login page, is its answer.
+func newProbeClient(transport http.RoundTripper, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Run probes the applications right away and then every interval until ctx
+// is cancelled.
+func (p *AppProber) Run(ctx context.Context) {
+	run(ctx, p.cfg.Interval, p.cfg.Log, p.Probe)
+}
+
+// Probe probes every HTTP application once and updates the metrics, deleting
+// the series of applications that are gone. If the applications cannot be
+// listed, the metrics of the previous round are kept. It must not be called
+// concurrently.
+func (p *AppProber) Probe(ctx context.Context) error {
+	clusterName, err := p.cfg.Source.GetClusterName(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the cluster name: %w", err)
+	}
+	apps, err := p.cfg.Source.GetApps(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list the applications: %w", err)
+	}
+
+	targets := make(map[string]appTarget, len(apps))
+	for _, app := range apps {
+		if target, ok := p.target(app); ok {
+			targets[app.Name] = appTarget{app: app, url: target}
+		}
+	}
+	results := probeAll(targets, func(target appTarget) result {
+		client, err := p.clientFor(ctx, clusterName, target.app)
+		if err != nil {
+			p.cfg.Log.V(1).Info("failed to authenticate the application probe", "app", target.app.Name, "error", err.Error())
+			return result{}
+		}
+		return p.probe(ctx, client, target.url)
+	})
+	p.forgetClients(targets)
+
+	m := resultMetrics{success: metrics.AppProbeSuccess, duration: metrics.AppProbeDuration, statusCode: metrics.AppProbeStatusCode}
+	p.last = m.apply(clusterName, results, p.last)
+	p.cfg.Log.V(1).Info("probed applications", "count", len(results))
+	return nil
+}
+
+// target returns the URL to probe app at, or false if app does not serve
+// HTTP, e.g. a TCP application, or has no public address.
+func (p *AppProber) target(app teleport.AppInfo) (string, bool) {
+	u, err := url.Parse(app.URI)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+	if p.cfg.Target == TargetURI {
+		return app.URI, true
+	}
+	if app.PublicAddr == "" {
+		return "", false
+	}
+	return "https://" + app.PublicAddr + "/", true
+}
+
+// clientFor returns the client to probe app with: with a certificate of app
+// if the public addresses are probed with certificates, renewed once half of
+// its validity passed.
+func (p *AppProber) clientFor(ctx context.Context, clusterName string, app teleport.AppInfo) (*http.Client, error) {
+	if p.cfg.Target != TargetPublicAddr || p.cfg.Certs == nil {
+		return p.client, nil
+	}
+	p.mu.Lock()
+	cached, found := p.authClients[app.Name]
+	p.mu.Unlock()
+	if found && time.Now().Before(cached.renewAt) {
+		return cached.client, nil
+	}
+
+	base, ok := p.client.Transport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("transport %T does not support client certificates", p.client.Transport)
+	}
+	cert, err := p.cfg.Certs.AppCert(ctx, clusterName, app)
+	if err != nil {
+		return nil, err
+	}
+	transport := base.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	renewAt := time.Now()
+	if cert.Leaf != nil {
+		renewAt = renewAt.Add(time.Until(cert.Leaf.NotAfter) / 2)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if found {
+		cached.client.CloseIdleConnections()
+	}
+	client := newProbeClient(transport, p.cfg.Timeout)
+	p.authClients[app.Name] = authClient{client: client, renewAt: renewAt}
+	return client, nil
+}
+
+// forgetClients drops the clients of the applications that are not probed
+// anymore.
+func (p *AppProber) forgetClients(targets map[string]appTarget) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for name, cached := range p.authClients {
+		if _, ok := targets[name]; !ok {
+			cached.client.CloseIdleConnections()
+			delete(p.authClients, name)
+		}
+	}
+}
+
+// probe sends a GET request to target with client. Any answer but a server
+// error or a redirect to the login page of the Teleport proxy counts as a
+// success, since applications commonly answer unauthenticated requests with
+// a redirect or a 401.
+func (p *AppProber) probe(ctx context.Context, client *http.Client, target string) result {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return result{}
+	}
+	req.Header.Set("User-Agent", "teleport-exporter/"+version.Get().Version)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	duration := time.Since(start)
+	if err != nil {
+		p.cfg.Log.V(1).Info("application probe failed", "target", target, "error", err.Error())
+		return result{duration: duration}
+	}
+	resp.Body.Close()
+	success := resp.StatusCode < http.StatusInternalServerError
+	if isLoginRedirect(resp) {
+		// The proxy did not forward the probe, so the application may as
+		// well be down
+		p.cfg.Log.V(1).Info("application probe redirected to the login page of the Teleport proxy", "target", target)
+		success = false
+	}
+	return result{
+		success:    success,
+		duration:   duration,
+		statusCode: resp.StatusCode,
+	}
+}
+
+// isLoginRedirect reports whether resp redirects to the login page of the
+// Teleport proxy.
+func isLoginRedirect(resp *http.Response) bool {
+	if resp.StatusCode < http.StatusMultipleChoices || resp.StatusCode >= http.StatusBadRequest {
+		return false
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return false
+	}
+	for _, prefix := range loginPaths {
+		if strings.HasPrefix(location.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probe
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+// fakeAppSource serves a fixed list of applications.
+type fakeAppSource struct {
+	apps []teleport.AppInfo
+	err  error
+}
+
+func (f *fakeAppSource) GetClusterName(context.Context) (string, error) {
+	return "test-cluster", nil
+}
+
+func (f *fakeAppSource) GetApps(context.Context) ([]teleport.AppInfo, error) {
+	return f.apps, f.err
+}
+
+func TestNewAppProber(t *testing.T) {
+	if _, err := NewAppProber(AppConfig{Target: "address"}); err == nil {
+		t.Error("expected an error for an unsupported target")
+	}
+}
+
+func TestAppProber_Probe(t *testing.T) {
+	metrics.AppProbeSuccess.Reset()
+	metrics.AppProbeDuration.Reset()
+	metrics.AppProbeStatusCode.Reset()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, _ *http.Request) {})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	})
+	mux.HandleFunc("/proxy", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://teleport.example.com/web/launch/grafana.example.com?path=%2F", http.StatusFound)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	source := &fakeAppSource{apps: []teleport.AppInfo{
+		{Name: "ok", URI: server.URL + "/ok"},
+		{Name: "login", URI: server.URL + "/login"},
+		{Name: "broken", URI: server.URL + "/broken"},
+		{Name: "proxy", URI: server.URL + "/proxy"},
+		{Name: "postgres", URI: "tcp://postgres:5432"},
+	}}
+	p, err := NewAppProber(AppConfig{Source: source, Interval: time.Minute, Timeout: time.Second, Target: TargetURI, Log: logr.Discard()})
+	if err != nil {
+		t.Fatalf("NewAppProber() failed: %v", err)
+	}
+	if err := p.Probe(context.Background()); err != nil {
+		t.Fatalf("Probe() failed: %v", err)
+	}
+
+	tests := map[string]struct {
+		success    float64
+		statusCode float64
+	}{
+		"ok":     {1, http.StatusOK},
+		"login":  {1, http.StatusFound},
+		"broken": {0, http.StatusBadGateway},
+		"proxy":  {0, http.StatusFound},
+	}
+	for app, want := range tests {
+		if got := testutil.ToFloat64(metrics.AppProbeSuccess.WithLabelValues("test-cluster", app)); got != want.success {
+			t.Errorf("expected success %v for %s, got %v", want.success, app, got)
+		}
+		if got := testutil.ToFloat64(metrics.AppProbeStatusCode.WithLabelValues("test-cluster", app)); got != want.statusCode {
+			t.Errorf("expected status code %v for %s, got %v", want.statusCode, app, got)
+		}
+	}
+	if got := testutil.CollectAndCount(metrics.AppProbeSuccess); got != 4 {
+		t.Errorf("expected TCP applications not to be probed, got %d series", got)
+	}
+
+	// The metrics are kept if the applications cannot be listed
+	source.err = errors.New("connection lost")
+	if err := p.Probe(context.Background()); err == nil {
+		t.Error("expected an error if the applications cannot be listed")
+	}
+	if got := testutil.CollectAndCount(metrics.AppProbeSuccess); got != 4 {
+		t.Errorf("expected the series of the previous round, got %d", got)
+	}
+
+	// Applications that are gone are removed, unreachable ones fail
+	server.Close()
+	source.apps, source.err = source.apps[:1], nil
+	if err := p.Probe(context.Background()); err != nil {
+		t.Fatalf("Probe() failed: %v", err)
+	}
+	if got := testutil.CollectAndCount(metrics.AppProbeDuration); got != 1 {
+		t.Errorf("expected 1 series, got %d", got)
+	}
+	if got := testutil.ToFloat64(metrics.AppProbeSuccess.WithLabelValues("test-cluster", "ok")); got != 0 {
+		t.Errorf("expected an unreachable application to fail, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.AppProbeStatusCode.WithLabelValues("test-cluster", "ok")); got != 0 {
+		t.Errorf("expected no status code without an answer, got %v", got)
+	}
+}
+
+func TestAppProber_Target(t *testing.T) {
+	p, err := NewAppProber(AppConfig{Target: TargetPublicAddr})
+	if err != nil {
+		t.Fatalf("NewAppProber() failed: %v", err)
+	}
+	tests := []struct {
+		app    teleport.AppInfo
+		want   string
+		probed bool
+	}{
+		{app: teleport.AppInfo{PublicAddr: "grafana.teleport.example.com", URI: "http://grafana:3000"}, want: "https://grafana.teleport.example.com/", probed: true},
+		{app: teleport.AppInfo{URI: "http://grafana:3000"}},
+		{app: teleport.AppInfo{PublicAddr: "postgres.teleport.example.com", URI: "tcp://postgres:5432"}},
+	}
+	for _, tt := range tests {
+		if got, probed := p.target(tt.app); got != tt.want || probed != tt.probed {
+			t.Errorf("target(%+v) = %q, %v, want %q, %v", tt.app, got, probed, tt.want, tt.probed)
+		}
+	}
+}
+
+// fakeAppCerts issues self-signed client certificates and counts them.
+type fakeAppCerts struct {
+	issued atomic.Int32
+	err    error
+}
+
+func (f *fakeAppCerts) AppCert(_ context.Context, clusterName string, app teleport.AppInfo) (tls.Certificate, error) {
+	if f.err != nil {
+		return tls.Certificate{}, f.err
+	}
+	f.issued.Add(1)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: clusterName + "/" + app.Name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// newFakeAppProxy returns a fake Teleport proxy, which redirects requests
+// without a client certificate to its login page and forwards the others to
+// upstream, answering 502 if upstream is down.
+func newFakeAppProxy(t *testing.T, upstream string) *httptest.Server {
+	t.Helper()
+	target, err := url.Parse(upstream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forward := httputil.NewSingleHostReverseProxy(target)
+	proxy := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			http.Redirect(w, r, "https://teleport.example.com/web/launch/"+r.Host+"?path=%2F", http.StatusFound)
+			return
+		}
+		forward.ServeHTTP(w, r)
+	}))
+	proxy.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	proxy.StartTLS()
+	t.Cleanup(proxy.Close)
+	return proxy
+}
+
+func TestAppProber_ProbeThroughProxy(t *testing.T) {
+	metrics.AppProbeSuccess.Reset()
+	metrics.AppProbeDuration.Reset()
+	metrics.AppProbeStatusCode.Reset()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	proxy := newFakeAppProxy(t, upstream.URL)
+	source := &fakeAppSource{apps: []teleport.AppInfo{
+		{Name: "grafana", PublicAddr: strings.TrimPrefix(proxy.URL, "https://"), URI: "http://grafana:3000"},
+	}}
+	probeOnce := func(certs AppCertIssuer) (float64, float64) {
+		t.Helper()
+		p, err := NewAppProber(AppConfig{Source: source, Interval: time.Minute, Timeout: time.Second, Target: TargetPublicAddr,
+			Certs: certs, Transport: proxy.Client().Transport, Log: logr.Discard()})
+		if err != nil {
+			t.Fatalf("NewAppProber() failed: %v", err)
+		}
+		if err := p.Probe(context.Background()); err != nil {
+			t.Fatalf("Probe() failed: %v", err)
+		}
+		return testutil.ToFloat64(metrics.AppProbeSuccess.WithLabelValues("test-cluster", "grafana")),
+			testutil.ToFloat64(metrics.AppProbeStatusCode.WithLabelValues("test-cluster", "grafana"))
+	}
+
+	// With a certificate, the answer of the application counts
+	certs := &fakeAppCerts{}
+	if success, code := probeOnce(certs); success != 1 || code != http.StatusUnauthorized {
+		t.Errorf("expected the 401 of the application to count as up, got success %v and status code %v", success, code)
+	}
+
+	// Without a certificate, the proxy redirects also while the application
+	// is down
+	upstream.Close()
+	if success, code := probeOnce(nil); success != 0 || code != http.StatusFound {
+		t.Errorf("expected the login redirect of the proxy to fail, got success %v and status code %v", success, code)
+	}
+	if success, code := probeOnce(certs); success != 0 || code != http.StatusBadGateway {
+		t.Errorf("expected the probe of a down application to fail, got success %v and status code %v", success, code)
+	}
+
+	// Failing to issue a certificate fails the probe
+	if success, _ := probeOnce(&fakeAppCerts{err: errors.New("access denied")}); success != 0 {
+		t.Errorf("expected a probe without certificate to fail, got success %v", success)
+	}
+}
+
+func TestAppProber_RenewsCerts(t *testing.T) {
+	proxy := newFakeAppProxy(t, "http://127.0.0.1:1")
+	source := &fakeAppSource{apps: []teleport.AppInfo{
+		{Name: "grafana", PublicAddr: strings.TrimPrefix(proxy.URL, "https://"), URI: "http://grafana:3000"},
+	}}
+	certs := &fakeAppCerts{}
+	p, err := NewAppProber(AppConfig{Source: source, Interval: time.Minute, Timeout: time.Second, Target: TargetPublicAddr,
+		Certs: certs, Transport: proxy.Client().Transport, Log: logr.Discard()})
+	if err != nil {
+		t.Fatalf("NewAppProber() failed: %v", err)
+	}
+	for range 2 {
+		if err := p.Probe(context.Background()); err != nil {
+			t.Fatalf("Probe() failed: %v", err)
+		}
+	}
+	if got := certs.issued.Load(); got != 1 {
+		t.Errorf("expected the certificate to be reused, got %d issued", got)
+	}
+
+	// Certificates past half of their validity are renewed
+	p.authClients["grafana"] = authClient{client: p.authClients["grafana"].client, renewAt: time.Now()}
+	if err := p.Probe(context.Background()); err != nil {
+		t.Fatalf("Probe() failed: %v", err)
+	}
+	if got := certs.issued.Load(); got != 2 {
+		t.Errorf("expected the certificate to be renewed, got %d issued", got)
+	}
+
+	// The clients of applications that are gone are dropped
+	source.apps = nil
+	if err := p.Probe(context.Background()); err != nil {
+		t.Fatalf("Probe() failed: %v", err)
+	}
+	if len(p.authClients) != 0 {
+		t.Errorf("expected no clients, got %d", len(p.authClients))
+	}
+}
@@ -0,0 +1,271 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+// DatabaseSource is the part of the Teleport client the prober lists the
+// databases with.
+type DatabaseSource interface {
+	GetClusterName(ctx context.Context) (string, error)
+	GetDatabases(ctx context.Context) ([]teleport.DatabaseInfo, error)
+}
+
+// DatabaseAccess is the part of the Teleport client the prober connects to
+// the databases through the Teleport proxy with, implemented by
+// *teleport.Client.
+type DatabaseAccess interface {
+	ProxyPublicAddr(ctx context.Context) (string, error)
+	DatabaseCert(ctx context.Context, db teleport.DatabaseInfo, user, database string) (tls.Certificate, error)
+}
+
+// databaseProtocols are the ALPN protocols the Teleport proxy routes the
+// connections of each probed database protocol by. Both speak the PostgreSQL
+// wire protocol.
+var databaseProtocols = map[string]string{
+	"postgres":    "teleport-postgres",
+	"cockroachdb": "teleport-cockroachdb",
+}
+
+// DatabaseConfig holds the configuration of the database prober.
+type DatabaseConfig struct {
+	Source DatabaseSource
+	Access DatabaseAccess
+	// Interval is the time between two rounds of probes.
+	Interval time.Duration
+	// Timeout is the timeout of a single probe.
+	Timeout time.Duration
+	// Selector are the Teleport labels a database must have to be probed,
+	// all databases if empty.
+	Selector map[string]string
+	// User and Database are the database user and the database the probes
+	// log in as and to.
+	User     string
+	Database string
+	// TLSConfig is the base configuration of the connections to the Teleport
+	// proxy, verifying it with the system roots if nil.
+	TLSConfig *tls.Config
+	Log       logr.Logger
+}
+
+// DatabaseProber periodically connects to every selected database through the
+// Teleport proxy and a database agent, like tsh proxy db, logs in and exports
+// whether and how fast it succeeded. A probe fails if the proxy rejects the
+// certificate, no agent serves the database or the agent cannot connect to
+// it, e.g. because its database CA expired.
+type DatabaseProber struct {
+	cfg  DatabaseConfig
+	last series
+
+	mu sync.Mutex
+	// certs are the certificates of each database, by database name
+	certs map[string]databaseCert
+}
+
+// databaseCert is a certificate of a database.
+type databaseCert struct {
+	cert tls.Certificate
+	// renewAt is when half of the validity of the certificate passed
+	renewAt time.Time
+}
+
+// NewDatabaseProber creates a DatabaseProber.
+func NewDatabaseProber(cfg DatabaseConfig) (*DatabaseProber, error) {
+	if cfg.Access == nil {
+		return nil, errors.New("database probes require a connection to Teleport")
+	}
+	if cfg.User == "" || cfg.Database == "" {
+		return nil, errors.New("database probes require a database user and a database name")
+	}
+	return &DatabaseProber{cfg: cfg, last: make(series), certs: make(map[string]databaseCert)}, nil
+}
+
+// ParseSelector parses a comma-separated list of key=value Teleport labels,
+// e.g. "env=prod,team=data".
+func ParseSelector(s string) (map[string]string, error) {
+	selector := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label selector %q, must be key=value", pair)
+		}
+		selector[key] = value
+	}
+	return selector, nil
+}
+
+// Run probes the databases right away and then every interval until ctx is
+// cancelled.
+func (p *DatabaseProber) Run(ctx context.Context) {
+	run(ctx, p.cfg.Interval, p.cfg.Log, p.Probe)
+}
+
+// Probe probes every selected database once and updates the metrics, deleting
+// the series of databases that are gone. If the databases or the proxy
+// address cannot be looked up, the metrics of the previous round are kept. It
+// must not be called concurrently.
+func (p *DatabaseProber) Probe(ctx context.Context) error {
+	clusterName, err := p.cfg.Source.GetClusterName(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the cluster name: %w", err)
+	}
+	databases, err := p.cfg.Source.GetDatabases(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list the databases: %w", err)
+	}
+	proxyAddr, err := p.cfg.Access.ProxyPublicAddr(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the proxy address: %w", err)
+	}
+
+	targets := make(map[string]teleport.DatabaseInfo)
+	for _, db := range databases {
+		if _, ok := databaseProtocols[db.Protocol]; ok && p.selected(db) {
+			targets[db.Name] = db
+		}
+	}
+	results := probeAll(targets, func(db teleport.DatabaseInfo) result {
+		return p.probe(ctx, proxyAddr, db)
+	})
+	p.forgetCerts(targets)
+
+	m := resultMetrics{success: metrics.DatabaseProbeSuccess, duration: metrics.DatabaseProbeDuration}
+	p.last = m.apply(clusterName, results, p.last)
+	p.cfg.Log.V(1).Info("probed databases", "count", len(results))
+	return nil
+}
+
+// selected reports whether db has all labels of the selector.
+func (p *DatabaseProber) selected(db teleport.DatabaseInfo) bool {
+	for key, value := range p.cfg.Selector {
+		if db.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// probe connects to db through the proxy at proxyAddr and logs in. The
+// duration spans the TLS handshake with the proxy and the login, but not
+// issuing the certificate.
+func (p *DatabaseProber) probe(ctx context.Context, proxyAddr string, db teleport.DatabaseInfo) result {
+	cert, err := p.certFor(ctx, db)
+	if err != nil {
+		p.cfg.Log.V(1).Info("failed to issue the database certificate", "database", db.Name, "error", err.Error())
+		return result{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	err = p.connect(ctx, proxyAddr, databaseProtocols[db.Protocol], cert)
+	duration := time.Since(start)
+	if err != nil {
+		p.cfg.Log.V(1).Info("database probe failed", "database", db.Name, "error", err.Error())
+		return result{duration: duration}
+	}
+	return result{success: true, duration: duration}
+}
+
+// connect dials the proxy at proxyAddr with cert and the ALPN protocol
+// protocol, and logs in to the database the certificate routes to.
+func (p *DatabaseProber) connect(ctx context.Context, proxyAddr, protocol string, cert tls.Certificate) error {
+	config := &tls.Config{}
+	if p.cfg.TLSConfig != nil {
+		config = p.cfg.TLSConfig.Clone()
+	}
+	config.Certificates = []tls.Certificate{cert}
+	config.NextProtos = []string{protocol}
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(proxyAddr)
+		if err != nil {
+			return fmt.Errorf("invalid proxy address %q: %w", proxyAddr, err)
+		}
+		config.ServerName = host
+	}
+
+	dialer := tls.Dialer{Config: config}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if negotiated := conn.(*tls.Conn).ConnectionState().NegotiatedProtocol; negotiated != protocol {
+		return fmt.Errorf("the proxy does not route %s connections, negotiated %q", protocol, negotiated)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	return postgresLogin(conn, p.cfg.User, p.cfg.Database)
+}
+
+// certFor returns the certificate to probe db with, renewed once half of its
+// validity passed.
+func (p *DatabaseProber) certFor(ctx context.Context, db teleport.DatabaseInfo) (tls.Certificate, error) {
+	p.mu.Lock()
+	cached, found := p.certs[db.Name]
+	p.mu.Unlock()
+	if found && time.Now().Before(cached.renewAt) {
+		return cached.cert, nil
+	}
+
+	cert, err := p.cfg.Access.DatabaseCert(ctx, db, p.cfg.User, p.cfg.Database)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	renewAt := time.Now()
+	if cert.Leaf != nil {
+		renewAt = renewAt.Add(time.Until(cert.Leaf.NotAfter) / 2)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.certs[db.Name] = databaseCert{cert: cert, renewAt: renewAt}
+	return cert, nil
+}
+
+// forgetCerts drops the certificates of the databases that are not probed
+// anymore.
+func (p *DatabaseProber) forgetCerts(targets map[string]teleport.DatabaseInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for name := range p.certs {
+		if _, ok := targets[name]; !ok {
+			delete(p.certs, name)
+		}
+	}
+}
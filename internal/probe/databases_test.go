@@ -0,0 +1,329 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probe
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+// fakeDatabaseSource serves a fixed list of databases.
+type fakeDatabaseSource struct {
+	databases []teleport.DatabaseInfo
+}
+
+func (f *fakeDatabaseSource) GetClusterName(context.Context) (string, error) {
+	return "test-cluster", nil
+}
+
+func (f *fakeDatabaseSource) GetDatabases(context.Context) ([]teleport.DatabaseInfo, error) {
+	return f.databases, nil
+}
+
+func TestParseSelector(t *testing.T) {
+	selector, err := ParseSelector("env=prod, team=data")
+	if err != nil {
+		t.Fatalf("ParseSelector() failed: %v", err)
+	}
+	if len(selector) != 2 || selector["env"] != "prod" || selector["team"] != "data" {
+		t.Errorf("unexpected selector %v", selector)
+	}
+	if selector, err := ParseSelector(""); err != nil || len(selector) != 0 {
+		t.Errorf("expected an empty selector, got %v, %v", selector, err)
+	}
+	for _, s := range []string{"env", "=prod"} {
+		if _, err := ParseSelector(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+}
+
+// newTestCert returns a certificate for commonName, self-signed, valid for
+// 127.0.0.1 and for an hour.
+func newTestCert(t *testing.T, commonName string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// fakeDatabaseAccess issues certificates with the database name as common
+// name.
+type fakeDatabaseAccess struct {
+	t         *testing.T
+	proxyAddr string
+	// failing are the databases no certificate is issued for
+	failing map[string]bool
+	issued  atomic.Int32
+}
+
+func (f *fakeDatabaseAccess) ProxyPublicAddr(context.Context) (string, error) {
+	return f.proxyAddr, nil
+}
+
+func (f *fakeDatabaseAccess) DatabaseCert(_ context.Context, db teleport.DatabaseInfo, user, database string) (tls.Certificate, error) {
+	if f.failing[db.Name] {
+		return tls.Certificate{}, errors.New("access denied")
+	}
+	if user != "probe" || database != "postgres" {
+		f.t.Errorf("unexpected user %q and database %q", user, database)
+	}
+	f.issued.Add(1)
+	return newTestCert(f.t, db.Name), nil
+}
+
+// Behaviors of the fake database agents.
+const (
+	agentUp       = "up"
+	agentRefusing = "refusing"
+	agentPassword = "password"
+	// Without an agent, the proxy closes the connection
+	agentDown = "down"
+)
+
+// fakeDatabaseProxy routes the connections with a database certificate to
+// fake agents, which speak just enough of the PostgreSQL wire protocol.
+type fakeDatabaseProxy struct {
+	listener net.Listener
+	roots    *x509.CertPool
+
+	mu     sync.Mutex
+	agents map[string]string
+}
+
+func newFakeDatabaseProxy(t *testing.T, agents map[string]string) *fakeDatabaseProxy {
+	t.Helper()
+	cert := newTestCert(t, "proxy")
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.Leaf)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAnyClientCert,
+		NextProtos:   []string{"teleport-postgres", "teleport-cockroachdb"},
+	})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	proxy := &fakeDatabaseProxy{listener: listener, roots: roots, agents: agents}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go proxy.serve(conn.(*tls.Conn))
+		}
+	}()
+	return proxy
+}
+
+func (f *fakeDatabaseProxy) setAgent(name, behavior string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.agents[name] = behavior
+}
+
+func (f *fakeDatabaseProxy) serve(conn *tls.Conn) {
+	defer conn.Close()
+	if err := conn.Handshake(); err != nil {
+		return
+	}
+	f.mu.Lock()
+	behavior := f.agents[conn.ConnectionState().PeerCertificates[0].Subject.CommonName]
+	f.mu.Unlock()
+
+	r := bufio.NewReader(conn)
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return
+	}
+	startup := make([]byte, size-4)
+	if _, err := io.ReadFull(r, startup); err != nil || !strings.Contains(string(startup), "user\x00probe\x00") {
+		return
+	}
+	switch behavior {
+	case agentUp:
+		writePostgresMessage(conn, 'R', []byte{0, 0, 0, 0})
+		writePostgresMessage(conn, 'S', []byte("server_version\x0016\x00"))
+		writePostgresMessage(conn, 'Z', []byte{'I'})
+		// Wait for the Terminate message
+		_, _ = r.ReadByte()
+	case agentRefusing:
+		writePostgresMessage(conn, 'E', []byte("SFATAL\x00C28000\x00Mcertificate has expired\x00\x00"))
+	case agentPassword:
+		writePostgresMessage(conn, 'R', []byte{0, 0, 0, 3})
+	}
+}
+
+func writePostgresMessage(w io.Writer, kind byte, body []byte) {
+	msg := append([]byte{kind}, binary.BigEndian.AppendUint32(nil, uint32(4+len(body)))...)
+	_, _ = w.Write(append(msg, body...))
+}
+
+func TestNewDatabaseProber(t *testing.T) {
+	if _, err := NewDatabaseProber(DatabaseConfig{User: "probe", Database: "postgres"}); err == nil {
+		t.Error("expected an error without a connection to Teleport")
+	}
+	if _, err := NewDatabaseProber(DatabaseConfig{Access: &fakeDatabaseAccess{}, Database: "postgres"}); err == nil {
+		t.Error("expected an error without a database user")
+	}
+}
+
+func TestDatabaseProber_Probe(t *testing.T) {
+	metrics.DatabaseProbeSuccess.Reset()
+	metrics.DatabaseProbeDuration.Reset()
+
+	proxy := newFakeDatabaseProxy(t, map[string]string{
+		"orders":   agentUp,
+		"ledger":   agentUp,
+		"expired":  agentRefusing,
+		"password": agentPassword,
+		"orphaned": agentDown,
+		"denied":   agentUp,
+	})
+	access := &fakeDatabaseAccess{t: t, proxyAddr: proxy.listener.Addr().String(), failing: map[string]bool{"denied": true}}
+	prod := map[string]string{"env": "prod"}
+	source := &fakeDatabaseSource{databases: []teleport.DatabaseInfo{
+		{Name: "orders", Protocol: "postgres", Labels: prod},
+		{Name: "ledger", Protocol: "cockroachdb", Labels: prod},
+		{Name: "expired", Protocol: "postgres", Labels: prod},
+		{Name: "password", Protocol: "postgres", Labels: prod},
+		{Name: "orphaned", Protocol: "postgres", Labels: prod},
+		{Name: "denied", Protocol: "postgres", Labels: prod},
+		{Name: "staging", Protocol: "postgres", Labels: map[string]string{"env": "staging"}},
+		{Name: "sessions", Protocol: "mongodb", Labels: prod},
+	}}
+	p, err := NewDatabaseProber(DatabaseConfig{Source: source, Access: access, Interval: time.Minute, Timeout: time.Second,
+		Selector: prod, User: "probe", Database: "postgres", TLSConfig: &tls.Config{RootCAs: proxy.roots}, Log: logr.Discard()})
+	if err != nil {
+		t.Fatalf("NewDatabaseProber() failed: %v", err)
+	}
+	if err := p.Probe(context.Background()); err != nil {
+		t.Fatalf("Probe() failed: %v", err)
+	}
+
+	want := map[string]float64{"orders": 1, "ledger": 1, "expired": 0, "password": 0, "orphaned": 0, "denied": 0}
+	for db, want := range want {
+		if got := testutil.ToFloat64(metrics.DatabaseProbeSuccess.WithLabelValues("test-cluster", db)); got != want {
+			t.Errorf("expected success %v for %s, got %v", want, db, got)
+		}
+	}
+	if got := testutil.CollectAndCount(metrics.DatabaseProbeSuccess); got != len(want) {
+		t.Errorf("expected unselected databases and unsupported protocols not to be probed, got %d series", got)
+	}
+
+	// A broken agent fails the probe, with the certificate of the previous
+	// round
+	issued := access.issued.Load()
+	proxy.setAgent("orders", agentDown)
+	if err := p.Probe(context.Background()); err != nil {
+		t.Fatalf("Probe() failed: %v", err)
+	}
+	if got := testutil.ToFloat64(metrics.DatabaseProbeSuccess.WithLabelValues("test-cluster", "orders")); got != 0 {
+		t.Errorf("expected the probe of a database without agent to fail, got %v", got)
+	}
+	if got := access.issued.Load(); got != issued {
+		t.Errorf("expected the certificates to be reused, got %d issued instead of %d", got, issued)
+	}
+
+	// Databases that are gone are removed
+	source.databases = source.databases[:1]
+	if err := p.Probe(context.Background()); err != nil {
+		t.Fatalf("Probe() failed: %v", err)
+	}
+	if got := testutil.CollectAndCount(metrics.DatabaseProbeDuration); got != 1 {
+		t.Errorf("expected 1 series, got %d", got)
+	}
+	if len(p.certs) != 1 {
+		t.Errorf("expected the certificates of removed databases to be dropped, got %d", len(p.certs))
+	}
+}
+
+func TestDatabaseProber_ProbeWithoutRouting(t *testing.T) {
+	// A proxy that does not route database connections by ALPN
+	cert := newTestCert(t, "proxy")
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.Leaf)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+
+	p, err := NewDatabaseProber(DatabaseConfig{Access: &fakeDatabaseAccess{t: t}, Timeout: time.Second,
+		User: "probe", Database: "postgres", TLSConfig: &tls.Config{RootCAs: roots}, Log: logr.Discard()})
+	if err != nil {
+		t.Fatalf("NewDatabaseProber() failed: %v", err)
+	}
+	res := p.probe(context.Background(), listener.Addr().String(), teleport.DatabaseInfo{Name: "orders", Protocol: "postgres"})
+	if res.success {
+		t.Error("expected the probe to fail without ALPN routing")
+	}
+}
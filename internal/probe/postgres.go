@@ -0,0 +1,107 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probe
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+const (
+	// postgresProtocolVersion is version 3.0 of the PostgreSQL wire protocol.
+	postgresProtocolVersion = 3 << 16
+	// maxPostgresMessageSize limits the size of the messages read before
+	// the login completes, which are small.
+	maxPostgresMessageSize = 1 << 20
+)
+
+// postgresLogin sends a startup message for user and database over conn and
+// reads the answers until the server is ready for queries, then terminates
+// the session. The Teleport proxy authenticates the client by its
+// certificate, so the agent answers with AuthenticationOk once it logged in
+// to the database, or with an error.
+func postgresLogin(conn net.Conn, user, database string) error {
+	var params bytes.Buffer
+	for _, param := range []string{"user", user, "database", database, "application_name", "teleport-exporter"} {
+		params.WriteString(param)
+		params.WriteByte(0)
+	}
+	params.WriteByte(0)
+	startup := binary.BigEndian.AppendUint32(nil, uint32(8+params.Len()))
+	startup = binary.BigEndian.AppendUint32(startup, postgresProtocolVersion)
+	if _, err := conn.Write(append(startup, params.Bytes()...)); err != nil {
+		return fmt.Errorf("failed to send the startup message: %w", err)
+	}
+
+	r := bufio.NewReader(conn)
+	for {
+		kind, body, err := readPostgresMessage(r)
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case 'R':
+			if len(body) < 4 || binary.BigEndian.Uint32(body) != 0 {
+				return errors.New("the server requested a password")
+			}
+		case 'E':
+			return fmt.Errorf("the server refused the login: %s", postgresError(body))
+		case 'Z':
+			// Terminate the session
+			_, _ = conn.Write([]byte{'X', 0, 0, 0, 4})
+			return nil
+		}
+	}
+}
+
+// readPostgresMessage reads the type and body of a message.
+func readPostgresMessage(r *bufio.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, fmt.Errorf("failed to read the answer: %w", err)
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size < 4 || size > maxPostgresMessageSize {
+		return 0, nil, fmt.Errorf("invalid message size %d", size)
+	}
+	body := make([]byte, size-4)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, fmt.Errorf("failed to read the answer: %w", err)
+	}
+	return header[0], body, nil
+}
+
+// postgresError returns the message field of the body of an ErrorResponse.
+func postgresError(body []byte) string {
+	for len(body) > 1 {
+		field := body[0]
+		value, rest, ok := bytes.Cut(body[1:], []byte{0})
+		if !ok {
+			break
+		}
+		if field == 'M' {
+			return string(value)
+		}
+		body = rest
+	}
+	return "unknown error"
+}
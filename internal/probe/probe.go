@@ -0,0 +1,121 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package probe checks that the applications and databases registered in
+// Teleport answer.
+package probe
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxConcurrentProbes limits the number of probes of a prober in flight at
+// the same time.
+const maxConcurrentProbes = 10
+
+// result is the outcome of a single probe.
+type result struct {
+	success  bool
+	duration time.Duration
+	// statusCode is the HTTP status code of the answer, 0 without an answer
+	// or for probes that are not HTTP requests.
+	statusCode int
+}
+
+// resultMetrics are the metrics the results of a prober are exported as.
+type resultMetrics struct {
+	success  *prometheus.GaugeVec
+	duration *prometheus.GaugeVec
+	// statusCode is nil for probes that are not HTTP requests.
+	statusCode *prometheus.GaugeVec
+}
+
+// series holds the cluster and resource names of the series of a prober.
+type series map[[2]string]struct{}
+
+// run calls probe right away and then every interval until ctx is cancelled.
+func run(ctx context.Context, interval time.Duration, log logr.Logger, probe func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := probe(ctx); err != nil && ctx.Err() == nil {
+			log.Error(err, "failed to probe")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probeAll probes every target, at most maxConcurrentProbes at a time, and
+// returns the results by resource name.
+func probeAll[T any](targets map[string]T, probe func(target T) result) map[string]result {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]result, len(targets))
+	)
+	sem := make(chan struct{}, maxConcurrentProbes)
+	for name, target := range targets {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			res := probe(target)
+			mu.Lock()
+			defer mu.Unlock()
+			results[name] = res
+		})
+	}
+	wg.Wait()
+	return results
+}
+
+// apply sets the metrics of every result and deletes the series from last
+// that are gone. It returns the series to track for the next round.
+func (m resultMetrics) apply(clusterName string, results map[string]result, last series) series {
+	current := make(series, len(results))
+	for name, res := range results {
+		labels := [2]string{clusterName, name}
+		success := 0.0
+		if res.success {
+			success = 1
+		}
+		m.success.WithLabelValues(labels[:]...).Set(success)
+		m.duration.WithLabelValues(labels[:]...).Set(res.duration.Seconds())
+		if m.statusCode != nil {
+			m.statusCode.WithLabelValues(labels[:]...).Set(float64(res.statusCode))
+		}
+		current[labels] = struct{}{}
+	}
+	for labels := range last {
+		if _, exists := current[labels]; exists {
+			continue
+		}
+		m.success.DeleteLabelValues(labels[:]...)
+		m.duration.DeleteLabelValues(labels[:]...)
+		if m.statusCode != nil {
+			m.statusCode.DeleteLabelValues(labels[:]...)
+		}
+	}
+	return current
+}
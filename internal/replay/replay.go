@@ -0,0 +1,446 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package replay records the responses of the Teleport API calls of the
+// collector to a directory and serves them back, to reproduce metric
+// anomalies offline.
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/gravitational/trace"
+
+	"github.com/giantswarm/teleport-exporter/internal/collector"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+// Methods of collector.TeleportClient, as recorded in the records.
+const (
+	methodGetClusterName     = "GetClusterName"
+	methodGetNodes           = "GetNodes"
+	methodGetKubeClusters    = "GetKubeClusters"
+	methodGetDatabases       = "GetDatabases"
+	methodGetApps            = "GetApps"
+	methodGetUsers           = "GetUsers"
+	methodGetLocks           = "GetLocks"
+	methodGetRoles           = "GetRoles"
+	methodGetTokens          = "GetTokens"
+	methodGetAccessRequests  = "GetAccessRequests"
+	methodGetSessions        = "GetSessions"
+	methodGetRemoteClusters  = "GetRemoteClusters"
+	methodGetCertAuthorities = "GetCertAuthorities"
+	methodCheckAccess        = "CheckAccess"
+)
+
+// record is a recorded response, saved as one JSON file per call, named by
+// its sequence number and key so that the files sort in call order.
+type record struct {
+	Method string `json:"method"`
+	// Resource is the resource type of CheckAccess.
+	Resource string          `json:"resource,omitempty"`
+	Time     time.Time       `json:"time"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	// ErrorClass is the teleport.ErrorClass of Error, to replay an error the
+	// collector classifies the same way.
+	ErrorClass string `json:"errorClass,omitempty"`
+}
+
+// key identifies the calls whose responses are replayed in sequence.
+func (r record) key() string {
+	if r.Resource != "" {
+		return r.Method + "-" + r.Resource
+	}
+	return r.Method
+}
+
+// Recorder is a collector.TeleportClient that records the responses of the
+// wrapped client. Failing to write a record is logged and does not fail the
+// call.
+type Recorder struct {
+	client collector.TeleportClient
+	dir    string
+	log    logr.Logger
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewRecorder returns a Recorder writing to dir, which is created if needed.
+// Records already in dir are kept, and new records are numbered after them.
+func NewRecorder(client collector.TeleportClient, dir string, log logr.Logger) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	files, err := recordFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{client: client, dir: dir, log: log, seq: len(files)}, nil
+}
+
+// write saves the response of a call.
+func (r *Recorder) write(method, resource string, result any, err error) {
+	rec := record{Method: method, Resource: resource, Time: time.Now()}
+	if err != nil {
+		rec.Error = err.Error()
+		rec.ErrorClass = teleport.ErrorClass(err)
+	} else {
+		data, err := json.Marshal(result)
+		if err != nil {
+			r.log.Error(err, "failed to encode the response", "method", method)
+			return
+		}
+		rec.Result = data
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		r.log.Error(err, "failed to encode the record", "method", method)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	path := filepath.Join(r.dir, fmt.Sprintf("%08d-%s.json", r.seq, rec.key()))
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		r.log.Error(err, "failed to write the record", "path", path)
+	}
+}
+
+// GetClusterName records the cluster name.
+func (r *Recorder) GetClusterName(ctx context.Context) (string, error) {
+	name, err := r.client.GetClusterName(ctx)
+	r.write(methodGetClusterName, "", name, err)
+	return name, err
+}
+
+// GetNodes records the nodes.
+func (r *Recorder) GetNodes(ctx context.Context) ([]teleport.NodeInfo, error) {
+	nodes, err := r.client.GetNodes(ctx)
+	r.write(methodGetNodes, "", nodes, err)
+	return nodes, err
+}
+
+// ForEachNode records the nodes like GetNodes, so they are held in memory
+// until written.
+func (r *Recorder) ForEachNode(ctx context.Context, fn func(teleport.NodeInfo)) error {
+	nodes := make([]teleport.NodeInfo, 0)
+	err := r.client.ForEachNode(ctx, func(node teleport.NodeInfo) {
+		nodes = append(nodes, node)
+		fn(node)
+	})
+	r.write(methodGetNodes, "", nodes, err)
+	return err
+}
+
+// GetKubeClusters records the Kubernetes clusters.
+func (r *Recorder) GetKubeClusters(ctx context.Context) ([]teleport.KubeClusterInfo, error) {
+	clusters, err := r.client.GetKubeClusters(ctx)
+	r.write(methodGetKubeClusters, "", clusters, err)
+	return clusters, err
+}
+
+// GetDatabases records the databases.
+func (r *Recorder) GetDatabases(ctx context.Context) ([]teleport.DatabaseInfo, error) {
+	databases, err := r.client.GetDatabases(ctx)
+	r.write(methodGetDatabases, "", databases, err)
+	return databases, err
+}
+
+// GetApps records the applications.
+func (r *Recorder) GetApps(ctx context.Context) ([]teleport.AppInfo, error) {
+	apps, err := r.client.GetApps(ctx)
+	r.write(methodGetApps, "", apps, err)
+	return apps, err
+}
+
+// GetUsers records the users.
+func (r *Recorder) GetUsers(ctx context.Context) ([]teleport.UserInfo, error) {
+	users, err := r.client.GetUsers(ctx)
+	r.write(methodGetUsers, "", users, err)
+	return users, err
+}
+
+// GetLocks records the locks.
+func (r *Recorder) GetLocks(ctx context.Context) ([]teleport.LockInfo, error) {
+	locks, err := r.client.GetLocks(ctx)
+	r.write(methodGetLocks, "", locks, err)
+	return locks, err
+}
+
+// GetRoles records the roles.
+func (r *Recorder) GetRoles(ctx context.Context) ([]teleport.RoleInfo, error) {
+	roles, err := r.client.GetRoles(ctx)
+	r.write(methodGetRoles, "", roles, err)
+	return roles, err
+}
+
+// GetTokens records the tokens.
+func (r *Recorder) GetTokens(ctx context.Context) ([]teleport.TokenInfo, error) {
+	tokens, err := r.client.GetTokens(ctx)
+	r.write(methodGetTokens, "", tokens, err)
+	return tokens, err
+}
+
+// GetAccessRequests records the access requests.
+func (r *Recorder) GetAccessRequests(ctx context.Context) ([]teleport.AccessRequestInfo, error) {
+	accessRequests, err := r.client.GetAccessRequests(ctx)
+	r.write(methodGetAccessRequests, "", accessRequests, err)
+	return accessRequests, err
+}
+
+// GetSessions records the sessions.
+func (r *Recorder) GetSessions(ctx context.Context) ([]teleport.SessionInfo, error) {
+	sessions, err := r.client.GetSessions(ctx)
+	r.write(methodGetSessions, "", sessions, err)
+	return sessions, err
+}
+
+// GetRemoteClusters records the remote clusters.
+func (r *Recorder) GetRemoteClusters(ctx context.Context) ([]teleport.RemoteClusterInfo, error) {
+	remoteClusters, err := r.client.GetRemoteClusters(ctx)
+	r.write(methodGetRemoteClusters, "", remoteClusters, err)
+	return remoteClusters, err
+}
+
+// GetCertAuthorities records the cert authorities.
+func (r *Recorder) GetCertAuthorities(ctx context.Context) ([]teleport.CertAuthorityInfo, error) {
+	certAuthorities, err := r.client.GetCertAuthorities(ctx)
+	r.write(methodGetCertAuthorities, "", certAuthorities, err)
+	return certAuthorities, err
+}
+
+// CheckAccess records the result of the access check of the resource type.
+func (r *Recorder) CheckAccess(ctx context.Context, resource string) error {
+	err := r.client.CheckAccess(ctx, resource)
+	r.write(methodCheckAccess, resource, nil, err)
+	return err
+}
+
+// Reconnect reconnects the wrapped client and is not recorded.
+func (r *Recorder) Reconnect(ctx context.Context) error {
+	return r.client.Reconnect(ctx)
+}
+
+// Player is a collector.TeleportClient that serves recorded responses. The
+// responses of each call are served in the order they were recorded, and the
+// last one is served again once all were served, so that replaying continues
+// with the final state.
+type Player struct {
+	mu      sync.Mutex
+	records map[string][]record
+}
+
+// Load returns a Player serving the records in dir.
+func Load(dir string) (*Player, error) {
+	files, err := recordFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no records in %s", dir)
+	}
+
+	p := &Player{records: make(map[string][]record)}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var rec record
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("failed to parse record %s: %w", file, err)
+		}
+		p.records[rec.key()] = append(p.records[rec.key()], rec)
+	}
+	return p, nil
+}
+
+// next returns the next record of key.
+func (p *Player) next(key string) (record, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	recs := p.records[key]
+	if len(recs) == 0 {
+		return record{}, false
+	}
+	if len(recs) > 1 {
+		p.records[key] = recs[1:]
+	}
+	return recs[0], true
+}
+
+// replay serves the next recorded response of key.
+func replay[T any](ctx context.Context, p *Player, key string) (T, error) {
+	var result T
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	rec, ok := p.next(key)
+	if !ok {
+		return result, fmt.Errorf("no recorded %s response", key)
+	}
+	if rec.Error != "" {
+		return result, replayError(rec)
+	}
+	if err := json.Unmarshal(rec.Result, &result); err != nil {
+		return result, fmt.Errorf("failed to parse recorded %s response: %w", key, err)
+	}
+	return result, nil
+}
+
+// GetClusterName serves the next recorded cluster name.
+func (p *Player) GetClusterName(ctx context.Context) (string, error) {
+	return replay[string](ctx, p, methodGetClusterName)
+}
+
+// GetNodes serves the next recorded nodes.
+func (p *Player) GetNodes(ctx context.Context) ([]teleport.NodeInfo, error) {
+	return replay[[]teleport.NodeInfo](ctx, p, methodGetNodes)
+}
+
+// ForEachNode calls fn for each of the next recorded nodes.
+func (p *Player) ForEachNode(ctx context.Context, fn func(teleport.NodeInfo)) error {
+	nodes, err := p.GetNodes(ctx)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		fn(node)
+	}
+	return nil
+}
+
+// GetKubeClusters serves the next recorded Kubernetes clusters.
+func (p *Player) GetKubeClusters(ctx context.Context) ([]teleport.KubeClusterInfo, error) {
+	return replay[[]teleport.KubeClusterInfo](ctx, p, methodGetKubeClusters)
+}
+
+// GetDatabases serves the next recorded databases.
+func (p *Player) GetDatabases(ctx context.Context) ([]teleport.DatabaseInfo, error) {
+	return replay[[]teleport.DatabaseInfo](ctx, p, methodGetDatabases)
+}
+
+// GetApps serves the next recorded applications.
+func (p *Player) GetApps(ctx context.Context) ([]teleport.AppInfo, error) {
+	return replay[[]teleport.AppInfo](ctx, p, methodGetApps)
+}
+
+// GetUsers serves the next recorded users.
+func (p *Player) GetUsers(ctx context.Context) ([]teleport.UserInfo, error) {
+	return replay[[]teleport.UserInfo](ctx, p, methodGetUsers)
+}
+
+// GetLocks serves the next recorded locks.
+func (p *Player) GetLocks(ctx context.Context) ([]teleport.LockInfo, error) {
+	return replay[[]teleport.LockInfo](ctx, p, methodGetLocks)
+}
+
+// GetRoles serves the next recorded roles.
+func (p *Player) GetRoles(ctx context.Context) ([]teleport.RoleInfo, error) {
+	return replay[[]teleport.RoleInfo](ctx, p, methodGetRoles)
+}
+
+// GetTokens serves the next recorded tokens.
+func (p *Player) GetTokens(ctx context.Context) ([]teleport.TokenInfo, error) {
+	return replay[[]teleport.TokenInfo](ctx, p, methodGetTokens)
+}
+
+// GetAccessRequests serves the next recorded access requests.
+func (p *Player) GetAccessRequests(ctx context.Context) ([]teleport.AccessRequestInfo, error) {
+	return replay[[]teleport.AccessRequestInfo](ctx, p, methodGetAccessRequests)
+}
+
+// GetSessions serves the next recorded sessions.
+func (p *Player) GetSessions(ctx context.Context) ([]teleport.SessionInfo, error) {
+	return replay[[]teleport.SessionInfo](ctx, p, methodGetSessions)
+}
+
+// GetRemoteClusters serves the next recorded remote clusters.
+func (p *Player) GetRemoteClusters(ctx context.Context) ([]teleport.RemoteClusterInfo, error) {
+	return replay[[]teleport.RemoteClusterInfo](ctx, p, methodGetRemoteClusters)
+}
+
+// GetCertAuthorities serves the next recorded cert authorities.
+func (p *Player) GetCertAuthorities(ctx context.Context) ([]teleport.CertAuthorityInfo, error) {
+	return replay[[]teleport.CertAuthorityInfo](ctx, p, methodGetCertAuthorities)
+}
+
+// CheckAccess serves the next recorded access check of the resource type.
+// Access is granted if it was not recorded.
+func (p *Player) CheckAccess(ctx context.Context, resource string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	rec, ok := p.next(record{Method: methodCheckAccess, Resource: resource}.key())
+	if !ok || rec.Error == "" {
+		return nil
+	}
+	return replayError(rec)
+}
+
+// Reconnect does nothing.
+func (p *Player) Reconnect(ctx context.Context) error {
+	return nil
+}
+
+// replayError returns an error with the recorded message, which the
+// collector classifies into the recorded error class.
+func replayError(rec record) error {
+	switch rec.ErrorClass {
+	case teleport.ErrorClassTimeout:
+		return fmt.Errorf("%s: %w", rec.Error, context.DeadlineExceeded)
+	case teleport.ErrorClassPermissionDenied:
+		return trace.AccessDenied("%s", rec.Error)
+	case teleport.ErrorClassRateLimited:
+		return trace.LimitExceeded("%s", rec.Error)
+	case teleport.ErrorClassNotFound:
+		return trace.NotFound("%s", rec.Error)
+	case teleport.ErrorClassConnection:
+		return trace.ConnectionProblem(nil, "%s", rec.Error)
+	default:
+		// The other classes are told apart by the message
+		return errors.New(rec.Error)
+	}
+}
+
+// recordFiles returns the record files in dir in call order.
+func recordFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	slices.Sort(files)
+	return files, nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replay
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/gravitational/trace"
+
+	"github.com/giantswarm/teleport-exporter/internal/collector"
+	"github.com/giantswarm/teleport-exporter/internal/fakes"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+var (
+	_ collector.TeleportClient = (*Recorder)(nil)
+	_ collector.TeleportClient = (*Player)(nil)
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	fake := &fakes.Client{
+		ClusterName: "example.com",
+		Nodes:       []teleport.NodeInfo{{Name: "n1", Hostname: "node-1", Labels: map[string]string{"env": "prod"}}},
+	}
+	rec, err := NewRecorder(fake, dir, logr.Discard())
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+
+	// Record a successful collection, then one where nodes are denied
+	rec.GetClusterName(ctx)
+	rec.GetNodes(ctx)
+	rec.CheckAccess(ctx, teleport.CacheNodes)
+	fake.Errors = map[string]error{fakes.MethodGetNodes: trace.AccessDenied("access denied to node")}
+	rec.GetNodes(ctx)
+
+	p, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if name, err := p.GetClusterName(ctx); err != nil || name != "example.com" {
+		t.Errorf("GetClusterName() = %q, %v, want example.com", name, err)
+	}
+	nodes, err := p.GetNodes(ctx)
+	if err != nil || len(nodes) != 1 || nodes[0].Labels["env"] != "prod" {
+		t.Errorf("GetNodes() = %+v, %v, want the recorded node", nodes, err)
+	}
+	// The last response is served again once all were served
+	for range 2 {
+		_, err = p.GetNodes(ctx)
+		if teleport.ErrorReason(err) != teleport.ErrorReasonPermissionDenied {
+			t.Errorf("GetNodes() error = %v, want a permission denied error", err)
+		}
+	}
+	if err := p.CheckAccess(ctx, teleport.CacheApps); err != nil {
+		t.Errorf("CheckAccess() of an unrecorded resource type = %v, want nil", err)
+	}
+	if _, err := p.GetApps(ctx); err == nil {
+		t.Error("GetApps() without records should fail")
+	}
+
+	// Recording again continues the sequence
+	rec, err = NewRecorder(fake, dir, logr.Discard())
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	if rec.seq != 4 {
+		t.Errorf("expected the sequence to continue at 4, got %d", rec.seq)
+	}
+}
+
+func TestLoad_Empty(t *testing.T) {
+	if _, err := Load(t.TempDir()); err == nil {
+		t.Error("Load() of an empty directory should fail")
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package statsd mirrors the exporter gauges and counters to a DogStatsD agent.
+package statsd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// maxPacketSize keeps datagrams below the usual Ethernet MTU.
+const maxPacketSize = 1432
+
+// tagReplacer replaces the characters that have a meaning in the DogStatsD
+// datagram format.
+var tagReplacer = strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_")
+
+// Config holds the configuration of the StatsD emitter.
+type Config struct {
+	// Address is the host:port of the DogStatsD agent.
+	Address string
+	// Interval is the time between two emissions.
+	Interval time.Duration
+	// Prefix restricts the emitted metrics to the names starting with it.
+	Prefix   string
+	Gatherer prometheus.Gatherer
+	Log      logr.Logger
+}
+
+// Emitter periodically sends gauges as StatsD gauges and counters as StatsD
+// counters of the increase since the previous emission.
+type Emitter struct {
+	cfg  Config
+	conn net.Conn
+	// last holds the counter values of the previous emission, keyed by
+	// metric name and tags.
+	last map[string]float64
+}
+
+// New creates an Emitter sending to cfg.Address.
+func New(cfg Config) (*Emitter, error) {
+	conn, err := net.Dial("udp", cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to StatsD agent: %w", err)
+	}
+	return &Emitter{cfg: cfg, conn: conn, last: make(map[string]float64)}, nil
+}
+
+// Run emits the metrics every interval until ctx is cancelled, and once more
+// before returning, so that the agent receives the terminal state on shutdown.
+func (e *Emitter) Run(ctx context.Context) {
+	defer e.conn.Close()
+
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := e.Emit(); err != nil {
+				e.cfg.Log.Error(err, "failed to emit the final StatsD metrics")
+			}
+			return
+		case <-ticker.C:
+			if err := e.Emit(); err != nil {
+				e.cfg.Log.Error(err, "failed to emit StatsD metrics")
+			}
+		}
+	}
+}
+
+// Emit gathers the metrics and sends them to the agent.
+func (e *Emitter) Emit() error {
+	families, err := e.cfg.Gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+
+	var packet bytes.Buffer
+	for _, line := range e.lines(families) {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxPacketSize {
+			if _, err := e.conn.Write(packet.Bytes()); err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	if packet.Len() > 0 {
+		if _, err := e.conn.Write(packet.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lines converts the gauges and counters of families to DogStatsD lines.
+// Counters are sent as the increase since the previous call; the first call
+// only records their values.
+func (e *Emitter) lines(families []*dto.MetricFamily) []string {
+	var lines []string
+	for _, mf := range families {
+		name := mf.GetName()
+		if !strings.HasPrefix(name, e.cfg.Prefix) {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			tags := formatTags(m.GetLabel())
+			switch mf.GetType() {
+			case dto.MetricType_GAUGE:
+				lines = append(lines, formatLine(name, m.GetGauge().GetValue(), "g", tags))
+			case dto.MetricType_COUNTER:
+				key := name + tags
+				value := m.GetCounter().GetValue()
+				last, seen := e.last[key]
+				e.last[key] = value
+				// A counter lower than before has been reset
+				if !seen || value < last {
+					continue
+				}
+				if delta := value - last; delta > 0 {
+					lines = append(lines, formatLine(name, delta, "c", tags))
+				}
+			}
+		}
+	}
+	return lines
+}
+
+func formatLine(name string, value float64, kind, tags string) string {
+	line := name + ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|" + kind
+	if tags != "" {
+		line += "|#" + tags
+	}
+	return line
+}
+
+func formatTags(labels []*dto.LabelPair) string {
+	tags := make([]string, 0, len(labels))
+	for _, l := range labels {
+		tags = append(tags, l.GetName()+":"+tagReplacer.Replace(l.GetValue()))
+	}
+	sort.Strings(tags)
+	return strings.Join(tags, ",")
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statsd
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestEmitter_Emit(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	reg := prometheus.NewRegistry()
+	up := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_up"})
+	errs := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_errors_total"}, []string{"resource", "reason"})
+	other := prometheus.NewGauge(prometheus.GaugeOpts{Name: "other_gauge"})
+	hist := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_duration_seconds"})
+	reg.MustRegister(up, errs, other, hist)
+
+	e, err := New(Config{
+		Address:  listener.LocalAddr().String(),
+		Interval: time.Minute,
+		Prefix:   "test_",
+		Gatherer: reg,
+		Log:      logr.Discard(),
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer e.conn.Close()
+
+	read := func() []string {
+		t.Helper()
+		buf := make([]byte, maxPacketSize)
+		listener.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := listener.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("failed to read packet: %v", err)
+		}
+		return strings.Split(string(buf[:n]), "\n")
+	}
+
+	up.Set(1)
+	errs.WithLabelValues("nodes", "a,b").Add(2)
+	other.Set(5)
+	hist.Observe(1)
+
+	// The first emission only records the counter values
+	if err := e.Emit(); err != nil {
+		t.Fatalf("Emit() error = %v", err)
+	}
+	if got, want := read(), []string{"test_up:1|g"}; strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("first packet = %q, want %q", got, want)
+	}
+
+	errs.WithLabelValues("nodes", "a,b").Add(3)
+	if err := e.Emit(); err != nil {
+		t.Fatalf("Emit() error = %v", err)
+	}
+	want := []string{"test_errors_total:3|c|#reason:a_b,resource:nodes", "test_up:1|g"}
+	if got := read(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("second packet = %q, want %q", got, want)
+	}
+}
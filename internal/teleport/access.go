@@ -0,0 +1,111 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gravitational/teleport/api/client"
+	"github.com/gravitational/teleport/api/client/proto"
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/trace"
+)
+
+// AccessClusterName is the resource type of CheckAccess for the cluster name.
+// The other resource types are the ones that can be cached, e.g. CacheNodes.
+const AccessClusterName = "cluster"
+
+// accessKinds maps the resource types of CheckAccess to the Teleport kind
+// listed to collect them.
+var accessKinds = map[string]string{
+	CacheNodes:        types.KindNode,
+	CacheKubeClusters: types.KindKubeServer,
+	CacheDatabases:    types.KindDatabaseServer,
+	CacheApps:         types.KindAppServer,
+}
+
+// accessLists lists a single resource of the resource types of CheckAccess
+// that are not listed with ListResources.
+var accessLists = map[string]func(context.Context, *client.Client) error{
+	CacheUsers:           func(ctx context.Context, clt *client.Client) error { return checkUsers(ctx, clt) },
+	CacheLocks:           func(ctx context.Context, clt *client.Client) error { return checkLocks(ctx, clt) },
+	CacheRoles:           func(ctx context.Context, clt *client.Client) error { return checkRoles(ctx, clt) },
+	CacheTokens:          func(ctx context.Context, clt *client.Client) error { return checkTokens(ctx, clt) },
+	CacheAccessRequests:  func(ctx context.Context, clt *client.Client) error { return checkAccessRequests(ctx, clt) },
+	CacheSessions:        func(ctx context.Context, clt *client.Client) error { return checkSessions(ctx, clt) },
+	CacheRemoteClusters:  func(ctx context.Context, clt *client.Client) error { return checkRemoteClusters(ctx, clt) },
+	CacheCertAuthorities: func(ctx context.Context, clt *client.Client) error { return checkCertAuthorities(ctx, clt) },
+}
+
+// CheckAccess checks whether the identity may read the given resource type by
+// fetching a single resource of it, bypassing the cache. It returns nil if
+// the read succeeded, and the error otherwise; ErrorReason classifies a
+// missing permission as ErrorReasonPermissionDenied. Note that Teleport
+// silently omits resources whose labels the role does not match, so a
+// successful check does not imply that all resources are visible.
+func (c *Client) CheckAccess(ctx context.Context, resource string) error {
+	if resource == AccessClusterName {
+		_, err := c.GetClusterName(ctx)
+		return err
+	}
+	list, ok := accessLists[resource]
+	if !ok {
+		kind, ok := accessKinds[resource]
+		if !ok {
+			return fmt.Errorf("unknown resource type %q", resource)
+		}
+		list = func(ctx context.Context, clt *client.Client) error {
+			return checkList(ctx, clt, kind, c.namespaces[0])
+		}
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if err := c.injectFault(ctx, "CheckAccess"); err != nil {
+		return err
+	}
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	clt, err := c.api()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = list(ctx, clt)
+	observe("CheckAccess", start, err)
+	return err
+}
+
+// checkList lists a single resource of the given kind in the namespace, which
+// fails with an access denied error if the identity may not list the kind.
+func checkList(ctx context.Context, clt client.GetResourcesClient, kind, namespace string) error {
+	_, err := clt.GetResources(ctx, &proto.ListResourcesRequest{
+		ResourceType: kind,
+		Namespace:    namespace,
+		Limit:        1,
+	})
+	return trace.Wrap(err)
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gravitational/teleport/api/client/proto"
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/trace"
+)
+
+// deniedResourcesClient denies listing every resource kind.
+type deniedResourcesClient struct{}
+
+func (deniedResourcesClient) GetResources(context.Context, *proto.ListResourcesRequest) (*proto.ListResourcesResponse, error) {
+	return nil, trace.AccessDenied("access denied to perform action \"list\" on \"node\"")
+}
+
+func TestCheckList(t *testing.T) {
+	clt := &fakeResourcesClient{}
+	if err := checkList(context.Background(), clt, types.KindNode, "default"); err != nil {
+		t.Fatalf("checkList() failed: %v", err)
+	}
+	if len(clt.limits) != 1 || clt.limits[0] != 1 {
+		t.Errorf("expected a single request for 1 resource, got limits %v", clt.limits)
+	}
+
+	err := checkList(context.Background(), deniedResourcesClient{}, types.KindNode, "default")
+	if reason := ErrorReason(err); reason != ErrorReasonPermissionDenied {
+		t.Errorf("expected reason %s, got %s (%v)", ErrorReasonPermissionDenied, reason, err)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gravitational/teleport/api/client/proto"
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/trace"
+)
+
+// States of AccessRequestInfo.State, the lower case names of the Teleport
+// request states.
+const (
+	AccessRequestPending  = "pending"
+	AccessRequestApproved = "approved"
+	AccessRequestDenied   = "denied"
+	AccessRequestPromoted = "promoted"
+)
+
+// AccessRequestInfo represents information about a Teleport access request.
+type AccessRequestInfo struct {
+	Name string `json:"name"`
+	User string `json:"user"`
+	// Roles are the requested roles.
+	Roles []string `json:"roles,omitempty"`
+	// State is the review state, e.g. AccessRequestPending.
+	State   string    `json:"state"`
+	Created time.Time `json:"created"`
+}
+
+// accessRequestsClient is the part of the Teleport API client that lists
+// access requests.
+type accessRequestsClient interface {
+	ListAccessRequests(ctx context.Context, req *proto.ListAccessRequestsRequest) (*proto.ListAccessRequestsResponse, error)
+}
+
+// GetAccessRequests returns all access requests of Teleport, in any state.
+// The result is served from the cache if one is configured for the resource
+// type.
+func (c *Client) GetAccessRequests(ctx context.Context) ([]AccessRequestInfo, error) {
+	return c.accessRequestsCache.get(ctx, c.fetchAccessRequests)
+}
+
+// fetchAccessRequests fetches the access requests from the Teleport API.
+func (c *Client) fetchAccessRequests(ctx context.Context) ([]AccessRequestInfo, error) {
+	c.log.V(1).Info("fetching access requests from Teleport")
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if err := c.injectFault(ctx, "GetAccessRequests"); err != nil {
+		return nil, err
+	}
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	result := make([]AccessRequestInfo, 0)
+	clt, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	err = forEachAccessRequest(ctx, clt, c.pageSize, func(req types.AccessRequest) {
+		result = append(result, accessRequestInfo(req))
+	})
+	observe("ListAccessRequests", start, err)
+	if err != nil {
+		c.log.Error(err, "failed to get access requests")
+		return nil, err
+	}
+
+	c.log.V(1).Info("fetched access requests", "count", len(result))
+	return result, nil
+}
+
+// forEachAccessRequest calls fn for every access request, fetching pageSize
+// requests per request (Teleport default if zero).
+func forEachAccessRequest(ctx context.Context, clt accessRequestsClient, pageSize int, fn func(types.AccessRequest)) error {
+	req := &proto.ListAccessRequestsRequest{Limit: int32(pageSize)}
+	for {
+		resp, err := clt.ListAccessRequests(ctx, req)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, accessRequest := range resp.AccessRequests {
+			fn(accessRequest)
+		}
+		if resp.NextKey == "" || len(resp.AccessRequests) == 0 {
+			return nil
+		}
+		req.StartKey = resp.NextKey
+	}
+}
+
+// accessRequestInfo converts a Teleport access request into an
+// AccessRequestInfo.
+func accessRequestInfo(req types.AccessRequest) AccessRequestInfo {
+	return AccessRequestInfo{
+		Name:    req.GetName(),
+		User:    req.GetUser(),
+		Roles:   req.GetRoles(),
+		State:   strings.ToLower(req.GetState().String()),
+		Created: req.GetCreationTime(),
+	}
+}
+
+// checkAccessRequests lists a single access request, which fails with an
+// access denied error if the identity may not list access requests.
+func checkAccessRequests(ctx context.Context, clt accessRequestsClient) error {
+	_, err := clt.ListAccessRequests(ctx, &proto.ListAccessRequestsRequest{Limit: 1})
+	return trace.Wrap(err)
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gravitational/teleport/api/client/proto"
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/trace"
+)
+
+// fakeAccessRequestsClient serves access requests page by page and records
+// the requested limits.
+type fakeAccessRequestsClient struct {
+	requests []*types.AccessRequestV3
+	limits   []int32
+	err      error
+}
+
+func (f *fakeAccessRequestsClient) ListAccessRequests(_ context.Context, req *proto.ListAccessRequestsRequest) (*proto.ListAccessRequestsResponse, error) {
+	f.limits = append(f.limits, req.Limit)
+	if f.err != nil {
+		return nil, f.err
+	}
+	start := 0
+	if req.StartKey != "" {
+		var err error
+		if start, err = strconv.Atoi(req.StartKey); err != nil {
+			return nil, err
+		}
+	}
+	end := min(start+int(req.Limit), len(f.requests))
+
+	resp := &proto.ListAccessRequestsResponse{AccessRequests: f.requests[start:end]}
+	if end < len(f.requests) {
+		resp.NextKey = strconv.Itoa(end)
+	}
+	return resp, nil
+}
+
+func newAccessRequest(t *testing.T, name, user string, roles ...string) *types.AccessRequestV3 {
+	t.Helper()
+	req, err := types.NewAccessRequest(name, user, roles...)
+	if err != nil {
+		t.Fatalf("failed to create access request: %v", err)
+	}
+	return req.(*types.AccessRequestV3)
+}
+
+func TestForEachAccessRequest(t *testing.T) {
+	clt := &fakeAccessRequestsClient{}
+	for i := range 5 {
+		clt.requests = append(clt.requests, newAccessRequest(t, fmt.Sprintf("request-%d", i), "alice", "admin"))
+	}
+
+	var names []string
+	err := forEachAccessRequest(context.Background(), clt, 2, func(req types.AccessRequest) {
+		names = append(names, req.GetName())
+	})
+	if err != nil {
+		t.Fatalf("forEachAccessRequest() failed: %v", err)
+	}
+	if len(names) != 5 {
+		t.Errorf("expected 5 access requests, got %v", names)
+	}
+	if want := []int32{2, 2, 2}; !slices.Equal(clt.limits, want) {
+		t.Errorf("expected limits %v, got %v", want, clt.limits)
+	}
+}
+
+func TestAccessRequestInfo(t *testing.T) {
+	created := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	req := newAccessRequest(t, "request-1", "alice", "admin", "dba")
+	req.SetCreationTime(created)
+
+	got := accessRequestInfo(req)
+	if got.Name != "request-1" || got.User != "alice" || !slices.Equal(got.Roles, []string{"admin", "dba"}) {
+		t.Errorf("unexpected access request %+v", got)
+	}
+	if got.State != AccessRequestPending || !got.Created.Equal(created) {
+		t.Errorf("expected a pending request created at %s, got %+v", created, got)
+	}
+
+	if err := req.SetState(types.RequestState_APPROVED); err != nil {
+		t.Fatal(err)
+	}
+	if got := accessRequestInfo(req); got.State != AccessRequestApproved {
+		t.Errorf("expected state %s, got %s", AccessRequestApproved, got.State)
+	}
+}
+
+func TestCheckAccessRequests(t *testing.T) {
+	clt := &fakeAccessRequestsClient{err: trace.AccessDenied("access denied to perform action \"list\" on \"access_request\"")}
+	if reason := ErrorReason(checkAccessRequests(context.Background(), clt)); reason != ErrorReasonPermissionDenied {
+		t.Errorf("expected reason %s, got %s", ErrorReasonPermissionDenied, reason)
+	}
+}
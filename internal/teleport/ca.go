@@ -0,0 +1,63 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// certFileEnv and certDirEnv are the environment variables Go reads the
+	// trusted CA certificates from on Linux.
+	certFileEnv = "SSL_CERT_FILE"
+	certDirEnv  = "SSL_CERT_DIR"
+)
+
+// defaultCertDirs are the directories Go reads CA certificates from on Linux
+// when SSL_CERT_DIR is not set. They also hold the system CA bundles.
+var defaultCertDirs = []string{"/etc/ssl/certs", "/etc/pki/tls/certs"}
+
+// UseCAFile makes the PEM encoded CA certificates in path trusted to verify
+// the Teleport proxy, in addition to the system CAs.
+//
+// The Teleport API client verifies the proxy against the system CAs during
+// proxy discovery and TLS routing and cannot be given a certificate pool, so
+// the bundle is set as SSL_CERT_FILE, which replaces the system CA bundle,
+// while the system CA directories stay in SSL_CERT_DIR. It must be called
+// before the first TLS connection, as Go loads the system CAs only once.
+func UseCAFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read CA file: %w", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(data) {
+		return fmt.Errorf("no PEM encoded certificates found in CA file %s", path)
+	}
+	if f := os.Getenv(certFileEnv); f != "" {
+		return fmt.Errorf("%s is already set to %s, add the CA certificates to it instead", certFileEnv, f)
+	}
+
+	if os.Getenv(certDirEnv) == "" {
+		if err := os.Setenv(certDirEnv, strings.Join(defaultCertDirs, ":")); err != nil {
+			return err
+		}
+	}
+	return os.Setenv(certFileEnv, path)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUseCAFile(t *testing.T) {
+	t.Setenv(certFileEnv, "")
+	t.Setenv(certDirEnv, "")
+
+	caFile := writeCA(t)
+	if err := UseCAFile(caFile); err != nil {
+		t.Fatalf("UseCAFile() failed: %v", err)
+	}
+	if f := os.Getenv(certFileEnv); f != caFile {
+		t.Errorf("expected %s to be %q, got %q", certFileEnv, caFile, f)
+	}
+	if d := os.Getenv(certDirEnv); d != strings.Join(defaultCertDirs, ":") {
+		t.Errorf("expected %s to keep the system CA directories, got %q", certDirEnv, d)
+	}
+}
+
+func TestUseCAFile_CertDirSet(t *testing.T) {
+	t.Setenv(certFileEnv, "")
+	t.Setenv(certDirEnv, "/etc/custom-certs")
+
+	if err := UseCAFile(writeCA(t)); err != nil {
+		t.Fatalf("UseCAFile() failed: %v", err)
+	}
+	if d := os.Getenv(certDirEnv); d != "/etc/custom-certs" {
+		t.Errorf("expected %s to be unchanged, got %q", certDirEnv, d)
+	}
+}
+
+func TestUseCAFile_Invalid(t *testing.T) {
+	t.Setenv(certFileEnv, "")
+	t.Setenv(certDirEnv, "")
+
+	if err := UseCAFile(filepath.Join(t.TempDir(), "missing.crt")); err == nil {
+		t.Error("expected error for missing CA file")
+	}
+	invalid := filepath.Join(t.TempDir(), "invalid.crt")
+	if err := os.WriteFile(invalid, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := UseCAFile(invalid); err == nil {
+		t.Error("expected error for CA file without certificates")
+	}
+	if f := os.Getenv(certFileEnv); f != "" {
+		t.Errorf("expected %s to be unchanged, got %q", certFileEnv, f)
+	}
+
+	t.Setenv(certFileEnv, "/etc/ssl/custom.pem")
+	if err := UseCAFile(writeCA(t)); err == nil {
+		t.Errorf("expected error when %s is already set", certFileEnv)
+	}
+}
+
+// writeCA writes a self-signed PEM encoded CA certificate and returns its path.
+func writeCA(t *testing.T) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
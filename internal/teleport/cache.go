@@ -0,0 +1,170 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+)
+
+// Resource types whose API results can be cached.
+const (
+	CacheNodes           = "nodes"
+	CacheKubeClusters    = "kubernetes_clusters"
+	CacheDatabases       = "databases"
+	CacheApps            = "apps"
+	CacheUsers           = "users"
+	CacheLocks           = "locks"
+	CacheRoles           = "roles"
+	CacheTokens          = "tokens"
+	CacheAccessRequests  = "access_requests"
+	CacheSessions        = "sessions"
+	CacheRemoteClusters  = "remote_clusters"
+	CacheCertAuthorities = "cert_authorities"
+)
+
+var cacheResources = []string{CacheNodes, CacheKubeClusters, CacheDatabases, CacheApps, CacheUsers, CacheLocks, CacheRoles, CacheTokens, CacheAccessRequests, CacheSessions, CacheRemoteClusters, CacheCertAuthorities}
+
+// ParseCacheTTLs parses a comma-separated list of TTLs. A plain duration
+// applies to all resource types, resource=duration to a single one, e.g.
+// "1m,nodes=5m".
+func ParseCacheTTLs(s string) (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration)
+	overrides := make(map[string]time.Duration)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		resource, value, ok := strings.Cut(item, "=")
+		if !ok {
+			value = resource
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache TTL %q: %w", item, err)
+		}
+		if !ok {
+			for _, r := range cacheResources {
+				ttls[r] = ttl
+			}
+			continue
+		}
+		resource = strings.TrimSpace(resource)
+		if !slices.Contains(cacheResources, resource) {
+			return nil, fmt.Errorf("invalid cache resource %q, must be one of %s", resource, strings.Join(cacheResources, ", "))
+		}
+		overrides[resource] = ttl
+	}
+	// Overrides win regardless of their position
+	for r, ttl := range overrides {
+		ttls[r] = ttl
+	}
+	return ttls, nil
+}
+
+// cache holds the last good result of an API call. Once the result is older
+// than the TTL, it is still served while a refresh runs in the background.
+// A nil cache disables caching.
+type cache[T any] struct {
+	resource string
+	ttl      time.Duration
+	log      logr.Logger
+
+	mu         sync.Mutex
+	value      T
+	fetchedAt  time.Time
+	valid      bool
+	refreshing bool
+	// refreshErr is the error of the last background refresh. The next get
+	// fetches synchronously, so that errors reach the caller.
+	refreshErr error
+}
+
+// newCache returns a cache for the given resource type, or nil if ttl is not
+// positive.
+func newCache[T any](resource string, ttl time.Duration, log logr.Logger) *cache[T] {
+	if ttl <= 0 {
+		return nil
+	}
+	return &cache[T]{resource: resource, ttl: ttl, log: log}
+}
+
+// get returns the cached result, calling fetch synchronously if there is no
+// usable result and in the background if the result is stale.
+func (c *cache[T]) get(ctx context.Context, fetch func(context.Context) (T, error)) (T, error) {
+	if c == nil {
+		return fetch(ctx)
+	}
+
+	c.mu.Lock()
+	if !c.valid || c.refreshErr != nil {
+		c.mu.Unlock()
+		value, err := fetch(ctx)
+		if err != nil {
+			return value, err
+		}
+		c.mu.Lock()
+		c.store(value)
+		c.mu.Unlock()
+		metrics.CacheAgeSeconds.WithLabelValues(c.resource).Set(0)
+		return value, nil
+	}
+
+	age := time.Since(c.fetchedAt)
+	if age >= c.ttl && !c.refreshing {
+		c.refreshing = true
+		// The fetch functions bound the call by the API timeout themselves
+		go c.refresh(context.WithoutCancel(ctx), fetch)
+	}
+	value := c.value
+	c.mu.Unlock()
+
+	metrics.CacheAgeSeconds.WithLabelValues(c.resource).Set(age.Seconds())
+	return value, nil
+}
+
+// refresh replaces the cached result in the background.
+func (c *cache[T]) refresh(ctx context.Context, fetch func(context.Context) (T, error)) {
+	value, err := fetch(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshing = false
+	if err != nil {
+		c.refreshErr = err
+		c.log.Error(err, "failed to refresh cache", "resource", c.resource)
+		return
+	}
+	c.store(value)
+}
+
+// store replaces the cached result. c.mu must be held.
+func (c *cache[T]) store(value T) {
+	c.value = value
+	c.fetchedAt = time.Now()
+	c.valid = true
+	c.refreshErr = nil
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+)
+
+func TestParseCacheTTLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]time.Duration
+		wantErr bool
+	}{
+		{name: "empty", input: "", want: map[string]time.Duration{}},
+		{
+			name:  "default",
+			input: "1m",
+			want:  map[string]time.Duration{CacheNodes: time.Minute, CacheKubeClusters: time.Minute, CacheDatabases: time.Minute, CacheApps: time.Minute, CacheUsers: time.Minute, CacheLocks: time.Minute, CacheRoles: time.Minute, CacheTokens: time.Minute, CacheAccessRequests: time.Minute, CacheSessions: time.Minute, CacheRemoteClusters: time.Minute, CacheCertAuthorities: time.Minute},
+		},
+		{
+			name:  "override before default",
+			input: "nodes=5m, 1m",
+			want:  map[string]time.Duration{CacheNodes: 5 * time.Minute, CacheKubeClusters: time.Minute, CacheDatabases: time.Minute, CacheApps: time.Minute, CacheUsers: time.Minute, CacheLocks: time.Minute, CacheRoles: time.Minute, CacheTokens: time.Minute, CacheAccessRequests: time.Minute, CacheSessions: time.Minute, CacheRemoteClusters: time.Minute, CacheCertAuthorities: time.Minute},
+		},
+		{name: "single resource", input: "apps=30s", want: map[string]time.Duration{CacheApps: 30 * time.Second}},
+		{name: "unknown resource", input: "widgets=1m", wantErr: true},
+		{name: "invalid duration", input: "nodes=soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCacheTTLs(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCacheTTLs(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCacheTTLs(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache_Disabled(t *testing.T) {
+	c := newCache[int](CacheNodes, 0, logr.Discard())
+	if c != nil {
+		t.Fatal("expected no cache for a zero TTL")
+	}
+
+	var calls int
+	fetch := func(context.Context) (int, error) { calls++; return calls, nil }
+	for want := 1; want <= 2; want++ {
+		if got, _ := c.get(context.Background(), fetch); got != want {
+			t.Errorf("get() = %d, want %d", got, want)
+		}
+	}
+}
+
+func TestCache_StaleWhileRevalidate(t *testing.T) {
+	c := newCache[int](CacheNodes, time.Hour, logr.Discard())
+
+	var calls atomic.Int32
+	fetchErr := errors.New("unavailable")
+	var fail atomic.Bool
+	refreshed := make(chan struct{}, 1)
+	fetch := func(context.Context) (int, error) {
+		n := int(calls.Add(1))
+		defer func() { refreshed <- struct{}{} }()
+		if fail.Load() {
+			return 0, fetchErr
+		}
+		return n, nil
+	}
+	get := func() (int, error) {
+		t.Helper()
+		return c.get(context.Background(), fetch)
+	}
+
+	// The first call fetches synchronously
+	if got, err := get(); got != 1 || err != nil {
+		t.Fatalf("get() = %d, %v, want 1, nil", got, err)
+	}
+	<-refreshed
+
+	// A fresh result is served from the cache
+	if got, _ := get(); got != 1 || calls.Load() != 1 {
+		t.Errorf("get() = %d after %d calls, want 1 after 1 call", got, calls.Load())
+	}
+
+	// A stale result is served while refreshing in the background
+	c.mu.Lock()
+	c.fetchedAt = time.Now().Add(-2 * time.Hour)
+	c.mu.Unlock()
+	if got, _ := get(); got != 1 {
+		t.Errorf("get() = %d, want stale result 1", got)
+	}
+	if age := testutil.ToFloat64(metrics.CacheAgeSeconds.WithLabelValues(CacheNodes)); age < time.Hour.Seconds() {
+		t.Errorf("expected cache age of at least an hour, got %f", age)
+	}
+	<-refreshed
+	waitForRefresh(t, c)
+	if got, _ := get(); got != 2 {
+		t.Errorf("get() = %d, want refreshed result 2", got)
+	}
+
+	// A failed background refresh makes the next call fetch synchronously
+	fail.Store(true)
+	c.mu.Lock()
+	c.fetchedAt = time.Now().Add(-2 * time.Hour)
+	c.mu.Unlock()
+	get()
+	<-refreshed
+	waitForRefresh(t, c)
+	if _, err := get(); !errors.Is(err, fetchErr) {
+		t.Errorf("get() error = %v, want %v", err, fetchErr)
+	}
+	<-refreshed
+
+	fail.Store(false)
+	if got, err := get(); got != 5 || err != nil {
+		t.Errorf("get() = %d, %v, want 5, nil", got, err)
+	}
+}
+
+// waitForRefresh waits until the background refresh of c has finished.
+func waitForRefresh(t *testing.T, c *cache[int]) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		refreshing := c.refreshing
+		c.mu.Unlock()
+		if !refreshing {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("background refresh did not finish")
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/trace"
+)
+
+// certAuthorityTypes are the CA types whose expiry is collected: those
+// signing the TLS certificates of the hosts, users and databases.
+var certAuthorityTypes = []types.CertAuthType{types.HostCA, types.UserCA, types.DatabaseCA}
+
+// CertAuthorityInfo represents a CA of the cluster or of a trusted cluster.
+type CertAuthorityInfo struct {
+	// ClusterName is the cluster the CA belongs to.
+	ClusterName string `json:"clusterName"`
+	Type        string `json:"type"`
+	// Expires is when the first active TLS CA certificate expires.
+	Expires time.Time `json:"expires"`
+}
+
+// certAuthoritiesClient is the part of the Teleport API client that lists
+// CAs.
+type certAuthoritiesClient interface {
+	GetCertAuthorities(ctx context.Context, caType types.CertAuthType, loadKeys bool) ([]types.CertAuthority, error)
+}
+
+// GetCertAuthorities returns the host, user and database CAs known to
+// Teleport with the expiry of their TLS certificates. The result is served
+// from the cache if one is configured for the resource type.
+func (c *Client) GetCertAuthorities(ctx context.Context) ([]CertAuthorityInfo, error) {
+	return c.certAuthoritiesCache.get(ctx, c.fetchCertAuthorities)
+}
+
+// fetchCertAuthorities fetches the CAs from the Teleport API.
+func (c *Client) fetchCertAuthorities(ctx context.Context) ([]CertAuthorityInfo, error) {
+	c.log.V(1).Info("fetching cert authorities from Teleport")
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if err := c.injectFault(ctx, "GetCertAuthorities"); err != nil {
+		return nil, err
+	}
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	clt, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result, err := listCertAuthorities(ctx, clt)
+	observe("GetCertAuthorities", start, err)
+	if err != nil {
+		c.log.Error(err, "failed to get cert authorities")
+		return nil, err
+	}
+
+	c.log.V(1).Info("fetched cert authorities", "count", len(result))
+	return result, nil
+}
+
+// listCertAuthorities lists the CAs of each of certAuthorityTypes, without
+// their private keys. CAs without a TLS certificate are left out.
+func listCertAuthorities(ctx context.Context, clt certAuthoritiesClient) ([]CertAuthorityInfo, error) {
+	result := make([]CertAuthorityInfo, 0)
+	for _, caType := range certAuthorityTypes {
+		cas, err := clt.GetCertAuthorities(ctx, caType, false)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, ca := range cas {
+			if info, ok := certAuthorityInfo(ca); ok {
+				result = append(result, info)
+			}
+		}
+	}
+	return result, nil
+}
+
+// certAuthorityInfo converts a Teleport CA into a CertAuthorityInfo. It
+// returns false if the CA has no active TLS certificate that can be parsed.
+func certAuthorityInfo(ca types.CertAuthority) (CertAuthorityInfo, bool) {
+	var expires time.Time
+	for _, keyPair := range ca.GetActiveKeys().TLS {
+		block, _ := pem.Decode(keyPair.Cert)
+		if block == nil {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		if expires.IsZero() || cert.NotAfter.Before(expires) {
+			expires = cert.NotAfter
+		}
+	}
+	if expires.IsZero() {
+		return CertAuthorityInfo{}, false
+	}
+	return CertAuthorityInfo{
+		ClusterName: ca.GetClusterName(),
+		Type:        string(ca.GetType()),
+		Expires:     expires,
+	}, true
+}
+
+// checkCertAuthorities lists the host CAs, which fails with an access denied
+// error if the identity may not read cert authorities.
+func checkCertAuthorities(ctx context.Context, clt certAuthoritiesClient) error {
+	_, err := clt.GetCertAuthorities(ctx, types.HostCA, false)
+	return trace.Wrap(err)
+}
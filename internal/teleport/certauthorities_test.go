@@ -0,0 +1,111 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/gravitational/teleport/api/types"
+)
+
+// fakeCertAuthoritiesClient serves the CAs of each type.
+type fakeCertAuthoritiesClient struct {
+	cas      map[types.CertAuthType][]types.CertAuthority
+	loadKeys []bool
+}
+
+func (f *fakeCertAuthoritiesClient) GetCertAuthorities(_ context.Context, caType types.CertAuthType, loadKeys bool) ([]types.CertAuthority, error) {
+	f.loadKeys = append(f.loadKeys, loadKeys)
+	return f.cas[caType], nil
+}
+
+// newCACert returns a PEM encoded self-signed CA certificate expiring at
+// notAfter.
+func newCACert(t *testing.T, notAfter time.Time) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "teleport.example.com"},
+		NotBefore:             notAfter.AddDate(-10, 0, 0),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func newTLSCA(cluster string, caType types.CertAuthType, certs ...[]byte) types.CertAuthority {
+	ca := &types.CertAuthorityV2{Spec: types.CertAuthoritySpecV2{ClusterName: cluster, Type: caType}}
+	for _, cert := range certs {
+		ca.Spec.ActiveKeys.TLS = append(ca.Spec.ActiveKeys.TLS, &types.TLSKeyPair{Cert: cert})
+	}
+	return ca
+}
+
+func TestListCertAuthorities(t *testing.T) {
+	soon := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := soon.AddDate(1, 0, 0)
+	clt := &fakeCertAuthoritiesClient{cas: map[types.CertAuthType][]types.CertAuthority{
+		types.HostCA: {
+			newTLSCA("main", types.HostCA, newCACert(t, later), newCACert(t, soon)),
+			newTLSCA("leaf", types.HostCA, newCACert(t, later)),
+		},
+		types.UserCA: {
+			newTLSCA("main", types.UserCA),
+			newTLSCA("broken", types.UserCA, []byte("not a certificate")),
+		},
+	}}
+
+	cas, err := listCertAuthorities(context.Background(), clt)
+	if err != nil {
+		t.Fatalf("listCertAuthorities() failed: %v", err)
+	}
+	if len(cas) != 2 {
+		t.Fatalf("expected the 2 CAs with a TLS certificate, got %+v", cas)
+	}
+	if cas[0].ClusterName != "main" || cas[0].Type != string(types.HostCA) || !cas[0].Expires.Equal(soon) {
+		t.Errorf("expected the host CA of main to expire with its first certificate at %s, got %+v", soon, cas[0])
+	}
+	if cas[1].ClusterName != "leaf" || !cas[1].Expires.Equal(later) {
+		t.Errorf("expected the host CA of leaf to expire at %s, got %+v", later, cas[1])
+	}
+	for _, loadKeys := range clt.loadKeys {
+		if loadKeys {
+			t.Error("expected the CAs to be listed without their private keys")
+		}
+	}
+	if len(clt.loadKeys) != len(certAuthorityTypes) {
+		t.Errorf("expected a request per CA type, got %d", len(clt.loadKeys))
+	}
+}
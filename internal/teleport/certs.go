@@ -0,0 +1,152 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/gravitational/teleport/api/client/proto"
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/trace"
+)
+
+// routedCertTTL is the validity of the certificates issued to probe
+// applications and databases through the Teleport proxy.
+const routedCertTTL = time.Hour
+
+// userCertsClient is the part of the Teleport API client that issues
+// certificates to the identity of the client.
+type userCertsClient interface {
+	GetCurrentUser(ctx context.Context) (types.User, error)
+	GenerateUserCerts(ctx context.Context, req proto.UserCertsRequest) (*proto.Certs, error)
+}
+
+// AppCert issues a short-lived certificate of the identity of the client for
+// the application app of the cluster, like tsh app login. The Teleport proxy
+// forwards the requests made with it to the application instead of
+// redirecting them to its login page.
+func (c *Client) AppCert(ctx context.Context, clusterName string, app AppInfo) (tls.Certificate, error) {
+	return c.routedCert(ctx, "app", func(req *proto.UserCertsRequest) {
+		req.Usage = proto.UserCertsRequest_App
+		req.RouteToApp = proto.RouteToApp{Name: app.Name, PublicAddr: app.PublicAddr, ClusterName: clusterName}
+	})
+}
+
+// DatabaseCert issues a short-lived certificate of the identity of the client
+// for the database db, as user and database, like tsh db login. The Teleport
+// proxy routes the connections made with it to an agent of db.
+func (c *Client) DatabaseCert(ctx context.Context, db DatabaseInfo, user, database string) (tls.Certificate, error) {
+	return c.routedCert(ctx, "database", func(req *proto.UserCertsRequest) {
+		req.Usage = proto.UserCertsRequest_Database
+		req.RouteToDatabase = proto.RouteToDatabase{ServiceName: db.Name, Protocol: db.Protocol, Username: user, Database: database}
+	})
+}
+
+// ProxyPublicAddr returns the public address of the Teleport proxy, which the
+// connections made with the certificates of DatabaseCert go to.
+func (c *Client) ProxyPublicAddr(ctx context.Context) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	clt, err := c.api()
+	if err != nil {
+		return "", err
+	}
+
+	start := time.Now()
+	resp, err := clt.Ping(ctx)
+	observe("Ping", start, err)
+	if err != nil {
+		return "", err
+	}
+	if resp.ProxyPublicAddr == "" {
+		return "", fmt.Errorf("teleport reports no public proxy address")
+	}
+	return resp.ProxyPublicAddr, nil
+}
+
+// routedCert issues a certificate for the request route sets, e.g. the
+// application to route to.
+func (c *Client) routedCert(ctx context.Context, kind string, route func(*proto.UserCertsRequest)) (tls.Certificate, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	defer release()
+
+	clt, err := c.api()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	start := time.Now()
+	cert, err := issueUserCert(ctx, clt, time.Now().Add(routedCertTTL), route)
+	observe("GenerateUserCerts", start, err)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to issue %s certificate: %w", kind, err)
+	}
+	return cert, nil
+}
+
+// issueUserCert generates a key and has Teleport sign it for the current user
+// until expires, with the request modified by route.
+func issueUserCert(ctx context.Context, clt userCertsClient, expires time.Time, route func(*proto.UserCertsRequest)) (tls.Certificate, error) {
+	user, err := clt.GetCurrentUser(ctx)
+	if err != nil {
+		return tls.Certificate{}, trace.Wrap(err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	req := proto.UserCertsRequest{
+		TLSPublicKey: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}),
+		Username:     user.GetName(),
+		Expires:      expires,
+	}
+	route(&req)
+	certs, err := clt.GenerateUserCerts(ctx, req)
+	if err != nil {
+		return tls.Certificate{}, trace.Wrap(err)
+	}
+
+	block, _ := pem.Decode(certs.TLS)
+	if block == nil {
+		return tls.Certificate{}, fmt.Errorf("no TLS certificate issued")
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to parse the issued certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{block.Bytes}, PrivateKey: key, Leaf: leaf}, nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/gravitational/teleport/api/client/proto"
+	"github.com/gravitational/teleport/api/types"
+)
+
+// fakeUserCertsClient signs the requested public key with a self-signed CA
+// and records the requests.
+type fakeUserCertsClient struct {
+	t        *testing.T
+	user     string
+	requests []proto.UserCertsRequest
+}
+
+func (f *fakeUserCertsClient) GetCurrentUser(context.Context) (types.User, error) {
+	return types.NewUser(f.user)
+}
+
+func (f *fakeUserCertsClient) GenerateUserCerts(_ context.Context, req proto.UserCertsRequest) (*proto.Certs, error) {
+	f.requests = append(f.requests, req)
+	block, _ := pem.Decode(req.TLSPublicKey)
+	if block == nil {
+		f.t.Fatal("expected a PEM encoded public key")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		f.t.Fatalf("failed to parse the public key: %v", err)
+	}
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		f.t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: req.Username},
+		NotBefore:    time.Now(),
+		NotAfter:     req.Expires,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, caKey)
+	if err != nil {
+		f.t.Fatalf("failed to create certificate: %v", err)
+	}
+	return &proto.Certs{TLS: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}, nil
+}
+
+func TestIssueUserCert(t *testing.T) {
+	clt := &fakeUserCertsClient{t: t, user: "bot-exporter"}
+	expires := time.Now().Add(routedCertTTL).Truncate(time.Second)
+	cert, err := issueUserCert(context.Background(), clt, expires, func(req *proto.UserCertsRequest) {
+		req.Usage = proto.UserCertsRequest_App
+		req.RouteToApp = proto.RouteToApp{Name: "grafana", PublicAddr: "grafana.example.com", ClusterName: "main"}
+	})
+	if err != nil {
+		t.Fatalf("issueUserCert() failed: %v", err)
+	}
+
+	if len(clt.requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(clt.requests))
+	}
+	req := clt.requests[0]
+	if req.Username != "bot-exporter" || req.Usage != proto.UserCertsRequest_App || req.RouteToApp.Name != "grafana" {
+		t.Errorf("unexpected request %+v", req)
+	}
+	if cert.Leaf == nil || cert.Leaf.Subject.CommonName != "bot-exporter" || !cert.Leaf.NotAfter.Equal(expires) {
+		t.Errorf("unexpected certificate %+v", cert.Leaf)
+	}
+	key, ok := cert.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok || !key.PublicKey.Equal(cert.Leaf.PublicKey) {
+		t.Error("expected the private key of the issued certificate")
+	}
+}
+
+func TestIssueUserCert_Database(t *testing.T) {
+	clt := &fakeUserCertsClient{t: t, user: "bot-exporter"}
+	_, err := issueUserCert(context.Background(), clt, time.Now().Add(routedCertTTL), func(req *proto.UserCertsRequest) {
+		req.Usage = proto.UserCertsRequest_Database
+		req.RouteToDatabase = proto.RouteToDatabase{ServiceName: "orders", Protocol: "postgres", Username: "probe", Database: "postgres"}
+	})
+	if err != nil {
+		t.Fatalf("issueUserCert() failed: %v", err)
+	}
+	if req := clt.requests[0]; req.Usage != proto.UserCertsRequest_Database || req.RouteToDatabase.ServiceName != "orders" || req.RouteToApp.Name != "" {
+		t.Errorf("unexpected request %+v", req)
+	}
+}
@@ -18,134 +18,590 @@ package teleport
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/gravitational/teleport/api/client"
+	"github.com/gravitational/teleport/api/client/proto"
+	apidefaults "github.com/gravitational/teleport/api/defaults"
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
 )
 
 const (
 	// Default timeout for API operations if not specified
 	defaultAPITimeout = 30 * time.Second
+	// initialConnectBackoff and maxConnectBackoff bound the delay between the
+	// attempts of ConnectWithRetry
+	initialConnectBackoff = time.Second
+	maxConnectBackoff     = time.Minute
+	// healthCheckTimeout bounds each Ping of MonitorHealth
+	healthCheckTimeout = 5 * time.Second
 )
 
+// ErrNotConnected is returned by the API calls of a client created with
+// NewDisconnectedClient until it connected to Teleport. ErrorReason classifies
+// it as ErrorReasonConnection.
+var ErrNotConnected = trace.ConnectionProblem(nil, "not connected to Teleport yet")
+
 // Config holds the configuration for the Teleport client.
 type Config struct {
 	// ProxyAddr is the address of the Teleport proxy or auth server.
 	ProxyAddr string
 	// IdentityFile is the path to the identity file for authentication.
 	IdentityFile string
+	// IdentityContent returns the content of an identity file to
+	// authenticate with, e.g. loaded from a secret store. It is called on
+	// every connect, so it may return renewed identities.
+	IdentityContent func() string
+	// CertFile, KeyFile and CAFile are the paths of a PEM encoded client
+	// certificate, its private key and the cluster CA certificates, to
+	// authenticate with instead of IdentityFile.
+	CertFile string
+	KeyFile  string
+	CAFile   string
+	// Profile is the name of a tsh profile, usually the proxy host name, to
+	// authenticate with instead of IdentityFile.
+	Profile string
+	// ProfileDir is the directory of the tsh profiles. Empty means ~/.tsh.
+	ProfileDir string
 	// Insecure skips TLS certificate verification.
 	Insecure bool
+	// ProxyURL is the HTTP CONNECT or SOCKS5 proxy to dial Teleport
+	// through. Nil dials through the proxy from HTTPS_PROXY and NO_PROXY, if
+	// any.
+	ProxyURL *url.URL
+	// ConnectionMode is one of ConnectionModeAuto, ConnectionModeProxy and
+	// ConnectionModeAuth. Empty means ConnectionModeAuto.
+	ConnectionMode string
+	// ALPNConnUpgrade tunnels the TLS routing connection through an HTTP
+	// upgrade, for proxies behind load balancers that strip ALPN.
+	ALPNConnUpgrade bool
+	// TLSMinVersion is the minimum TLS version of the connection to
+	// Teleport. Zero keeps the version of the credentials.
+	TLSMinVersion uint16
+	// TLSCipherSuites restricts the TLS 1.2 cipher suites of the connection
+	// to Teleport. Nil keeps the Go defaults.
+	TLSCipherSuites []uint16
 	// APITimeout is the timeout for API calls.
 	APITimeout time.Duration
+	// DialTimeout bounds each attempt to dial Teleport. Zero means the
+	// Teleport default of 30s.
+	DialTimeout time.Duration
+	// KeepAliveTime is the interval of keepalive pings on the connection.
+	// Zero means the Teleport default of 1m.
+	KeepAliveTime time.Duration
+	// KeepAliveTimeout is how long unanswered keepalive pings are tolerated
+	// before the connection is closed, rounded up to a multiple of
+	// KeepAliveTime. Zero means the Teleport default of 3 keepalive intervals.
+	KeepAliveTimeout time.Duration
+	// MaxRecvMsgSize is the maximum size in bytes of a message received from
+	// Teleport. Zero means the Teleport default of 4MiB.
+	MaxRecvMsgSize int
+	// IdleTimeout is how long the gRPC connection may be unused before it is
+	// closed, to be reopened by the next call. Zero means the gRPC default.
+	IdleTimeout time.Duration
+	// ListPageSize is the number of resources fetched per page when listing
+	// resources. Zero means the Teleport default of defaults.DefaultChunkSize.
+	ListPageSize int
+	// Namespaces are the Teleport namespaces to list resources in, one after
+	// the other. Empty means the default namespace.
+	Namespaces []string
+	// MaxConcurrentCalls caps the number of API calls in flight at the same
+	// time, not counting health checks. Zero means unlimited.
+	MaxConcurrentCalls int
+	// CacheTTLs are the TTLs of the cached results by resource type. Results
+	// of resource types without a positive TTL are not cached.
+	CacheTTLs map[string]time.Duration
+	// Faults injects errors into the API calls, for resilience testing. Nil
+	// injects nothing.
+	Faults *Faults
 	// Log is the logger to use.
 	Log logr.Logger
 }
 
 // Client wraps the Teleport API client.
 type Client struct {
-	client     *client.Client
+	client *client.Client
+	// closeProxy closes the connection to the proxy the client is tunneled
+	// through in ConnectionModeProxy.
+	closeProxy func() error
+	cfg        Config
 	log        logr.Logger
 	apiTimeout time.Duration
-	connected  bool
-	mu         sync.RWMutex
+	pageSize   int
+	namespaces []string
+	// closed is set by Close, so that a concurrent Reconnect does not leak
+	// its connection
+	closed bool
+	// healthy is the result of the last health check, see MonitorHealth
+	healthy bool
+	mu      sync.RWMutex
+	// sem limits the number of API calls in flight, nil if unlimited.
+	sem chan struct{}
+
+	nodesCache           *cache[[]NodeInfo]
+	kubeClustersCache    *cache[[]KubeClusterInfo]
+	databasesCache       *cache[[]DatabaseInfo]
+	appsCache            *cache[[]AppInfo]
+	usersCache           *cache[[]UserInfo]
+	locksCache           *cache[[]LockInfo]
+	rolesCache           *cache[[]RoleInfo]
+	tokensCache          *cache[[]TokenInfo]
+	accessRequestsCache  *cache[[]AccessRequestInfo]
+	sessionsCache        *cache[[]SessionInfo]
+	remoteClustersCache  *cache[[]RemoteClusterInfo]
+	certAuthoritiesCache *cache[[]CertAuthorityInfo]
 }
 
 // NodeInfo represents information about a Teleport node.
 type NodeInfo struct {
-	Name      string
-	Hostname  string
-	Address   string
-	Labels    map[string]string
-	Namespace string
-	SubKind   string
+	Name      string            `json:"name"`
+	Hostname  string            `json:"hostname"`
+	Address   string            `json:"address"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Namespace string            `json:"namespace"`
+	// SubKind is the kind of node, e.g. NodeSubKindOpenSSH for agentless
+	// nodes. Older Teleport versions leave it empty for Teleport nodes.
+	SubKind string `json:"subKind,omitempty"`
+}
+
+// Subkinds of NodeInfo.SubKind.
+const (
+	NodeSubKindTeleport    = types.SubKindTeleportNode
+	NodeSubKindOpenSSH     = types.SubKindOpenSSHNode
+	NodeSubKindOpenSSHEICE = types.SubKindOpenSSHEICENode
+)
+
+// ServerInfo represents a Teleport agent serving a Kubernetes cluster,
+// database or application.
+type ServerInfo struct {
+	HostID   string `json:"hostID"`
+	Hostname string `json:"hostname"`
 }
 
 // KubeClusterInfo represents information about a Kubernetes cluster registered in Teleport.
 type KubeClusterInfo struct {
-	Name   string
-	Labels map[string]string
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// Servers are the agents serving the cluster.
+	Servers []ServerInfo `json:"servers,omitempty"`
 }
 
+// Clouds of DatabaseInfo.Cloud.
+const (
+	DatabaseCloudAWS        = "aws"
+	DatabaseCloudGCP        = "gcp"
+	DatabaseCloudAzure      = "azure"
+	DatabaseCloudMongoAtlas = types.DatabaseTypeMongoAtlas
+	DatabaseCloudSelfHosted = types.DatabaseTypeSelfHosted
+)
+
+// TLS modes of DatabaseInfo.TLSMode, named as in the Teleport configuration.
+const (
+	TLSModeVerifyFull = "verify-full"
+	TLSModeVerifyCA   = "verify-ca"
+	TLSModeInsecure   = "insecure"
+)
+
 // DatabaseInfo represents information about a database registered in Teleport.
 type DatabaseInfo struct {
-	Name     string
-	Protocol string
-	Type     string
-	Labels   map[string]string
+	Name     string            `json:"name"`
+	Protocol string            `json:"protocol"`
+	Type     string            `json:"type"`
+	URI      string            `json:"uri,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	// Cloud is where the database is hosted, one of the DatabaseCloud*
+	// constants.
+	Cloud string `json:"cloud,omitempty"`
+	// TLSMode is how Teleport verifies the database certificate, one of the
+	// TLSMode* constants.
+	TLSMode string `json:"tlsMode,omitempty"`
+	// Servers are the agents serving the database.
+	Servers []ServerInfo `json:"servers,omitempty"`
 }
 
 // AppInfo represents information about an application registered in Teleport.
 type AppInfo struct {
-	Name       string
-	PublicAddr string
-	URI        string
-	Labels     map[string]string
+	Name       string            `json:"name"`
+	PublicAddr string            `json:"publicAddr"`
+	URI        string            `json:"uri"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	// Servers are the agents serving the application.
+	Servers []ServerInfo `json:"servers,omitempty"`
 }
 
 // NewClient creates a new Teleport client.
 func NewClient(cfg Config) (*Client, error) {
-	cfg.Log.Info("connecting to Teleport", "addr", cfg.ProxyAddr)
-
-	apiTimeout := cfg.APITimeout
-	if apiTimeout == 0 {
-		apiTimeout = defaultAPITimeout
-	}
+	c := NewDisconnectedClient(cfg)
+	c.log.Info("connecting to Teleport", "addr", cfg.ProxyAddr)
 
 	// Use timeout for initial connection
-	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), c.apiTimeout)
 	defer cancel()
 
-	creds := client.LoadIdentityFile(cfg.IdentityFile)
+	clt, closeProxy, err := connect(ctx, c.cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.client, c.closeProxy = clt, closeProxy
+	c.healthy = true
+	metrics.ConnectionHealthy.Set(1)
+
+	c.log.Info("connected to Teleport successfully")
+	return c, nil
+}
+
+// ParseNamespaces parses a comma-separated list of Teleport namespaces to list
+// resources in. An empty string means the default namespace.
+func ParseNamespaces(s string) []string {
+	var namespaces []string
+	for _, namespace := range strings.Split(s, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" && !slices.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// NewDisconnectedClient creates a client without connecting to Teleport, e.g.
+// to serve metrics while Teleport is unreachable on startup. Its API calls
+// fail with ErrNotConnected until Reconnect or ConnectWithRetry succeeded.
+func NewDisconnectedClient(cfg Config) *Client {
+	if cfg.APITimeout == 0 {
+		cfg.APITimeout = defaultAPITimeout
+	}
+
+	var sem chan struct{}
+	if cfg.MaxConcurrentCalls > 0 {
+		sem = make(chan struct{}, cfg.MaxConcurrentCalls)
+	}
+	namespaces := cfg.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{apidefaults.Namespace}
+	}
 
-	c, err := client.New(ctx, client.Config{
-		Addrs:                    []string{cfg.ProxyAddr},
+	return &Client{
+		cfg:        cfg,
+		log:        cfg.Log,
+		apiTimeout: cfg.APITimeout,
+		pageSize:   cfg.ListPageSize,
+		namespaces: namespaces,
+		sem:        sem,
+
+		nodesCache:           newCache[[]NodeInfo](CacheNodes, cfg.CacheTTLs[CacheNodes], cfg.Log),
+		kubeClustersCache:    newCache[[]KubeClusterInfo](CacheKubeClusters, cfg.CacheTTLs[CacheKubeClusters], cfg.Log),
+		databasesCache:       newCache[[]DatabaseInfo](CacheDatabases, cfg.CacheTTLs[CacheDatabases], cfg.Log),
+		appsCache:            newCache[[]AppInfo](CacheApps, cfg.CacheTTLs[CacheApps], cfg.Log),
+		usersCache:           newCache[[]UserInfo](CacheUsers, cfg.CacheTTLs[CacheUsers], cfg.Log),
+		locksCache:           newCache[[]LockInfo](CacheLocks, cfg.CacheTTLs[CacheLocks], cfg.Log),
+		rolesCache:           newCache[[]RoleInfo](CacheRoles, cfg.CacheTTLs[CacheRoles], cfg.Log),
+		tokensCache:          newCache[[]TokenInfo](CacheTokens, cfg.CacheTTLs[CacheTokens], cfg.Log),
+		accessRequestsCache:  newCache[[]AccessRequestInfo](CacheAccessRequests, cfg.CacheTTLs[CacheAccessRequests], cfg.Log),
+		sessionsCache:        newCache[[]SessionInfo](CacheSessions, cfg.CacheTTLs[CacheSessions], cfg.Log),
+		remoteClustersCache:  newCache[[]RemoteClusterInfo](CacheRemoteClusters, cfg.CacheTTLs[CacheRemoteClusters], cfg.Log),
+		certAuthoritiesCache: newCache[[]CertAuthorityInfo](CacheCertAuthorities, cfg.CacheTTLs[CacheCertAuthorities], cfg.Log),
+	}
+}
+
+// connect creates a Teleport API client for the given configuration, trying
+// the configured credential sources in priority order until one
+// authenticates. The returned function closes the connection to the proxy the
+// client is tunneled through, if any, and must be called after closing the
+// client.
+func connect(ctx context.Context, cfg Config) (*client.Client, func() error, error) {
+	var errs []error
+	for _, source := range credentialSources(cfg) {
+		c, closeProxy, err := connectWith(ctx, cfg, withTLSPolicy(cfg, source.creds))
+		if err != nil {
+			cfg.Log.Info("failed to connect to Teleport with credentials", "source", source.name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", source.name, err))
+			continue
+		}
+		cfg.Log.Info("authenticated to Teleport", "source", source.name)
+		for _, name := range []string{CredentialsIdentityFile, CredentialsIdentityContent, CredentialsKeyPair, CredentialsProfile} {
+			var value float64
+			if name == source.name {
+				value = 1
+			}
+			metrics.CredentialsSource.WithLabelValues(name).Set(value)
+		}
+		return c, closeProxy, nil
+	}
+	if len(errs) == 0 {
+		return nil, nil, errors.New("no credentials configured")
+	}
+	return nil, nil, errors.Join(errs...)
+}
+
+// connectWith creates a Teleport API client authenticating with creds.
+func connectWith(ctx context.Context, cfg Config, creds client.Credentials) (*client.Client, func() error, error) {
+	dialOpts := []grpc.DialOption{grpc.WithStatsHandler(statsHandler{})}
+	if cfg.IdleTimeout > 0 {
+		dialOpts = append(dialOpts, grpc.WithIdleTimeout(cfg.IdleTimeout))
+	}
+	if cfg.MaxRecvMsgSize > 0 {
+		// Applied after the Teleport default, so it takes precedence
+		dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(cfg.MaxRecvMsgSize)))
+	}
+
+	apiCfg, closeProxy, err := apiConfig(ctx, cfg, client.Config{
 		Credentials:              []client.Credentials{creds},
 		InsecureAddressDiscovery: cfg.Insecure,
+		ALPNConnUpgradeRequired:  cfg.ALPNConnUpgrade,
+		DialTimeout:              cfg.DialTimeout,
+		KeepAlivePeriod:          cfg.KeepAliveTime,
+		KeepAliveCount:           keepAliveCount(cfg.KeepAliveTime, cfg.KeepAliveTimeout),
+		DialOpts:                 dialOpts,
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	cfg.Log.Info("connected to Teleport successfully")
+	c, err := client.New(ctx, apiCfg)
+	if err != nil {
+		return nil, nil, errors.Join(err, closeProxy())
+	}
+	return c, closeProxy, nil
+}
 
-	return &Client{
-		client:     c,
-		log:        cfg.Log,
-		apiTimeout: apiTimeout,
-		connected:  true,
-	}, nil
+// keepAliveCount converts a keepalive timeout to the number of keepalive
+// intervals the Teleport client waits for, since it derives the gRPC
+// keepalive timeout from the interval. Zero keeps the Teleport default.
+func keepAliveCount(interval, timeout time.Duration) int {
+	if timeout <= 0 {
+		return 0
+	}
+	if interval <= 0 {
+		interval = apidefaults.ServerKeepAliveTTL()
+	}
+	return int((timeout + interval - 1) / interval)
+}
+
+// Reconnect replaces the underlying Teleport API client with a newly connected
+// one and closes the previous one. The credentials are reloaded, so renewed
+// credentials are picked up. If connecting fails, the previous client is kept.
+func (c *Client) Reconnect(ctx context.Context) error {
+	c.log.Info("reconnecting to Teleport", "addr", c.cfg.ProxyAddr)
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	newClient, newCloseProxy, err := connect(ctx, c.cfg)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		// Close was called while connecting
+		c.mu.Unlock()
+		return errors.Join(newClient.Close(), newCloseProxy())
+	}
+	oldClient, oldCloseProxy := c.client, c.closeProxy
+	c.client, c.closeProxy = newClient, newCloseProxy
+	c.healthy = true
+	c.mu.Unlock()
+	metrics.ConnectionHealthy.Set(1)
+
+	if oldClient != nil {
+		if err := errors.Join(oldClient.Close(), oldCloseProxy()); err != nil {
+			c.log.V(1).Info("failed to close previous Teleport client", "error", err)
+		}
+	}
+
+	c.log.Info("reconnected to Teleport successfully")
+	return nil
+}
+
+// ConnectWithRetry connects a client created with NewDisconnectedClient,
+// retrying with exponential backoff until it succeeds. It returns false if
+// ctx is cancelled first.
+func (c *Client) ConnectWithRetry(ctx context.Context) bool {
+	backoff := initialConnectBackoff
+	for {
+		err := c.Reconnect(ctx)
+		if err == nil {
+			return true
+		}
+		c.log.Error(err, "failed to connect to Teleport, retrying", "backoff", backoff)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, maxConnectBackoff)
+	}
+}
+
+// api returns the current Teleport API client, which changes on Reconnect, or
+// ErrNotConnected if the client has not connected yet.
+func (c *Client) api() (*client.Client, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.client == nil {
+		return nil, ErrNotConnected
+	}
+	return c.client, nil
 }
 
 // Close closes the Teleport client connection.
 func (c *Client) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.connected = false
-	return c.client.Close()
+	c.closed = true
+	c.healthy = false
+	if c.client == nil {
+		return nil
+	}
+	return errors.Join(c.client.Close(), c.closeProxy())
 }
 
-// IsConnected returns whether the client is connected by performing a health check.
+// IsConnected returns whether the client is connected, as of the last health
+// check of MonitorHealth or the last successful connect.
 func (c *Client) IsConnected() bool {
 	c.mu.RLock()
-	if !c.connected {
-		c.mu.RUnlock()
-		return false
+	defer c.mu.RUnlock()
+	return c.healthy && !c.closed
+}
+
+// MonitorHealth pings Teleport every interval until ctx is cancelled, and
+// updates IsConnected and the connection_healthy gauge with the result.
+func (c *Client) MonitorHealth(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.checkHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkHealth pings Teleport and records whether it answered.
+func (c *Client) checkHealth(ctx context.Context) {
+	err := c.ping(ctx)
+
+	c.mu.Lock()
+	wasHealthy := c.healthy
+	c.healthy = err == nil && !c.closed
+	healthy := c.healthy
+	c.mu.Unlock()
+
+	if healthy {
+		metrics.ConnectionHealthy.Set(1)
+	} else {
+		metrics.ConnectionHealthy.Set(0)
+	}
+	switch {
+	case wasHealthy && !healthy:
+		c.log.Info("health check of the connection to Teleport failed", "error", err, "class", ErrorClass(err))
+	case !wasHealthy && healthy:
+		c.log.Info("connection to Teleport is healthy again")
+	}
+}
+
+// ping performs a health check of the connection to Teleport.
+func (c *Client) ping(ctx context.Context) error {
+	clt, err := c.api()
+	if err != nil {
+		return err
 	}
-	c.mu.RUnlock()
 
-	// Perform actual health check with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
 	defer cancel()
 
-	_, err := c.client.Ping(ctx)
+	start := time.Now()
+	_, err = clt.Ping(ctx)
+	observe("Ping", start, err)
+	return err
+}
+
+// connectionStates lists the gRPC connection states exported by WatchConnectionState.
+var connectionStates = []connectivity.State{
+	connectivity.Idle,
+	connectivity.Connecting,
+	connectivity.Ready,
+	connectivity.TransientFailure,
+	connectivity.Shutdown,
+}
+
+// WatchConnectionState exports the state of the underlying gRPC connection and
+// counts reconnects until ctx is cancelled. It must only be called once the
+// client is connected.
+func (c *Client) WatchConnectionState(ctx context.Context) {
+	var wasReady bool
+	for {
+		// Reconnect replaces the connection, so follow the current one until
+		// it is shut down.
+		conn := c.conn()
+		state := conn.GetState()
+		for {
+			setConnectionState(state)
+			if state == connectivity.Ready {
+				if wasReady {
+					metrics.GRPCReconnectsTotal.Inc()
+					c.log.Info("gRPC connection to Teleport is ready again")
+				}
+				wasReady = true
+			}
+			if state == connectivity.Shutdown && conn != c.conn() {
+				break
+			}
+			if !conn.WaitForStateChange(ctx, state) {
+				return
+			}
+			state = conn.GetState()
+			c.log.V(1).Info("gRPC connection state changed", "state", state.String())
+		}
+	}
+}
+
+// ConnectionState returns the current state of the underlying gRPC connection,
+// e.g. "ready" or "transient_failure", or "not_connected" before the client
+// connected.
+func (c *Client) ConnectionState() string {
+	conn := c.conn()
+	if conn == nil {
+		return "not_connected"
+	}
+	return strings.ToLower(conn.GetState().String())
+}
+
+// conn returns the current gRPC connection, or nil if the client has not
+// connected yet.
+func (c *Client) conn() *grpc.ClientConn {
+	clt, err := c.api()
 	if err != nil {
-		c.log.V(1).Info("health check failed", "error", err)
-		return false
+		return nil
+	}
+	return clt.GetConnection()
+}
+
+// setConnectionState sets the gauge of the given state to 1 and all others to 0.
+func setConnectionState(state connectivity.State) {
+	for _, s := range connectionStates {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		metrics.GRPCConnectionState.WithLabelValues(strings.ToLower(s.String())).Set(value)
 	}
-	return true
 }
 
 // withTimeout returns a context with the configured API timeout.
@@ -153,22 +609,107 @@ func (c *Client) withTimeout(ctx context.Context) (context.Context, context.Canc
 	return context.WithTimeout(ctx, c.apiTimeout)
 }
 
-// GetNodes returns all nodes registered in Teleport.
+// acquire waits until fewer than MaxConcurrentCalls API calls are in flight
+// and returns a function that releases the slot again.
+func (c *Client) acquire(ctx context.Context) (func(), error) {
+	if c.sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case c.sem <- struct{}{}:
+		return func() { <-c.sem }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("failed to wait for a free API call slot: %w", ctx.Err())
+	}
+}
+
+// forEachResource calls fn for every resource of the given kind in each of
+// the namespaces, fetching at most pageSize resources per page (the Teleport
+// default if zero). Only one page of Teleport resources is held in memory at a
+// time, so callers should convert each resource into a smaller representation
+// in fn.
+func forEachResource[T types.ResourceWithLabels](ctx context.Context, clt client.GetResourcesClient, kind string, namespaces []string, pageSize int, fn func(T)) error {
+	for _, namespace := range namespaces {
+		req := &proto.ListResourcesRequest{
+			ResourceType: kind,
+			Namespace:    namespace,
+			Limit:        int32(pageSize),
+		}
+		for {
+			// GetResourcePage also halves the page size if a page exceeds the
+			// maximum gRPC message size
+			page, err := client.GetResourcePage[T](ctx, clt, req)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			for _, resource := range page.Resources {
+				fn(resource)
+			}
+			if page.NextKey == "" || len(page.Resources) == 0 {
+				break
+			}
+			req.StartKey = page.NextKey
+		}
+	}
+	return nil
+}
+
+// observe records the duration and result of the Teleport API call method that
+// started at start and returned err.
+func observe(method string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = ErrorReason(err)
+		metrics.APIErrorsTotal.WithLabelValues(method, ErrorClass(err)).Inc()
+	}
+	metrics.APIRequestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	metrics.APIRequestsTotal.WithLabelValues(method, result).Inc()
+}
+
+// GetNodes returns all nodes registered in Teleport. The result is served
+// from the cache if one is configured for the resource type.
 func (c *Client) GetNodes(ctx context.Context) ([]NodeInfo, error) {
+	return c.nodesCache.get(ctx, c.fetchNodes)
+}
+
+// fetchNodes fetches the nodes from the Teleport API.
+func (c *Client) fetchNodes(ctx context.Context) ([]NodeInfo, error) {
+	result := make([]NodeInfo, 0)
+	if err := c.ForEachNode(ctx, func(node NodeInfo) { result = append(result, node) }); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ForEachNode calls fn for every node registered in Teleport while listing
+// them page by page, without keeping them, so that memory does not grow with
+// the number of nodes. It bypasses the cache.
+func (c *Client) ForEachNode(ctx context.Context, fn func(NodeInfo)) error {
 	c.log.V(1).Info("fetching nodes from Teleport")
 
 	ctx, cancel := c.withTimeout(ctx)
 	defer cancel()
 
-	nodes, err := c.client.GetNodes(ctx, "default")
+	if err := c.injectFault(ctx, "GetNodes"); err != nil {
+		return err
+	}
+
+	release, err := c.acquire(ctx)
 	if err != nil {
-		c.log.Error(err, "failed to get nodes")
-		return nil, err
+		return err
+	}
+	defer release()
+
+	clt, err := c.api()
+	if err != nil {
+		return err
 	}
 
-	result := make([]NodeInfo, 0, len(nodes))
-	for _, node := range nodes {
-		result = append(result, NodeInfo{
+	count := 0
+	start := time.Now()
+	err = forEachResource(ctx, clt, types.KindNode, c.namespaces, c.pageSize, func(node types.Server) {
+		count++
+		fn(NodeInfo{
 			Name:      node.GetName(),
 			Hostname:  node.GetHostname(),
 			Address:   node.GetAddr(),
@@ -176,35 +717,63 @@ func (c *Client) GetNodes(ctx context.Context) ([]NodeInfo, error) {
 			Namespace: node.GetNamespace(),
 			SubKind:   node.GetSubKind(),
 		})
+	})
+	observe("GetNodes", start, err)
+	if err != nil {
+		c.log.Error(err, "failed to get nodes")
+		return err
 	}
 
-	c.log.V(1).Info("fetched nodes", "count", len(result))
-	return result, nil
+	c.log.V(1).Info("fetched nodes", "count", count)
+	return nil
 }
 
-// GetKubeClusters returns all Kubernetes clusters registered in Teleport.
+// GetKubeClusters returns all Kubernetes clusters registered in Teleport. The result is served
+// from the cache if one is configured for the resource type.
 func (c *Client) GetKubeClusters(ctx context.Context) ([]KubeClusterInfo, error) {
+	return c.kubeClustersCache.get(ctx, c.fetchKubeClusters)
+}
+
+// fetchKubeClusters fetches the Kubernetes clusters from the Teleport API.
+func (c *Client) fetchKubeClusters(ctx context.Context) ([]KubeClusterInfo, error) {
 	c.log.V(1).Info("fetching Kubernetes clusters from Teleport")
 
 	ctx, cancel := c.withTimeout(ctx)
 	defer cancel()
 
-	clusters, err := c.client.GetKubernetesServers(ctx)
+	if err := c.injectFault(ctx, "GetKubeClusters"); err != nil {
+		return nil, err
+	}
+
+	release, err := c.acquire(ctx)
 	if err != nil {
-		c.log.Error(err, "failed to get Kubernetes clusters")
 		return nil, err
 	}
+	defer release()
 
-	// Use a map to deduplicate clusters (multiple servers can serve the same cluster)
+	// Use a map to deduplicate clusters (multiple servers can serve the same cluster),
+	// keeping the servers of each
 	clusterMap := make(map[string]KubeClusterInfo)
-	for _, server := range clusters {
+	clt, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	err = forEachResource(ctx, clt, types.KindKubeServer, c.namespaces, c.pageSize, func(server types.KubeServer) {
 		cluster := server.GetCluster()
 		if cluster != nil {
 			clusterMap[cluster.GetName()] = KubeClusterInfo{
-				Name:   cluster.GetName(),
-				Labels: cluster.GetAllLabels(),
+				Name:    cluster.GetName(),
+				Labels:  cluster.GetAllLabels(),
+				Servers: append(clusterMap[cluster.GetName()].Servers, ServerInfo{HostID: server.GetHostID(), Hostname: server.GetHostname()}),
 			}
 		}
+	})
+	observe("GetKubernetesServers", start, err)
+	if err != nil {
+		c.log.Error(err, "failed to get Kubernetes clusters")
+		return nil, err
 	}
 
 	result := make([]KubeClusterInfo, 0, len(clusterMap))
@@ -216,31 +785,57 @@ func (c *Client) GetKubeClusters(ctx context.Context) ([]KubeClusterInfo, error)
 	return result, nil
 }
 
-// GetDatabases returns all databases registered in Teleport.
+// GetDatabases returns all databases registered in Teleport. The result is served
+// from the cache if one is configured for the resource type.
 func (c *Client) GetDatabases(ctx context.Context) ([]DatabaseInfo, error) {
+	return c.databasesCache.get(ctx, c.fetchDatabases)
+}
+
+// fetchDatabases fetches the databases from the Teleport API.
+func (c *Client) fetchDatabases(ctx context.Context) ([]DatabaseInfo, error) {
 	c.log.V(1).Info("fetching databases from Teleport")
 
 	ctx, cancel := c.withTimeout(ctx)
 	defer cancel()
 
-	databases, err := c.client.GetDatabaseServers(ctx, "default")
+	if err := c.injectFault(ctx, "GetDatabases"); err != nil {
+		return nil, err
+	}
+
+	release, err := c.acquire(ctx)
 	if err != nil {
-		c.log.Error(err, "failed to get databases")
 		return nil, err
 	}
+	defer release()
 
-	// Use a map to deduplicate databases (multiple servers can serve the same database)
+	// Use a map to deduplicate databases (multiple servers can serve the same database),
+	// keeping the servers of each
 	dbMap := make(map[string]DatabaseInfo)
-	for _, server := range databases {
+	clt, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	err = forEachResource(ctx, clt, types.KindDatabaseServer, c.namespaces, c.pageSize, func(server types.DatabaseServer) {
 		db := server.GetDatabase()
 		if db != nil {
 			dbMap[db.GetName()] = DatabaseInfo{
 				Name:     db.GetName(),
 				Protocol: db.GetProtocol(),
 				Type:     db.GetType(),
+				Cloud:    databaseCloud(db),
+				URI:      db.GetURI(),
 				Labels:   db.GetAllLabels(),
+				TLSMode:  tlsMode(db.GetTLS().Mode),
+				Servers:  append(dbMap[db.GetName()].Servers, ServerInfo{HostID: server.GetHostID(), Hostname: server.GetHostname()}),
 			}
 		}
+	})
+	observe("GetDatabaseServers", start, err)
+	if err != nil {
+		c.log.Error(err, "failed to get databases")
+		return nil, err
 	}
 
 	result := make([]DatabaseInfo, 0, len(dbMap))
@@ -252,22 +847,70 @@ func (c *Client) GetDatabases(ctx context.Context) ([]DatabaseInfo, error) {
 	return result, nil
 }
 
-// GetApps returns all applications registered in Teleport.
+// tlsMode converts the TLS mode of a database into one of the TLSMode*
+// constants.
+func tlsMode(mode types.DatabaseTLSMode) string {
+	switch mode {
+	case types.DatabaseTLSMode_VERIFY_CA:
+		return TLSModeVerifyCA
+	case types.DatabaseTLSMode_INSECURE:
+		return TLSModeInsecure
+	default:
+		return TLSModeVerifyFull
+	}
+}
+
+// databaseCloud returns the DatabaseCloud* constant of where db is hosted.
+// MongoDB Atlas is not a cloud provider to Teleport, but not self-hosted
+// either.
+func databaseCloud(db types.Database) string {
+	switch db.GetCloud() {
+	case types.CloudAWS:
+		return DatabaseCloudAWS
+	case types.CloudGCP:
+		return DatabaseCloudGCP
+	case types.CloudAzure:
+		return DatabaseCloudAzure
+	}
+	if db.GetType() == types.DatabaseTypeMongoAtlas {
+		return DatabaseCloudMongoAtlas
+	}
+	return DatabaseCloudSelfHosted
+}
+
+// GetApps returns all applications registered in Teleport. The result is served
+// from the cache if one is configured for the resource type.
 func (c *Client) GetApps(ctx context.Context) ([]AppInfo, error) {
+	return c.appsCache.get(ctx, c.fetchApps)
+}
+
+// fetchApps fetches the applications from the Teleport API.
+func (c *Client) fetchApps(ctx context.Context) ([]AppInfo, error) {
 	c.log.V(1).Info("fetching applications from Teleport")
 
 	ctx, cancel := c.withTimeout(ctx)
 	defer cancel()
 
-	servers, err := c.client.GetApplicationServers(ctx, "default")
+	if err := c.injectFault(ctx, "GetApps"); err != nil {
+		return nil, err
+	}
+
+	release, err := c.acquire(ctx)
 	if err != nil {
-		c.log.Error(err, "failed to get applications")
 		return nil, err
 	}
+	defer release()
 
-	// Use a map to deduplicate apps (multiple servers can serve the same app)
+	// Use a map to deduplicate apps (multiple servers can serve the same app),
+	// keeping the servers of each
 	appMap := make(map[string]AppInfo)
-	for _, server := range servers {
+	clt, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	err = forEachResource(ctx, clt, types.KindAppServer, c.namespaces, c.pageSize, func(server types.AppServer) {
 		app := server.GetApp()
 		if app != nil {
 			appMap[app.GetName()] = AppInfo{
@@ -275,8 +918,14 @@ func (c *Client) GetApps(ctx context.Context) ([]AppInfo, error) {
 				PublicAddr: app.GetPublicAddr(),
 				URI:        app.GetURI(),
 				Labels:     app.GetAllLabels(),
+				Servers:    append(appMap[app.GetName()].Servers, ServerInfo{HostID: server.GetHostID(), Hostname: server.GetHostname()}),
 			}
 		}
+	})
+	observe("GetApplicationServers", start, err)
+	if err != nil {
+		c.log.Error(err, "failed to get applications")
+		return nil, err
 	}
 
 	result := make([]AppInfo, 0, len(appMap))
@@ -293,7 +942,24 @@ func (c *Client) GetClusterName(ctx context.Context) (string, error) {
 	ctx, cancel := c.withTimeout(ctx)
 	defer cancel()
 
-	cn, err := c.client.GetClusterName(ctx)
+	if err := c.injectFault(ctx, "GetClusterName"); err != nil {
+		return "", err
+	}
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	clt, err := c.api()
+	if err != nil {
+		return "", err
+	}
+
+	start := time.Now()
+	cn, err := clt.GetClusterName(ctx)
+	observe("GetClusterName", start, err)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,238 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/gravitational/teleport/api/client"
+	"github.com/gravitational/teleport/api/client/proxy"
+	"github.com/gravitational/teleport/api/client/webclient"
+	apidefaults "github.com/gravitational/teleport/api/defaults"
+	"google.golang.org/grpc"
+)
+
+const (
+	// ConnectionModeAuto pings the address as a proxy and connects through it
+	// with the settings the proxy reports. If the address is not a proxy, all
+	// ways to connect to it are tried at once and the first that succeeds is
+	// used.
+	ConnectionModeAuto = "auto"
+	// ConnectionModeProxy connects to the auth server through the proxy at
+	// the address.
+	ConnectionModeProxy = "proxy"
+	// ConnectionModeAuth connects directly to the auth server at the address.
+	ConnectionModeAuth = "auth"
+)
+
+// apiConfig returns the Teleport API client configuration for the given
+// connection mode, and a function closing the connection to the proxy the
+// client is tunneled through, if any.
+func apiConfig(ctx context.Context, cfg Config, base client.Config) (client.Config, func() error, error) {
+	noop := func() error { return nil }
+
+	switch cfg.ConnectionMode {
+	case "", ConnectionModeAuto:
+		ping, err := pingProxy(ctx, cfg)
+		if err != nil {
+			cfg.Log.V(1).Info("address is not a Teleport proxy, trying all connection methods", "error", err)
+			return fallbackConfig(ctx, cfg, base)
+		}
+		apiCfg, closeProxy, err := proxyConfig(ctx, cfg, base, ping)
+		if errors.Is(err, errNoSSHCredentials) {
+			cfg.Log.V(1).Info("credentials cannot be tunneled through the proxy, trying all connection methods", "error", err)
+			return fallbackConfig(ctx, cfg, base)
+		}
+		return apiCfg, closeProxy, err
+	case ConnectionModeAuth:
+		dialer, err := addrDialer(ctx, cfg, cfg.ALPNConnUpgrade)
+		if err != nil {
+			return client.Config{}, nil, err
+		}
+		base.Dialer = dialer
+		return base, noop, nil
+	case ConnectionModeProxy:
+		ping, err := pingProxy(ctx, cfg)
+		if err != nil {
+			return client.Config{}, nil, fmt.Errorf("failed to discover proxy settings: %w", err)
+		}
+		return proxyConfig(ctx, cfg, base, ping)
+	default:
+		return client.Config{}, nil, fmt.Errorf("unsupported connection mode %q, must be %q, %q or %q",
+			cfg.ConnectionMode, ConnectionModeAuto, ConnectionModeProxy, ConnectionModeAuth)
+	}
+}
+
+// fallbackConfig returns a configuration that tries all ways to connect to
+// the configured address at once. Only the direct connection to the auth
+// server can be tunneled through cfg.ProxyURL, so with a proxy URL that is
+// the only one tried.
+func fallbackConfig(ctx context.Context, cfg Config, base client.Config) (client.Config, func() error, error) {
+	noop := func() error { return nil }
+	if cfg.ProxyURL == nil {
+		base.Addrs = []string{cfg.ProxyAddr}
+		return base, noop, nil
+	}
+	dialer, err := addrDialer(ctx, cfg, cfg.ALPNConnUpgrade)
+	if err != nil {
+		return client.Config{}, nil, err
+	}
+	base.Dialer = dialer
+	return base, noop, nil
+}
+
+// errALPNConnUpgradeThroughProxy is returned by addrDialer if an ALPN
+// connection upgrade is required together with cfg.ProxyURL.
+var errALPNConnUpgradeThroughProxy = errors.New("the ALPN connection upgrade cannot be combined with a proxy URL, set HTTPS_PROXY instead")
+
+// addrDialer returns a dialer that always dials the configured address,
+// optionally through an ALPN connection upgrade or cfg.ProxyURL.
+func addrDialer(ctx context.Context, cfg Config, alpnConnUpgrade bool) (client.ContextDialer, error) {
+	var dialer client.ContextDialer
+	if cfg.ProxyURL != nil {
+		// The Teleport API client implements the upgrade only on top of
+		// its own dialer
+		if alpnConnUpgrade {
+			return nil, errALPNConnUpgradeThroughProxy
+		}
+		dialer = proxyDialer(cfg)
+	} else {
+		dialer = client.NewDialer(ctx, dialKeepAlive(cfg), dialTimeout(cfg),
+			client.WithInsecureSkipVerify(cfg.Insecure),
+			client.WithALPNConnUpgrade(alpnConnUpgrade),
+			// Use the Ping protocol for the long-lived connection
+			client.WithALPNConnUpgradePing(true),
+		)
+	}
+	return client.ContextDialerFunc(func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, cfg.ProxyAddr)
+	}), nil
+}
+
+// dialKeepAlive returns the keepalive period of the connection to Teleport.
+func dialKeepAlive(cfg Config) time.Duration {
+	if cfg.KeepAliveTime <= 0 {
+		return apidefaults.ServerKeepAliveTTL()
+	}
+	return cfg.KeepAliveTime
+}
+
+// dialTimeout returns the timeout of each attempt to dial Teleport.
+func dialTimeout(cfg Config) time.Duration {
+	if cfg.DialTimeout <= 0 {
+		return apidefaults.DefaultIOTimeout
+	}
+	return cfg.DialTimeout
+}
+
+// pingProxy calls the /webapi/ping endpoint of the proxy at the configured
+// address, which reports the cluster name and how the proxy listens.
+func pingProxy(ctx context.Context, cfg Config) (*webclient.PingResponse, error) {
+	if cfg.ProxyURL != nil {
+		return pingThroughProxy(ctx, cfg)
+	}
+	return webclient.Ping(&webclient.Config{
+		Context:   ctx,
+		ProxyAddr: cfg.ProxyAddr,
+		Insecure:  cfg.Insecure,
+	})
+}
+
+// errNoSSHCredentials is returned by proxyConfig for proxies without TLS
+// routing if the credentials contain no SSH certificate to connect with.
+var errNoSSHCredentials = errors.New("proxies without TLS routing require credentials with an SSH certificate")
+
+// proxyConfig returns a configuration that reaches the auth server through
+// the proxy described by ping. With TLS routing, the proxy routes the
+// connection to the auth server by ALPN. Otherwise, the connection is
+// tunneled through the transport service of the proxy, which the returned
+// function disconnects from.
+func proxyConfig(ctx context.Context, cfg Config, base client.Config, ping *webclient.PingResponse) (client.Config, func() error, error) {
+	// Load balancers in front of TLS routing proxies may strip ALPN, which
+	// requires tunneling the connection through an HTTP upgrade. The check
+	// cannot dial through cfg.ProxyURL, so it is skipped with a proxy URL.
+	alpnConnUpgrade := cfg.ALPNConnUpgrade
+	if ping.Proxy.TLSRoutingEnabled && !alpnConnUpgrade && cfg.ProxyURL == nil {
+		alpnConnUpgrade = client.IsALPNConnUpgradeRequired(ctx, cfg.ProxyAddr, cfg.Insecure)
+	}
+	cfg.Log.Info("detected Teleport proxy",
+		"clusterName", ping.ClusterName,
+		"serverVersion", ping.ServerVersion,
+		"tlsRouting", ping.Proxy.TLSRoutingEnabled,
+		"alpnConnUpgrade", alpnConnUpgrade,
+	)
+
+	if ping.Proxy.TLSRoutingEnabled {
+		dialer, err := addrDialer(ctx, cfg, alpnConnUpgrade)
+		if err != nil {
+			return client.Config{}, nil, err
+		}
+		base.Dialer = dialer
+		base.ALPNSNIAuthDialClusterName = ping.ClusterName
+		base.ALPNConnUpgradeRequired = alpnConnUpgrade
+		return base, func() error { return nil }, nil
+	}
+
+	creds := base.Credentials[0]
+	sshConfig, err := creds.SSHClientConfig()
+	if err != nil {
+		return client.Config{}, nil, fmt.Errorf("%w: %w", errNoSSHCredentials, err)
+	}
+	sshHost, sshPort, err := ping.Proxy.SSHProxyHostPort()
+	if err != nil {
+		return client.Config{}, nil, fmt.Errorf("failed to get proxy SSH address: %w", err)
+	}
+
+	var dialOpts []grpc.DialOption
+	if cfg.ProxyURL != nil {
+		// Dial options take precedence over the dialer of the proxy client
+		dialOpts = append(dialOpts, grpc.WithContextDialer(client.GRPCContextDialer(proxyDialer(cfg))))
+	}
+	proxyClient, err := proxy.NewClient(ctx, proxy.ClientConfig{
+		ProxyAddress: net.JoinHostPort(sshHost, sshPort),
+		TLSConfigFunc: func(string) (*tls.Config, error) {
+			tlsConfig, err := creds.TLSConfig()
+			if err != nil {
+				return nil, err
+			}
+			return tlsConfig.Clone(), nil
+		},
+		SSHConfig:          sshConfig,
+		DialTimeout:        cfg.DialTimeout,
+		DialOpts:           dialOpts,
+		InsecureSkipVerify: cfg.Insecure,
+	})
+	if err != nil {
+		return client.Config{}, nil, fmt.Errorf("failed to connect to proxy: %w", err)
+	}
+
+	proxied, err := proxyClient.ClientConfig(ctx, ping.ClusterName)
+	if err != nil {
+		return client.Config{}, nil, errors.Join(
+			fmt.Errorf("failed to get auth server configuration from proxy: %w", err), proxyClient.Close())
+	}
+
+	// Keep the settings of the exporter, but dial through the proxy
+	base.Dialer = proxied.Dialer
+	base.Credentials = proxied.Credentials
+	return base, proxyClient.Close, nil
+}
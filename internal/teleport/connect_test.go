@@ -0,0 +1,353 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gravitational/teleport/api/client"
+	"golang.org/x/crypto/ssh"
+)
+
+// noSSHCredentials are credentials without an SSH configuration.
+type noSSHCredentials struct{}
+
+func (noSSHCredentials) TLSConfig() (*tls.Config, error) { return &tls.Config{}, nil }
+func (noSSHCredentials) SSHClientConfig() (*ssh.ClientConfig, error) {
+	return nil, errors.New("no SSH certificate")
+}
+func (noSSHCredentials) Expiry() (time.Time, bool) { return time.Time{}, false }
+
+func TestAPIConfig(t *testing.T) {
+	// Nothing listens on port 1, so the address is not a proxy
+	const unreachable = "127.0.0.1:1"
+
+	tests := []struct {
+		mode       string
+		wantAddrs  bool
+		wantDialer bool
+		wantErr    bool
+	}{
+		{mode: "", wantAddrs: true},
+		{mode: ConnectionModeAuto, wantAddrs: true},
+		{mode: ConnectionModeAuth, wantDialer: true},
+		{mode: ConnectionModeProxy, wantErr: true},
+		{mode: "tunnel", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg := Config{ProxyAddr: unreachable, ConnectionMode: tt.mode}
+			apiCfg, closeProxy, err := apiConfig(context.Background(), cfg, client.Config{})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("apiConfig() failed: %v", err)
+			}
+			if err := closeProxy(); err != nil {
+				t.Errorf("closeProxy() failed: %v", err)
+			}
+			if got := len(apiCfg.Addrs) == 1 && apiCfg.Addrs[0] == cfg.ProxyAddr; got != tt.wantAddrs {
+				t.Errorf("expected address discovery %v, got addrs %v", tt.wantAddrs, apiCfg.Addrs)
+			}
+			if got := apiCfg.Dialer != nil; got != tt.wantDialer {
+				t.Errorf("expected dialer %v, got %v", tt.wantDialer, got)
+			}
+		})
+	}
+}
+
+// newPingServer returns a fake proxy answering /webapi/ping.
+func newPingServer(t *testing.T, tlsRouting bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/webapi/ping" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"cluster_name":"example","server_version":"18.0.0","proxy":{"tls_routing_enabled":%t,"ssh":{"ssh_public_addr":"127.0.0.1:3023"}}}`, tlsRouting)
+	}))
+	// The ALPN connection upgrade check fails the handshake, which is expected
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAPIConfig_TLSRouting(t *testing.T) {
+	srv := newPingServer(t, true)
+
+	for _, mode := range []string{ConnectionModeAuto, ConnectionModeProxy} {
+		t.Run(mode, func(t *testing.T) {
+			cfg := Config{
+				ProxyAddr:      strings.TrimPrefix(srv.URL, "https://"),
+				Insecure:       true,
+				ConnectionMode: mode,
+			}
+			apiCfg, _, err := apiConfig(context.Background(), cfg, client.Config{
+				Credentials: []client.Credentials{noSSHCredentials{}},
+			})
+			if err != nil {
+				t.Fatalf("apiConfig() failed: %v", err)
+			}
+			// The proxy routes to the auth server of the pinged cluster by ALPN
+			if apiCfg.ALPNSNIAuthDialClusterName != "example" {
+				t.Errorf("expected ALPN routing to cluster example, got %q", apiCfg.ALPNSNIAuthDialClusterName)
+			}
+			if apiCfg.Dialer == nil || len(apiCfg.Addrs) != 0 {
+				t.Errorf("expected only the proxy to be dialed, got dialer %v and addrs %v", apiCfg.Dialer != nil, apiCfg.Addrs)
+			}
+		})
+	}
+}
+
+func TestAPIConfig_NoTLSRoutingWithoutSSH(t *testing.T) {
+	srv := newPingServer(t, false)
+	cfg := Config{
+		ProxyAddr: strings.TrimPrefix(srv.URL, "https://"),
+		Insecure:  true,
+	}
+	base := client.Config{Credentials: []client.Credentials{noSSHCredentials{}}}
+
+	// Without an SSH certificate, auto falls back to trying all methods
+	cfg.ConnectionMode = ConnectionModeAuto
+	apiCfg, _, err := apiConfig(context.Background(), cfg, base)
+	if err != nil {
+		t.Fatalf("apiConfig() failed: %v", err)
+	}
+	if len(apiCfg.Addrs) != 1 || apiCfg.Dialer != nil {
+		t.Errorf("expected address discovery, got addrs %v and dialer %v", apiCfg.Addrs, apiCfg.Dialer != nil)
+	}
+
+	// ... while the proxy mode fails
+	cfg.ConnectionMode = ConnectionModeProxy
+	if _, _, err := apiConfig(context.Background(), cfg, base); !errors.Is(err, errNoSSHCredentials) {
+		t.Errorf("expected errNoSSHCredentials, got %v", err)
+	}
+}
+
+// newConnectProxy returns an HTTP CONNECT proxy and the number of tunnels it
+// opened.
+func newConnectProxy(t *testing.T) (*url.URL, *atomic.Int32) {
+	t.Helper()
+	var tunnels atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		tunnels.Add(1)
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go func() {
+			_, _ = io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		go func() {
+			_, _ = io.Copy(conn, upstream)
+			conn.Close()
+		}()
+	}))
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u, &tunnels
+}
+
+// newSOCKS5Proxy returns a SOCKS5 proxy without authentication and the
+// number of tunnels it opened.
+func newSOCKS5Proxy(t *testing.T) (*url.URL, *atomic.Int32) {
+	t.Helper()
+	var tunnels atomic.Int32
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				upstream, err := socks5Handshake(conn)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				tunnels.Add(1)
+				go func() {
+					_, _ = io.Copy(upstream, conn)
+					upstream.Close()
+				}()
+				_, _ = io.Copy(conn, upstream)
+				conn.Close()
+			}()
+		}
+	}()
+	return &url.URL{Scheme: "socks5", Host: ln.Addr().String()}, &tunnels
+}
+
+// socks5Handshake answers the SOCKS5 greeting and CONNECT request of conn
+// (RFC 1928) and dials the requested address.
+func socks5Handshake(conn net.Conn) (net.Conn, error) {
+	// Version, number of methods and the methods; no authentication
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write([]byte{5, 0}); err != nil {
+		return nil, err
+	}
+
+	// Version, command, reserved and address type
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return nil, err
+	}
+	var host string
+	switch request[3] {
+	case 1:
+		ip := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return nil, err
+		}
+		host = net.IP(ip).String()
+	case 3:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return nil, err
+		}
+		name := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return nil, err
+		}
+		host = string(name)
+	default:
+		return nil, fmt.Errorf("unsupported address type %d", request[3])
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return nil, err
+	}
+
+	upstream, err := net.Dial("tcp", net.JoinHostPort(host, fmt.Sprint(int(port[0])<<8|int(port[1]))))
+	if err != nil {
+		_, _ = conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+		return nil, err
+	}
+	if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+		upstream.Close()
+		return nil, err
+	}
+	return upstream, nil
+}
+
+func TestAPIConfig_ProxyURL(t *testing.T) {
+	srv := newPingServer(t, true)
+	ctx := context.Background()
+	base := client.Config{Credentials: []client.Credentials{noSSHCredentials{}}}
+
+	proxies := map[string]func(*testing.T) (*url.URL, *atomic.Int32){
+		"http":   newConnectProxy,
+		"socks5": newSOCKS5Proxy,
+	}
+	for name, newProxy := range proxies {
+		t.Run(name, func(t *testing.T) {
+			proxyURL, tunnels := newProxy(t)
+			cfg := Config{
+				ProxyAddr: strings.TrimPrefix(srv.URL, "https://"),
+				Insecure:  true,
+				ProxyURL:  proxyURL,
+			}
+			apiCfg, _, err := apiConfig(ctx, cfg, base)
+			if err != nil {
+				t.Fatalf("apiConfig() failed: %v", err)
+			}
+			if tunnels.Load() != 1 {
+				t.Errorf("expected the ping to be tunneled through the proxy, got %d tunnels", tunnels.Load())
+			}
+			if apiCfg.ALPNSNIAuthDialClusterName != "example" {
+				t.Errorf("expected ALPN routing to cluster example, got %q", apiCfg.ALPNSNIAuthDialClusterName)
+			}
+
+			// The auth server is dialed through the proxy as well
+			conn, err := apiCfg.Dialer.DialContext(ctx, "tcp", "ignored")
+			if err != nil {
+				t.Fatalf("DialContext() failed: %v", err)
+			}
+			conn.Close()
+			if tunnels.Load() != 2 {
+				t.Errorf("expected the auth server to be dialed through the proxy, got %d tunnels", tunnels.Load())
+			}
+		})
+	}
+}
+
+func TestAPIConfig_ProxyURLFallback(t *testing.T) {
+	proxyURL, _ := newConnectProxy(t)
+	ctx := context.Background()
+	base := client.Config{Credentials: []client.Credentials{noSSHCredentials{}}}
+
+	// Of all connection methods, only the direct one can be tunneled
+	cfg := Config{ProxyAddr: "127.0.0.1:1", ProxyURL: proxyURL}
+	apiCfg, _, err := apiConfig(ctx, cfg, base)
+	if err != nil {
+		t.Fatalf("apiConfig() failed: %v", err)
+	}
+	if apiCfg.Dialer == nil || len(apiCfg.Addrs) != 0 {
+		t.Errorf("expected only the address to be dialed, got dialer %v and addrs %v", apiCfg.Dialer != nil, apiCfg.Addrs)
+	}
+
+	cfg.ConnectionMode = ConnectionModeAuth
+	cfg.ALPNConnUpgrade = true
+	if _, _, err := apiConfig(ctx, cfg, base); !errors.Is(err, errALPNConnUpgradeThroughProxy) {
+		t.Errorf("expected errALPNConnUpgradeThroughProxy, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"errors"
+
+	"github.com/gravitational/teleport/api/client"
+)
+
+// Credential sources, in the order they are tried.
+const (
+	CredentialsIdentityFile    = "identity_file"
+	CredentialsIdentityContent = "identity_content"
+	CredentialsKeyPair         = "key_pair"
+	CredentialsProfile         = "profile"
+)
+
+// credentialSource is a named source of credentials.
+type credentialSource struct {
+	name  string
+	creds client.Credentials
+}
+
+// credentialSources returns the configured sources of credentials in priority
+// order: the identity file, the identity content, the key pair and the tsh
+// profile.
+func credentialSources(cfg Config) []credentialSource {
+	var sources []credentialSource
+	if cfg.IdentityFile != "" {
+		sources = append(sources, credentialSource{CredentialsIdentityFile, client.LoadIdentityFile(cfg.IdentityFile)})
+	}
+	if cfg.IdentityContent != nil {
+		sources = append(sources, credentialSource{CredentialsIdentityContent, client.LoadIdentityFileFromString(cfg.IdentityContent())})
+	}
+	if cfg.CertFile != "" {
+		sources = append(sources, credentialSource{CredentialsKeyPair, client.LoadKeyPair(cfg.CertFile, cfg.KeyFile, cfg.CAFile)})
+	}
+	if cfg.Profile != "" {
+		sources = append(sources, credentialSource{CredentialsProfile, client.LoadProfile(cfg.ProfileDir, cfg.Profile)})
+	}
+	return sources
+}
+
+// ValidateCredentials checks that at least one source of credentials is
+// configured: an identity file, identity content, a key pair or a tsh profile.
+func ValidateCredentials(cfg Config) error {
+	if cfg.IdentityFile == "" && cfg.IdentityContent == nil && cfg.CertFile == "" && cfg.Profile == "" {
+		return errors.New("at least one of identity-file, cert-file or profile is required")
+	}
+	if cfg.CertFile != "" && (cfg.KeyFile == "" || cfg.CAFile == "") {
+		return errors.New("cert-file requires key-file and ca-file")
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gravitational/teleport/api/client"
+)
+
+func TestCredentialSources(t *testing.T) {
+	sources := credentialSources(Config{
+		Profile:      "teleport.example.com",
+		CertFile:     "tls.crt",
+		KeyFile:      "tls.key",
+		CAFile:       "ca.crt",
+		IdentityFile: "identity",
+		IdentityContent: func() string {
+			return "identity"
+		},
+	})
+	var names []string
+	for _, source := range sources {
+		names = append(names, source.name)
+	}
+	want := []string{CredentialsIdentityFile, CredentialsIdentityContent, CredentialsKeyPair, CredentialsProfile}
+	if !slices.Equal(names, want) {
+		t.Errorf("credentialSources() = %v, want %v", names, want)
+	}
+	if _, ok := sources[3].creds.(client.CredentialsWithDefaultAddrs); !ok {
+		t.Error("expected profile credentials for the profile source")
+	}
+
+	if sources := credentialSources(Config{IdentityFile: "identity"}); len(sources) != 1 || sources[0].name != CredentialsIdentityFile {
+		t.Errorf("expected only the identity file source, got %v", sources)
+	}
+}
+
+func TestValidateCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "identity file", cfg: Config{IdentityFile: "identity"}},
+		{name: "key pair", cfg: Config{CertFile: "tls.crt", KeyFile: "tls.key", CAFile: "ca.crt"}},
+		{name: "profile", cfg: Config{Profile: "teleport.example.com"}},
+		{name: "none", cfg: Config{}, wantErr: true},
+		{name: "identity file and profile", cfg: Config{IdentityFile: "identity", Profile: "teleport.example.com"}},
+		{name: "key pair without CA", cfg: Config{CertFile: "tls.crt", KeyFile: "tls.key"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateCredentials(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCredentials_KeyPair(t *testing.T) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "exporter"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	cfg := Config{
+		CertFile: filepath.Join(dir, "tls.crt"),
+		KeyFile:  filepath.Join(dir, "tls.key"),
+		CAFile:   filepath.Join(dir, "ca.crt"),
+	}
+	for path, data := range map[string][]byte{
+		cfg.CertFile: certPEM,
+		cfg.KeyFile:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+		cfg.CAFile:   certPEM,
+	} {
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	creds := credentialSources(cfg)[0].creds
+	tlsConfig, err := creds.TLSConfig()
+	if err != nil {
+		t.Fatalf("TLSConfig() failed: %v", err)
+	}
+	if tlsConfig.GetClientCertificate == nil || tlsConfig.RootCAs == nil {
+		t.Error("expected the client certificate and CA to be loaded")
+	}
+	// Key pairs cannot connect through proxies without TLS routing
+	if _, err := creds.SSHClientConfig(); err == nil {
+		t.Error("expected no SSH configuration for a key pair")
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/gravitational/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error reasons returned by ErrorReason.
+const (
+	ErrorReasonTimeout          = "timeout"
+	ErrorReasonPermissionDenied = "permission_denied"
+	ErrorReasonConnection       = "connection"
+	ErrorReasonCredentials      = "credentials"
+	ErrorReasonOther            = "other"
+)
+
+// Error classes returned by ErrorClass.
+const (
+	ErrorClassTimeout           = "timeout"
+	ErrorClassPermissionDenied  = "permission_denied"
+	ErrorClassRateLimited       = "rate_limited"
+	ErrorClassNotFound          = "not_found"
+	ErrorClassAuthExpired       = "auth_expired"
+	ErrorClassTLS               = "tls"
+	ErrorClassConnectionRefused = "connection_refused"
+	ErrorClassConnection        = "connection"
+	ErrorClassOther             = "other"
+)
+
+// ErrorReason classifies an error returned by the Client into one of the
+// ErrorReason* constants, e.g. for use as a metric label.
+func ErrorReason(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded:
+		return ErrorReasonTimeout
+	case trace.IsAccessDenied(err):
+		return ErrorReasonPermissionDenied
+	case isCredentialError(err):
+		return ErrorReasonCredentials
+	case trace.IsConnectionProblem(err) || status.Code(err) == codes.Unavailable:
+		return ErrorReasonConnection
+	default:
+		return ErrorReasonOther
+	}
+}
+
+// ErrorClass classifies an error returned by the Client into one of the
+// ErrorClass* constants. The classes are finer than the reasons of
+// ErrorReason, to tell why API calls fail, e.g. in dashboards.
+func ErrorClass(err error) string {
+	switch {
+	case ErrorReason(err) == ErrorReasonTimeout:
+		return ErrorClassTimeout
+	case trace.IsAccessDenied(err):
+		return ErrorClassPermissionDenied
+	case trace.IsLimitExceeded(err) || status.Code(err) == codes.ResourceExhausted:
+		return ErrorClassRateLimited
+	case trace.IsNotFound(err) || status.Code(err) == codes.NotFound:
+		return ErrorClassNotFound
+	case errorContains(err, expiredCertErrors):
+		return ErrorClassAuthExpired
+	case errorContains(err, tlsErrors):
+		return ErrorClassTLS
+	case errors.Is(err, syscall.ECONNREFUSED) || errorContains(err, connectionRefusedErrors):
+		return ErrorClassConnectionRefused
+	case ErrorReason(err) == ErrorReasonConnection:
+		return ErrorClassConnection
+	default:
+		return ErrorClassOther
+	}
+}
+
+// expiredCertErrors are the messages of TLS errors caused by an expired
+// client certificate.
+var expiredCertErrors = []string{
+	"expired certificate",
+	"certificate expired",
+	"certificate has expired",
+}
+
+// credentialErrors are the messages of TLS errors, usually surfaced as
+// unavailable connections, that reloaded credentials may fix: our certificate
+// expired, or the cluster CAs were rotated.
+var credentialErrors = append(slices.Clone(expiredCertErrors),
+	"bad certificate",
+	"unknown certificate authority",
+	"certificate signed by unknown authority",
+)
+
+// tlsErrors are the prefixes of TLS and certificate verification errors.
+var tlsErrors = []string{
+	"tls:",
+	"x509:",
+}
+
+// connectionRefusedErrors are the messages of refused connections, which
+// gRPC reports as text only.
+var connectionRefusedErrors = []string{
+	"connection refused",
+}
+
+// isCredentialError reports whether err indicates that Teleport rejected the
+// client certificate or the server certificate is signed by an unknown CA.
+func isCredentialError(err error) bool {
+	return errorContains(err, credentialErrors)
+}
+
+// errorContains reports whether the message of err contains any of msgs.
+// Wrapped errors are checked too, since trace errors hide their message.
+func errorContains(err error, msgs []string) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	for _, s := range msgs {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	switch err := err.(type) {
+	case interface{ Unwrap() error }:
+		return errorContains(err.Unwrap(), msgs)
+	case interface{ Unwrap() []error }:
+		return slices.ContainsFunc(err.Unwrap(), func(err error) bool { return errorContains(err, msgs) })
+	}
+	return false
+}
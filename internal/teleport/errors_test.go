@@ -0,0 +1,85 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"context deadline", fmt.Errorf("get nodes: %w", context.DeadlineExceeded), ErrorReasonTimeout},
+		{"grpc deadline", status.Error(codes.DeadlineExceeded, "deadline exceeded"), ErrorReasonTimeout},
+		{"access denied", trace.AccessDenied("access to node denied"), ErrorReasonPermissionDenied},
+		{"connection problem", trace.ConnectionProblem(errors.New("connection refused"), "failed to connect"), ErrorReasonConnection},
+		{"grpc unavailable", status.Error(codes.Unavailable, "unavailable"), ErrorReasonConnection},
+		{"expired certificate", status.Error(codes.Unavailable, "connection error: remote error: tls: expired certificate"), ErrorReasonCredentials},
+		{"bad certificate", trace.ConnectionProblem(errors.New("remote error: tls: bad certificate"), "failed to connect"), ErrorReasonCredentials},
+		{"rotated CA", status.Error(codes.Unavailable, "tls: failed to verify certificate: x509: certificate signed by unknown authority"), ErrorReasonCredentials},
+		{"unknown CA alert", status.Error(codes.Unavailable, "remote error: tls: unknown certificate authority"), ErrorReasonCredentials},
+		{"other", errors.New("boom"), ErrorReasonOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := ErrorReason(tt.err); result != tt.expected {
+				t.Errorf("ErrorReason(%v) = %q, expected %q", tt.err, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"context deadline", fmt.Errorf("get nodes: %w", context.DeadlineExceeded), ErrorClassTimeout},
+		{"access denied", trace.AccessDenied("access to node denied"), ErrorClassPermissionDenied},
+		{"limit exceeded", trace.LimitExceeded("too many requests"), ErrorClassRateLimited},
+		{"grpc resource exhausted", status.Error(codes.ResourceExhausted, "rate limit exceeded"), ErrorClassRateLimited},
+		{"not found", trace.NotFound("cluster name not found"), ErrorClassNotFound},
+		{"expired certificate", status.Error(codes.Unavailable, "connection error: remote error: tls: expired certificate"), ErrorClassAuthExpired},
+		{"rotated CA", status.Error(codes.Unavailable, "tls: failed to verify certificate: x509: certificate signed by unknown authority"), ErrorClassTLS},
+		{"handshake failure", trace.ConnectionProblem(errors.New("remote error: tls: handshake failure"), "failed to connect"), ErrorClassTLS},
+		{"connection refused", status.Error(codes.Unavailable, "connection error: desc = \"transport: Error while dialing: dial tcp 10.0.0.1:3025: connect: connection refused\""), ErrorClassConnectionRefused},
+		{"wrapped connection refused", trace.ConnectionProblem(fmt.Errorf("dial: %w", syscall.ECONNREFUSED), "failed to connect"), ErrorClassConnectionRefused},
+		{"grpc unavailable", status.Error(codes.Unavailable, "unavailable"), ErrorClassConnection},
+		{"other", errors.New("boom"), ErrorClassOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := ErrorClass(tt.err); result != tt.expected {
+				t.Errorf("ErrorClass(%v) = %q, expected %q", tt.err, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+// faultKinds are the faults that can be injected, named by the ErrorClass of
+// the injected error.
+var faultKinds = []string{
+	ErrorClassTimeout,
+	ErrorClassPermissionDenied,
+	ErrorClassConnection,
+	ErrorClassRateLimited,
+	ErrorClassNotFound,
+	ErrorClassAuthExpired,
+}
+
+// faultMethods are the API calls faults can be injected into.
+var faultMethods = []string{"GetClusterName", "GetNodes", "GetKubeClusters", "GetDatabases", "GetApps", "GetUsers", "GetLocks", "GetRoles", "GetTokens", "GetAccessRequests", "GetSessions", "GetRemoteClusters", "GetCertAuthorities", "CheckAccess"}
+
+// Faults injects errors into the API calls of the Client, to test backoff and
+// partial failures deterministically. A nil *Faults injects nothing.
+type Faults struct {
+	mu     sync.Mutex
+	faults map[string]*fault
+}
+
+// fault is the error injected into the calls of one method.
+type fault struct {
+	kind string
+	// count is the number of calls that fail before the method recovers, 0
+	// to fail all calls.
+	count int
+	calls int
+}
+
+// ParseFaults parses a comma-separated list of method=fault[:count] pairs,
+// e.g. "GetNodes=timeout:3,GetApps=permission_denied": the first count calls
+// of the method fail with an error of the fault's ErrorClass, or all calls
+// without a count. It returns nil for an empty list.
+func ParseFaults(s string) (*Faults, error) {
+	faults := make(map[string]*fault)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		method, spec, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid fault %q, must be method=fault[:count]", item)
+		}
+		if !slices.Contains(faultMethods, method) {
+			return nil, fmt.Errorf("invalid fault method %q, must be one of %s", method, strings.Join(faultMethods, ", "))
+		}
+		kind, countStr, hasCount := strings.Cut(spec, ":")
+		if !slices.Contains(faultKinds, kind) {
+			return nil, fmt.Errorf("invalid fault %q, must be one of %s", kind, strings.Join(faultKinds, ", "))
+		}
+		f := &fault{kind: kind}
+		if hasCount {
+			count, err := strconv.Atoi(countStr)
+			if err != nil || count <= 0 {
+				return nil, fmt.Errorf("invalid fault count %q, must be a positive number", countStr)
+			}
+			f.count = count
+		}
+		faults[method] = f
+	}
+	if len(faults) == 0 {
+		return nil, nil
+	}
+	return &Faults{faults: faults}, nil
+}
+
+// inject returns the error of the fault of method, or nil if the call should
+// go through. A timeout waits for the deadline of ctx.
+func (f *Faults) inject(ctx context.Context, method string) error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	flt, ok := f.faults[method]
+	if ok {
+		flt.calls++
+		ok = flt.count == 0 || flt.calls <= flt.count
+	}
+	f.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	msg := "injected fault in " + method
+	switch flt.kind {
+	case ErrorClassTimeout:
+		if _, hasDeadline := ctx.Deadline(); hasDeadline {
+			<-ctx.Done()
+		}
+		return fmt.Errorf("%s: %w", msg, context.DeadlineExceeded)
+	case ErrorClassPermissionDenied:
+		return trace.AccessDenied("%s: access denied", msg)
+	case ErrorClassConnection:
+		return trace.ConnectionProblem(nil, "%s: connection reset", msg)
+	case ErrorClassRateLimited:
+		return trace.LimitExceeded("%s: too many requests", msg)
+	case ErrorClassNotFound:
+		return trace.NotFound("%s: not found", msg)
+	default:
+		return errors.New(msg + ": remote error: tls: expired certificate")
+	}
+}
+
+// injectFault returns the error injected into the call of method, recorded
+// like a failed API call, or nil if the call should go through.
+func (c *Client) injectFault(ctx context.Context, method string) error {
+	if c.cfg.Faults == nil {
+		return nil
+	}
+	start := time.Now()
+	err := c.cfg.Faults.inject(ctx, method)
+	if err != nil {
+		c.log.V(1).Info("injected fault", "method", method, "error", err)
+		observe(method, start, err)
+	}
+	return err
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+)
+
+func TestParseFaults(t *testing.T) {
+	faults, err := ParseFaults("GetNodes=timeout:3, GetApps=permission_denied")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f := faults.faults["GetNodes"]; f == nil || f.kind != ErrorClassTimeout || f.count != 3 {
+		t.Errorf("unexpected GetNodes fault %+v", f)
+	}
+	if f := faults.faults["GetApps"]; f == nil || f.kind != ErrorClassPermissionDenied || f.count != 0 {
+		t.Errorf("unexpected GetApps fault %+v", f)
+	}
+
+	if faults, err := ParseFaults(""); err != nil || faults != nil {
+		t.Errorf("expected no faults for an empty list, got %v, %v", faults, err)
+	}
+	for _, s := range []string{
+		"GetNodes",
+		"GetWidgets=timeout",
+		"GetNodes=crash",
+		"GetNodes=timeout:0",
+		"GetNodes=timeout:x",
+	} {
+		if _, err := ParseFaults(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+}
+
+func TestFaults_Inject(t *testing.T) {
+	faults, err := ParseFaults("GetNodes=rate_limited:2,GetApps=auth_expired,GetDatabases=timeout")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	for i := range 2 {
+		if err := faults.inject(ctx, "GetNodes"); ErrorClass(err) != ErrorClassRateLimited {
+			t.Errorf("call %d: expected a rate limit error, got %v", i+1, err)
+		}
+	}
+	if err := faults.inject(ctx, "GetNodes"); err != nil {
+		t.Errorf("expected GetNodes to recover after 2 calls, got %v", err)
+	}
+	for i := range 3 {
+		if err := faults.inject(ctx, "GetApps"); ErrorClass(err) != ErrorClassAuthExpired {
+			t.Errorf("call %d: expected an expired credentials error, got %v", i+1, err)
+		}
+	}
+	if err := faults.inject(ctx, "GetKubeClusters"); err != nil {
+		t.Errorf("expected no fault for GetKubeClusters, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := faults.inject(ctx, "GetDatabases"); !errors.Is(err, context.DeadlineExceeded) || ctx.Err() == nil {
+		t.Errorf("expected a timeout after the deadline, got %v", err)
+	}
+
+	var none *Faults
+	if err := none.inject(ctx, "GetNodes"); err != nil {
+		t.Errorf("expected no fault from nil faults, got %v", err)
+	}
+}
+
+func TestClient_InjectFault(t *testing.T) {
+	metrics.APIErrorsTotal.Reset()
+	faults, err := ParseFaults("CheckAccess=permission_denied:1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := NewDisconnectedClient(Config{Log: logr.Discard(), Faults: faults})
+
+	if err := c.CheckAccess(context.Background(), CacheNodes); ErrorClass(err) != ErrorClassPermissionDenied {
+		t.Errorf("expected the injected permission error, got %v", err)
+	}
+	if value := testutil.ToFloat64(metrics.APIErrorsTotal.WithLabelValues("CheckAccess", ErrorClassPermissionDenied)); value != 1 {
+		t.Errorf("expected the injected error to be counted, got %f", value)
+	}
+	// Once the fault is exhausted, the call reaches the disconnected client.
+	if err := c.CheckAccess(context.Background(), CacheNodes); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("expected ErrNotConnected after the fault, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+)
+
+// rpcMethodKey is the context key under which TagRPC stores the full gRPC method name.
+type rpcMethodKey struct{}
+
+// statsHandler is a gRPC stats.Handler that exports request counts, status
+// codes and message sizes of the calls made to Teleport.
+type statsHandler struct{}
+
+// TagRPC stores the method name in the context for use by HandleRPC.
+func (statsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, rpcMethodKey{}, info.FullMethodName)
+}
+
+// HandleRPC records the size of each message and the status code of each finished call.
+func (statsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	fullMethod, _ := ctx.Value(rpcMethodKey{}).(string)
+	service, method := splitMethodName(fullMethod)
+
+	switch s := s.(type) {
+	case *stats.OutPayload:
+		metrics.GRPCClientMsgSentBytes.WithLabelValues(service, method).Observe(float64(s.WireLength))
+	case *stats.InPayload:
+		metrics.GRPCClientMsgReceivedBytes.WithLabelValues(service, method).Observe(float64(s.WireLength))
+	case *stats.End:
+		metrics.GRPCClientHandledTotal.WithLabelValues(service, method, status.Code(s.Error).String()).Inc()
+	}
+}
+
+// TagConn is a no-op, connections are tracked by WatchConnectionState.
+func (statsHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+// HandleConn is a no-op, connections are tracked by WatchConnectionState.
+func (statsHandler) HandleConn(context.Context, stats.ConnStats) {}
+
+// splitMethodName splits a full gRPC method name like "/proto.AuthService/GetNodes"
+// into its service and method.
+func splitMethodName(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.Index(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", "unknown"
+}
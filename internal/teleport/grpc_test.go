@@ -0,0 +1,76 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+)
+
+func TestSplitMethodName(t *testing.T) {
+	tests := []struct {
+		fullMethod      string
+		expectedService string
+		expectedMethod  string
+	}{
+		{"/proto.AuthService/GetNodes", "proto.AuthService", "GetNodes"},
+		{"proto.AuthService/Ping", "proto.AuthService", "Ping"},
+		{"", "unknown", "unknown"},
+	}
+
+	for _, tt := range tests {
+		service, method := splitMethodName(tt.fullMethod)
+		if service != tt.expectedService || method != tt.expectedMethod {
+			t.Errorf("splitMethodName(%q) = (%q, %q), expected (%q, %q)",
+				tt.fullMethod, service, method, tt.expectedService, tt.expectedMethod)
+		}
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	metrics.GRPCClientHandledTotal.Reset()
+	metrics.GRPCClientMsgSentBytes.Reset()
+	metrics.GRPCClientMsgReceivedBytes.Reset()
+
+	var h statsHandler
+	ctx := h.TagRPC(context.Background(), &stats.RPCTagInfo{FullMethodName: "/proto.AuthService/GetNodes"})
+
+	h.HandleRPC(ctx, &stats.OutPayload{Client: true, WireLength: 100})
+	h.HandleRPC(ctx, &stats.InPayload{Client: true, WireLength: 2048})
+	h.HandleRPC(ctx, &stats.End{Client: true})
+	h.HandleRPC(ctx, &stats.End{Client: true, Error: status.Error(codes.PermissionDenied, "denied")})
+
+	if value := testutil.ToFloat64(metrics.GRPCClientHandledTotal.WithLabelValues("proto.AuthService", "GetNodes", "OK")); value != 1 {
+		t.Errorf("expected 1 OK call, got %f", value)
+	}
+	if value := testutil.ToFloat64(metrics.GRPCClientHandledTotal.WithLabelValues("proto.AuthService", "GetNodes", "PermissionDenied")); value != 1 {
+		t.Errorf("expected 1 PermissionDenied call, got %f", value)
+	}
+	if got := testutil.CollectAndCount(metrics.GRPCClientMsgSentBytes); got != 1 {
+		t.Errorf("expected 1 GRPCClientMsgSentBytes series, got %d", got)
+	}
+	if got := testutil.CollectAndCount(metrics.GRPCClientMsgReceivedBytes); got != 1 {
+		t.Errorf("expected 1 GRPCClientMsgReceivedBytes series, got %d", got)
+	}
+}
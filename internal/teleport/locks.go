@@ -0,0 +1,148 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/trace"
+)
+
+// LockTargetUser is the LockInfo.Targets key of locks of a user.
+const LockTargetUser = "user"
+
+// LockInfo represents information about a Teleport lock in force.
+type LockInfo struct {
+	Name string `json:"name"`
+	// Targets maps the kinds of the locked targets, e.g. LockTargetUser, to
+	// the locked values. A lock usually has a single target.
+	Targets map[string]string `json:"targets"`
+	Message string            `json:"message,omitempty"`
+	// Expires is when the lock ceases to be in force, nil if it is in force
+	// until it is deleted.
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// locksClient is the part of the Teleport API client that lists locks.
+type locksClient interface {
+	ListLocks(ctx context.Context, limit int, startKey string, filter *types.LockFilter) ([]types.Lock, string, error)
+}
+
+// GetLocks returns the locks of Teleport that are in force. The result is
+// served from the cache if one is configured for the resource type.
+func (c *Client) GetLocks(ctx context.Context) ([]LockInfo, error) {
+	return c.locksCache.get(ctx, c.fetchLocks)
+}
+
+// fetchLocks fetches the locks in force from the Teleport API.
+func (c *Client) fetchLocks(ctx context.Context) ([]LockInfo, error) {
+	c.log.V(1).Info("fetching locks from Teleport")
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if err := c.injectFault(ctx, "GetLocks"); err != nil {
+		return nil, err
+	}
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	result := make([]LockInfo, 0)
+	clt, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	err = forEachLock(ctx, clt, c.pageSize, func(lock types.Lock) {
+		result = append(result, lockInfo(lock))
+	})
+	observe("ListLocks", start, err)
+	if err != nil {
+		c.log.Error(err, "failed to get locks")
+		return nil, err
+	}
+
+	c.log.V(1).Info("fetched locks", "count", len(result))
+	return result, nil
+}
+
+// forEachLock calls fn for every lock in force, fetching pageSize locks per
+// request (Teleport default if zero).
+func forEachLock(ctx context.Context, clt locksClient, pageSize int, fn func(types.Lock)) error {
+	filter := &types.LockFilter{InForceOnly: true}
+	startKey := ""
+	for {
+		locks, next, err := clt.ListLocks(ctx, pageSize, startKey, filter)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, lock := range locks {
+			fn(lock)
+		}
+		if next == "" || len(locks) == 0 {
+			return nil
+		}
+		startKey = next
+	}
+}
+
+// lockInfo converts a Teleport lock into a LockInfo.
+func lockInfo(lock types.Lock) LockInfo {
+	return LockInfo{
+		Name:    lock.GetName(),
+		Targets: lockTargets(lock.Target()),
+		Message: lock.Message(),
+		Expires: lock.LockExpiry(),
+	}
+}
+
+// lockTargets returns the set fields of target, keyed by their JSON names.
+func lockTargets(target types.LockTarget) map[string]string {
+	all := map[string]string{
+		LockTargetUser:    target.User,
+		"role":            target.Role,
+		"login":           target.Login,
+		"mfa_device":      target.MFADevice,
+		"windows_desktop": target.WindowsDesktop,
+		"access_request":  target.AccessRequest,
+		"device":          target.Device,
+		"server_id":       target.ServerID,
+		"bot_instance_id": target.BotInstanceID,
+		"join_token":      target.JoinToken,
+	}
+	targets := make(map[string]string)
+	for kind, value := range all {
+		if value != "" {
+			targets[kind] = value
+		}
+	}
+	return targets
+}
+
+// checkLocks lists a single lock, which fails with an access denied error if
+// the identity may not list locks.
+func checkLocks(ctx context.Context, clt locksClient) error {
+	_, _, err := clt.ListLocks(ctx, 1, "", nil)
+	return trace.Wrap(err)
+}
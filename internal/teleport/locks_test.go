@@ -0,0 +1,122 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/trace"
+)
+
+// fakeLocksClient serves locks page by page and records the filters.
+type fakeLocksClient struct {
+	locks   []types.Lock
+	filters []*types.LockFilter
+	err     error
+}
+
+func (f *fakeLocksClient) ListLocks(_ context.Context, limit int, startKey string, filter *types.LockFilter) ([]types.Lock, string, error) {
+	f.filters = append(f.filters, filter)
+	if f.err != nil {
+		return nil, "", f.err
+	}
+	start := 0
+	if startKey != "" {
+		var err error
+		if start, err = strconv.Atoi(startKey); err != nil {
+			return nil, "", err
+		}
+	}
+	end := min(start+limit, len(f.locks))
+	next := ""
+	if end < len(f.locks) {
+		next = strconv.Itoa(end)
+	}
+	return f.locks[start:end], next, nil
+}
+
+func newLock(t *testing.T, name string, spec types.LockSpecV2) types.Lock {
+	t.Helper()
+	lock, err := types.NewLock(name, spec)
+	if err != nil {
+		t.Fatalf("failed to create lock: %v", err)
+	}
+	return lock
+}
+
+func TestForEachLock(t *testing.T) {
+	clt := &fakeLocksClient{}
+	for i := range 3 {
+		clt.locks = append(clt.locks, newLock(t, fmt.Sprintf("lock-%d", i), types.LockSpecV2{
+			Target: types.LockTarget{User: fmt.Sprintf("user-%d", i)},
+		}))
+	}
+
+	count := 0
+	err := forEachLock(context.Background(), clt, 2, func(types.Lock) { count++ })
+	if err != nil {
+		t.Fatalf("forEachLock() failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 locks, got %d", count)
+	}
+	if len(clt.filters) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(clt.filters))
+	}
+	for _, filter := range clt.filters {
+		if filter == nil || !filter.InForceOnly {
+			t.Errorf("expected only locks in force to be listed, got filter %v", filter)
+		}
+	}
+}
+
+func TestLockInfo(t *testing.T) {
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	lock := newLock(t, "lock-1", types.LockSpecV2{
+		Target:  types.LockTarget{User: "alice", Login: "root"},
+		Message: "compromised laptop",
+		Expires: &expires,
+	})
+
+	got := lockInfo(lock)
+	if want := map[string]string{LockTargetUser: "alice", "login": "root"}; !maps.Equal(got.Targets, want) {
+		t.Errorf("expected targets %v, got %v", want, got.Targets)
+	}
+	if got.Name != "lock-1" || got.Message != "compromised laptop" {
+		t.Errorf("unexpected lock %+v", got)
+	}
+	if got.Expires == nil || !got.Expires.Equal(expires) {
+		t.Errorf("expected the lock to expire at %s, got %v", expires, got.Expires)
+	}
+
+	if got := lockInfo(newLock(t, "lock-2", types.LockSpecV2{Target: types.LockTarget{Role: "admin"}})); got.Expires != nil {
+		t.Errorf("expected no expiry, got %v", got.Expires)
+	}
+}
+
+func TestCheckLocks(t *testing.T) {
+	clt := &fakeLocksClient{err: trace.AccessDenied("access denied to perform action \"list\" on \"lock\"")}
+	if reason := ErrorReason(checkLocks(context.Background(), clt)); reason != ErrorReasonPermissionDenied {
+		t.Errorf("expected reason %s, got %s", ErrorReasonPermissionDenied, reason)
+	}
+}
@@ -0,0 +1,33 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"fmt"
+
+	"github.com/gravitational/teleport/api/profile"
+)
+
+// ProfileProxyAddr returns the address of the web proxy a tsh profile was
+// logged in to. An empty dir means ~/.tsh.
+func ProfileProxyAddr(dir, name string) (string, error) {
+	p, err := profile.FromDir(dir, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to load tsh profile %q: %w", name, err)
+	}
+	return p.WebProxyAddr, nil
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileProxyAddr(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte("web_proxy_addr: teleport.example.com:443\nusername: alice\n")
+	if err := os.WriteFile(filepath.Join(dir, "teleport.example.com.yaml"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	addr, err := ProfileProxyAddr(dir, "teleport.example.com")
+	if err != nil {
+		t.Fatalf("ProfileProxyAddr() failed: %v", err)
+	}
+	if addr != "teleport.example.com:443" {
+		t.Errorf("expected teleport.example.com:443, got %q", addr)
+	}
+
+	if _, err := ProfileProxyAddr(dir, "other.example.com"); err == nil {
+		t.Error("expected error for missing profile")
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/trace"
+)
+
+// Connection statuses of RemoteClusterInfo.Status.
+const (
+	RemoteClusterOnline  = "online"
+	RemoteClusterOffline = "offline"
+)
+
+// RemoteClusterInfo represents a leaf cluster trusted by the cluster.
+type RemoteClusterInfo struct {
+	Name string `json:"name"`
+	// Status is the connection status of the leaf cluster to the root
+	// cluster, RemoteClusterOnline or RemoteClusterOffline.
+	Status string `json:"status"`
+	// LastHeartbeat is when the leaf cluster was last connected, nil if it
+	// never was.
+	LastHeartbeat *time.Time `json:"lastHeartbeat,omitempty"`
+}
+
+// remoteClustersClient is the part of the Teleport API client that lists
+// leaf clusters.
+type remoteClustersClient interface {
+	ListRemoteClusters(ctx context.Context, pageSize int, nextToken string) ([]types.RemoteCluster, string, error)
+}
+
+// GetRemoteClusters returns the leaf clusters of Teleport and whether they
+// are connected. The result is served from the cache if one is configured
+// for the resource type.
+func (c *Client) GetRemoteClusters(ctx context.Context) ([]RemoteClusterInfo, error) {
+	return c.remoteClustersCache.get(ctx, c.fetchRemoteClusters)
+}
+
+// fetchRemoteClusters fetches the leaf clusters from the Teleport API.
+func (c *Client) fetchRemoteClusters(ctx context.Context) ([]RemoteClusterInfo, error) {
+	c.log.V(1).Info("fetching remote clusters from Teleport")
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if err := c.injectFault(ctx, "GetRemoteClusters"); err != nil {
+		return nil, err
+	}
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	result := make([]RemoteClusterInfo, 0)
+	clt, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	err = forEachRemoteCluster(ctx, clt, c.pageSize, func(rc types.RemoteCluster) {
+		result = append(result, remoteClusterInfo(rc))
+	})
+	observe("ListRemoteClusters", start, err)
+	if err != nil {
+		c.log.Error(err, "failed to get remote clusters")
+		return nil, err
+	}
+
+	c.log.V(1).Info("fetched remote clusters", "count", len(result))
+	return result, nil
+}
+
+// forEachRemoteCluster calls fn for every leaf cluster, fetching pageSize
+// clusters per request (Teleport default if zero).
+func forEachRemoteCluster(ctx context.Context, clt remoteClustersClient, pageSize int, fn func(types.RemoteCluster)) error {
+	pageToken := ""
+	for {
+		clusters, next, err := clt.ListRemoteClusters(ctx, pageSize, pageToken)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, rc := range clusters {
+			fn(rc)
+		}
+		if next == "" || len(clusters) == 0 {
+			return nil
+		}
+		pageToken = next
+	}
+}
+
+// remoteClusterInfo converts a Teleport remote cluster into a
+// RemoteClusterInfo.
+func remoteClusterInfo(rc types.RemoteCluster) RemoteClusterInfo {
+	info := RemoteClusterInfo{
+		Name:   rc.GetName(),
+		Status: rc.GetConnectionStatus(),
+	}
+	if heartbeat := rc.GetLastHeartbeat(); !heartbeat.IsZero() {
+		info.LastHeartbeat = &heartbeat
+	}
+	return info
+}
+
+// checkRemoteClusters lists a single leaf cluster, which fails with an access
+// denied error if the identity may not list remote clusters.
+func checkRemoteClusters(ctx context.Context, clt remoteClustersClient) error {
+	_, _, err := clt.ListRemoteClusters(ctx, 1, "")
+	return trace.Wrap(err)
+}
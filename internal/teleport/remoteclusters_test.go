@@ -0,0 +1,90 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gravitational/teleport/api/types"
+)
+
+// fakeRemoteClustersClient serves leaf clusters page by page.
+type fakeRemoteClustersClient struct {
+	clusters []types.RemoteCluster
+}
+
+func (f *fakeRemoteClustersClient) ListRemoteClusters(_ context.Context, pageSize int, nextToken string) ([]types.RemoteCluster, string, error) {
+	start := 0
+	if nextToken != "" {
+		var err error
+		if start, err = strconv.Atoi(nextToken); err != nil {
+			return nil, "", err
+		}
+	}
+	end := min(start+pageSize, len(f.clusters))
+	next := ""
+	if end < len(f.clusters) {
+		next = strconv.Itoa(end)
+	}
+	return f.clusters[start:end], next, nil
+}
+
+func newRemoteCluster(t *testing.T, name, status string, heartbeat time.Time) types.RemoteCluster {
+	t.Helper()
+	rc, err := types.NewRemoteCluster(name)
+	if err != nil {
+		t.Fatalf("failed to create remote cluster: %v", err)
+	}
+	rc.SetConnectionStatus(status)
+	rc.SetLastHeartbeat(heartbeat)
+	return rc
+}
+
+func TestForEachRemoteCluster(t *testing.T) {
+	clt := &fakeRemoteClustersClient{}
+	for i := range 3 {
+		clt.clusters = append(clt.clusters, newRemoteCluster(t, fmt.Sprintf("leaf-%d", i), RemoteClusterOnline, time.Time{}))
+	}
+
+	count := 0
+	err := forEachRemoteCluster(context.Background(), clt, 2, func(types.RemoteCluster) { count++ })
+	if err != nil {
+		t.Fatalf("forEachRemoteCluster() failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 remote clusters, got %d", count)
+	}
+}
+
+func TestRemoteClusterInfo(t *testing.T) {
+	heartbeat := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	info := remoteClusterInfo(newRemoteCluster(t, "leaf", RemoteClusterOffline, heartbeat))
+	if info.Name != "leaf" || info.Status != RemoteClusterOffline {
+		t.Errorf("unexpected remote cluster %+v", info)
+	}
+	if info.LastHeartbeat == nil || !info.LastHeartbeat.Equal(heartbeat) {
+		t.Errorf("expected the last heartbeat %s, got %v", heartbeat, info.LastHeartbeat)
+	}
+
+	if info := remoteClusterInfo(newRemoteCluster(t, "new", RemoteClusterOffline, time.Time{})); info.LastHeartbeat != nil {
+		t.Errorf("expected no heartbeat of a leaf cluster that never connected, got %s", info.LastHeartbeat)
+	}
+}
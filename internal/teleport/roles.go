@@ -0,0 +1,146 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/gravitational/teleport/api/client/proto"
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/trace"
+)
+
+// RoleInfo represents information about a Teleport role, reduced to the
+// allow conditions that grant broad access.
+type RoleInfo struct {
+	Name string `json:"name"`
+	// Logins are the allowed node logins.
+	Logins []string `json:"logins,omitempty"`
+	// WildcardLabels lists the resource kinds, e.g. "node", whose allowed
+	// labels match every resource ('*': '*').
+	WildcardLabels []string `json:"wildcardLabels,omitempty"`
+	// WildcardResources is set if an allow rule applies to all resources.
+	WildcardResources bool              `json:"wildcardResources,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+}
+
+// rolesClient is the part of the Teleport API client that lists roles.
+type rolesClient interface {
+	ListRoles(ctx context.Context, req *proto.ListRolesRequest) (*proto.ListRolesResponse, error)
+}
+
+// GetRoles returns all roles of Teleport, except the system roles. The
+// result is served from the cache if one is configured for the resource type.
+func (c *Client) GetRoles(ctx context.Context) ([]RoleInfo, error) {
+	return c.rolesCache.get(ctx, c.fetchRoles)
+}
+
+// fetchRoles fetches the roles from the Teleport API.
+func (c *Client) fetchRoles(ctx context.Context) ([]RoleInfo, error) {
+	c.log.V(1).Info("fetching roles from Teleport")
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if err := c.injectFault(ctx, "GetRoles"); err != nil {
+		return nil, err
+	}
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	result := make([]RoleInfo, 0)
+	clt, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	err = forEachRole(ctx, clt, c.pageSize, func(role types.Role) {
+		result = append(result, roleInfo(role))
+	})
+	observe("ListRoles", start, err)
+	if err != nil {
+		c.log.Error(err, "failed to get roles")
+		return nil, err
+	}
+
+	c.log.V(1).Info("fetched roles", "count", len(result))
+	return result, nil
+}
+
+// forEachRole calls fn for every role except the system roles, fetching
+// pageSize roles per request (Teleport default if zero).
+func forEachRole(ctx context.Context, clt rolesClient, pageSize int, fn func(types.Role)) error {
+	req := &proto.ListRolesRequest{
+		Limit:  int32(pageSize),
+		Filter: &types.RoleFilter{SkipSystemRoles: true},
+	}
+	for {
+		resp, err := clt.ListRoles(ctx, req)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, role := range resp.Roles {
+			fn(role)
+		}
+		if resp.NextKey == "" || len(resp.Roles) == 0 {
+			return nil
+		}
+		req.StartKey = resp.NextKey
+	}
+}
+
+// roleInfo converts a Teleport role into a RoleInfo.
+func roleInfo(role types.Role) RoleInfo {
+	info := RoleInfo{
+		Name:   role.GetName(),
+		Logins: role.GetLogins(types.Allow),
+		Labels: role.GetAllLabels(),
+	}
+	for _, kind := range []struct {
+		name   string
+		labels types.Labels
+	}{
+		{types.KindNode, role.GetNodeLabels(types.Allow)},
+		{types.KindKubernetesCluster, role.GetKubernetesLabels(types.Allow)},
+		{types.KindDatabase, role.GetDatabaseLabels(types.Allow)},
+		{types.KindApp, role.GetAppLabels(types.Allow)},
+	} {
+		if slices.Contains(kind.labels[types.Wildcard], types.Wildcard) {
+			info.WildcardLabels = append(info.WildcardLabels, kind.name)
+		}
+	}
+	for _, rule := range role.GetRules(types.Allow) {
+		if slices.Contains(rule.Resources, types.Wildcard) {
+			info.WildcardResources = true
+		}
+	}
+	return info
+}
+
+// checkRoles lists a single role, which fails with an access denied error if
+// the identity may not list roles.
+func checkRoles(ctx context.Context, clt rolesClient) error {
+	_, err := clt.ListRoles(ctx, &proto.ListRolesRequest{Limit: 1})
+	return trace.Wrap(err)
+}
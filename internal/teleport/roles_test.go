@@ -0,0 +1,122 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/gravitational/teleport/api/client/proto"
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/trace"
+)
+
+// fakeRolesClient serves roles page by page and records the requests.
+type fakeRolesClient struct {
+	roles    []*types.RoleV6
+	requests []*proto.ListRolesRequest
+	err      error
+}
+
+func (f *fakeRolesClient) ListRoles(_ context.Context, req *proto.ListRolesRequest) (*proto.ListRolesResponse, error) {
+	f.requests = append(f.requests, req)
+	if f.err != nil {
+		return nil, f.err
+	}
+	start := 0
+	if req.StartKey != "" {
+		var err error
+		if start, err = strconv.Atoi(req.StartKey); err != nil {
+			return nil, err
+		}
+	}
+	end := min(start+int(req.Limit), len(f.roles))
+
+	resp := &proto.ListRolesResponse{Roles: f.roles[start:end]}
+	if end < len(f.roles) {
+		resp.NextKey = strconv.Itoa(end)
+	}
+	return resp, nil
+}
+
+func newRole(t *testing.T, name string, allow types.RoleConditions) *types.RoleV6 {
+	t.Helper()
+	role, err := types.NewRole(name, types.RoleSpecV6{Allow: allow})
+	if err != nil {
+		t.Fatalf("failed to create role: %v", err)
+	}
+	return role.(*types.RoleV6)
+}
+
+func TestForEachRole(t *testing.T) {
+	clt := &fakeRolesClient{}
+	for i := range 3 {
+		clt.roles = append(clt.roles, newRole(t, fmt.Sprintf("role-%d", i), types.RoleConditions{}))
+	}
+
+	var names []string
+	err := forEachRole(context.Background(), clt, 2, func(role types.Role) {
+		names = append(names, role.GetName())
+	})
+	if err != nil {
+		t.Fatalf("forEachRole() failed: %v", err)
+	}
+	if want := []string{"role-0", "role-1", "role-2"}; !slices.Equal(names, want) {
+		t.Errorf("expected roles %v, got %v", want, names)
+	}
+	for _, req := range clt.requests {
+		if req.Filter == nil || !req.Filter.SkipSystemRoles {
+			t.Errorf("expected the system roles to be skipped, got filter %v", req.Filter)
+		}
+	}
+}
+
+func TestRoleInfo(t *testing.T) {
+	role := newRole(t, "admin", types.RoleConditions{
+		Logins:     []string{"root", types.Wildcard},
+		NodeLabels: types.Labels{types.Wildcard: {types.Wildcard}},
+		AppLabels:  types.Labels{"env": {"dev"}},
+		Rules:      []types.Rule{types.NewRule(types.Wildcard, []string{types.VerbList})},
+	})
+	got := roleInfo(role)
+	if !slices.Contains(got.Logins, types.Wildcard) {
+		t.Errorf("expected the wildcard login, got %v", got.Logins)
+	}
+	if want := []string{types.KindNode}; !slices.Equal(got.WildcardLabels, want) {
+		t.Errorf("expected wildcard labels %v, got %v", want, got.WildcardLabels)
+	}
+	if !got.WildcardResources {
+		t.Error("expected the wildcard resources rule to be detected")
+	}
+
+	narrow := roleInfo(newRole(t, "viewer", types.RoleConditions{
+		Rules: []types.Rule{types.NewRule(types.KindNode, []string{types.VerbList})},
+	}))
+	if len(narrow.WildcardLabels) != 0 || narrow.WildcardResources {
+		t.Errorf("expected no wildcards, got %+v", narrow)
+	}
+}
+
+func TestCheckRoles(t *testing.T) {
+	clt := &fakeRolesClient{err: trace.AccessDenied("access denied to perform action \"list\" on \"role\"")}
+	if reason := ErrorReason(checkRoles(context.Background(), clt)); reason != ErrorReasonPermissionDenied {
+		t.Errorf("expected reason %s, got %s", ErrorReasonPermissionDenied, reason)
+	}
+}
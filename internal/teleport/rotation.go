@@ -0,0 +1,121 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gravitational/teleport/api/types"
+
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+)
+
+// WatchCARotation checks the rotation state of the cluster CAs every interval
+// until ctx is cancelled, and calls onRotation after it changed, e.g. to
+// reload the credentials before the certificates of the previous CA are no
+// longer trusted.
+func (c *Client) WatchCARotation(ctx context.Context, interval time.Duration, onRotation func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last string
+	for {
+		state, err := c.caRotation(ctx)
+		switch {
+		case err != nil:
+			c.log.V(1).Info("failed to check the rotation of the cluster CAs", "error", err)
+		case last != "" && state != last:
+			c.log.Info("cluster CA rotation detected, reloading credentials", "from", last, "to", state)
+			metrics.CARotationChangesTotal.Inc()
+			onRotation()
+		}
+		if err == nil {
+			last = state
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// caRotation returns a description of the rotation state of the host CAs,
+// which changes with every rotation phase. Without read access to the
+// cert_authority resource, it falls back to a fingerprint of the cluster CA
+// certificates, which any identity may read and which change when a rotation
+// starts, switches to the new CA and completes.
+func (c *Client) caRotation(ctx context.Context) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	clt, err := c.api()
+	if err != nil {
+		return "", err
+	}
+
+	start := time.Now()
+	cas, err := clt.GetCertAuthorities(ctx, types.HostCA, false)
+	observe("GetCertAuthorities", start, err)
+	if err == nil {
+		return rotationState(cas), nil
+	}
+	if ErrorReason(err) != ErrorReasonPermissionDenied {
+		return "", err
+	}
+
+	start = time.Now()
+	resp, err := clt.GetClusterCACert(ctx)
+	observe("GetClusterCACert", start, err)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(resp.TLSCA)
+	return "ca_certs=" + hex.EncodeToString(sum[:8]), nil
+}
+
+// rotationState describes the rotation state, phase and current key of each
+// CA, sorted so that the order of the CAs does not matter.
+func rotationState(cas []types.CertAuthority) string {
+	states := make([]string, 0, len(cas))
+	for _, ca := range cas {
+		rotation := ca.GetRotation()
+		state, phase := rotation.State, rotation.Phase
+		if state == "" {
+			state = types.RotationStateStandby
+		}
+		if phase == "" {
+			phase = types.RotationPhaseStandby
+		}
+		states = append(states, fmt.Sprintf("%s: state=%s phase=%s id=%s", ca.GetClusterName(), state, phase, rotation.CurrentID))
+	}
+	slices.Sort(states)
+	return strings.Join(states, ", ")
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"testing"
+
+	"github.com/gravitational/teleport/api/types"
+)
+
+func TestRotationState(t *testing.T) {
+	newCA := func(cluster string, rotation *types.Rotation) types.CertAuthority {
+		return &types.CertAuthorityV2{Spec: types.CertAuthoritySpecV2{ClusterName: cluster, Rotation: rotation}}
+	}
+
+	standby := rotationState([]types.CertAuthority{newCA("main", nil), newCA("leaf", nil)})
+	want := "leaf: state=standby phase=standby id=, main: state=standby phase=standby id="
+	if standby != want {
+		t.Errorf("rotationState() = %q, want %q", standby, want)
+	}
+
+	rotating := rotationState([]types.CertAuthority{
+		newCA("main", &types.Rotation{State: types.RotationStateInProgress, Phase: types.RotationPhaseUpdateClients, CurrentID: "2"}),
+		newCA("leaf", nil),
+	})
+	if rotating == standby {
+		t.Error("expected the state to change with the rotation phase")
+	}
+	reordered := rotationState([]types.CertAuthority{
+		newCA("leaf", nil),
+		newCA("main", &types.Rotation{State: types.RotationStateInProgress, Phase: types.RotationPhaseUpdateClients, CurrentID: "2"}),
+	})
+	if reordered != rotating {
+		t.Errorf("expected the state to not depend on the order of the CAs, got %q and %q", rotating, reordered)
+	}
+}
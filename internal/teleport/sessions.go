@@ -0,0 +1,156 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"time"
+
+	apidefaults "github.com/gravitational/teleport/api/defaults"
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/teleport/api/types/events"
+	"github.com/gravitational/trace"
+)
+
+// Audit event types searched for sessions.
+const (
+	eventSessionEnd    = "session.end"
+	eventSessionUpload = "session.upload"
+)
+
+// RecordingOff is the SessionInfo.Recording mode of sessions that are not
+// recorded.
+const RecordingOff = types.RecordOff
+
+// sessionLookback is how far back the audit log is searched for ended
+// sessions.
+const sessionLookback = time.Hour
+
+// defaultEventsLimit is the number of audit events fetched per request if no
+// page size is configured, since the search has no server side default.
+const defaultEventsLimit = 1000
+
+// SessionInfo represents a Teleport session that ended within the last hour.
+type SessionInfo struct {
+	ID    string    `json:"id"`
+	User  string    `json:"user"`
+	Ended time.Time `json:"ended"`
+	// Recording is the recording mode of the cluster when the session
+	// ended, e.g. "node" or RecordingOff. It is empty for sessions of
+	// Teleport versions that did not report it.
+	Recording string `json:"recording,omitempty"`
+	// Recorded is set if the recording of the session was uploaded.
+	Recorded bool `json:"recorded,omitempty"`
+}
+
+// sessionsClient is the part of the Teleport API client that searches the
+// audit log.
+type sessionsClient interface {
+	SearchEvents(ctx context.Context, fromUTC, toUTC time.Time, namespace string, eventTypes []string, limit int, order types.EventOrder, startKey string, search string) ([]events.AuditEvent, string, error)
+}
+
+// GetSessions returns the sessions that ended within the last hour, from
+// their session.end audit events, and whether their recording was uploaded.
+// The result is served from the cache if one is configured for the resource
+// type.
+func (c *Client) GetSessions(ctx context.Context) ([]SessionInfo, error) {
+	return c.sessionsCache.get(ctx, c.fetchSessions)
+}
+
+// fetchSessions fetches the sessions from the Teleport audit log.
+func (c *Client) fetchSessions(ctx context.Context) ([]SessionInfo, error) {
+	c.log.V(1).Info("fetching sessions from Teleport")
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if err := c.injectFault(ctx, "GetSessions"); err != nil {
+		return nil, err
+	}
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	clt, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result, err := searchSessions(ctx, clt, start.Add(-sessionLookback), start, c.pageSize)
+	observe("SearchEvents", start, err)
+	if err != nil {
+		c.log.Error(err, "failed to get sessions")
+		return nil, err
+	}
+
+	c.log.V(1).Info("fetched sessions", "count", len(result))
+	return result, nil
+}
+
+// searchSessions returns the sessions that ended between from and to,
+// fetching pageSize audit events per request (defaultEventsLimit if zero). A
+// session counts as recorded if a session.upload event for it was emitted in
+// the same period.
+func searchSessions(ctx context.Context, clt sessionsClient, from, to time.Time, pageSize int) ([]SessionInfo, error) {
+	if pageSize <= 0 {
+		pageSize = defaultEventsLimit
+	}
+	result := make([]SessionInfo, 0)
+	uploaded := make(map[string]bool)
+	startKey := ""
+	for {
+		page, nextKey, err := clt.SearchEvents(ctx, from.UTC(), to.UTC(), apidefaults.Namespace,
+			[]string{eventSessionEnd, eventSessionUpload}, pageSize, types.EventOrderAscending, startKey, "")
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, event := range page {
+			switch e := event.(type) {
+			case *events.SessionEnd:
+				result = append(result, SessionInfo{
+					ID:        e.SessionID,
+					User:      e.User,
+					Ended:     e.EndTime,
+					Recording: e.SessionRecording,
+				})
+			case *events.SessionUpload:
+				uploaded[e.SessionID] = true
+			}
+		}
+		if nextKey == "" || len(page) == 0 {
+			break
+		}
+		startKey = nextKey
+	}
+	for i := range result {
+		result[i].Recorded = uploaded[result[i].ID]
+	}
+	return result, nil
+}
+
+// checkSessions searches for a single session.end event, which fails with an
+// access denied error if the identity may not read the audit log.
+func checkSessions(ctx context.Context, clt sessionsClient) error {
+	now := time.Now().UTC()
+	_, _, err := clt.SearchEvents(ctx, now.Add(-time.Minute), now, apidefaults.Namespace,
+		[]string{eventSessionEnd}, 1, types.EventOrderAscending, "", "")
+	return trace.Wrap(err)
+}
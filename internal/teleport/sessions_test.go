@@ -0,0 +1,123 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/teleport/api/types/events"
+	"github.com/gravitational/trace"
+)
+
+// fakeSessionsClient serves audit events page by page and records the
+// requested limits.
+type fakeSessionsClient struct {
+	events []events.AuditEvent
+	limits []int
+	err    error
+}
+
+func (f *fakeSessionsClient) SearchEvents(_ context.Context, _, _ time.Time, _ string, _ []string, limit int, _ types.EventOrder, startKey string, _ string) ([]events.AuditEvent, string, error) {
+	f.limits = append(f.limits, limit)
+	if f.err != nil {
+		return nil, "", f.err
+	}
+	start := 0
+	if startKey != "" {
+		var err error
+		if start, err = strconv.Atoi(startKey); err != nil {
+			return nil, "", err
+		}
+	}
+	end := min(start+limit, len(f.events))
+
+	nextKey := ""
+	if end < len(f.events) {
+		nextKey = strconv.Itoa(end)
+	}
+	return f.events[start:end], nextKey, nil
+}
+
+func sessionEnd(id, recording string, ended time.Time) *events.SessionEnd {
+	return &events.SessionEnd{
+		UserMetadata:     events.UserMetadata{User: "alice"},
+		SessionMetadata:  events.SessionMetadata{SessionID: id},
+		EndTime:          ended,
+		SessionRecording: recording,
+	}
+}
+
+func sessionUpload(id string) *events.SessionUpload {
+	return &events.SessionUpload{SessionMetadata: events.SessionMetadata{SessionID: id}}
+}
+
+func TestSearchSessions(t *testing.T) {
+	ended := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	clt := &fakeSessionsClient{events: []events.AuditEvent{
+		sessionEnd("s1", types.RecordAtNode, ended),
+		sessionEnd("s2", types.RecordAtProxy, ended),
+		sessionUpload("s1"),
+		sessionEnd("s3", RecordingOff, ended),
+		sessionUpload("other"),
+	}}
+
+	sessions, err := searchSessions(context.Background(), clt, ended.Add(-time.Hour), ended, 2)
+	if err != nil {
+		t.Fatalf("searchSessions() failed: %v", err)
+	}
+	want := []SessionInfo{
+		{ID: "s1", User: "alice", Ended: ended, Recording: types.RecordAtNode, Recorded: true},
+		{ID: "s2", User: "alice", Ended: ended, Recording: types.RecordAtProxy},
+		{ID: "s3", User: "alice", Ended: ended, Recording: RecordingOff},
+	}
+	if !slices.EqualFunc(sessions, want, func(a, b SessionInfo) bool {
+		return a.ID == b.ID && a.User == b.User && a.Ended.Equal(b.Ended) && a.Recording == b.Recording && a.Recorded == b.Recorded
+	}) {
+		t.Errorf("expected sessions %+v, got %+v", want, sessions)
+	}
+	if want := []int{2, 2, 2}; !slices.Equal(clt.limits, want) {
+		t.Errorf("expected limits %v, got %v", want, clt.limits)
+	}
+
+	clt = &fakeSessionsClient{}
+	if _, err := searchSessions(context.Background(), clt, ended.Add(-time.Hour), ended, 0); err != nil {
+		t.Fatalf("searchSessions() failed: %v", err)
+	}
+	if want := []int{defaultEventsLimit}; !slices.Equal(clt.limits, want) {
+		t.Errorf("expected limits %v without a page size, got %v", want, clt.limits)
+	}
+}
+
+func TestCheckSessions(t *testing.T) {
+	clt := &fakeSessionsClient{}
+	if err := checkSessions(context.Background(), clt); err != nil {
+		t.Fatalf("checkSessions() failed: %v", err)
+	}
+	if want := []int{1}; !slices.Equal(clt.limits, want) {
+		t.Errorf("expected a single search for 1 event, got limits %v", clt.limits)
+	}
+
+	clt = &fakeSessionsClient{err: trace.AccessDenied("access denied to perform action \"list\" on \"event\"")}
+	if reason := ErrorReason(checkSessions(context.Background(), clt)); reason != ErrorReasonPermissionDenied {
+		t.Errorf("expected reason %s, got %s", ErrorReasonPermissionDenied, reason)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"github.com/gravitational/teleport/api/client"
+)
+
+// ParseTLSVersion parses a minimum TLS version, "1.2" or "1.3".
+func ParseTLSVersion(s string) (uint16, error) {
+	switch s {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q, must be 1.2 or 1.3", s)
+	}
+}
+
+// ParseCipherSuites parses a comma separated list of TLS 1.2 cipher suite
+// names as listed by tls.CipherSuites, e.g.
+// TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256. Insecure cipher suites are
+// rejected. An empty list returns nil, which keeps the Go defaults.
+func ParseCipherSuites(s string) ([]uint16, error) {
+	if s == "" {
+		return nil, nil
+	}
+	ids := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		ids[suite.Name] = suite.ID
+	}
+	var suites []uint16
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		id, ok := ids[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported or insecure cipher suite %q", name)
+		}
+		suites = append(suites, id)
+	}
+	return suites, nil
+}
+
+// tlsPolicyCreds enforces a minimum TLS version and cipher suites on the TLS
+// configuration of the wrapped credentials.
+type tlsPolicyCreds struct {
+	client.Credentials
+	minVersion   uint16
+	cipherSuites []uint16
+}
+
+// withTLSPolicy wraps creds to apply the TLS policy of cfg, or returns creds
+// if none is configured.
+func withTLSPolicy(cfg Config, creds client.Credentials) client.Credentials {
+	if cfg.TLSMinVersion == 0 && cfg.TLSCipherSuites == nil {
+		return creds
+	}
+	return tlsPolicyCreds{Credentials: creds, minVersion: cfg.TLSMinVersion, cipherSuites: cfg.TLSCipherSuites}
+}
+
+// TLSConfig returns the TLS configuration of the wrapped credentials with the
+// policy applied. A higher minimum version of the credentials is kept.
+func (c tlsPolicyCreds) TLSConfig() (*tls.Config, error) {
+	tlsConfig, err := c.Credentials.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.MinVersion = max(tlsConfig.MinVersion, c.minVersion)
+	if c.cipherSuites != nil {
+		tlsConfig.CipherSuites = c.cipherSuites
+	}
+	return tlsConfig, nil
+}
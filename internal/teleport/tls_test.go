@@ -0,0 +1,80 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"crypto/tls"
+	"slices"
+	"testing"
+
+	"github.com/gravitational/teleport/api/client"
+)
+
+func TestParseTLSVersion(t *testing.T) {
+	for s, want := range map[string]uint16{"1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13} {
+		if got, err := ParseTLSVersion(s); err != nil || got != want {
+			t.Errorf("ParseTLSVersion(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "1.1", "tls1.3"} {
+		if _, err := ParseTLSVersion(s); err == nil {
+			t.Errorf("ParseTLSVersion(%q) expected an error", s)
+		}
+	}
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	suites, err := ParseCipherSuites("TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}
+	if !slices.Equal(suites, want) {
+		t.Errorf("ParseCipherSuites() = %v, want %v", suites, want)
+	}
+
+	if suites, err := ParseCipherSuites(""); err != nil || suites != nil {
+		t.Errorf("ParseCipherSuites(\"\") = %v, %v, want nil", suites, err)
+	}
+	for _, s := range []string{"TLS_RSA_WITH_RC4_128_SHA", "unknown"} {
+		if _, err := ParseCipherSuites(s); err == nil {
+			t.Errorf("ParseCipherSuites(%q) expected an error", s)
+		}
+	}
+}
+
+func TestWithTLSPolicy(t *testing.T) {
+	creds := client.LoadTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if got := withTLSPolicy(Config{}, creds); got != creds {
+		t.Error("expected the credentials to be kept without a TLS policy")
+	}
+
+	suites := []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}
+	tlsConfig, err := withTLSPolicy(Config{TLSMinVersion: tls.VersionTLS13, TLSCipherSuites: suites}, creds).TLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS13 || !slices.Equal(tlsConfig.CipherSuites, suites) {
+		t.Errorf("expected the TLS policy to be applied, got MinVersion %x, CipherSuites %v", tlsConfig.MinVersion, tlsConfig.CipherSuites)
+	}
+
+	// A higher minimum version of the credentials is kept
+	creds = client.LoadTLS(&tls.Config{MinVersion: tls.VersionTLS13})
+	if tlsConfig, err := withTLSPolicy(Config{TLSMinVersion: tls.VersionTLS12}, creds).TLSConfig(); err != nil || tlsConfig.MinVersion != tls.VersionTLS13 {
+		t.Errorf("expected MinVersion TLS 1.3, got %v, %v", tlsConfig, err)
+	}
+}
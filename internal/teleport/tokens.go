@@ -0,0 +1,125 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/trace"
+)
+
+// TokenInfo represents information about a Teleport provision token.
+type TokenInfo struct {
+	// Name is the name of the token, masked for the token join method, where
+	// the name is the secret.
+	Name       string   `json:"name"`
+	JoinMethod string   `json:"joinMethod"`
+	Roles      []string `json:"roles"`
+	// Expires is when the token expires, nil if it does not expire.
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// tokensClient is the part of the Teleport API client that lists provision
+// tokens.
+type tokensClient interface {
+	ListProvisionTokens(ctx context.Context, pageSize int, pageToken string, anyRoles types.SystemRoles, botName string) ([]types.ProvisionToken, string, error)
+}
+
+// GetTokens returns all provision tokens of Teleport, including the expired
+// ones Teleport did not delete yet. The result is served from the cache if
+// one is configured for the resource type.
+func (c *Client) GetTokens(ctx context.Context) ([]TokenInfo, error) {
+	return c.tokensCache.get(ctx, c.fetchTokens)
+}
+
+// fetchTokens fetches the provision tokens from the Teleport API.
+func (c *Client) fetchTokens(ctx context.Context) ([]TokenInfo, error) {
+	c.log.V(1).Info("fetching provision tokens from Teleport")
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if err := c.injectFault(ctx, "GetTokens"); err != nil {
+		return nil, err
+	}
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	result := make([]TokenInfo, 0)
+	clt, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	err = forEachToken(ctx, clt, c.pageSize, func(token types.ProvisionToken) {
+		result = append(result, tokenInfo(token))
+	})
+	observe("ListProvisionTokens", start, err)
+	if err != nil {
+		c.log.Error(err, "failed to get provision tokens")
+		return nil, err
+	}
+
+	c.log.V(1).Info("fetched provision tokens", "count", len(result))
+	return result, nil
+}
+
+// forEachToken calls fn for every provision token, fetching pageSize tokens
+// per request (Teleport default if zero).
+func forEachToken(ctx context.Context, clt tokensClient, pageSize int, fn func(types.ProvisionToken)) error {
+	pageToken := ""
+	for {
+		tokens, next, err := clt.ListProvisionTokens(ctx, pageSize, pageToken, nil, "")
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, token := range tokens {
+			fn(token)
+		}
+		if next == "" || len(tokens) == 0 {
+			return nil
+		}
+		pageToken = next
+	}
+}
+
+// tokenInfo converts a Teleport provision token into a TokenInfo.
+func tokenInfo(token types.ProvisionToken) TokenInfo {
+	info := TokenInfo{
+		Name:       token.GetSafeName(),
+		JoinMethod: string(token.GetJoinMethod()),
+		Roles:      token.GetRoles().StringSlice(),
+	}
+	if expires := token.Expiry(); !expires.IsZero() {
+		info.Expires = &expires
+	}
+	return info
+}
+
+// checkTokens lists a single provision token, which fails with an access
+// denied error if the identity may not list tokens.
+func checkTokens(ctx context.Context, clt tokensClient) error {
+	_, _, err := clt.ListProvisionTokens(ctx, 1, "", nil, "")
+	return trace.Wrap(err)
+}
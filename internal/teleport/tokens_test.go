@@ -0,0 +1,120 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/trace"
+)
+
+// fakeTokensClient serves provision tokens page by page and records the
+// requested page sizes.
+type fakeTokensClient struct {
+	tokens    []types.ProvisionToken
+	pageSizes []int
+	err       error
+}
+
+func (f *fakeTokensClient) ListProvisionTokens(_ context.Context, pageSize int, pageToken string, _ types.SystemRoles, _ string) ([]types.ProvisionToken, string, error) {
+	f.pageSizes = append(f.pageSizes, pageSize)
+	if f.err != nil {
+		return nil, "", f.err
+	}
+	start := 0
+	if pageToken != "" {
+		var err error
+		if start, err = strconv.Atoi(pageToken); err != nil {
+			return nil, "", err
+		}
+	}
+	end := min(start+pageSize, len(f.tokens))
+	next := ""
+	if end < len(f.tokens) {
+		next = strconv.Itoa(end)
+	}
+	return f.tokens[start:end], next, nil
+}
+
+func newToken(t *testing.T, name string, expires time.Time, spec types.ProvisionTokenSpecV2) types.ProvisionToken {
+	t.Helper()
+	token, err := types.NewProvisionTokenFromSpec(name, expires, spec)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	return token
+}
+
+func TestForEachToken(t *testing.T) {
+	clt := &fakeTokensClient{}
+	for i := range 3 {
+		clt.tokens = append(clt.tokens, newToken(t, fmt.Sprintf("token-%d", i), time.Time{},
+			types.ProvisionTokenSpecV2{Roles: types.SystemRoles{types.RoleNode}}))
+	}
+
+	count := 0
+	err := forEachToken(context.Background(), clt, 2, func(types.ProvisionToken) { count++ })
+	if err != nil {
+		t.Fatalf("forEachToken() failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 tokens, got %d", count)
+	}
+	if want := []int{2, 2}; !slices.Equal(clt.pageSizes, want) {
+		t.Errorf("expected page sizes %v, got %v", want, clt.pageSizes)
+	}
+}
+
+func TestTokenInfo(t *testing.T) {
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	secret := newToken(t, "0123456789abcdef", expires, types.ProvisionTokenSpecV2{
+		Roles:      types.SystemRoles{types.RoleNode, types.RoleApp},
+		JoinMethod: types.JoinMethodToken,
+	})
+	got := tokenInfo(secret)
+	if got.Name != "************cdef" {
+		t.Errorf("expected the name to be masked, got %q", got.Name)
+	}
+	if got.JoinMethod != string(types.JoinMethodToken) || !slices.Equal(got.Roles, []string{"Node", "App"}) {
+		t.Errorf("unexpected token %+v", got)
+	}
+	if got.Expires == nil || !got.Expires.Equal(expires) {
+		t.Errorf("expected the token to expire at %s, got %v", expires, got.Expires)
+	}
+
+	iam := newToken(t, "iam-token", time.Time{}, types.ProvisionTokenSpecV2{
+		Roles:      types.SystemRoles{types.RoleNode},
+		JoinMethod: types.JoinMethodIAM,
+		Allow:      []*types.TokenRule{{AWSAccount: "123456789012"}},
+	})
+	if got := tokenInfo(iam); got.Name != "iam-token" || got.Expires != nil {
+		t.Errorf("expected an unmasked token without expiry, got %+v", got)
+	}
+}
+
+func TestCheckTokens(t *testing.T) {
+	clt := &fakeTokensClient{err: trace.AccessDenied("access denied to perform action \"list\" on \"token\"")}
+	if reason := ErrorReason(checkTokens(context.Background(), clt)); reason != ErrorReasonPermissionDenied {
+		t.Errorf("expected reason %s, got %s", ErrorReasonPermissionDenied, reason)
+	}
+}
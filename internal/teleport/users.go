@@ -0,0 +1,208 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/teleport/api/constants"
+	userspb "github.com/gravitational/teleport/api/gen/proto/go/teleport/users/v1"
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/trace"
+)
+
+// User types of UserInfo.Type.
+const (
+	UserTypeLocal = string(types.UserTypeLocal)
+	UserTypeSSO   = string(types.UserTypeSSO)
+)
+
+// MFA states of UserInfo.MFA.
+const (
+	MFANone     = "none"
+	MFATOTP     = "totp"
+	MFAWebauthn = "webauthn"
+	// MFAUnknown is the state of users whose MFA devices Teleport did not
+	// track yet, e.g. users that did not log in since an upgrade to a
+	// version that tracks them.
+	MFAUnknown = "unknown"
+)
+
+// Origins of UserInfo.Origin besides the kinds of SSO connectors, e.g.
+// "github", and the origins of users synced from an identity service, e.g.
+// types.OriginOkta.
+const (
+	UserOriginLocal = "local"
+	UserOriginBot   = "bot"
+)
+
+// UserInfo represents information about a Teleport user.
+type UserInfo struct {
+	Name string `json:"name"`
+	// Type is UserTypeLocal for users created in Teleport and UserTypeSSO
+	// for users created by an SSO connector.
+	Type string `json:"type"`
+	// Bot is set for the users of Machine ID bots.
+	Bot bool `json:"bot,omitempty"`
+	// MFA is the weakest MFA device kind of the user, one of the MFA*
+	// constants.
+	MFA string `json:"mfa"`
+	// Origin is where the user comes from, see userOrigin.
+	Origin string `json:"origin,omitempty"`
+	// Roles are the roles assigned to the user.
+	Roles []string `json:"roles,omitempty"`
+	// LastLogin is when an SSO user last logged in, nil for other users.
+	// Teleport updates SSO users at every login, but records no login time
+	// of local users.
+	LastLogin *time.Time        `json:"lastLogin,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// usersClient is the part of the Teleport API client that lists users.
+type usersClient interface {
+	ListUsers(ctx context.Context, req *userspb.ListUsersRequest) (*userspb.ListUsersResponse, error)
+}
+
+// GetUsers returns all users of Teleport. The result is served from the cache
+// if one is configured for the resource type.
+func (c *Client) GetUsers(ctx context.Context) ([]UserInfo, error) {
+	return c.usersCache.get(ctx, c.fetchUsers)
+}
+
+// fetchUsers fetches the users from the Teleport API.
+func (c *Client) fetchUsers(ctx context.Context) ([]UserInfo, error) {
+	c.log.V(1).Info("fetching users from Teleport")
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if err := c.injectFault(ctx, "GetUsers"); err != nil {
+		return nil, err
+	}
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	result := make([]UserInfo, 0)
+	clt, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	err = forEachUser(ctx, clt, c.pageSize, func(user types.User) {
+		result = append(result, userInfo(user))
+	})
+	observe("ListUsers", start, err)
+	if err != nil {
+		c.log.Error(err, "failed to get users")
+		return nil, err
+	}
+
+	c.log.V(1).Info("fetched users", "count", len(result))
+	return result, nil
+}
+
+// forEachUser calls fn for every user, fetching pageSize users per request
+// (Teleport default if zero). Secrets like password hashes are not fetched.
+func forEachUser(ctx context.Context, clt usersClient, pageSize int, fn func(types.User)) error {
+	req := &userspb.ListUsersRequest{PageSize: int32(pageSize)}
+	for {
+		resp, err := clt.ListUsers(ctx, req)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, user := range resp.Users {
+			fn(user)
+		}
+		if resp.NextPageToken == "" || len(resp.Users) == 0 {
+			return nil
+		}
+		req.PageToken = resp.NextPageToken
+	}
+}
+
+// userInfo converts a Teleport user into a UserInfo.
+func userInfo(user types.User) UserInfo {
+	info := UserInfo{
+		Name:   user.GetName(),
+		Type:   string(user.GetUserType()),
+		Bot:    user.IsBot(),
+		MFA:    mfaState(user.GetWeakestDevice()),
+		Origin: userOrigin(user),
+		Roles:  user.GetRoles(),
+		Labels: user.GetAllLabels(),
+	}
+	// The time an SSO connector created the user is the time of the last
+	// login, since the connector recreates the user at every login
+	if createdBy := user.GetCreatedBy(); createdBy.Connector != nil && !createdBy.Time.IsZero() {
+		lastLogin := createdBy.Time
+		info.LastLogin = &lastLogin
+	}
+	return info
+}
+
+// userOrigin returns UserOriginBot for bots, the origin of users synced from
+// Okta or Entra ID, the kind of the SSO connector that created other SSO
+// users, e.g. "saml", and UserOriginLocal for all others.
+func userOrigin(user types.User) string {
+	if user.IsBot() {
+		return UserOriginBot
+	}
+	switch origin := user.Origin(); origin {
+	case types.OriginOkta, types.OriginEntraID:
+		return origin
+	}
+	if connector := user.GetCreatedBy().Connector; connector != nil && connector.Type != "" {
+		return connector.Type
+	}
+	switch {
+	case len(user.GetSAMLIdentities()) > 0:
+		return constants.SAML
+	case len(user.GetOIDCIdentities()) > 0:
+		return constants.OIDC
+	case len(user.GetGithubIdentities()) > 0:
+		return constants.Github
+	}
+	return UserOriginLocal
+}
+
+// mfaState converts the weakest MFA device kind of a user into one of the
+// MFA* constants.
+func mfaState(kind types.MFADeviceKind) string {
+	switch kind {
+	case types.MFADeviceKind_MFA_DEVICE_KIND_UNSET:
+		return MFANone
+	case types.MFADeviceKind_MFA_DEVICE_KIND_TOTP:
+		return MFATOTP
+	case types.MFADeviceKind_MFA_DEVICE_KIND_WEBAUTHN:
+		return MFAWebauthn
+	default:
+		return MFAUnknown
+	}
+}
+
+// checkUsers lists a single user, which fails with an access denied error if
+// the identity may not list users.
+func checkUsers(ctx context.Context, clt usersClient) error {
+	_, err := clt.ListUsers(ctx, &userspb.ListUsersRequest{PageSize: 1})
+	return trace.Wrap(err)
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package teleport
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	userspb "github.com/gravitational/teleport/api/gen/proto/go/teleport/users/v1"
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/trace"
+)
+
+// fakeUsersClient serves users page by page and records the requested page
+// sizes.
+type fakeUsersClient struct {
+	users     []*types.UserV2
+	pageSizes []int32
+	err       error
+}
+
+func (f *fakeUsersClient) ListUsers(_ context.Context, req *userspb.ListUsersRequest) (*userspb.ListUsersResponse, error) {
+	f.pageSizes = append(f.pageSizes, req.PageSize)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if req.WithSecrets {
+		return nil, trace.AccessDenied("secrets requested")
+	}
+	start := 0
+	if req.PageToken != "" {
+		var err error
+		if start, err = strconv.Atoi(req.PageToken); err != nil {
+			return nil, err
+		}
+	}
+	end := min(start+int(req.PageSize), len(f.users))
+
+	resp := &userspb.ListUsersResponse{Users: f.users[start:end]}
+	if end < len(f.users) {
+		resp.NextPageToken = strconv.Itoa(end)
+	}
+	return resp, nil
+}
+
+func newUser(t *testing.T, name string) *types.UserV2 {
+	t.Helper()
+	user, err := types.NewUser(name)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user.(*types.UserV2)
+}
+
+func TestForEachUser(t *testing.T) {
+	clt := &fakeUsersClient{}
+	for i := range 5 {
+		clt.users = append(clt.users, newUser(t, fmt.Sprintf("user-%d", i)))
+	}
+
+	var names []string
+	err := forEachUser(context.Background(), clt, 2, func(user types.User) {
+		names = append(names, user.GetName())
+	})
+	if err != nil {
+		t.Fatalf("forEachUser() failed: %v", err)
+	}
+	if want := []string{"user-0", "user-1", "user-2", "user-3", "user-4"}; !slices.Equal(names, want) {
+		t.Errorf("expected users %v, got %v", want, names)
+	}
+	if want := []int32{2, 2, 2}; !slices.Equal(clt.pageSizes, want) {
+		t.Errorf("expected page sizes %v, got %v", want, clt.pageSizes)
+	}
+}
+
+func TestUserInfo(t *testing.T) {
+	local := newUser(t, "alice")
+	local.SetWeakestDevice(types.MFADeviceKind_MFA_DEVICE_KIND_UNSET)
+	local.SetRoles([]string{"access", "editor"})
+	if got := userInfo(local); got.Type != string(types.UserTypeLocal) || got.Bot || got.MFA != MFANone || !slices.Equal(got.Roles, []string{"access", "editor"}) {
+		t.Errorf("unexpected local user %+v", got)
+	}
+
+	if got := userInfo(local); got.LastLogin != nil {
+		t.Errorf("expected no last login of a local user, got %v", got.LastLogin)
+	}
+
+	loggedIn := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	sso := newUser(t, "bob@example.com")
+	sso.SetCreatedBy(types.CreatedBy{Connector: &types.ConnectorRef{Type: "saml", ID: "okta"}, Time: loggedIn})
+	sso.SetWeakestDevice(types.MFADeviceKind_MFA_DEVICE_KIND_WEBAUTHN)
+	if got := userInfo(sso); got.Type != string(types.UserTypeSSO) || got.MFA != MFAWebauthn {
+		t.Errorf("unexpected SSO user %+v", got)
+	}
+	if got := userInfo(sso); got.LastLogin == nil || !got.LastLogin.Equal(loggedIn) {
+		t.Errorf("expected the last login at %v, got %v", loggedIn, got.LastLogin)
+	}
+
+	bot := newUser(t, "bot-ci")
+	bot.SetStaticLabels(map[string]string{types.BotLabel: "ci"})
+	if got := userInfo(bot); !got.Bot || got.MFA != MFAUnknown {
+		t.Errorf("unexpected bot user %+v", got)
+	}
+}
+
+func TestUserOrigin(t *testing.T) {
+	local := newUser(t, "alice")
+	if got := userOrigin(local); got != UserOriginLocal {
+		t.Errorf("expected origin %s for a local user, got %s", UserOriginLocal, got)
+	}
+
+	github := newUser(t, "bob")
+	github.SetCreatedBy(types.CreatedBy{Connector: &types.ConnectorRef{Type: "github", ID: "github"}})
+	if got := userOrigin(github); got != "github" {
+		t.Errorf("expected origin github for a user created by a GitHub connector, got %s", got)
+	}
+
+	oidc := newUser(t, "carol")
+	oidc.Spec.OIDCIdentities = []types.ExternalIdentity{{ConnectorID: "google", Username: "carol"}}
+	if got := userOrigin(oidc); got != "oidc" {
+		t.Errorf("expected origin oidc for a user with an OIDC identity, got %s", got)
+	}
+
+	okta := newUser(t, "dave@example.com")
+	okta.SetOrigin(types.OriginOkta)
+	okta.SetCreatedBy(types.CreatedBy{Connector: &types.ConnectorRef{Type: "saml", ID: "okta"}})
+	if got := userOrigin(okta); got != types.OriginOkta {
+		t.Errorf("expected origin %s for a user synced from Okta, got %s", types.OriginOkta, got)
+	}
+
+	bot := newUser(t, "bot-ci")
+	bot.SetStaticLabels(map[string]string{types.BotLabel: "ci"})
+	if got := userOrigin(bot); got != UserOriginBot {
+		t.Errorf("expected origin %s for a bot, got %s", UserOriginBot, got)
+	}
+}
+
+func TestMFAState(t *testing.T) {
+	tests := map[types.MFADeviceKind]string{
+		types.MFADeviceKind_MFA_DEVICE_KIND_UNSPECIFIED: MFAUnknown,
+		types.MFADeviceKind_MFA_DEVICE_KIND_UNSET:       MFANone,
+		types.MFADeviceKind_MFA_DEVICE_KIND_TOTP:        MFATOTP,
+		types.MFADeviceKind_MFA_DEVICE_KIND_WEBAUTHN:    MFAWebauthn,
+	}
+	for kind, want := range tests {
+		if got := mfaState(kind); got != want {
+			t.Errorf("mfaState(%s) = %q, want %q", kind, got, want)
+		}
+	}
+}
+
+func TestCheckUsers(t *testing.T) {
+	clt := &fakeUsersClient{}
+	if err := checkUsers(context.Background(), clt); err != nil {
+		t.Fatalf("checkUsers() failed: %v", err)
+	}
+	if len(clt.pageSizes) != 1 || clt.pageSizes[0] != 1 {
+		t.Errorf("expected a single request for 1 user, got page sizes %v", clt.pageSizes)
+	}
+
+	clt = &fakeUsersClient{err: trace.AccessDenied("access denied to perform action \"list\" on \"user\"")}
+	if reason := ErrorReason(checkUsers(context.Background(), clt)); reason != ErrorReasonPermissionDenied {
+		t.Errorf("expected reason %s, got %s", ErrorReasonPermissionDenied, reason)
+	}
+}
@@ -0,0 +1,331 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package validate implements the validate subcommand, which checks the
+// exporter configuration, the connection to Teleport and the permissions of
+// the identity, e.g. in CI before rolling out configuration changes.
+package validate
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/zapr"
+	"github.com/prometheus/exporter-toolkit/web"
+	"go.uber.org/zap"
+
+	"github.com/giantswarm/teleport-exporter/internal/awsidentity"
+	"github.com/giantswarm/teleport-exporter/internal/collector"
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/teleport"
+)
+
+// accessOrder is the order in which the access checks are reported.
+var accessOrder = []string{
+	teleport.AccessClusterName,
+	teleport.CacheNodes,
+	teleport.CacheKubeClusters,
+	teleport.CacheDatabases,
+	teleport.CacheApps,
+	teleport.CacheUsers,
+	teleport.CacheLocks,
+	teleport.CacheRoles,
+	teleport.CacheTokens,
+	teleport.CacheAccessRequests,
+	teleport.CacheSessions,
+	teleport.CacheRemoteClusters,
+	teleport.CacheCertAuthorities,
+}
+
+// Run runs the validate subcommand with the given arguments and returns the
+// exit code: 0 if all checks passed, 1 otherwise.
+func Run(args []string) int {
+	return run(args, os.Stdout)
+}
+
+// run runs the validate subcommand and writes the report to w.
+func run(args []string, w io.Writer) int {
+	var (
+		teleportAddr      string
+		identityFile      string
+		awsSecret         string
+		awsParameter      string
+		certFile          string
+		keyFile           string
+		credsCAFile       string
+		profileName       string
+		profileDir        string
+		apiTimeout        time.Duration
+		insecure          bool
+		alpnUpgrade       bool
+		caFile            string
+		tlsMin            string
+		tlsCiphers        string
+		namespaces        string
+		connMode          string
+		shardFlag         string
+		extraResources    string
+		cacheTTLs         string
+		redactFields      string
+		redactMode        string
+		webConfigFile     string
+		nodeLabels        string
+		kubeClusterLabels string
+		databaseLabels    string
+		appLabels         string
+		offline           bool
+	)
+
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.StringVar(&teleportAddr, "teleport-addr", "", "The address of the Teleport proxy/auth server (e.g., teleport.example.com:443).")
+	fs.StringVar(&identityFile, "identity-file", "", "Path to the identity file for authentication.")
+	fs.StringVar(&awsSecret, "identity-aws-secret", "", "Name or ARN of an AWS Secrets Manager secret holding the identity file content.")
+	fs.StringVar(&awsParameter, "identity-aws-parameter", "", "Name or ARN of an AWS SSM parameter holding the identity file content.")
+	fs.StringVar(&certFile, "cert-file", "", "Path to a PEM encoded Teleport client certificate to authenticate with.")
+	fs.StringVar(&keyFile, "key-file", "", "Path to the PEM encoded private key of cert-file.")
+	fs.StringVar(&credsCAFile, "ca-file", "", "Path to the PEM encoded Teleport cluster CA certificates.")
+	fs.StringVar(&profileName, "profile", "", "Name of a tsh profile to authenticate with.")
+	fs.StringVar(&profileDir, "profile-dir", "", "Directory of the tsh profiles (default ~/.tsh).")
+	fs.DurationVar(&apiTimeout, "api-timeout", 30*time.Second, "Timeout for Teleport API calls.")
+	fs.BoolVar(&insecure, "insecure", false, "Skip TLS certificate verification (not recommended for production).")
+	fs.BoolVar(&alpnUpgrade, "alpn-conn-upgrade", false, "Tunnel the connection to Teleport through an HTTP upgrade, for proxies with TLS routing behind load balancers that strip ALPN.")
+	fs.StringVar(&connMode, "connection-mode", teleport.ConnectionModeAuto, "How to connect to --teleport-addr: auto, proxy or auth.")
+	fs.StringVar(&caFile, "teleport-ca-file", "", "Path to a PEM bundle of CA certificates to verify the Teleport proxy with, in addition to the system CAs.")
+	fs.StringVar(&tlsMin, "tls-min-version", "1.2", "Minimum TLS version of the connection to Teleport: 1.2 or 1.3.")
+	fs.StringVar(&namespaces, "teleport-namespace", "default", "Comma-separated list of Teleport namespaces; access is checked in the first one.")
+	fs.StringVar(&tlsCiphers, "tls-cipher-suites", "", "Comma separated TLS 1.2 cipher suites allowed on the connection to Teleport (Go defaults if empty).")
+	fs.StringVar(&shardFlag, "shard", "", "Only check access to the resource types of this shard, as N/M.")
+	fs.StringVar(&extraResources, "extra-resources", "", "Comma-separated list of optional resource types to also check access to: users, locks, roles, tokens, access_requests, sessions.")
+	fs.StringVar(&cacheTTLs, "cache-ttl", "", "Cache TTLs to validate, as for the exporter.")
+	fs.StringVar(&redactFields, "redact-fields", "", "Comma-separated list of fields to redact to validate: hostname, address, public_addr, uri.")
+	fs.StringVar(&redactMode, "redact-mode", collector.RedactModeHash, "How to redact --redact-fields: hash or drop.")
+	fs.StringVar(&webConfigFile, "web.config.file", "", "Path to an exporter-toolkit web configuration file to validate.")
+	fs.StringVar(&nodeLabels, "node-label-to-metric-label", "", "Comma-separated list of Teleport node labels to validate.")
+	fs.StringVar(&kubeClusterLabels, "kube-cluster-label-to-metric-label", "", "Comma-separated list of Teleport Kubernetes cluster labels to validate.")
+	fs.StringVar(&databaseLabels, "database-label-to-metric-label", "", "Comma-separated list of Teleport database labels to validate.")
+	fs.StringVar(&appLabels, "app-label-to-metric-label", "", "Comma-separated list of Teleport application labels to validate.")
+	fs.BoolVar(&offline, "offline", false, "Only validate the configuration, without connecting to Teleport.")
+	fs.Parse(args)
+
+	zapLog, err := zap.NewProduction()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create logger: %v\n", err)
+		return 1
+	}
+	defer zapLog.Sync()
+	log := zapr.NewLogger(zapLog)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	var r report
+
+	var identityContent func() string
+	if (awsSecret != "" || awsParameter != "") && !offline {
+		src, err := awsidentity.New(ctx, awsidentity.Config{
+			SecretID:      awsSecret,
+			ParameterName: awsParameter,
+			Log:           log.WithName("aws-identity"),
+		})
+		if r.add("aws identity", strings.Join(splitList(awsSecret+","+awsParameter), ", "), err) {
+			identityContent = src.Identity
+		}
+	}
+
+	credsCfg := teleport.Config{
+		IdentityFile:    identityFile,
+		IdentityContent: identityContent,
+		CertFile:        certFile,
+		KeyFile:         keyFile,
+		CAFile:          credsCAFile,
+		Profile:         profileName,
+	}
+	if offline && (awsSecret != "" || awsParameter != "") {
+		// The identity is not fetched offline, only its source is checked
+		credsCfg.IdentityContent = func() string { return "" }
+	}
+	r.add("credentials", "", teleport.ValidateCredentials(credsCfg))
+
+	if teleportAddr == "" && profileName != "" {
+		teleportAddr, err = teleport.ProfileProxyAddr(profileDir, profileName)
+		r.add("profile", profileName, err)
+	}
+	if teleportAddr == "" {
+		r.add("teleport-addr", "", errors.New("teleport-addr is required"))
+	}
+	switch connMode {
+	case teleport.ConnectionModeAuto, teleport.ConnectionModeProxy, teleport.ConnectionModeAuth:
+	default:
+		r.add("connection-mode", connMode, fmt.Errorf("invalid connection mode %q, must be auto, proxy or auth", connMode))
+	}
+
+	minTLSVersion, err := teleport.ParseTLSVersion(tlsMin)
+	r.add("tls-min-version", tlsMin, err)
+	cipherSuites, err := teleport.ParseCipherSuites(tlsCiphers)
+	r.add("tls-cipher-suites", tlsCiphers, err)
+	if caFile != "" {
+		r.add("teleport-ca-file", caFile, teleport.UseCAFile(caFile))
+	}
+
+	shard, err := collector.ParseShard(shardFlag)
+	r.add("shard", shardFlag, err)
+	extraResourceList, err := collector.ParseExtraResources(extraResources)
+	r.add("extra-resources", extraResources, err)
+	_, err = collector.ParseRedaction(redactFields, redactMode)
+	r.add("redaction", redactFields, err)
+	_, err = teleport.ParseCacheTTLs(cacheTTLs)
+	r.add("cache-ttl", cacheTTLs, err)
+	r.add("metric labels", "", metrics.Setup(nil, metrics.Options{InfoLabels: metrics.InfoLabels{
+		Node:        splitList(nodeLabels),
+		KubeCluster: splitList(kubeClusterLabels),
+		Database:    splitList(databaseLabels),
+		App:         splitList(appLabels),
+	}}))
+	if webConfigFile != "" {
+		r.add("web.config.file", webConfigFile, web.Validate(webConfigFile))
+	}
+
+	if offline || r.failed() {
+		// Connecting with an invalid configuration only adds noise
+		r.write(w)
+		return r.exitCode()
+	}
+
+	client, err := teleport.NewClient(teleport.Config{
+		ProxyAddr:       teleportAddr,
+		IdentityFile:    identityFile,
+		IdentityContent: identityContent,
+		CertFile:        certFile,
+		KeyFile:         keyFile,
+		CAFile:          credsCAFile,
+		Profile:         profileName,
+		ProfileDir:      profileDir,
+		Insecure:        insecure,
+		ConnectionMode:  connMode,
+		ALPNConnUpgrade: alpnUpgrade,
+		TLSMinVersion:   minTLSVersion,
+		TLSCipherSuites: cipherSuites,
+		APITimeout:      apiTimeout,
+		Namespaces:      teleport.ParseNamespaces(namespaces),
+		Log:             log.WithName("teleport-client"),
+	})
+	if !r.add("connect", teleportAddr, err) {
+		r.write(w)
+		return r.exitCode()
+	}
+	defer client.Close()
+
+	col := collector.New(collector.Config{
+		TeleportClient: client,
+		APITimeout:     apiTimeout,
+		Shard:          shard,
+		ExtraResources: extraResourceList,
+		Log:            log.WithName("collector"),
+	})
+	results := col.CheckAccess(ctx)
+	for _, resource := range accessOrder {
+		if err, ok := results[resource]; ok {
+			r.add("access "+resource, "", err)
+		}
+	}
+
+	r.write(w)
+	return r.exitCode()
+}
+
+// check is the result of a single validation step.
+type check struct {
+	name   string
+	detail string
+	err    error
+}
+
+// report collects the results of the validation steps.
+type report struct {
+	checks []check
+}
+
+// add records the result of a check and reports whether it passed.
+func (r *report) add(name, detail string, err error) bool {
+	r.checks = append(r.checks, check{name: name, detail: detail, err: err})
+	return err == nil
+}
+
+// failed reports whether any check failed.
+func (r *report) failed() bool {
+	for _, c := range r.checks {
+		if c.err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// exitCode returns 1 if any check failed, 0 otherwise.
+func (r *report) exitCode() int {
+	if r.failed() {
+		return 1
+	}
+	return 0
+}
+
+// write writes one line per check, followed by a summary.
+func (r *report) write(w io.Writer) {
+	failures := 0
+	for _, c := range r.checks {
+		status := "OK"
+		if c.err != nil {
+			status = "FAIL"
+			failures++
+		}
+		line := fmt.Sprintf("%-4s  %s", status, c.name)
+		if c.detail != "" {
+			line += " (" + c.detail + ")"
+		}
+		if c.err != nil {
+			reason := teleport.ErrorReason(c.err)
+			if reason != teleport.ErrorReasonOther {
+				line += ": " + reason
+			}
+			line += ": " + c.err.Error()
+		}
+		fmt.Fprintln(w, line)
+	}
+	if failures > 0 {
+		fmt.Fprintf(w, "%d of %d checks failed\n", failures, len(r.checks))
+		return
+	}
+	fmt.Fprintf(w, "all %d checks passed\n", len(r.checks))
+}
+
+// splitList splits a comma-separated flag value into its non-empty, trimmed elements.
+func splitList(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
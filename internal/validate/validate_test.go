@@ -0,0 +1,70 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gravitational/trace"
+)
+
+func TestReport(t *testing.T) {
+	var r report
+	if !r.add("shard", "0/2", nil) {
+		t.Error("expected a check without error to pass")
+	}
+	if r.failed() || r.exitCode() != 0 {
+		t.Error("expected the report to pass without failed checks")
+	}
+	r.add("access nodes", "", trace.AccessDenied("access denied to perform action \"list\" on \"node\""))
+	r.add("cache-ttl", "", errors.New("invalid cache TTL"))
+	if !r.failed() || r.exitCode() != 1 {
+		t.Error("expected the report to fail with failed checks")
+	}
+
+	var buf bytes.Buffer
+	r.write(&buf)
+	want := []string{
+		"OK    shard (0/2)",
+		`FAIL  access nodes: permission_denied: access denied to perform action "list" on "node"`,
+		"FAIL  cache-ttl: invalid cache TTL",
+		"2 of 3 checks failed",
+	}
+	if got := strings.Split(strings.TrimSpace(buf.String()), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected report:\n%s\nwant:\n%s", buf.String(), strings.Join(want, "\n"))
+	}
+}
+
+func TestRun_Offline(t *testing.T) {
+	var buf bytes.Buffer
+	code := run([]string{"-offline", "-identity-file", "/does/not/matter", "-teleport-addr", "teleport.example.com:443", "-shard", "2/2"}, &buf)
+	if code != 1 {
+		t.Errorf("expected exit code 1 for an invalid shard, got %d", code)
+	}
+	if !strings.Contains(buf.String(), "FAIL  shard (2/2)") {
+		t.Errorf("expected the shard check to fail, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	code = run([]string{"-offline", "-identity-file", "/does/not/matter", "-teleport-addr", "teleport.example.com:443"}, &buf)
+	if code != 0 {
+		t.Errorf("expected exit code 0 for a valid configuration, got %d:\n%s", code, buf.String())
+	}
+}
@@ -18,20 +18,50 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
+	apidefaults "github.com/gravitational/teleport/api/defaults"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/exporter-toolkit/web"
 	"go.uber.org/zap"
+	"go.yaml.in/yaml/v2"
 
+	"github.com/giantswarm/teleport-exporter/internal/awsidentity"
 	"github.com/giantswarm/teleport-exporter/internal/collector"
+	"github.com/giantswarm/teleport-exporter/internal/docs"
+	"github.com/giantswarm/teleport-exporter/internal/emf"
+	"github.com/giantswarm/teleport-exporter/internal/export"
+	"github.com/giantswarm/teleport-exporter/internal/gen"
+	"github.com/giantswarm/teleport-exporter/internal/httpauth"
+	"github.com/giantswarm/teleport-exporter/internal/metrics"
+	"github.com/giantswarm/teleport-exporter/internal/mock"
+	"github.com/giantswarm/teleport-exporter/internal/otlp"
+	"github.com/giantswarm/teleport-exporter/internal/probe"
+	"github.com/giantswarm/teleport-exporter/internal/replay"
+	"github.com/giantswarm/teleport-exporter/internal/statsd"
 	"github.com/giantswarm/teleport-exporter/internal/teleport"
+	"github.com/giantswarm/teleport-exporter/internal/validate"
 	"github.com/giantswarm/teleport-exporter/internal/version"
 )
 
@@ -45,25 +75,229 @@ const (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			os.Exit(export.Run(os.Args[2:]))
+		case "validate":
+			os.Exit(validate.Run(os.Args[2:]))
+		case "docs-metrics":
+			os.Exit(docs.Run(os.Args[2:]))
+		case "gen":
+			os.Exit(gen.Run(os.Args[2:]))
+		}
+	}
+
 	var (
-		metricsAddr     string
-		probeAddr       string
-		teleportAddr    string
-		identityFile    string
-		refreshInterval time.Duration
-		apiTimeout      time.Duration
-		insecure        bool
-		showVersion     bool
+		metricsAddr          string
+		probeAddr            string
+		teleportAddr         string
+		identityFile         string
+		identityAWSSecret    string
+		identityAWSParameter string
+		identityAWSRefresh   time.Duration
+		certFile             string
+		keyFile              string
+		credsCAFile          string
+		profileName          string
+		profileDir           string
+		refreshInterval      time.Duration
+		refreshJitter        float64
+		collectOnStart       bool
+		collectTimeout       time.Duration
+		apiRetries           int
+		apiTimeout           time.Duration
+		dialTimeout          time.Duration
+		keepAliveTime        time.Duration
+		keepAliveTO          time.Duration
+		idleTimeout          time.Duration
+		healthCheckInterval  time.Duration
+		caRotationInterval   time.Duration
+		maxRecvMsgSize       int
+		insecure             bool
+		caFile               string
+		proxyURL             string
+		connectionMode       string
+		alpnConnUpgrade      bool
+		showVersion          bool
+
+		nodeLabels        string
+		kubeClusterLabels string
+		databaseLabels    string
+		appLabels         string
+
+		nodeGroupBy        string
+		kubeClusterGroupBy string
+		databaseGroupBy    string
+		appGroupBy         string
+		requiredLabels     string
+
+		maxSeriesPerMetric int
+		metricsNamespace   string
+		shardFlag          string
+		extraResources     string
+		cacheTTLs          string
+		maxConcurrentCalls int
+		listPageSize       int
+		namespaces         string
+		countsOnly         bool
+		nodeInventory      bool
+		perServer          bool
+		userWithoutMFAInfo bool
+		userLastLogin      bool
+		accessRequestSLA   time.Duration
+		stateFile          string
+		mockMode           bool
+		mockResources      string
+		mockChurn          float64
+		recordDir          string
+		replayDir          string
+		faultInjection     string
+		redactFields       string
+		redactMode         string
+		deniedInterval     time.Duration
+
+		readinessMaxAge time.Duration
+		livenessMaxAge  time.Duration
+
+		tlsCertFile     string
+		tlsKeyFile      string
+		tlsClientCAFile string
+		tlsMinVersion   string
+		tlsCiphers      string
+
+		metricsBearerTokenFile string
+		metricsHtpasswdFile    string
+		webConfigFile          string
+		enableLifecycle        bool
+
+		enableGoCollector      bool
+		enableProcessCollector bool
+
+		otlpEndpoint string
+		otlpProtocol string
+		otlpHeaders  string
+		otlpInsecure bool
+		otlpInterval time.Duration
+
+		once           bool
+		pushgatewayURL string
+		pushgatewayJob string
+
+		statsdAddress  string
+		statsdInterval time.Duration
+
+		emfNamespace string
+		emfInterval  time.Duration
+
+		appProbeInterval time.Duration
+		appProbeTimeout  time.Duration
+		appProbeTarget   string
+
+		dbProbeInterval time.Duration
+		dbProbeTimeout  time.Duration
+		dbProbeSelector string
+		dbProbeUser     string
+		dbProbeDatabase string
 	)
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.StringVar(&teleportAddr, "teleport-addr", "", "The address of the Teleport proxy/auth server (e.g., teleport.example.com:443).")
 	flag.StringVar(&identityFile, "identity-file", "", "Path to the identity file for authentication.")
+	flag.StringVar(&identityAWSSecret, "identity-aws-secret", "", "Name or ARN of an AWS Secrets Manager secret holding the identity file content.")
+	flag.StringVar(&identityAWSParameter, "identity-aws-parameter", "", "Name or ARN of an AWS SSM parameter holding the identity file content.")
+	flag.DurationVar(&identityAWSRefresh, "identity-aws-refresh-interval", 5*time.Minute, "How often the identity is fetched again from AWS; the client reconnects when it changed.")
+	flag.StringVar(&certFile, "cert-file", "", "Path to a PEM encoded Teleport client certificate to authenticate with; requires key-file and ca-file. Credential sources are tried in the order identity-file, identity-aws-secret or identity-aws-parameter, cert-file, profile.")
+	flag.StringVar(&keyFile, "key-file", "", "Path to the PEM encoded private key of cert-file.")
+	flag.StringVar(&credsCAFile, "ca-file", "", "Path to the PEM encoded Teleport cluster CA certificates to verify the auth server with when using cert-file.")
+	flag.StringVar(&profileName, "profile", "", "Name of a tsh profile, usually the proxy host name, to authenticate with; for local development.")
+	flag.StringVar(&profileDir, "profile-dir", "", "Directory of the tsh profiles (default ~/.tsh).")
 	flag.DurationVar(&refreshInterval, "refresh-interval", 60*time.Second, "How often to refresh metrics from Teleport API.")
+	flag.Float64Var(&refreshJitter, "refresh-jitter", collector.DefaultJitterFraction, "Fraction of refresh-interval by which each interval is randomly shortened or lengthened, e.g. 0.1 for ±10% (0 = fixed intervals).")
+	flag.BoolVar(&collectOnStart, "collect-on-start", false, "Collect right after startup instead of after a random delay of up to a quarter of refresh-interval.")
+	flag.DurationVar(&collectTimeout, "collect-timeout", 0, "Timeout of a whole collection, after which the remaining resource types are skipped and the results gathered so far are kept (0 = refresh-interval).")
 	flag.DurationVar(&apiTimeout, "api-timeout", 30*time.Second, "Timeout for Teleport API calls.")
+	flag.IntVar(&apiRetries, "api-retries", 2, "How often an API call failing with a timeout or connection error is retried within a collection, after 1s, 2s, 4s and so on, before the resource type counts as failed.")
+	flag.DurationVar(&dialTimeout, "dial-timeout", 0, "Timeout of each attempt to dial Teleport (0 = Teleport default of 30s).")
+	flag.DurationVar(&keepAliveTime, "keepalive-time", 0, "Interval of keepalive pings on the connection to Teleport (0 = Teleport default of 1m).")
+	flag.DurationVar(&keepAliveTO, "keepalive-timeout", 0, "How long unanswered keepalive pings are tolerated before the connection to Teleport is closed, rounded up to a multiple of keepalive-time (0 = Teleport default of 3 keepalive intervals).")
+	flag.DurationVar(&idleTimeout, "grpc-idle-timeout", 0, "How long the connection to Teleport may be unused before it is closed, to be reopened by the next API call (0 = gRPC default of 30m).")
+	flag.DurationVar(&healthCheckInterval, "health-check-interval", 30*time.Second, "Interval of the health checks (Ping) of the connection to Teleport, reported in teleport_exporter_connection_healthy (0 = disabled).")
+	flag.DurationVar(&caRotationInterval, "ca-rotation-check-interval", 5*time.Minute, "Interval of the checks for rotations of the Teleport cluster CAs, which reload the credentials when a rotation phase changes (0 = disabled).")
+	flag.IntVar(&maxRecvMsgSize, "grpc-max-recv-msg-size", 0, "Maximum size in bytes of a message received from Teleport, e.g. a page of resources (0 = Teleport default of 4MiB).")
+	flag.DurationVar(&readinessMaxAge, "readiness-max-age", 0, "Maximum age of the last successful collection before /readyz fails (default 3x refresh-interval).")
+	flag.DurationVar(&livenessMaxAge, "liveness-max-age", 0, "Maximum age of the collector loop heartbeat before /healthz fails (default the larger of collect-timeout and refresh-interval plus 10x api-timeout).")
 	flag.BoolVar(&insecure, "insecure", false, "Skip TLS certificate verification (not recommended for production).")
+	flag.StringVar(&caFile, "teleport-ca-file", "", "Path to a PEM bundle of CA certificates to verify the Teleport proxy with, in addition to the system CAs, for proxies with certificates of a private CA.")
+	flag.StringVar(&connectionMode, "connection-mode", teleport.ConnectionModeAuto, "How to connect to --teleport-addr: auto pings it as a proxy and connects through it with the detected settings, or tries all connection methods at once if it is not a proxy; proxy connects to the auth server through the proxy at the address, auth connects directly to the auth server at the address.")
+	flag.StringVar(&proxyURL, "proxy-url", "", "URL of an HTTP CONNECT or SOCKS5 proxy to dial Teleport through (e.g., http://proxy.example.com:3128 or socks5://proxy.example.com:1080); only used for the connection to Teleport, which then ignores HTTPS_PROXY and NO_PROXY.")
+	flag.BoolVar(&alpnConnUpgrade, "alpn-conn-upgrade", false, "Tunnel the connection to Teleport through an HTTP upgrade, for proxies with TLS routing behind load balancers that strip ALPN.")
 	flag.BoolVar(&showVersion, "version", false, "Print version information and exit.")
+	flag.StringVar(&nodeLabels, "node-label-to-metric-label", "", "Comma-separated list of Teleport node labels to add to teleport_exporter_node_info (e.g., env,region).")
+	flag.StringVar(&kubeClusterLabels, "kube-cluster-label-to-metric-label", "", "Comma-separated list of Teleport Kubernetes cluster labels to add to teleport_exporter_kubernetes_cluster_info.")
+	flag.StringVar(&databaseLabels, "database-label-to-metric-label", "", "Comma-separated list of Teleport database labels to add to teleport_exporter_database_info.")
+	flag.StringVar(&appLabels, "app-label-to-metric-label", "", "Comma-separated list of Teleport application labels to add to teleport_exporter_app_info.")
+	flag.StringVar(&nodeGroupBy, "node-group-by", "", "Comma-separated list of Teleport node label groups to count nodes by in teleport_exporter_nodes_by_label; join several labels of a group with + (e.g., cluster,role+env).")
+	flag.StringVar(&kubeClusterGroupBy, "kube-cluster-group-by", "", "Comma-separated list of Teleport Kubernetes cluster label groups to count clusters by in teleport_exporter_kubernetes_clusters_by_label, for fleet breakdowns without joining the info series; join several labels of a group with + (e.g., region,cloud).")
+	flag.StringVar(&databaseGroupBy, "database-group-by", "", "Comma-separated list of Teleport database label groups to count databases by in teleport_exporter_databases_by_label.")
+	flag.StringVar(&appGroupBy, "app-group-by", "", "Comma-separated list of Teleport application label groups to count applications by in teleport_exporter_apps_by_label.")
+	flag.StringVar(&requiredLabels, "required-labels", "", "Comma-separated list of Teleport labels every node, database and application must have; resources missing them are counted in teleport_exporter_resources_missing_label_total (e.g., team,env).")
+	flag.StringVar(&metricsNamespace, "metrics-namespace", metrics.DefaultNamespace, "Prefix of all exported metric names.")
+	flag.IntVar(&maxSeriesPerMetric, "max-series-per-metric", 10000, "Maximum number of series per *_info metric; when exceeded, the metric is not emitted at all (0 = unlimited).")
+	flag.StringVar(&tlsCertFile, "tls-cert-file", "", "Path to a TLS certificate to serve the metrics and probe endpoints over HTTPS.")
+	flag.StringVar(&tlsKeyFile, "tls-key-file", "", "Path to the private key of the TLS certificate.")
+	flag.StringVar(&tlsMinVersion, "tls-min-version", "1.2", "Minimum TLS version of the HTTPS endpoints and the connection to Teleport: 1.2 or 1.3.")
+	flag.StringVar(&tlsCiphers, "tls-cipher-suites", "", "Comma separated TLS 1.2 cipher suites allowed on the HTTPS endpoints and the connection to Teleport, e.g. TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 (Go defaults if empty).")
+	flag.StringVar(&tlsClientCAFile, "tls-client-ca-file", "", "Path to a CA bundle; if set, the metrics endpoint requires client certificates signed by it.")
+	flag.StringVar(&metricsBearerTokenFile, "metrics-bearer-token-file", "", "Path to a file with a bearer token required to access the metrics endpoint.")
+	flag.StringVar(&metricsHtpasswdFile, "metrics-htpasswd-file", "", "Path to an htpasswd file with bcrypt hashed passwords required to access the metrics endpoint via basic auth.")
+	flag.StringVar(&webConfigFile, "web.config.file", "", "Path to an exporter-toolkit web configuration file that configures TLS and authentication of the metrics endpoint.")
+	flag.BoolVar(&enableLifecycle, "web.enable-lifecycle", false, "Serve POST /-/collect and /-/reload on the metrics endpoint to trigger collections and reload credentials over HTTP. Requires authentication of the metrics endpoint: tls-client-ca-file, metrics-bearer-token-file, metrics-htpasswd-file, or basic_auth_users or a client_auth_type of RequireAndVerifyClientCert in web.config.file.")
+	flag.BoolVar(&enableGoCollector, "enable-go-collector", true, "Export Go runtime metrics (go_*).")
+	flag.BoolVar(&enableProcessCollector, "enable-process-collector", true, "Export process metrics (process_*).")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "", "host:port of an OpenTelemetry collector to push all metrics to via OTLP (disabled if empty).")
+	flag.StringVar(&otlpProtocol, "otlp-protocol", otlp.ProtocolGRPC, "OTLP protocol to push metrics with: grpc or http.")
+	flag.StringVar(&otlpHeaders, "otlp-headers", "", "Comma-separated list of key=value headers sent with every OTLP export (e.g., authorization=Bearer token).")
+	flag.BoolVar(&otlpInsecure, "otlp-insecure", false, "Push metrics to the OpenTelemetry collector without TLS.")
+	flag.DurationVar(&otlpInterval, "otlp-interval", 60*time.Second, "How often to push metrics to the OpenTelemetry collector.")
+	flag.BoolVar(&once, "once", false, "Collect once, write the metrics to stdout and exit with a non-zero code if the collection failed.")
+	flag.StringVar(&pushgatewayURL, "pushgateway-url", "", "URL of a Pushgateway; if set, the exporter collects once, pushes all metrics to it and exits.")
+	flag.StringVar(&pushgatewayJob, "pushgateway-job", "teleport-exporter", "Job name of the metrics pushed to the Pushgateway.")
+	flag.StringVar(&statsdAddress, "statsd-address", "", "host:port of a DogStatsD agent to mirror the exporter gauges and counters to (disabled if empty).")
+	flag.DurationVar(&statsdInterval, "statsd-interval", 60*time.Second, "How often to send metrics to the DogStatsD agent.")
+	flag.StringVar(&emfNamespace, "emf-namespace", "", "CloudWatch namespace; if set, the exporter metrics are written to stdout in CloudWatch Embedded Metric Format.")
+	flag.DurationVar(&emfInterval, "emf-interval", 60*time.Second, "How often to write metrics in CloudWatch Embedded Metric Format.")
+	flag.DurationVar(&appProbeInterval, "app-probe-interval", 0, "How often to send an HTTP request to every application to check that it answers (0 = disabled).")
+	flag.DurationVar(&appProbeTimeout, "app-probe-timeout", 10*time.Second, "Timeout of a single application probe.")
+	flag.StringVar(&appProbeTarget, "app-probe-target", probe.TargetPublicAddr, "Address to probe the applications at: public_addr (through the Teleport proxy, with an application certificate issued to the exporter) or uri (the address the Teleport agent forwards to).")
+	flag.DurationVar(&dbProbeInterval, "database-probe-interval", 0, "How often to log in to every selected PostgreSQL and CockroachDB database through the Teleport proxy and database agents, to check that they serve it (0 = disabled).")
+	flag.DurationVar(&dbProbeTimeout, "database-probe-timeout", 5*time.Second, "Timeout of a single database probe.")
+	flag.StringVar(&dbProbeSelector, "database-probe-selector", "", "Comma-separated list of key=value Teleport labels a database must have to be probed (all databases if empty).")
+	flag.StringVar(&dbProbeUser, "database-probe-user", "", "Database user the database probes log in as, required with --database-probe-interval.")
+	flag.StringVar(&dbProbeDatabase, "database-probe-database", "postgres", "Database the database probes log in to.")
+	flag.StringVar(&shardFlag, "shard", "", "Collect only a share of the resource types, as N/M for replica N (0-based) of M, to spread the API load of large clusters across replicas.")
+	flag.StringVar(&extraResources, "extra-resources", "", "Comma-separated list of optional resource types to collect, which need additional permissions: users, locks, roles, tokens, access_requests, sessions, remote_clusters, cert_authorities.")
+	flag.StringVar(&cacheTTLs, "cache-ttl", "", "Serve cached Teleport API results up to this age and refresh them in the background afterwards, as a duration for all resource types and/or resource=duration pairs (e.g., 5m,nodes=15m). Disabled if empty.")
+	flag.IntVar(&maxConcurrentCalls, "max-concurrent-api-calls", 0, "Maximum number of Teleport API calls in flight at the same time, including background cache refreshes (0 = unlimited).")
+	flag.IntVar(&listPageSize, "list-page-size", 0, "Number of resources fetched per page when listing resources, up to 1000; smaller pages need less memory, larger pages fewer round trips (0 = Teleport default of 1000).")
+	flag.StringVar(&namespaces, "teleport-namespace", apidefaults.Namespace, "Comma-separated list of Teleport namespaces to list resources in, for clusters that still use non-default namespaces.")
+	flag.StringVar(&redactFields, "redact-fields", "", "Comma-separated list of fields to redact in the *_info metrics and the inventory endpoints: hostname, address, public_addr, uri.")
+	flag.StringVar(&redactMode, "redact-mode", collector.RedactModeHash, "How to redact --redact-fields: hash (replace by a short SHA-256 hash) or drop (replace by an empty string).")
+	flag.DurationVar(&deniedInterval, "permission-denied-retry-interval", 30*time.Minute, "How long a resource type is not collected after Teleport denied access to it; it does not count as a collection error meanwhile (0 = treat access denied like any other error).")
+	flag.BoolVar(&countsOnly, "counts-only", false, "Only export totals and breakdown counts, without the per-resource *_info metrics.")
+	flag.BoolVar(&nodeInventory, "node-inventory", false, "Keep the nodes of the last collection in memory to serve them on /api/v1/nodes. Without it, the nodes are counted page by page while listing them and then dropped, so that memory does not grow with the number of nodes, unless state-file or a cache-ttl for nodes need them all.")
+	flag.StringVar(&stateFile, "state-file", "", "Path of a file to save the last collected inventory to and restore it from on startup, so that a restart does not blank the *_info metrics.")
+	flag.BoolVar(&perServer, "per-server-metrics", false, "Also export one *_server_info series per agent serving a Kubernetes cluster, database or application.")
+	flag.BoolVar(&userWithoutMFAInfo, "user-without-mfa-info", false, "Also export one user_without_mfa_info series per local user without an MFA device, if users are collected.")
+	flag.BoolVar(&userLastLogin, "user-last-login", false, "Also export the time each SSO user last logged in as user_last_login_timestamp_seconds, if users are collected.")
+	flag.DurationVar(&accessRequestSLA, "access-request-sla", 4*time.Hour, "Age after which pending access requests count towards teleport_exporter_access_requests_sla_breached_total, if access requests are collected.")
+	flag.BoolVar(&mockMode, "mock", false, "Export synthetic resources without contacting Teleport, e.g. to build dashboards or load test Prometheus.")
+	flag.StringVar(&mockResources, "mock-resources", mock.DefaultCounts, "Number of synthetic resources per type in --mock mode, as comma-separated resource=count pairs of nodes, kubernetes_clusters, databases, apps, users, locks, roles, tokens, access_requests, sessions, remote_clusters and cert_authorities.")
+	flag.Float64Var(&mockChurn, "mock-churn", 0, "Fraction of the synthetic resources of each type replaced by new ones on every collection in --mock mode, to load test series churn (0-1).")
+	flag.StringVar(&recordDir, "record-dir", "", "Debug: directory to record the responses of the Teleport API calls to, to replay them with --replay-dir.")
+	flag.StringVar(&replayDir, "replay-dir", "", "Debug: serve the responses recorded with --record-dir from this directory instead of contacting Teleport.")
+	flag.StringVar(&faultInjection, "fault-injection", "", "Debug: inject errors into the Teleport API calls, as comma-separated method=fault[:count] pairs, e.g. GetNodes=timeout:3. Without a count, every call fails.")
 	flag.Parse()
 
 	// Handle version flag
@@ -95,55 +329,716 @@ func main() {
 		"goVersion", v.GoVersion,
 	)
 
-	if teleportAddr == "" {
+	var identitySource *awsidentity.Source
+	var identityContent func() string
+	if identityAWSSecret != "" || identityAWSParameter != "" {
+		if identityAWSRefresh <= 0 {
+			log.Error(nil, "identity-aws-refresh-interval must be positive")
+			os.Exit(1)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+		src, err := awsidentity.New(ctx, awsidentity.Config{
+			SecretID:      identityAWSSecret,
+			ParameterName: identityAWSParameter,
+			Interval:      identityAWSRefresh,
+			Log:           log.WithName("aws-identity"),
+		})
+		cancel()
+		if err != nil {
+			log.Error(err, "failed to load identity from AWS")
+			os.Exit(1)
+		}
+		identitySource, identityContent = src, src.Identity
+	}
+
+	mockCounts, err := mock.ParseCounts(mockResources)
+	if err != nil {
+		log.Error(err, "invalid mock-resources")
+		os.Exit(1)
+	}
+
+	if mockChurn < 0 || mockChurn > 1 {
+		log.Error(nil, "mock-churn must be between 0 and 1")
+		os.Exit(1)
+	}
+	if mockMode && replayDir != "" {
+		log.Error(nil, "mock and replay-dir are mutually exclusive")
+		os.Exit(1)
+	}
+	if recordDir != "" && (mockMode || replayDir != "") {
+		log.Error(nil, "record-dir requires a connection to Teleport and cannot be combined with mock or replay-dir")
+		os.Exit(1)
+	}
+	faults, err := teleport.ParseFaults(faultInjection)
+	if err != nil {
+		log.Error(err, "invalid fault-injection")
+		os.Exit(1)
+	}
+	if faults != nil && (mockMode || replayDir != "") {
+		log.Error(nil, "fault-injection applies to the Teleport client and cannot be combined with mock or replay-dir")
+		os.Exit(1)
+	}
+	// standalone is whether the exporter runs without contacting Teleport
+	standalone := mockMode || replayDir != ""
+
+	// The standalone modes do not connect to Teleport, so they need no
+	// credentials
+	if err := teleport.ValidateCredentials(teleport.Config{
+		IdentityFile:    identityFile,
+		IdentityContent: identityContent,
+		CertFile:        certFile,
+		KeyFile:         keyFile,
+		CAFile:          credsCAFile,
+		Profile:         profileName,
+	}); err != nil && !standalone {
+		log.Error(err, "invalid credentials")
+		os.Exit(1)
+	}
+
+	if teleportAddr == "" && profileName != "" {
+		// Default to the proxy the profile was logged in to
+		addr, err := teleport.ProfileProxyAddr(profileDir, profileName)
+		if err != nil {
+			log.Error(err, "invalid profile")
+			os.Exit(1)
+		}
+		teleportAddr = addr
+	}
+
+	if teleportAddr == "" && !standalone {
 		log.Error(nil, "teleport-addr is required")
 		os.Exit(1)
 	}
 
-	if identityFile == "" {
-		log.Error(nil, "identity-file is required")
+	minTLSVersion, err := teleport.ParseTLSVersion(tlsMinVersion)
+	if err != nil {
+		log.Error(err, "invalid tls-min-version")
+		os.Exit(1)
+	}
+	cipherSuites, err := teleport.ParseCipherSuites(tlsCiphers)
+	if err != nil {
+		log.Error(err, "invalid tls-cipher-suites")
+		os.Exit(1)
+	}
+
+	tlsConfig, err := newTLSConfig(tlsCertFile, tlsKeyFile, minTLSVersion, cipherSuites)
+	if err != nil {
+		log.Error(err, "invalid TLS configuration")
+		os.Exit(1)
+	}
+	metricsTLSConfig, err := withClientCA(tlsConfig, tlsClientCAFile)
+	if err != nil {
+		log.Error(err, "invalid TLS client CA configuration")
+		os.Exit(1)
+	}
+
+	if webConfigFile != "" {
+		if tlsClientCAFile != "" || metricsBearerTokenFile != "" || metricsHtpasswdFile != "" {
+			log.Error(nil, "web.config.file cannot be combined with tls-client-ca-file, metrics-bearer-token-file or metrics-htpasswd-file")
+			os.Exit(1)
+		}
+		if err := web.Validate(webConfigFile); err != nil {
+			log.Error(err, "invalid web configuration file")
+			os.Exit(1)
+		}
+		// The web configuration file takes care of TLS for the metrics endpoint
+		metricsTLSConfig = nil
+	}
+
+	// The credential files are loaded again on reloads
+	metricsAuth := &httpauth.Files{
+		BearerTokenFile: metricsBearerTokenFile,
+		HtpasswdFile:    metricsHtpasswdFile,
+	}
+	if err := metricsAuth.Load(); err != nil {
+		log.Error(err, "failed to load metrics credentials")
+		os.Exit(1)
+	}
+
+	// Anyone able to reach the lifecycle endpoints can make the exporter hammer
+	// the Teleport API or reconnect, so they are only served with authentication
+	if enableLifecycle {
+		authenticated := metricsAuth.Enabled() || tlsClientCAFile != ""
+		if webConfigFile != "" {
+			authenticated, err = webConfigAuthenticates(webConfigFile)
+			if err != nil {
+				log.Error(err, "invalid web configuration file")
+				os.Exit(1)
+			}
+		}
+		if !authenticated {
+			log.Error(nil, "web.enable-lifecycle requires authentication of the metrics endpoint: set tls-client-ca-file, metrics-bearer-token-file or metrics-htpasswd-file, or configure basic_auth_users or client certificates in web.config.file")
+			os.Exit(1)
+		}
+	}
+
+	headers, err := otlp.ParseHeaders(otlpHeaders)
+	if err != nil {
+		log.Error(err, "invalid OTLP headers")
+		os.Exit(1)
+	}
+
+	shard, err := collector.ParseShard(shardFlag)
+	if err != nil {
+		log.Error(err, "invalid shard")
+		os.Exit(1)
+	}
+
+	extraResourceList, err := collector.ParseExtraResources(extraResources)
+	if err != nil {
+		log.Error(err, "invalid extra resources")
+		os.Exit(1)
+	}
+
+	redaction, err := collector.ParseRedaction(redactFields, redactMode)
+	if err != nil {
+		log.Error(err, "invalid redaction")
+		os.Exit(1)
+	}
+
+	dbProbeLabels, err := probe.ParseSelector(dbProbeSelector)
+	if err != nil {
+		log.Error(err, "invalid database probe selector")
+		os.Exit(1)
+	}
+
+	cacheTTLMap, err := teleport.ParseCacheTTLs(cacheTTLs)
+	if err != nil {
+		log.Error(err, "invalid cache TTLs")
+		os.Exit(1)
+	}
+	// The metrics are computed one node at a time, so the full list of nodes
+	// is only held for the consumers that need it
+	streamNodes := !nodeInventory && stateFile == "" && cacheTTLMap[teleport.CacheNodes] <= 0
+
+	var outboundProxy *url.URL
+	if proxyURL != "" {
+		outboundProxy, err = teleport.ParseProxyURL(proxyURL)
+		if err != nil {
+			log.Error(err, "invalid proxy URL")
+			os.Exit(1)
+		}
+		if alpnConnUpgrade {
+			log.Error(nil, "alpn-conn-upgrade cannot be combined with proxy-url, set HTTPS_PROXY instead")
+			os.Exit(1)
+		}
+	}
+
+	if caFile != "" {
+		if err := teleport.UseCAFile(caFile); err != nil {
+			log.Error(err, "invalid Teleport CA file")
+			os.Exit(1)
+		}
+	}
+
+	if dialTimeout < 0 || keepAliveTime < 0 || keepAliveTO < 0 || idleTimeout < 0 {
+		log.Error(nil, "dial-timeout, keepalive-time, keepalive-timeout and grpc-idle-timeout must not be negative")
+		os.Exit(1)
+	}
+
+	if healthCheckInterval < 0 || caRotationInterval < 0 {
+		log.Error(nil, "health-check-interval and ca-rotation-check-interval must not be negative")
+		os.Exit(1)
+	}
+
+	if maxRecvMsgSize < 0 {
+		log.Error(nil, "grpc-max-recv-msg-size must not be negative", "maxRecvMsgSize", maxRecvMsgSize)
+		os.Exit(1)
+	}
+
+	if refreshJitter < 0 || refreshJitter >= 1 {
+		log.Error(nil, "refresh-jitter must be at least 0 and less than 1", "refreshJitter", refreshJitter)
+		os.Exit(1)
+	}
+
+	if deniedInterval < 0 {
+		log.Error(nil, "permission-denied-retry-interval must not be negative")
+		os.Exit(1)
+	}
+
+	if listPageSize < 0 || listPageSize > apidefaults.DefaultChunkSize {
+		log.Error(nil, "list-page-size must be between 0 and 1000", "listPageSize", listPageSize)
+		os.Exit(1)
+	}
+
+	if apiRetries < 0 {
+		log.Error(nil, "api-retries must not be negative")
+		os.Exit(1)
+	}
+
+	if collectTimeout < 0 {
+		log.Error(nil, "collect-timeout must not be negative")
+		os.Exit(1)
+	}
+	if collectTimeout == 0 {
+		collectTimeout = refreshInterval
+	}
+
+	if readinessMaxAge == 0 {
+		readinessMaxAge = 3 * refreshInterval
+	}
+	if livenessMaxAge == 0 {
+		livenessMaxAge = collector.DefaultLivenessMaxAge(apiTimeout, collectTimeout, refreshInterval)
+	}
+
+	infoLabels := metrics.InfoLabels{
+		Node:        splitList(nodeLabels),
+		KubeCluster: splitList(kubeClusterLabels),
+		Database:    splitList(databaseLabels),
+		App:         splitList(appLabels),
+	}
+	groupBy := collector.GroupBy{
+		Node:        collector.ParseGroupBy(nodeGroupBy),
+		KubeCluster: collector.ParseGroupBy(kubeClusterGroupBy),
+		Database:    collector.ParseGroupBy(databaseGroupBy),
+		App:         collector.ParseGroupBy(appGroupBy),
+	}
+	// Use a dedicated registry so that only the enabled collectors are exported
+	registry := prometheus.NewRegistry()
+	if enableGoCollector {
+		registry.MustRegister(collectors.NewGoCollector())
+	}
+	if enableProcessCollector {
+		registry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
+	if err := metrics.Setup(registry, metrics.Options{
+		Namespace:  metricsNamespace,
+		InfoLabels: infoLabels,
+	}); err != nil {
+		log.Error(err, "invalid metrics configuration")
 		os.Exit(1)
 	}
 
 	log.Info("Configuration",
 		"teleportAddr", teleportAddr,
+		"profile", profileName,
+		"identityAWSSecret", identityAWSSecret,
+		"identityAWSParameter", identityAWSParameter,
+		"certFile", certFile,
 		"metricsAddr", metricsAddr,
 		"probeAddr", probeAddr,
+		"enableLifecycle", enableLifecycle,
+		"connectionMode", connectionMode,
+		"teleportCAFile", caFile,
+		"proxyURL", redactURL(proxyURL),
+		"alpnConnUpgrade", alpnConnUpgrade,
 		"refreshInterval", refreshInterval,
+		"refreshJitter", refreshJitter,
+		"collectOnStart", collectOnStart,
+		"collectTimeout", collectTimeout,
 		"apiTimeout", apiTimeout,
+		"apiRetries", apiRetries,
+		"dialTimeout", dialTimeout,
+		"keepAliveTime", keepAliveTime,
+		"keepAliveTimeout", keepAliveTO,
+		"grpcIdleTimeout", idleTimeout,
+		"healthCheckInterval", healthCheckInterval,
+		"caRotationCheckInterval", caRotationInterval,
+		"grpcMaxRecvMsgSize", maxRecvMsgSize,
+		"readinessMaxAge", readinessMaxAge,
+		"livenessMaxAge", livenessMaxAge,
+		"metricsNamespace", metricsNamespace,
+		"infoLabels", infoLabels,
+		"groupBy", groupBy,
+		"requiredLabels", splitList(requiredLabels),
+		"maxSeriesPerMetric", maxSeriesPerMetric,
+		"countsOnly", countsOnly,
+		"nodeInventory", nodeInventory,
+		"streamNodes", streamNodes,
+		"perServerMetrics", perServer,
+		"userWithoutMFAInfo", userWithoutMFAInfo,
+		"userLastLogin", userLastLogin,
+		"accessRequestSLA", accessRequestSLA,
+		"stateFile", stateFile,
+		"mock", mockMode,
+		"mockResources", mockResources,
+		"mockChurn", mockChurn,
+		"recordDir", recordDir,
+		"replayDir", replayDir,
+		"faultInjection", faultInjection,
+		"permissionDeniedRetryInterval", deniedInterval,
+		"redactFields", redactFields,
+		"redactMode", redactMode,
+		"shard", shard.String(),
+		"extraResources", extraResourceList,
+		"cacheTTLs", cacheTTLMap,
+		"maxConcurrentAPICalls", maxConcurrentCalls,
+		"listPageSize", listPageSize,
+		"teleportNamespaces", namespaces,
+		"tls", tlsConfig != nil,
+		"tlsClientAuth", tlsClientCAFile != "",
+		"tlsMinVersion", tlsMinVersion,
+		"tlsCipherSuites", tlsCiphers,
+		"metricsAuth", metricsAuth.Enabled(),
+		"webConfigFile", webConfigFile,
+		"goCollector", enableGoCollector,
+		"processCollector", enableProcessCollector,
+		"otlpEndpoint", otlpEndpoint,
+		"otlpProtocol", otlpProtocol,
+		"otlpInterval", otlpInterval,
+		"pushgatewayURL", pushgatewayURL,
+		"statsdAddress", statsdAddress,
+		"emfNamespace", emfNamespace,
+		"appProbeInterval", appProbeInterval,
+		"appProbeTimeout", appProbeTimeout,
+		"appProbeTarget", appProbeTarget,
+		"databaseProbeInterval", dbProbeInterval,
+		"databaseProbeTimeout", dbProbeTimeout,
+		"databaseProbeSelector", dbProbeSelector,
+		"databaseProbeUser", dbProbeUser,
+		"databaseProbeDatabase", dbProbeDatabase,
 	)
 
 	// Create Teleport client
-	teleportClient, err := teleport.NewClient(teleport.Config{
-		ProxyAddr:    teleportAddr,
-		IdentityFile: identityFile,
-		Insecure:     insecure,
-		APITimeout:   apiTimeout,
-		Log:          log.WithName("teleport-client"),
-	})
-	if err != nil {
-		log.Error(err, "failed to create Teleport client")
-		os.Exit(1)
+	teleportConfig := teleport.Config{
+		ProxyAddr:          teleportAddr,
+		IdentityFile:       identityFile,
+		IdentityContent:    identityContent,
+		CertFile:           certFile,
+		KeyFile:            keyFile,
+		CAFile:             credsCAFile,
+		Profile:            profileName,
+		ProfileDir:         profileDir,
+		Insecure:           insecure,
+		ProxyURL:           outboundProxy,
+		ConnectionMode:     connectionMode,
+		ALPNConnUpgrade:    alpnConnUpgrade,
+		TLSMinVersion:      minTLSVersion,
+		TLSCipherSuites:    cipherSuites,
+		APITimeout:         apiTimeout,
+		DialTimeout:        dialTimeout,
+		KeepAliveTime:      keepAliveTime,
+		KeepAliveTimeout:   keepAliveTO,
+		IdleTimeout:        idleTimeout,
+		MaxRecvMsgSize:     maxRecvMsgSize,
+		CacheTTLs:          cacheTTLMap,
+		MaxConcurrentCalls: maxConcurrentCalls,
+		ListPageSize:       listPageSize,
+		Namespaces:         teleport.ParseNamespaces(namespaces),
+		Faults:             faults,
+		Log:                log.WithName("teleport-client"),
+	}
+	var (
+		teleportClient *teleport.Client
+		// collectorClient is the client the collector lists the resources
+		// with: the Teleport client, the synthetic resources in mock mode or
+		// the recorded responses in replay mode
+		collectorClient collector.TeleportClient
+		connected       bool
+	)
+	switch {
+	case mockMode:
+		log.Info("mock mode, exporting synthetic resources without contacting Teleport", "resources", mockCounts, "churn", mockChurn)
+		teleportClient = teleport.NewDisconnectedClient(teleportConfig)
+		collectorClient = mock.New(mockCounts)
+		if mockChurn > 0 {
+			collectorClient = mock.NewChurning(mockCounts, mockChurn)
+		}
+	case replayDir != "":
+		player, err := replay.Load(replayDir)
+		if err != nil {
+			log.Error(err, "failed to load the recorded responses")
+			os.Exit(1)
+		}
+		log.Info("replay mode, serving recorded responses without contacting Teleport", "dir", replayDir)
+		teleportClient = teleport.NewDisconnectedClient(teleportConfig)
+		collectorClient = player
+	default:
+		teleportClient, err = teleport.NewClient(teleportConfig)
+		connected = err == nil
+		if !connected {
+			if once || pushgatewayURL != "" {
+				log.Error(err, "failed to create Teleport client")
+				os.Exit(1)
+			}
+			// Serve the probes and metrics meanwhile, so that Teleport being
+			// unreachable, e.g. restarting during a deploy, does not
+			// crash-loop the exporter
+			log.Error(err, "failed to connect to Teleport, retrying in the background")
+			teleportClient = teleport.NewDisconnectedClient(teleportConfig)
+			metrics.TeleportUp.Set(0)
+		}
+		collectorClient = teleportClient
+		if recordDir != "" {
+			recorder, err := replay.NewRecorder(teleportClient, recordDir, log.WithName("recorder"))
+			if err != nil {
+				log.Error(err, "failed to create the record directory")
+				os.Exit(1)
+			}
+			log.Info("recording the responses of the Teleport API calls", "dir", recordDir)
+			collectorClient = recorder
+		}
 	}
 	defer teleportClient.Close()
 
 	// Create and start the collector
 	col := collector.New(collector.Config{
-		TeleportClient:  teleportClient,
-		RefreshInterval: refreshInterval,
-		APITimeout:      apiTimeout,
-		Log:             log.WithName("collector"),
+		TeleportClient:           collectorClient,
+		RefreshInterval:          refreshInterval,
+		JitterFraction:           refreshJitter,
+		CollectOnStart:           collectOnStart,
+		CollectTimeout:           collectTimeout,
+		APITimeout:               apiTimeout,
+		Retries:                  apiRetries,
+		MaxSeriesPerMetric:       maxSeriesPerMetric,
+		InfoLabels:               infoLabels,
+		Shard:                    shard,
+		ExtraResources:           extraResourceList,
+		Redaction:                redaction,
+		CountsOnly:               countsOnly,
+		PerServer:                perServer,
+		UserWithoutMFAInfo:       userWithoutMFAInfo,
+		UserLastLogin:            userLastLogin,
+		AccessRequestSLA:         accessRequestSLA,
+		GroupBy:                  groupBy,
+		RequiredLabels:           splitList(requiredLabels),
+		PermissionDeniedInterval: deniedInterval,
+		StateFile:                stateFile,
+		StreamNodes:              streamNodes,
+		Log:                      log.WithName("collector"),
 	})
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Start the collector
-	go col.Run(ctx)
+	// In one-shot mode, write and/or push the result of a single collection
+	// and exit
+	if once || pushgatewayURL != "" {
+		err := col.CollectOnce(ctx)
+		if once {
+			err = errors.Join(err, writeMetrics(os.Stdout, registry))
+		}
+		if pushgatewayURL != "" {
+			err = errors.Join(err, pushMetrics(ctx, registry, pushgatewayURL, pushgatewayJob, log))
+		}
+		teleportClient.Close()
+		if err != nil {
+			log.Error(err, "one-shot collection failed")
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Export the inventory of the previous run until the first collection
+	if stateFile != "" {
+		if err := col.RestoreState(); err != nil {
+			log.Error(err, "failed to restore the inventory from the state file")
+		}
+	}
+
+	// Report which resource types the role of the identity may read, instead
+	// of only failing every collection with access denied errors
+	if connected || standalone {
+		col.CheckAccess(ctx)
+	}
+
+	// Start the collector and the probers. Shutdown waits for the in-flight
+	// collection and probes, so that they cannot overwrite the terminal
+	// state.
+	var workers sync.WaitGroup
+	workers.Go(func() { col.Run(ctx) })
+	// Optionally check that the applications answer HTTP requests
+	if appProbeInterval > 0 {
+		// Without Teleport, the probes of the public addresses cannot get
+		// past the login page of the proxy
+		var appCerts probe.AppCertIssuer
+		if !mockMode && replayDir == "" {
+			appCerts = teleportClient
+		}
+		prober, err := probe.NewAppProber(probe.AppConfig{
+			Source:   collectorClient,
+			Interval: appProbeInterval,
+			Timeout:  appProbeTimeout,
+			Target:   appProbeTarget,
+			Certs:    appCerts,
+			Log:      log.WithName("probe"),
+		})
+		if err != nil {
+			log.Error(err, "invalid application probe configuration")
+			os.Exit(1)
+		}
+		workers.Go(func() { prober.Run(ctx) })
+	}
+	// Optionally check that the databases accept logins through Teleport
+	if dbProbeInterval > 0 {
+		var dbAccess probe.DatabaseAccess
+		if !mockMode && replayDir == "" {
+			dbAccess = teleportClient
+		}
+		prober, err := probe.NewDatabaseProber(probe.DatabaseConfig{
+			Source:    collectorClient,
+			Access:    dbAccess,
+			Interval:  dbProbeInterval,
+			Timeout:   dbProbeTimeout,
+			Selector:  dbProbeLabels,
+			User:      dbProbeUser,
+			Database:  dbProbeDatabase,
+			TLSConfig: &tls.Config{InsecureSkipVerify: insecure},
+			Log:       log.WithName("probe"),
+		})
+		if err != nil {
+			log.Error(err, "invalid database probe configuration")
+			os.Exit(1)
+		}
+		workers.Go(func() { prober.Run(ctx) })
+	}
+	// Reload the credentials on SIGHUP and /-/reload, like Prometheus
+	rl := &reloader{
+		metricsAuth:    metricsAuth,
+		identitySource: identitySource,
+		client:         collectorClient,
+		log:            log.WithName("reload"),
+	}
+	metrics.ConfigLastReloadSuccessful.Set(1)
+	metrics.ConfigLastReloadSuccessTime.SetToCurrentTime()
+	reloadCh := make(chan os.Signal, 1)
+	signal.Notify(reloadCh, syscall.SIGHUP)
+	go func() {
+		for range reloadCh {
+			rl.log.Info("received SIGHUP")
+			rl.reload(ctx)
+		}
+	}()
+	if len(collectSignals) > 0 {
+		collectCh := make(chan os.Signal, 1)
+		signal.Notify(collectCh, collectSignals...)
+		go func() {
+			for sig := range collectCh {
+				log.Info("received signal, triggering a collection", "signal", sig.String())
+				col.Trigger()
+			}
+		}()
+	}
+	if !standalone {
+		go func() {
+			if !connected {
+				if !teleportClient.ConnectWithRetry(ctx) {
+					return
+				}
+				col.CheckAccess(ctx)
+			}
+			teleportClient.WatchConnectionState(ctx)
+		}()
+	}
+	if healthCheckInterval > 0 && !standalone {
+		go teleportClient.MonitorHealth(ctx, healthCheckInterval)
+	}
+	if caRotationInterval > 0 && !standalone {
+		// Pick up the certificates renewed for the new CA, e.g. by tbot,
+		// before the previous CA is no longer trusted
+		go teleportClient.WatchCARotation(ctx, caRotationInterval, func() { rl.reload(ctx) })
+	}
+	if identitySource != nil {
+		// Connect with the renewed certificates right away
+		go identitySource.Run(ctx, func() {
+			if err := collectorClient.Reconnect(ctx); err != nil {
+				log.Error(err, "failed to reconnect with the renewed identity")
+			}
+		})
+	}
+
+	// The push exporters run until the terminal state is set on shutdown, see
+	// setTerminalState
+	pushCtx, stopPush := context.WithCancel(context.Background())
+	defer stopPush()
+	var pushers sync.WaitGroup
+
+	// Optionally push all metrics to an OpenTelemetry collector
+	otlpShutdown := func(context.Context) error { return nil }
+	if otlpEndpoint != "" {
+		otlpShutdown, err = otlp.Start(ctx, otlp.Config{
+			Endpoint: otlpEndpoint,
+			Protocol: otlpProtocol,
+			Headers:  headers,
+			Insecure: otlpInsecure,
+			Interval: otlpInterval,
+			Gatherer: registry,
+		})
+		if err != nil {
+			log.Error(err, "failed to start OTLP metrics export")
+			os.Exit(1)
+		}
+	}
+
+	// Optionally mirror the exporter metrics to a DogStatsD agent
+	if statsdAddress != "" {
+		emitter, err := statsd.New(statsd.Config{
+			Address:  statsdAddress,
+			Interval: statsdInterval,
+			Prefix:   metricsNamespace + "_",
+			Gatherer: registry,
+			Log:      log.WithName("statsd"),
+		})
+		if err != nil {
+			log.Error(err, "failed to start StatsD emitter")
+			os.Exit(1)
+		}
+		pushers.Go(func() { emitter.Run(pushCtx) })
+	}
+
+	// Optionally write the exporter metrics to stdout for CloudWatch; logs go
+	// to stderr and do not interfere
+	if emfNamespace != "" {
+		writer := emf.New(emf.Config{
+			Output:    os.Stdout,
+			Namespace: emfNamespace,
+			Interval:  emfInterval,
+			Prefix:    metricsNamespace + "_",
+			Gatherer:  registry,
+			Log:       log.WithName("emf"),
+		})
+		pushers.Go(func() { writer.Run(pushCtx) })
+	}
 
 	// Set up metrics server with security hardening
 	metricsMux := http.NewServeMux()
-	metricsMux.Handle("/metrics", promhttp.Handler())
+	metricsHandler := promhttp.InstrumentMetricHandler(registry, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	metricsMux.Handle("/metrics", metricsAuth.Handler(metricsHandler))
+	// Also serve the probes here so the landing page links work. The verbose
+	// readiness lists the errors of the collection, so protect them like
+	// /metrics and only serve the terse status on the unprotected probe
+	// endpoint the kubelet uses.
+	metricsMux.Handle("/healthz", metricsAuth.Handler(healthHandler(col, livenessMaxAge)))
+	metricsMux.Handle("/readyz", metricsAuth.Handler(readyHandler(col, teleportClient, readinessMaxAge, true)))
+	// The inventory contains the same data as the *_info metrics, so protect
+	// it like /metrics
+	if !streamNodes {
+		metricsMux.Handle("/api/v1/nodes", metricsAuth.Handler(inventoryHandler(col, log, func(inv collector.Inventory) any { return inv.Nodes })))
+	}
+	metricsMux.Handle("/api/v1/kubernetes_clusters", metricsAuth.Handler(inventoryHandler(col, log, func(inv collector.Inventory) any { return inv.KubeClusters })))
+	metricsMux.Handle("/api/v1/databases", metricsAuth.Handler(inventoryHandler(col, log, func(inv collector.Inventory) any { return inv.Databases })))
+	metricsMux.Handle("/api/v1/apps", metricsAuth.Handler(inventoryHandler(col, log, func(inv collector.Inventory) any { return inv.Apps })))
+	metricsMux.Handle("/api/v1/users", metricsAuth.Handler(inventoryHandler(col, log, func(inv collector.Inventory) any { return inv.Users })))
+	metricsMux.Handle("/api/v1/locks", metricsAuth.Handler(inventoryHandler(col, log, func(inv collector.Inventory) any { return inv.Locks })))
+	metricsMux.Handle("/api/v1/roles", metricsAuth.Handler(inventoryHandler(col, log, func(inv collector.Inventory) any { return inv.Roles })))
+	metricsMux.Handle("/api/v1/tokens", metricsAuth.Handler(inventoryHandler(col, log, func(inv collector.Inventory) any { return inv.Tokens })))
+	metricsMux.Handle("/api/v1/access_requests", metricsAuth.Handler(inventoryHandler(col, log, func(inv collector.Inventory) any { return inv.AccessRequests })))
+	metricsMux.Handle("/api/v1/sessions", metricsAuth.Handler(inventoryHandler(col, log, func(inv collector.Inventory) any { return inv.Sessions })))
+	metricsMux.Handle("/api/v1/remote_clusters", metricsAuth.Handler(inventoryHandler(col, log, func(inv collector.Inventory) any { return inv.RemoteClusters })))
+	metricsMux.Handle("/api/v1/cert_authorities", metricsAuth.Handler(inventoryHandler(col, log, func(inv collector.Inventory) any { return inv.CertAuthorities })))
+	// Triggered collections and reloads call the Teleport API, so they are
+	// opt-in like the lifecycle endpoints of Prometheus and protected too
+	if enableLifecycle {
+		metricsMux.Handle("/-/collect", metricsAuth.Handler(collectHandler(col)))
+		metricsMux.Handle("/-/reload", metricsAuth.Handler(reloadHandler(rl)))
+	}
+
+	landingPage, err := web.NewLandingPage(web.LandingConfig{
+		Name:        "Teleport Exporter",
+		Description: fmt.Sprintf("Prometheus exporter for the Teleport cluster at %s", teleportAddr),
+		Version:     version.Get().Version,
+		Profiling:   "false",
+		Links: []web.LandingLinks{
+			{Address: "/metrics", Text: "Metrics"},
+			{Address: "/healthz", Text: "Liveness"},
+			{Address: "/readyz", Text: "Readiness"},
+		},
+	})
+	if err != nil {
+		log.Error(err, "failed to create landing page")
+		os.Exit(1)
+	}
+	metricsMux.Handle("/", landingPage)
 
 	metricsServer := &http.Server{
 		Addr:           metricsAddr,
@@ -152,12 +1047,13 @@ func main() {
 		WriteTimeout:   httpWriteTimeout,
 		IdleTimeout:    httpIdleTimeout,
 		MaxHeaderBytes: httpMaxHeaderBytes,
+		TLSConfig:      metricsTLSConfig,
 	}
 
 	// Set up health probe server with security hardening
 	probeMux := http.NewServeMux()
-	probeMux.HandleFunc("/healthz", healthHandler)
-	probeMux.HandleFunc("/readyz", readyHandler(teleportClient))
+	probeMux.HandleFunc("/healthz", healthHandler(col, livenessMaxAge))
+	probeMux.HandleFunc("/readyz", readyHandler(col, teleportClient, readinessMaxAge, false))
 
 	probeServer := &http.Server{
 		Addr:           probeAddr,
@@ -166,19 +1062,31 @@ func main() {
 		WriteTimeout:   httpWriteTimeout,
 		IdleTimeout:    httpIdleTimeout,
 		MaxHeaderBytes: httpMaxHeaderBytes,
+		TLSConfig:      tlsConfig,
 	}
 
 	// Start servers
 	go func() {
 		log.Info("starting metrics server", "addr", metricsAddr)
-		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if webConfigFile != "" {
+			systemdSocket := false
+			err = web.ListenAndServe(metricsServer, &web.FlagConfig{
+				WebListenAddresses: &[]string{metricsAddr},
+				WebSystemdSocket:   &systemdSocket,
+				WebConfigFile:      &webConfigFile,
+			}, slog.New(logr.ToSlogHandler(log.WithName("web"))))
+		} else {
+			err = serve(metricsServer)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Error(err, "metrics server failed")
 		}
 	}()
 
 	go func() {
 		log.Info("starting health probe server", "addr", probeAddr)
-		if err := probeServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := serve(probeServer); err != nil && err != http.ErrServerClosed {
 			log.Error(err, "health probe server failed")
 		}
 	}()
@@ -194,8 +1102,36 @@ func main() {
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
 	defer shutdownCancel()
 
-	// Shutdown servers gracefully
 	var shutdownErr error
+	stopped := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		log.Error(shutdownCtx.Err(), "failed to wait for the collector and probers to stop")
+		shutdownErr = shutdownCtx.Err()
+	}
+	setTerminalState()
+
+	// Push the terminal state once more, so that consumers of pushed metrics
+	// see a clean shutdown rather than stale values
+	stopPush()
+	pushed := make(chan struct{})
+	go func() {
+		pushers.Wait()
+		close(pushed)
+	}()
+	select {
+	case <-pushed:
+	case <-shutdownCtx.Done():
+		log.Error(shutdownCtx.Err(), "failed to push the terminal state")
+		shutdownErr = shutdownCtx.Err()
+	}
+
+	// Shutdown servers gracefully
 	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
 		log.Error(err, "failed to shutdown metrics server")
 		shutdownErr = err
@@ -204,6 +1140,10 @@ func main() {
 		log.Error(err, "failed to shutdown probe server")
 		shutdownErr = err
 	}
+	if err := otlpShutdown(shutdownCtx); err != nil {
+		log.Error(err, "failed to shutdown OTLP metrics export")
+		shutdownErr = err
+	}
 
 	if shutdownErr != nil {
 		log.Info("shutdown completed with errors")
@@ -212,19 +1152,285 @@ func main() {
 	log.Info("shutdown completed successfully")
 }
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("ok"))
+// setTerminalState marks the exporter as disconnected before the last scrapes
+// and pushes on shutdown.
+func setTerminalState() {
+	metrics.TeleportUp.Set(0)
+	metrics.ConnectionHealthy.Set(0)
 }
 
-func readyHandler(client *teleport.Client) http.HandlerFunc {
+// writeMetrics writes all metrics of g to w in the Prometheus text format.
+func writeMetrics(w io.Writer, g prometheus.Gatherer) error {
+	families, err := g.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+	enc := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			return fmt.Errorf("failed to write metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// pushMetrics pushes all metrics of g to the Pushgateway at url, also when
+// the collection failed, so that teleport_exporter_up and the error metrics
+// reach the Pushgateway.
+func pushMetrics(ctx context.Context, g prometheus.Gatherer, url, job string, log logr.Logger) error {
+	log.Info("pushing metrics to Pushgateway", "url", url, "job", job)
+	if err := push.New(url, job).Gatherer(g).PushContext(ctx); err != nil {
+		return fmt.Errorf("failed to push metrics to Pushgateway: %w", err)
+	}
+	return nil
+}
+
+// inventoryResponse is the response of the /api/v1 endpoints.
+type inventoryResponse struct {
+	ClusterName string `json:"clusterName"`
+	Items       any    `json:"items"`
+}
+
+// inventoryHandler responds with the resources selected by items from the
+// last collected inventory as JSON.
+func inventoryHandler(col *collector.Collector, log logr.Logger, items func(collector.Inventory) any) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if client.IsConnected() {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("ok"))
-		} else {
-			w.WriteHeader(http.StatusServiceUnavailable)
-			w.Write([]byte("not connected to Teleport"))
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		inv := col.Inventory()
+		resp := inventoryResponse{ClusterName: inv.ClusterName, Items: items(inv)}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Error(err, "failed to write inventory response", "path", r.URL.Path)
+		}
+	}
+}
+
+// collectHandler triggers a collection outside the schedule on POST requests.
+// It does not wait for the collection to finish.
+func collectHandler(col *collector.Collector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		if !col.Trigger() {
+			w.Write([]byte("collection already pending"))
+			return
+		}
+		w.Write([]byte("collection triggered"))
+	}
+}
+
+// reloader reloads the credentials of the exporter at runtime.
+type reloader struct {
+	mu             sync.Mutex
+	metricsAuth    *httpauth.Files
+	identitySource *awsidentity.Source
+	client         collector.TeleportClient
+	log            logr.Logger
+}
+
+// reload loads the metrics credential files again, refreshes the identity
+// from AWS and reconnects to Teleport with reloaded credentials. Credentials
+// that fail to load are kept. Flags are not reloaded; changing them requires
+// a restart.
+func (rl *reloader) reload(ctx context.Context) error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.log.Info("reloading credentials")
+	var errs []error
+	if err := rl.metricsAuth.Load(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to reload metrics credentials: %w", err))
+	}
+	if rl.identitySource != nil {
+		if err := rl.identitySource.Refresh(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to refresh identity from AWS: %w", err))
+		}
+	}
+	if err := rl.client.Reconnect(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to reconnect to Teleport: %w", err))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		rl.log.Error(err, "reload failed")
+		metrics.ConfigLastReloadSuccessful.Set(0)
+		return err
+	}
+	rl.log.Info("reloaded credentials")
+	metrics.ConfigLastReloadSuccessful.Set(1)
+	metrics.ConfigLastReloadSuccessTime.SetToCurrentTime()
+	return nil
+}
+
+// reloadHandler reloads the credentials on POST and PUT requests, like the
+// /-/reload endpoint of Prometheus. It fails with 500 if the reload failed.
+func reloadHandler(rl *reloader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodPut {
+			w.Header().Set("Allow", "POST, PUT")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		if err := rl.reload(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("ok"))
+	}
+}
+
+// healthHandler reports healthy as long as the collector loop heartbeat is not
+// older than maxAge, so that a collector stuck in an API call gets restarted.
+func healthHandler(col *collector.Collector, maxAge time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if age := time.Since(col.LastHeartbeat()); age > maxAge {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "collector loop has been stuck for %s", age.Round(time.Second))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	}
+}
+
+// readyStatus is the response of /readyz?verbose.
+type readyStatus struct {
+	Ready             bool   `json:"ready"`
+	ConnectionState   string `json:"connectionState"`
+	ConnectionHealthy bool   `json:"connectionHealthy"`
+	collector.Status
+}
+
+// readyHandler reports ready as long as the last successful collection is not
+// older than maxAge, and not ready before the first one. If allowVerbose is
+// set, it responds with the collector state as JSON to the verbose query
+// parameter.
+func readyHandler(col *collector.Collector, client *teleport.Client, maxAge time.Duration, allowVerbose bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lastSuccess := col.LastSuccess()
+		age := time.Since(lastSuccess)
+		status := http.StatusOK
+		if lastSuccess.IsZero() || age > maxAge {
+			status = http.StatusServiceUnavailable
+		}
+
+		if allowVerbose && r.URL.Query().Has("verbose") {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(readyStatus{
+				Ready:             status == http.StatusOK,
+				ConnectionState:   client.ConnectionState(),
+				ConnectionHealthy: client.IsConnected(),
+				Status:            col.Status(),
+			})
+			return
+		}
+
+		w.WriteHeader(status)
+		if lastSuccess.IsZero() {
+			w.Write([]byte("no successful collection yet"))
+			return
+		}
+		if status != http.StatusOK {
+			fmt.Fprintf(w, "last successful collection was %s ago", age.Round(time.Second))
+			return
+		}
+		w.Write([]byte("ok"))
+	}
+}
+
+// newTLSConfig returns the TLS configuration for the HTTP servers, or nil if no
+// certificate is configured.
+func newTLSConfig(certFile, keyFile string, minVersion uint16, cipherSuites []uint16) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("tls-cert-file and tls-key-file must be set together")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   minVersion,
+		CipherSuites: cipherSuites,
+	}, nil
+}
+
+// withClientCA returns a copy of cfg that requires client certificates signed
+// by the CAs in caFile, or cfg itself if caFile is empty.
+func withClientCA(cfg *tls.Config, caFile string) (*tls.Config, error) {
+	if caFile == "" {
+		return cfg, nil
+	}
+	if cfg == nil {
+		return nil, fmt.Errorf("tls-client-ca-file requires tls-cert-file and tls-key-file")
+	}
+
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in client CA file %s", caFile)
+	}
+
+	cfg = cfg.Clone()
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	return cfg, nil
+}
+
+// webConfigAuthenticates returns whether the exporter-toolkit web configuration
+// file at path requires basic auth or verified client certificates.
+func webConfigAuthenticates(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	var cfg web.Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return len(cfg.Users) > 0 || cfg.TLSConfig.ClientAuth == "RequireAndVerifyClientCert", nil
+}
+
+// serve starts srv, over HTTPS if it has a TLS configuration.
+func serve(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		// The certificate is already part of the TLS configuration
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
+// redactURL hides the password of a URL so it can be logged.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+	return u.Redacted()
+}
+
+// splitList splits a comma-separated flag value into its non-empty, trimmed elements.
+func splitList(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
 		}
 	}
+	return result
 }